@@ -2,12 +2,15 @@ package analyzer
 
 import (
 	commands "backend/commands"
+	stores "backend/stores"
+	structures "backend/structures"
 	"errors"
 	"fmt"
 	"strings"
 )
 
-func Analyzer(input string) (string, error) {
+// Analyzer ejecuta una línea de comando con la sesión del cliente que la envió
+func Analyzer(input string, session *stores.Session) (string, error) {
 
 	tokens := strings.Fields(input)
 
@@ -17,6 +20,23 @@ func Analyzer(input string) (string, error) {
 
 	command := strings.ToLower(tokens[0])
 
+	// Las peticiones corren en paralelo: el comando espera su turno en el disco o la partición que usa
+	unlock := lockCommand(command, tokens, session)
+	defer unlock()
+
+	output, err := dispatch(command, tokens, session)
+
+	// Los cambios quedan en la caché de los discos abiertos hasta sincronizarlos
+	syncErr := structures.SyncDevices()
+	if err == nil && syncErr != nil {
+		return "", fmt.Errorf("error al sincronizar los discos: %w", syncErr)
+	}
+
+	return output, err
+}
+
+// dispatch ejecuta el comando; los que modifican el sistema de archivos corren como transacciones del journal
+func dispatch(command string, tokens []string, session *stores.Session) (string, error) {
 	switch command {
 	case "mkdisk":
 		return commands.ParseMkdisk(tokens[1:])
@@ -35,49 +55,61 @@ func Analyzer(input string) (string, error) {
 	case "mounted":
 		return commands.ParseMounted(tokens[1:])
 	case "mkdir":
-		return commands.ParseMkdir(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseMkdir)
 	case "mkfile":
-		return commands.ParseMKfile(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseMKfile)
 	case "cat":
-		return commands.ParseCat(tokens[1:])
+		return commands.ParseCat(tokens[1:], session)
 	case "login":
-		return commands.ParseLogin(tokens[1:])
+		return commands.ParseLogin(tokens[1:], session)
 	case "logout":
-		return commands.ParseLogout(tokens[1:])
+		return commands.ParseLogout(tokens[1:], session)
 	case "mkgrp":
-		return commands.ParseMkgroup(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseMkgroup)
 	case "rmgrp":
-		return commands.ParseRmgroup(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseRmgroup)
 	case "chgrp":
-		return commands.ParseChgrp(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseChgrp)
 	case "mkusr":
-		return commands.ParseMkuser(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseMkuser)
 	case "rmusr":
-		return commands.ParseRmuser(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseRmuser)
 	case "getfs":
 		return commands.ParseGetfs(tokens[1:])
 	case "remove":
-		return commands.ParseRemove(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseRemove)
 	case "edit":
-		return commands.ParseEdit(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseEdit)
 	case "rename":
-		return commands.ParseRename(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseRename)
 	case "copy":
-		return commands.ParseCopy(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseCopy)
 	case "move":
-		return commands.ParseMove(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseMove)
 	case "chown":
-		return commands.ParseCHOWN(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseCHOWN)
 	case "chmod":
-		return commands.ParseCHMOD(tokens[1:])
+		return commands.Journaled(command, tokens[1:], session, commands.ParseCHMOD)
 	case "find":
-		return commands.ParseFIND(tokens[1:])
+		return commands.ParseFIND(tokens[1:], session)
+	case "ln":
+		return commands.Journaled(command, tokens[1:], session, commands.ParseLn)
 	case "journaling":
 		return commands.ParseJournal(tokens[1:])
 	case "loss":
 		return commands.ParseLoss(tokens[1:])
 	case "recovery":
 		return commands.ParseRecovery(tokens[1:])
+	case "fsck":
+		return commands.ParseFsck(tokens[1:])
+	case "tunefs":
+		return commands.ParseTunefs(tokens[1:])
+	case "resizefs":
+		return commands.ParseResizefs(tokens[1:])
+	case "sessions":
+		return commands.ParseSessions(tokens[1:], session)
+	case "stress":
+		return parseStress(tokens[1:], session)
 
 	default:
 		return "", fmt.Errorf("comando desconocido: %s", tokens[0])
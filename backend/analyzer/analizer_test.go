@@ -0,0 +1,515 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	structures "backend/structures"
+	utils "backend/utils"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testDir es la carpeta temporal de los discos del host y del estado. Los discos usan siempre las mismas rutas
+// porque cada ruta nueva toma una de las 26 letras de disco del proceso.
+var testDir string
+
+// Las pruebas crean sus discos en memoria o en testDir y guardan ahí el estado, así no tocan el resto del host
+func TestMain(m *testing.M) {
+	var err error
+	testDir, err = os.MkdirTemp("", "analyzer")
+	if err != nil {
+		panic(err)
+	}
+	stores.StateFile = filepath.Join(testDir, "state.json")
+	code := m.Run()
+	os.RemoveAll(testDir)
+	os.Exit(code)
+}
+
+// run ejecuta una línea de comando y detiene la prueba si falla
+func run(t *testing.T, session *stores.Session, line string) string {
+	t.Helper()
+	output, err := Analyzer(line, session)
+	if err != nil {
+		t.Fatalf("%s: %v", line, err)
+	}
+	return output
+}
+
+// newMemoryPartition crea un disco en memoria con una partición primaria de fit, la monta, la formatea con fs e
+// inicia sesión como root. Devuelve la sesión y el id de montaje.
+func newMemoryPartition(t *testing.T, fit string, fs string) (*stores.Session, string) {
+	t.Helper()
+	path := structures.MemoryPathPrefix + "prueba.mia"
+	session := &stores.Session{}
+
+	run(t, session, "mkdisk -size=3 -unit=M -path="+path)
+	run(t, session, "fdisk -size=2 -unit=M -fit="+fit+" -name=P1 -path="+path)
+	run(t, session, "mount -name=P1 -path="+path)
+
+	id := mountedID(t, path)
+	t.Cleanup(func() {
+		Analyzer("logout", session)
+		Analyzer("unmount -id="+id, session)
+		Analyzer("rmdisk -path="+path, session)
+	})
+
+	run(t, session, "mkfs -id="+id+" -type=full -fs="+fs)
+	run(t, session, "login -user=root -pass=123 -id="+id)
+	return session, id
+}
+
+// mountImage descomprime la imagen de testdata en testDir, monta su partición name e inicia sesión
+// como root. Devuelve la sesión, el id de montaje y la ruta del disco.
+func mountImage(t *testing.T, image string, name string) (*stores.Session, string, string) {
+	t.Helper()
+	compressed, err := os.Open(filepath.Join("testdata", image+".gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer compressed.Close()
+	reader, err := gzip.NewReader(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(testDir, image)
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := &stores.Session{}
+	run(t, session, "mount -name="+name+" -path="+path)
+	id := mountedID(t, path)
+	t.Cleanup(func() {
+		Analyzer("logout", session)
+		Analyzer("unmount -id="+id, session)
+		structures.DropDevice(path)
+	})
+	run(t, session, "login -user=root -pass=123 -id="+id)
+	return session, id, path
+}
+
+// mountedID devuelve el id de la partición montada del disco en path
+func mountedID(t *testing.T, path string) string {
+	t.Helper()
+	for _, id := range stores.GetMountedPartitions() {
+		if stores.MountedPath(id) == path {
+			return id
+		}
+	}
+	t.Fatalf("ninguna partición de %s está montada", path)
+	return ""
+}
+
+// fsckClean revisa la partición sin reparar y falla si encuentra problemas
+func fsckClean(t *testing.T, id string) {
+	t.Helper()
+	sb, partition, path, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := sb.Fsck(path, partition, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) > 0 {
+		t.Errorf("fsck encontró %d problemas: %v", len(report.Problems), report.Problems)
+	}
+}
+
+// inodeOf devuelve el número y el inodo del archivo en filePath
+func inodeOf(t *testing.T, id string, filePath string) (int32, *structures.Inode, *structures.SuperBlock) {
+	t.Helper()
+	sb, _, path, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parents, name := utils.GetParentDirectories(filePath)
+	index, err := sb.GetInode(path, parents, name)
+	if err != nil {
+		t.Fatalf("%s: %v", filePath, err)
+	}
+	inode := &structures.Inode{}
+	err = inode.Deserialize(path, int64(sb.S_inode_start+index*sb.S_inode_size))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return index, inode, sb
+}
+
+// freeCounts devuelve los inodos y bloques libres de la partición
+func freeCounts(t *testing.T, id string) (int32, int32) {
+	t.Helper()
+	sb, _, _, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sb.S_free_inodes_count, sb.S_free_blocks_count
+}
+
+func TestFitAllocation(t *testing.T) {
+	for _, fit := range []string{"ff", "bf", "wf"} {
+		t.Run(fit, func(t *testing.T) {
+			session, id := newMemoryPartition(t, fit, "2fs")
+
+			run(t, session, "mkdir -path=/datos")
+			for _, name := range []string{"a", "b", "c"} {
+				run(t, session, "mkfile -size=150 -path=/datos/"+name+".txt")
+			}
+			before, _, _, err := stores.GetMountedPartitionSuperblock(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Al borrar se devuelven el inodo y los bloques, y el siguiente archivo los vuelve a tomar
+			run(t, session, "remove -path=/datos/b.txt")
+			freed, _, _, err := stores.GetMountedPartitionSuperblock(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if freed.S_free_inodes_count != before.S_free_inodes_count+1 {
+				t.Errorf("remove liberó %d inodos, se esperaba 1", freed.S_free_inodes_count-before.S_free_inodes_count)
+			}
+			if freed.S_free_blocks_count != before.S_free_blocks_count+3 {
+				t.Errorf("remove liberó %d bloques, se esperaban 3", freed.S_free_blocks_count-before.S_free_blocks_count)
+			}
+
+			run(t, session, "mkfile -size=150 -path=/datos/d.txt")
+			after, _, _, err := stores.GetMountedPartitionSuperblock(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if after.S_free_inodes_count != before.S_free_inodes_count || after.S_free_blocks_count != before.S_free_blocks_count {
+				t.Errorf("los contadores no volvieron a los de antes de borrar: %d/%d inodos y %d/%d bloques libres",
+					after.S_free_inodes_count, before.S_free_inodes_count, after.S_free_blocks_count, before.S_free_blocks_count)
+			}
+
+			output := run(t, session, "cat -file1=/datos/d.txt")
+			if !strings.Contains(output, stressContent(150)) {
+				t.Errorf("el contenido de d.txt no es el que se escribió:\n%s", output)
+			}
+			fsckClean(t, id)
+		})
+	}
+}
+
+func TestLongNames(t *testing.T) {
+	session, id := newMemoryPartition(t, "ff", "2fs")
+
+	folder := "/una_carpeta_con_un_nombre_bastante_largo"
+	file := folder + "/archivo_con_un_nombre_mucho_mas_largo_que_doce_bytes.txt"
+	run(t, session, "mkdir -path="+folder)
+	run(t, session, "mkfile -size=40 -path="+file)
+
+	output := run(t, session, "cat -file1="+file)
+	if !strings.Contains(output, stressContent(40)) {
+		t.Errorf("el contenido del archivo no es el que se escribió:\n%s", output)
+	}
+
+	// getfs lee los nombres de vuelta desde las carpetas
+	output = run(t, session, "getfs")
+	for _, name := range []string{filepath.Base(folder), filepath.Base(file)} {
+		if !strings.Contains(output, `"`+name+`"`) {
+			t.Errorf("getfs no devuelve el nombre %s", name)
+		}
+	}
+
+	run(t, session, "rename -path="+file+" -name=otro_nombre_largo_para_el_mismo_archivo.txt")
+	renamed := folder + "/otro_nombre_largo_para_el_mismo_archivo.txt"
+	output = run(t, session, "cat -file1="+renamed)
+	if !strings.Contains(output, stressContent(40)) {
+		t.Errorf("el contenido del archivo renombrado no es el que se escribió:\n%s", output)
+	}
+	_, err := Analyzer("cat -file1="+file, session)
+	if err == nil {
+		t.Error("el nombre anterior sigue existiendo después de rename")
+	}
+	fsckClean(t, id)
+}
+
+func TestLongNameCycle(t *testing.T) {
+	session, id := newMemoryPartition(t, "ff", "2fs")
+	folder := "/una_carpeta_con_un_nombre_bastante_largo"
+	run(t, session, "mkdir -path="+folder)
+
+	// Buscar la entrada del nombre largo en la carpeta raíz y hacer que su último NameBlock apunte a sí mismo
+	sb, _, path, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := &structures.Inode{}
+	err = root.Deserialize(path, int64(sb.S_inode_start))
+	if err != nil {
+		t.Fatal(err)
+	}
+	folderBlock := sb.NewFolderBlock()
+	err = folderBlock.Deserialize(path, int64(sb.S_block_start+root.I_block[0]*sb.S_block_size))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry *structures.FolderContent
+	for i := range folderBlock.B_content {
+		if folderBlock.B_content[i].B_inodo != -1 && folderBlock.B_content[i].B_name[0] == 0xFF {
+			entry = &folderBlock.B_content[i]
+		}
+	}
+	if entry == nil {
+		t.Fatal("la carpeta raíz no tiene la entrada del nombre largo")
+	}
+	blocks, err := sb.EntryNameBlocks(path, *entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := blocks[len(blocks)-1]
+	next := make([]byte, 4)
+	binary.LittleEndian.PutUint32(next, uint32(last))
+	err = structures.DeviceWriteAt(path, next, int64(sb.S_block_start+(last+1)*sb.S_block_size-4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = sb.EntryNameBlocks(path, *entry)
+	if err == nil {
+		t.Fatal("se recorrió una cadena de nombre que vuelve sobre sí misma")
+	}
+	_, err = Analyzer("remove -path="+folder, session)
+	if err == nil {
+		t.Error("remove borró una entrada con la cadena del nombre dañada")
+	}
+}
+
+func TestHardLinkCount(t *testing.T) {
+	session, id := newMemoryPartition(t, "ff", "2fs")
+
+	run(t, session, "mkdir -path=/docs")
+	run(t, session, "mkfile -size=30 -path=/docs/original.txt")
+	run(t, session, "ln -path=/docs/copia.txt -destino=/docs/original.txt")
+	run(t, session, "ln -path=/enlace.txt -destino=/docs/original.txt")
+
+	original, inode, sb := inodeOf(t, id, "/docs/original.txt")
+	if links := sb.InodeLinks(inode); links != 3 {
+		t.Errorf("el inodo tiene %d enlaces, se esperaban 3", links)
+	}
+	for _, name := range []string{"/docs/copia.txt", "/enlace.txt"} {
+		index, _, _ := inodeOf(t, id, name)
+		if index != original {
+			t.Errorf("%s apunta al inodo %d, se esperaba %d", name, index, original)
+		}
+	}
+
+	// Borrar un nombre solo baja el contador; el inodo se libera con el último
+	run(t, session, "remove -path=/docs/original.txt")
+	_, inode, sb = inodeOf(t, id, "/enlace.txt")
+	if links := sb.InodeLinks(inode); links != 2 {
+		t.Errorf("el inodo tiene %d enlaces después de borrar uno, se esperaban 2", links)
+	}
+	output := run(t, session, "cat -file1=/enlace.txt")
+	if !strings.Contains(output, stressContent(30)) {
+		t.Errorf("el contenido cambió al borrar un enlace:\n%s", output)
+	}
+
+	free := sb.S_free_inodes_count
+	run(t, session, "remove -path=/docs/copia.txt")
+	run(t, session, "remove -path=/enlace.txt")
+	after, _, _, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.S_free_inodes_count != free+1 {
+		t.Errorf("borrar los últimos enlaces liberó %d inodos, se esperaba 1", after.S_free_inodes_count-free)
+	}
+	fsckClean(t, id)
+}
+
+// lastCommit devuelve la última transacción del journal y la posición de su registro commit
+func lastCommit(t *testing.T, id string) (structures.JournalTransaction, int64) {
+	t.Helper()
+	sb, _, path, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions, js, err := sb.ReadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) == 0 {
+		t.Fatal("el journal no tiene transacciones")
+	}
+	last := transactions[len(transactions)-1]
+	slot := (last.Slot + last.Records() - 1) % js.JS_slots
+	return last, sb.JournalStart() + int64(binary.Size(structures.Journal{}))*int64(slot+1)
+}
+
+func TestJournalCommitReplay(t *testing.T) {
+	session, id := newMemoryPartition(t, "ff", "3fs")
+
+	run(t, session, "mkdir -path=/logs")
+	run(t, session, "mkfile -size=80 -path=/logs/dia.txt")
+
+	// El commit de mkfile queda aplicado y guarda el contenido completo del archivo
+	last, commit := lastCommit(t, id)
+	if last.Status != structures.JournalApplied {
+		t.Errorf("la última transacción quedó en estado %c, se esperaba %c", last.Status, structures.JournalApplied)
+	}
+	if last.Info.Operation() != "mkfile" || last.Info.Path() != "/logs/dia.txt" {
+		t.Errorf("la última transacción es %s %s", last.Info.Operation(), last.Info.Path())
+	}
+	if len(last.Payloads) != 1 || last.Payloads[0] != stressContent(80) {
+		t.Errorf("la transacción guardó %d contenidos: %q", len(last.Payloads), last.Payloads)
+	}
+
+	// Se simula un corte después del commit y antes de escribir en su lugar: se borran las actualizaciones de
+	// la partición y el commit vuelve a estar pendiente
+	sb, _, path, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, update := range last.Updates {
+		err := structures.DeviceWriteAt(path, make([]byte, update.J_length), update.J_offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	record := &structures.Journal{}
+	err = record.Deserialize(path, commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record.J_type[0] = structures.JournalCommit
+	err = record.Serialize(path, commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := sb.ReplayJournal(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed != 1 {
+		t.Errorf("se rehicieron %d transacciones, se esperaba 1", replayed)
+	}
+	output := run(t, session, "cat -file1=/logs/dia.txt")
+	if !strings.Contains(output, stressContent(80)) {
+		t.Errorf("el contenido no volvió con el journal:\n%s", output)
+	}
+	last, _ = lastCommit(t, id)
+	if last.Status != structures.JournalApplied {
+		t.Errorf("la transacción rehecha quedó en estado %c", last.Status)
+	}
+	fsckClean(t, id)
+}
+
+func TestJournalCheckpoint(t *testing.T) {
+	session, id := newMemoryPartition(t, "ff", "3fs")
+
+	run(t, session, "mkdir -path=/logs")
+	run(t, session, "mkfile -size=80 -path=/logs/dia.txt")
+	last, _ := lastCommit(t, id)
+
+	// El checkpoint libera todo el anillo
+	run(t, session, "journaling -id="+id+" -checkpoint")
+	sb, _, path, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions, js, err := sb.ReadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 0 || js.JS_used != 0 || js.JS_checkpoints != 1 {
+		t.Errorf("después del checkpoint quedan %d transacciones y %d registros, %d checkpoints",
+			len(transactions), js.JS_used, js.JS_checkpoints)
+	}
+
+	// Las transacciones siguientes continúan la numeración
+	run(t, session, "mkfile -size=10 -path=/logs/noche.txt")
+	next, _ := lastCommit(t, id)
+	if next.Number <= last.Number {
+		t.Errorf("la transacción después del checkpoint tiene el número %d, la anterior %d", next.Number, last.Number)
+	}
+	fsckClean(t, id)
+}
+
+func TestJournalTooSmall(t *testing.T) {
+	session, id := newMemoryPartition(t, "ff", "3fs")
+	run(t, session, "logout")
+
+	// Con tan pocos inodos el journal no tendría lugar ni para un comando: mkfs falla sin tocar la partición
+	_, err := Analyzer("mkfs -id="+id+" -type=full -fs=3fs -inode_ratio=700000", session)
+	if err == nil {
+		t.Fatal("mkfs formateó un journal de menos registros que el mínimo")
+	}
+	run(t, session, "login -user=root -pass=123 -id="+id)
+	run(t, session, "cat -file1=/users.txt")
+	fsckClean(t, id)
+
+	// Un comando que no cabe en todo el journal se rechaza entero
+	reformat(t, session, id, "-fs=3fs -inode_ratio=7000")
+	inodes, blocks := freeCounts(t, id)
+	_, err = Analyzer("mkfile -size=30000 -path=/grande.txt", session)
+	if err == nil || !strings.Contains(err.Error(), "registros del journal") {
+		t.Fatalf("un mkfile más grande que el journal devolvió %v", err)
+	}
+	if i, b := freeCounts(t, id); i != inodes || b != blocks {
+		t.Errorf("el mkfile rechazado reservó %d inodos y %d bloques", inodes-i, blocks-b)
+	}
+	_, err = Analyzer("cat -file1=/grande.txt", session)
+	if err == nil {
+		t.Error("el archivo del mkfile rechazado existe")
+	}
+	fsckClean(t, id)
+
+	run(t, session, "mkfile -size=300 -path=/chico.txt")
+	output := run(t, session, "cat -file1=/chico.txt")
+	if !strings.Contains(output, stressContent(300)) {
+		t.Errorf("el archivo creado después del rechazo no tiene su contenido:\n%s", output)
+	}
+}
+
+func TestLossRecovery(t *testing.T) {
+	session, id := newMemoryPartition(t, "ff", "3fs")
+
+	run(t, session, "mkdir -p -path=/home/user/docs")
+	run(t, session, "mkfile -size=120 -path=/home/user/docs/notas.txt")
+	run(t, session, "mkfile -size=20 -path=/home/user/a.txt")
+	run(t, session, "ln -path=/home/b.txt -destino=/home/user/a.txt")
+
+	run(t, session, "loss -id="+id)
+	_, err := Analyzer("cat -file1=/home/user/docs/notas.txt", session)
+	if err == nil {
+		t.Fatal("el archivo se puede leer después de loss")
+	}
+
+	run(t, session, "recovery -id="+id)
+
+	output := run(t, session, "cat -file1=/home/user/docs/notas.txt")
+	if !strings.Contains(output, stressContent(120)) {
+		t.Errorf("recovery no devolvió el contenido de notas.txt:\n%s", output)
+	}
+	_, inode, sb := inodeOf(t, id, "/home/b.txt")
+	if links := sb.InodeLinks(inode); links != 2 {
+		t.Errorf("el enlace duro quedó con %d enlaces, se esperaban 2", links)
+	}
+
+	// getfs recorre el árbol recuperado desde la raíz
+	var disks []map[string]interface{}
+	output = run(t, session, "getfs")
+	err = json.Unmarshal([]byte(output), &disks)
+	if err != nil {
+		t.Fatalf("getfs no devolvió JSON: %v", err)
+	}
+	if !strings.Contains(output, `"notas.txt"`) {
+		t.Error("getfs no encuentra notas.txt después de recovery")
+	}
+	fsckClean(t, id)
+}
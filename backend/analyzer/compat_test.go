@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	structures "backend/structures"
+	"strings"
+	"testing"
+)
+
+func TestCompatMBR(t *testing.T) {
+	path := structures.MemoryPathPrefix + "compat.mia"
+	session := &stores.Session{}
+	run(t, session, "mkdisk -size=5 -unit=M -compat -path="+path)
+	t.Cleanup(func() { Analyzer("rmdisk -path="+path, session) })
+	run(t, session, "fdisk -size=1 -unit=M -name=P1 -path="+path)
+	run(t, session, "fdisk -size=2 -unit=M -type=E -name=EXT -path="+path)
+	run(t, session, "fdisk -size=500 -unit=K -type=L -name=L1 -path="+path)
+
+	run(t, session, "mount -name=P1 -path="+path)
+	id := mountedID(t, path)
+	t.Cleanup(func() {
+		Analyzer("logout", session)
+		Analyzer("unmount -id="+id, session)
+	})
+	run(t, session, "mkfs -id="+id+" -type=full")
+	run(t, session, "login -user=root -pass=123 -id="+id)
+	run(t, session, "mkfile -size=70 -path=/a.txt")
+	output := run(t, session, "cat -file1=/a.txt")
+	if !strings.Contains(output, stressContent(70)) {
+		t.Errorf("el archivo del disco compat no tiene lo escrito:\n%s", output)
+	}
+
+	// LBA 0 es un MBR estándar que describe las mismas particiones que el MBR del proyecto
+	if !structures.IsCompatMBR(path) {
+		t.Fatal("el disco no se reconoce como compat")
+	}
+	standard, err := structures.ReadStandardMBR(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if standard.Signature != [2]byte{0x55, 0xAA} {
+		t.Errorf("la firma de LBA 0 es %x", standard.Signature)
+	}
+	var mbr structures.MBR
+	err = mbr.DeserializeMBR(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := []byte{0x83, 0xDA}
+	for i, osType := range types {
+		record := standard.Partitions[i]
+		partition := mbr.Mbr_partitions[i]
+		if record.OSType != osType {
+			t.Errorf("la entrada %d tiene el tipo %#x, se esperaba %#x", i+1, record.OSType, osType)
+		}
+		if int64(record.StartingLBA)*512 != int64(partition.Part_start) || int64(record.SizeInLBA)*512 != int64(partition.Part_size) {
+			t.Errorf("la entrada %d va de LBA %d con %d sectores y la partición de %d con %d bytes", i+1,
+				record.StartingLBA, record.SizeInLBA, partition.Part_start, partition.Part_size)
+		}
+	}
+	if standard.Partitions[2].OSType != 0 || standard.Partitions[3].OSType != 0 {
+		t.Error("las entradas sin partición no están vacías")
+	}
+}
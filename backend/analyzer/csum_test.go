@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	structures "backend/structures"
+	"errors"
+	"testing"
+)
+
+// reformat vuelve a formatear la partición con las opciones de mkfs dadas e inicia sesión como root
+func reformat(t *testing.T, session *stores.Session, id string, options string) {
+	t.Helper()
+	run(t, session, "logout")
+	run(t, session, "mkfs -id="+id+" -type=full "+options)
+	run(t, session, "login -user=root -pass=123 -id="+id)
+}
+
+func TestChecksumDetectsCorruption(t *testing.T) {
+	session, id := newMemoryPartition(t, "ff", "2fs")
+	reformat(t, session, id, "-fs=3fs -csum")
+	run(t, session, "mkdir -path=/docs")
+	run(t, session, "mkfile -size=100 -path=/docs/a.txt")
+	run(t, session, "cat -file1=/docs/a.txt")
+	fsckClean(t, id)
+
+	// Cambiar un byte del inodo del archivo sin pasar por Serialize
+	index, _, sb := inodeOf(t, id, "/docs/a.txt")
+	_, _, path, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := int64(sb.S_inode_start + index*sb.S_inode_size)
+	raw := make([]byte, 1)
+	err = structures.DeviceReadAt(path, raw, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[0] ^= 0xFF
+	err = structures.DeviceWriteAt(path, raw, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inode := &structures.Inode{}
+	err = inode.Deserialize(path, offset)
+	var corruption *structures.CorruptionError
+	if !errors.As(err, &corruption) || corruption.Offset != offset {
+		t.Fatalf("leer el inodo dañado devolvió %v y no un error de corrupción", err)
+	}
+	_, err = Analyzer("cat -file1=/docs/a.txt", session)
+	if err == nil {
+		t.Error("cat leyó un archivo con el inodo dañado")
+	}
+}
+
+func TestChecksumsOffByDefault(t *testing.T) {
+	session, id := newMemoryPartition(t, "ff", "2fs")
+	run(t, session, "mkfile -size=100 -path=/a.txt")
+
+	index, _, sb := inodeOf(t, id, "/a.txt")
+	if sb.HasFeature(structures.FeatureMetadataCsum) {
+		t.Fatal("mkfs sin -csum habilitó los checksums")
+	}
+	_, _, path, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sin checksums un cambio en el inodo se lee sin error
+	offset := int64(sb.S_inode_start + index*sb.S_inode_size)
+	err = structures.DeviceWriteAt(path, []byte{0x7F}, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inode := &structures.Inode{}
+	err = inode.Deserialize(path, offset)
+	if err != nil {
+		t.Errorf("leer un inodo sin checksums falló: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	structures "backend/structures"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// rootEntry devuelve el bloque de la carpeta raíz que tiene la entrada name, su posición y el índice de la entrada
+func rootEntry(t *testing.T, id string, name string) (*structures.FolderBlock, int64, int) {
+	t.Helper()
+	sb, _, path, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := &structures.Inode{}
+	err = root.Deserialize(path, int64(sb.S_inode_start))
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := int64(sb.S_block_start + root.I_block[0]*sb.S_block_size)
+	folderBlock := sb.NewFolderBlock()
+	err = folderBlock.Deserialize(path, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, content := range folderBlock.B_content {
+		if content.B_inodo != -1 && strings.TrimRight(string(content.B_name[:]), "\x00") == name {
+			return folderBlock, offset, i
+		}
+	}
+	t.Fatalf("la carpeta raíz no tiene la entrada %s", name)
+	return nil, 0, 0
+}
+
+func TestFsckRepair(t *testing.T) {
+	session, id := newMemoryPartition(t, "ff", "2fs")
+	run(t, session, "mkdir -path=/docs")
+	run(t, session, "mkfile -size=100 -path=/docs/a.txt")
+	docs, _, _ := inodeOf(t, id, "/docs")
+	fsckClean(t, id)
+
+	// Dejar /docs sin entrada en la raíz y descuadrar el contador de bloques libres
+	sb, partition, path, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	folderBlock, offset, entry := rootEntry(t, id, "docs")
+	folderBlock.B_content[entry].B_inodo = -1
+	err = folderBlock.Serialize(path, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb.S_free_blocks_count += 5
+	err = sb.Serialize(path, int64(partition.Part_start))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := run(t, session, "fsck -id="+id)
+	if strings.Contains(output, "Problemas encontrados: 0") {
+		t.Fatalf("fsck no encontró los problemas:\n%s", output)
+	}
+	output = run(t, session, "fsck -id="+id+" -repair")
+	if strings.Contains(output, "Reparaciones: 0") {
+		t.Fatalf("fsck -repair no corrigió nada:\n%s", output)
+	}
+	fsckClean(t, id)
+
+	// El huérfano vuelve colgado de /lost+found con su contenido
+	output = run(t, session, fmt.Sprintf("cat -file1=/lost+found/#%d/a.txt", docs))
+	if !strings.Contains(output, stressContent(100)) {
+		t.Errorf("el archivo reconectado no tiene su contenido:\n%s", output)
+	}
+}
@@ -0,0 +1,45 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	structures "backend/structures"
+	"strings"
+	"testing"
+)
+
+func TestGPTDisk(t *testing.T) {
+	path := structures.MemoryPathPrefix + "gptdisk.mia"
+	session := &stores.Session{}
+	run(t, session, "mkdisk -size=4 -unit=M -table=gpt -path="+path)
+	t.Cleanup(func() { Analyzer("rmdisk -path="+path, session) })
+
+	run(t, session, "fdisk -size=1 -unit=M -name=uno -path="+path)
+	run(t, session, "fdisk -size=1 -unit=M -name=dos -path="+path)
+	_, err := Analyzer("fdisk -size=1 -unit=M -name=uno -path="+path, session)
+	if err == nil {
+		t.Fatal("se creó una segunda partición GPT con el nombre uno")
+	}
+
+	run(t, session, "mount -name=dos -path="+path)
+	id := mountedID(t, path)
+	t.Cleanup(func() {
+		Analyzer("logout", session)
+		Analyzer("unmount -id="+id, session)
+	})
+	run(t, session, "mkfs -id="+id+" -type=full")
+	run(t, session, "login -user=root -pass=123 -id="+id)
+	run(t, session, "mkfile -size=50 -path=/a.txt")
+	output := run(t, session, "cat -file1=/a.txt")
+	if !strings.Contains(output, stressContent(50)) {
+		t.Errorf("el archivo de la partición GPT no tiene lo escrito:\n%s", output)
+	}
+	fsckClean(t, id)
+
+	gpt, err := structures.ReadGPT(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gpt.FindByName("uno") == -1 || gpt.FindByName("dos") == -1 {
+		t.Error("la GPT no tiene las dos particiones")
+	}
+}
@@ -0,0 +1,111 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	"strings"
+	"testing"
+)
+
+// Las imágenes de testdata se formatearon con la versión anterior a las features: superbloque de 68 bytes,
+// inodos de 88 y un journal de entradas de 114 bytes. Cada una tiene /home/docs/a.txt de 30 bytes.
+
+func TestLegacyImage(t *testing.T) {
+	session, id, _ := mountImage(t, "legacy2.mia", "L2")
+	fsckClean(t, id)
+
+	output := run(t, session, "cat -file1=/home/docs/a.txt")
+	if !strings.Contains(output, stressContent(30)) {
+		t.Errorf("el contenido de a.txt no es el que se escribió:\n%s", output)
+	}
+
+	// Los archivos nuevos toman inodos libres sin pisar a los que ya estaban
+	run(t, session, "mkfile -size=20 -path=/home/docs/b.txt")
+	first, _, _ := inodeOf(t, id, "/home/docs/a.txt")
+	second, _, sb := inodeOf(t, id, "/home/docs/b.txt")
+	if first == second {
+		t.Errorf("a.txt y b.txt comparten el inodo %d", first)
+	}
+	if !sb.Legacy() {
+		t.Error("el superbloque dejó de tener el formato anterior")
+	}
+	output = run(t, session, "cat -file1=/home/docs/a.txt")
+	if !strings.Contains(output, stressContent(30)) {
+		t.Errorf("a.txt cambió al crear otros archivos:\n%s", output)
+	}
+
+	// Sin la feature de nombres largos los nombres siguen limitados a 12 bytes
+	_, err := Analyzer("mkdir -path=/home/nombre_mas_largo_que_doce", session)
+	if err == nil {
+		t.Error("se creó un nombre largo en un sistema de archivos sin nombres largos")
+	}
+	run(t, session, "mkdir -path=/home/corto")
+	fsckClean(t, id)
+}
+
+func TestLegacyExt3Journal(t *testing.T) {
+	session, id, path := mountImage(t, "legacy3.mia", "L3")
+
+	run(t, session, "mkfile -size=25 -path=/home/docs/b.txt")
+	sb, _, _, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions, _, err := sb.ReadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) == 0 || transactions[len(transactions)-1].Info.Operation() != "mkfile" {
+		t.Errorf("mkfile no quedó en el journal: %d transacciones", len(transactions))
+	}
+
+	// Al volver a montar el journal sigue siendo válido
+	run(t, session, "logout")
+	run(t, session, "unmount -id="+id)
+	run(t, session, "mount -name=L3 -path="+path)
+	id = mountedID(t, path)
+	run(t, session, "login -user=root -pass=123 -id="+id)
+	output := run(t, session, "cat -file1=/home/docs/b.txt")
+	if !strings.Contains(output, stressContent(25)) {
+		t.Errorf("el contenido de b.txt no es el que se escribió:\n%s", output)
+	}
+	fsckClean(t, id)
+}
+
+func TestLegacyResize(t *testing.T) {
+	session, id, path := mountImage(t, "legacy2.mia", "L2")
+	before, _, _, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fdisk cambia solo el tamaño de la partición y el sistema de archivos queda como estaba
+	run(t, session, "fdisk -add=1 -unit=M -name=L2 -path="+path)
+	after, partition, _, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partition.Part_size != 2*1024*1024 {
+		t.Errorf("la partición mide %d bytes, se esperaban %d", partition.Part_size, 2*1024*1024)
+	}
+	if !after.Legacy() || after.TotalInodes() != before.TotalInodes() || after.TotalBlocks() != before.TotalBlocks() {
+		t.Errorf("el sistema de archivos cambió: %d inodos y %d bloques, antes %d y %d",
+			after.TotalInodes(), after.TotalBlocks(), before.TotalInodes(), before.TotalBlocks())
+	}
+
+	// Vuelve al tamaño original, pero no se puede achicar más allá del sistema de archivos
+	run(t, session, "fdisk -add=-1 -unit=M -name=L2 -path="+path)
+	_, err = Analyzer("fdisk -add=-100 -unit=K -name=L2 -path="+path, session)
+	if err == nil {
+		t.Error("fdisk achicó la partición por debajo del sistema de archivos")
+	}
+	_, err = Analyzer("resizefs -id="+id, session)
+	if err == nil {
+		t.Error("resizefs aceptó un sistema de archivos del formato anterior")
+	}
+
+	output := run(t, session, "cat -file1=/home/docs/a.txt")
+	if !strings.Contains(output, stressContent(30)) {
+		t.Errorf("el contenido de a.txt no es el que se escribió:\n%s", output)
+	}
+	fsckClean(t, id)
+}
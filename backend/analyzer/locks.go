@@ -0,0 +1,91 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	"regexp"
+	"strings"
+)
+
+// Qué candado toma cada comando antes de correr, ver stores.LockDisk y stores.LockPartition
+type lockKind int
+
+const (
+	lockNone         lockKind = iota
+	lockDiskPath              // escribe la estructura del disco de -path
+	lockDiskOfID              // escribe la estructura del disco de la partición -id
+	lockWriteID               // modifica la partición -id
+	lockReadID                // solo lee la partición -id
+	lockWriteSession          // modifica la partición de la sesión
+	lockReadSession           // solo lee la partición de la sesión
+)
+
+var commandLocks = map[string]lockKind{
+	"mkdisk":     lockDiskPath,
+	"rmdisk":     lockDiskPath,
+	"fdisk":      lockDiskPath,
+	"mount":      lockDiskPath,
+	"unmount":    lockDiskOfID,
+	"mkfs":       lockWriteID,
+	"loss":       lockWriteID,
+	"recovery":   lockWriteID,
+	"fsck":       lockWriteID,
+	"tunefs":     lockWriteID,
+	"resizefs":   lockWriteID,
+	"journaling": lockWriteID, // -checkpoint aplica el journal
+	"rep":        lockReadID,
+	"login":      lockReadID,
+	"mkdir":      lockWriteSession,
+	"mkfile":     lockWriteSession,
+	"mkgrp":      lockWriteSession,
+	"rmgrp":      lockWriteSession,
+	"chgrp":      lockWriteSession,
+	"mkusr":      lockWriteSession,
+	"rmusr":      lockWriteSession,
+	"remove":     lockWriteSession,
+	"edit":       lockWriteSession,
+	"rename":     lockWriteSession,
+	"copy":       lockWriteSession,
+	"move":       lockWriteSession,
+	"chown":      lockWriteSession,
+	"chmod":      lockWriteSession,
+	"ln":         lockWriteSession,
+	"cat":        lockReadSession,
+	"find":       lockReadSession,
+	// getfs toma cada partición que lee por su cuenta; stress corre sus comandos por Analyzer, que los toma
+}
+
+var (
+	pathArgument = regexp.MustCompile(`(?i)-path="[^"]+"|-path=[^\s]+`)
+	idArgument   = regexp.MustCompile(`(?i)-id="[^"]+"|-id=[^\s]+`)
+)
+
+// lockCommand toma el candado que necesita el comando y devuelve la función que lo suelta
+func lockCommand(command string, tokens []string, session *stores.Session) func() {
+	args := strings.Join(tokens[1:], " ")
+	switch commandLocks[command] {
+	case lockDiskPath:
+		return stores.LockDisk(argument(pathArgument, args))
+	case lockDiskOfID:
+		return stores.LockDisk(stores.MountedPath(argument(idArgument, args)))
+	case lockWriteID:
+		return stores.LockPartition(argument(idArgument, args), true)
+	case lockReadID:
+		return stores.LockPartition(argument(idArgument, args), false)
+	case lockWriteSession, lockReadSession:
+		_, partitionID, _, _ := session.Get()
+		if partitionID == "" {
+			return func() {}
+		}
+		return stores.LockPartition(partitionID, commandLocks[command] == lockWriteSession)
+	}
+	return func() {}
+}
+
+// argument devuelve el valor del parámetro sin comillas, o "" si no está
+func argument(re *regexp.Regexp, args string) string {
+	match := re.FindString(args)
+	if match == "" {
+		return ""
+	}
+	return strings.Trim(strings.SplitN(match, "=", 2)[1], "\"")
+}
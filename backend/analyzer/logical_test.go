@@ -0,0 +1,57 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	structures "backend/structures"
+	"strings"
+	"testing"
+)
+
+func TestLogicalPartitions(t *testing.T) {
+	path := structures.MemoryPathPrefix + "logicas.mia"
+	session := &stores.Session{}
+	run(t, session, "mkdisk -size=5 -unit=M -path="+path)
+	t.Cleanup(func() { Analyzer("rmdisk -path="+path, session) })
+	run(t, session, "fdisk -size=4 -unit=M -type=E -name=EXT -path="+path)
+	run(t, session, "fdisk -size=1 -unit=M -type=L -name=L1 -path="+path)
+	run(t, session, "fdisk -size=1 -unit=M -type=L -name=L2 -path="+path)
+	_, err := Analyzer("fdisk -size=1 -unit=M -type=L -name=L1 -path="+path, session)
+	if err == nil {
+		t.Fatal("se creó una segunda partición lógica con el nombre L1")
+	}
+
+	run(t, session, "mount -name=L2 -path="+path)
+	id := mountedID(t, path)
+	run(t, session, "mkfs -id="+id+" -type=full")
+	run(t, session, "login -user=root -pass=123 -id="+id)
+	run(t, session, "mkfile -size=60 -path=/a.txt")
+	fsckClean(t, id)
+
+	// Borrar la lógica anterior no mueve a L2 ni a sus archivos
+	run(t, session, "logout")
+	run(t, session, "unmount -id="+id)
+	run(t, session, "fdisk -delete=full -name=L1 -path="+path)
+	run(t, session, "mount -name=L2 -path="+path)
+	id = mountedID(t, path)
+	t.Cleanup(func() {
+		Analyzer("logout", session)
+		Analyzer("unmount -id="+id, session)
+	})
+	run(t, session, "login -user=root -pass=123 -id="+id)
+	output := run(t, session, "cat -file1=/a.txt")
+	if !strings.Contains(output, stressContent(60)) {
+		t.Errorf("el archivo de L2 cambió al borrar L1:\n%s", output)
+	}
+
+	// La lógica crece dentro de la extendida y su sistema de archivos con ella
+	run(t, session, "fdisk -add=512 -unit=K -name=L2 -path="+path)
+	partition, _, err := stores.GetMountedPartition(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partition.Part_size != 1536*1024 {
+		t.Errorf("L2 tiene %d bytes después de crecer", partition.Part_size)
+	}
+	fsckClean(t, id)
+	run(t, session, "cat -file1=/a.txt")
+}
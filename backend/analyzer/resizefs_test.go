@@ -0,0 +1,70 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	"strings"
+	"testing"
+)
+
+// totalBlocks devuelve la cantidad de bloques del sistema de archivos de la partición
+func totalBlocks(t *testing.T, id string) int32 {
+	t.Helper()
+	sb, _, _, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sb.TotalBlocks()
+}
+
+func TestResizeWithFdiskAdd(t *testing.T) {
+	session, id := newMemoryPartition(t, "ff", "3fs")
+	path := stores.MountedPath(id)
+	run(t, session, "mkdir -path=/docs")
+	run(t, session, "mkfile -size=5000 -path=/docs/a.txt")
+	blocks := totalBlocks(t, id)
+
+	check := func() {
+		t.Helper()
+		output := run(t, session, "cat -file1=/docs/a.txt")
+		if !strings.Contains(output, stressContent(5000)) {
+			t.Error("a.txt cambió al redimensionar la partición")
+		}
+		fsckClean(t, id)
+	}
+
+	// fdisk -add agranda el sistema de archivos junto con la partición
+	run(t, session, "fdisk -add=512 -unit=K -name=P1 -path="+path)
+	grown := totalBlocks(t, id)
+	if grown <= blocks {
+		t.Fatalf("el sistema de archivos tiene %d bloques después de crecer, antes %d", grown, blocks)
+	}
+	check()
+	run(t, session, "mkfile -size=20 -path=/docs/b.txt")
+
+	run(t, session, "fdisk -add=-512 -unit=K -name=P1 -path="+path)
+	if shrunk := totalBlocks(t, id); shrunk != blocks {
+		t.Errorf("el sistema de archivos tiene %d bloques después de achicarse, se esperaban %d", shrunk, blocks)
+	}
+	check()
+
+	// Achicar por debajo de lo que está en uso se rechaza sin cambiar nada
+	_, err := Analyzer("fdisk -add=-2040 -unit=K -name=P1 -path="+path, session)
+	if err == nil {
+		t.Fatal("fdisk achicó la partición por debajo de lo que usan los archivos")
+	}
+	if got := totalBlocks(t, id); got != blocks {
+		t.Errorf("el sistema de archivos quedó con %d bloques", got)
+	}
+	partition, _, err := stores.GetMountedPartition(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partition.Part_size != 2*1024*1024 {
+		t.Errorf("la partición quedó de %d bytes", partition.Part_size)
+	}
+	check()
+
+	// Sin cambios en la partición resizefs deja el sistema de archivos como está
+	run(t, session, "resizefs -id="+id)
+	check()
+}
@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionsPerClient(t *testing.T) {
+	root, id := newMemoryPartition(t, "ff", "2fs")
+	run(t, root, "mkgrp -name=usuarios")
+	run(t, root, "mkusr -user=ana -pass=abc -grp=usuarios")
+
+	other := &stores.Session{}
+	t.Cleanup(func() { Analyzer("logout", other) })
+	run(t, other, "login -user=ana -pass=abc -id="+id)
+	if root.GetToken() == other.GetToken() {
+		t.Fatal("dos clientes recibieron el mismo token")
+	}
+	if found := stores.FindSession(other.GetToken()); found == nil || found.User != "ana" {
+		t.Fatalf("el token de ana no lleva a su sesión: %+v", found)
+	}
+	if user, _, _, _ := root.Get(); user != "root" {
+		t.Errorf("el login de ana cambió la sesión de root a %q", user)
+	}
+
+	// Cerrar una sesión no toca la otra
+	run(t, other, "logout")
+	if user, _, _, _ := root.Get(); user != "root" {
+		t.Errorf("el logout de ana cerró la sesión de root")
+	}
+	if _, err := Analyzer("mkdir -path=/ana", other); err == nil {
+		t.Error("un cliente sin sesión creó una carpeta")
+	}
+}
+
+func TestSessionExpires(t *testing.T) {
+	session, _ := newMemoryPartition(t, "ff", "2fs")
+	token := session.GetToken()
+
+	timeout := stores.SessionTimeout
+	stores.SessionTimeout = time.Millisecond
+	t.Cleanup(func() { stores.SessionTimeout = timeout })
+	time.Sleep(5 * time.Millisecond)
+
+	if stores.FindSession(token) != nil {
+		t.Error("una sesión expirada se siguió encontrando por su token")
+	}
+	if user, _, _, _ := session.Get(); user != "" {
+		t.Errorf("la sesión expirada sigue con el usuario %q", user)
+	}
+}
+
+func TestSessionStateKeepsNoToken(t *testing.T) {
+	session, id, _ := newHostDisk(t)
+	run(t, session, "mkfs -id="+id+" -type=full")
+	run(t, session, "login -user=root -pass=123 -id="+id)
+	token := session.GetToken()
+
+	err := stores.SaveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(stores.StateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), token) {
+		t.Fatal("el archivo de estado tiene el token de la sesión en claro")
+	}
+
+	// Después de un reinicio el cliente sigue entrando con el mismo token
+	stores.EndSession(session)
+	forgetMount(t, id)
+	_, err = stores.RestoreState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := stores.FindSession(token)
+	if restored == nil {
+		t.Fatal("la sesión no volvió después del reinicio")
+	}
+	t.Cleanup(func() { stores.EndSession(restored) })
+	if user, partition, _, _ := restored.Get(); user != "root" || partition != id {
+		t.Errorf("la sesión restaurada es de %s en %s", user, partition)
+	}
+	if restored.GetToken() != token {
+		t.Error("la sesión restaurada no devuelve el token con que se encontró")
+	}
+	run(t, restored, "mkdir -path=/despues")
+}
@@ -0,0 +1,110 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	structures "backend/structures"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newHostDisk crea un disco en testDir, que a diferencia de los discos en memoria sí se guarda en el estado, con
+// una partición primaria P1 montada
+func newHostDisk(t *testing.T) (*stores.Session, string, string) {
+	t.Helper()
+	path := filepath.Join(testDir, "disco.mia")
+	session := &stores.Session{}
+	run(t, session, "mkdisk -size=3 -unit=M -path="+path)
+	run(t, session, "fdisk -size=2 -unit=M -name=P1 -path="+path)
+	run(t, session, "mount -name=P1 -path="+path)
+	id := mountedID(t, path)
+	t.Cleanup(func() {
+		Analyzer("logout", session)
+		for _, mounted := range stores.GetMountedPartitions() {
+			if stores.MountedPath(mounted) == path {
+				Analyzer("unmount -force -id="+mounted, session)
+			}
+		}
+		structures.DropDevice(path)
+		os.Remove(path)
+	})
+	return session, id, path
+}
+
+// forgetMount quita el montaje de las tablas del proceso sin tocar el disco, como si el servidor se reiniciara
+func forgetMount(t *testing.T, id string) {
+	t.Helper()
+	partition, path, err := stores.GetMountedPartition(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores.RemoveMount(id)
+	err = structures.DeactivatePartition(path, partition.Part_start)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMountByLabel(t *testing.T) {
+	session, id, path := newHostDisk(t)
+	run(t, session, "mkfs -id="+id+" -type=full -label=datos")
+	run(t, session, "unmount -id="+id)
+
+	output := run(t, session, "mount -label=datos -path="+path)
+	if !strings.Contains(output, "mounting partition P1 ") {
+		t.Errorf("el mensaje no tiene el nombre de la partición: %s", output)
+	}
+	mountedID(t, path)
+
+	_, err := Analyzer("mount -label=otra -path="+path, session)
+	if err == nil {
+		t.Error("se montó una partición con una etiqueta que no existe")
+	}
+}
+
+func TestRestoreState(t *testing.T) {
+	session, id, path := newHostDisk(t)
+	run(t, session, "mkfs -id="+id+" -type=full")
+	run(t, session, "login -user=root -pass=123 -id="+id)
+	run(t, session, "mkfile -size=10 -path=/a.txt")
+	err := stores.SaveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forgetMount(t, id)
+	dropped, err := stores.RestoreState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dropped) > 0 {
+		t.Errorf("se descartó lo guardado: %v", dropped)
+	}
+	if stores.MountedPath(id) != path {
+		t.Fatalf("el montaje %s no volvió", id)
+	}
+	run(t, session, "cat -file1=/a.txt")
+
+	// Si la partición se desmontó mientras el servidor estaba detenido el montaje se descarta
+	err = stores.SaveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(stores.StateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, session, "unmount -force -id="+id)
+	err = os.WriteFile(stores.StateFile, saved, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropped, err = stores.RestoreState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dropped) == 0 || stores.MountedPath(id) != "" {
+		t.Errorf("se restauró un montaje que ya no está en el MBR: %v", dropped)
+	}
+}
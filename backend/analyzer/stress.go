@@ -0,0 +1,363 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	structures "backend/structures"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type STRESS struct {
+	workers int // clientes que escriben en paralelo
+	readers int // clientes que leen mientras tanto
+	files   int // archivos que crea cada cliente
+	size    int // tamaño de cada archivo
+}
+
+// stressTarget es una partición en la que escriben los clientes de la prueba
+type stressTarget struct {
+	id      string
+	session *stores.Session
+	folder  string                 // carpeta de la corrida, "" cuando todavía no existe o ya se borró
+	initial *structures.SuperBlock // antes de crear la carpeta, para comprobar que la limpieza libera todo
+	before  *structures.SuperBlock // con la carpeta y el archivo de lectura ya creados
+	created int
+}
+
+// Tamaño del archivo que leen los clientes con cat mientras los demás escriben
+const stressSeedSize = 200
+
+// parseStress corre muchos mkfile en paralelo, igual que peticiones HTTP simultáneas, sobre la partición de la
+// sesión y otra partición montada del mismo disco en la que el mismo usuario tenga sesión, mientras otros clientes
+// leen con cat, getfs y rep. Después comprueba que ningún inodo ni bloque quedó reservado dos veces y que fsck no
+// encuentra problemas, y borra lo que creó.
+func parseStress(tokens []string, session *stores.Session) (string, error) {
+	cmd := &STRESS{workers: 8, readers: 4, files: 20, size: 64}
+
+	args := strings.Join(tokens, " ")
+	re := regexp.MustCompile(`-workers=[^\s]+|-readers=[^\s]+|-files=[^\s]+|-size=[^\s]+`)
+	matches := re.FindAllString(args, -1)
+
+	if len(matches) != len(tokens) {
+		for _, token := range tokens {
+			if !re.MatchString(token) {
+				return "", fmt.Errorf("parámetro inválido: %s", token)
+			}
+		}
+	}
+
+	for _, match := range matches {
+		kv := strings.SplitN(match, "=", 2)
+		key := strings.ToLower(kv[0])
+		value, err := strconv.Atoi(kv[1])
+		if err != nil || value < 0 || (value == 0 && key != "-readers") {
+			return "", fmt.Errorf("%s debe ser un entero positivo", key)
+		}
+
+		switch key {
+		case "-workers":
+			cmd.workers = value
+		case "-readers":
+			cmd.readers = value
+		case "-files":
+			cmd.files = value
+		case "-size":
+			cmd.size = value
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	_, partitionID, _, _ := session.Get()
+	if partitionID == "" {
+		return "", errors.New("no hay sesión activa")
+	}
+
+	return commandStress(cmd, session, partitionID)
+}
+
+func commandStress(cmd *STRESS, session *stores.Session, partitionID string) (string, error) {
+	targets := []*stressTarget{{id: partitionID, session: session}}
+
+	// La segunda partición solo se usa con una sesión que el mismo usuario ya abrió en ella
+	if other := stressOtherSession(session, partitionID); other != nil {
+		_, otherID, _, _ := other.Get()
+		targets = append(targets, &stressTarget{id: otherID, session: other})
+	}
+
+	// Cada corrida usa su propia carpeta en la raíz, así los contadores de antes y después solo cambian por los
+	// archivos; el nombre cabe en 12 bytes para que también sirva sin nombres largos
+	folder := fmt.Sprintf("/stress%d", time.Now().UnixNano()%100000)
+	defer stressCleanup(targets)
+	seed := folder + "/seed"
+	for _, target := range targets {
+		var err error
+		target.initial, err = stressSuperBlock(target.id)
+		if err != nil {
+			return "", err
+		}
+		_, err = Analyzer(fmt.Sprintf("mkdir -path=%s", folder), target.session)
+		if err != nil {
+			return "", fmt.Errorf("error al crear la carpeta de la prueba en %s: %w", target.id, err)
+		}
+		target.folder = folder
+		_, err = Analyzer(fmt.Sprintf("mkfile -path=%s -size=%d", seed, stressSeedSize), target.session)
+		if err != nil {
+			return "", fmt.Errorf("error al crear el archivo de lectura en %s: %w", target.id, err)
+		}
+
+		target.before, err = stressSuperBlock(target.id)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	reports, err := os.MkdirTemp("", "stress")
+	if err != nil {
+		return "", fmt.Errorf("error al crear la carpeta de los reportes: %w", err)
+	}
+	defer os.RemoveAll(reports)
+
+	var (
+		writers  sync.WaitGroup
+		readers  sync.WaitGroup
+		mu       sync.Mutex
+		reads    int
+		failures []string
+	)
+	fail := func(line string, err error) {
+		mu.Lock()
+		failures = append(failures, fmt.Sprintf("%s: %v", line, err))
+		mu.Unlock()
+	}
+
+	// Los lectores dan vueltas por cat, getfs y rep hasta que los escritores terminan
+	done := make(chan struct{})
+	expected := stressContent(stressSeedSize)
+	for reader := 1; reader <= cmd.readers; reader++ {
+		readers.Add(1)
+		go func(reader int) {
+			defer readers.Done()
+			for round := 0; ; round++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				target := targets[(reader+round)%len(targets)]
+				var line string
+				switch round % 3 {
+				case 0:
+					line = "cat -file1=" + seed
+					output, err := Analyzer(line, target.session)
+					if err == nil && !strings.Contains(output, expected) {
+						err = errors.New("el contenido leído no es el que se escribió")
+					}
+					if err != nil {
+						fail(line, err)
+					}
+				case 1:
+					line = "getfs"
+					_, err := Analyzer(line, target.session)
+					if err != nil {
+						fail(line, err)
+					}
+				case 2:
+					report := filepath.Join(reports, fmt.Sprintf("r%d-%d.json", reader, round))
+					line = fmt.Sprintf("rep -id=%s -path=%s -name=fs", target.id, report)
+					_, err := Analyzer(line, target.session)
+					if err != nil {
+						fail(line, err)
+					}
+				}
+
+				mu.Lock()
+				reads++
+				mu.Unlock()
+			}
+		}(reader)
+	}
+
+	started := time.Now()
+	for worker := 1; worker <= cmd.workers; worker++ {
+		writers.Add(1)
+		go func(worker int) {
+			defer writers.Done()
+			target := targets[worker%len(targets)]
+			for file := 1; file <= cmd.files; file++ {
+				line := fmt.Sprintf("mkfile -path=%s/w%df%d -size=%d", target.folder, worker, file, cmd.size)
+				_, err := Analyzer(line, target.session)
+				if err != nil {
+					fail(line, err)
+					continue
+				}
+				mu.Lock()
+				target.created++
+				mu.Unlock()
+			}
+		}(worker)
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+	elapsed := time.Since(started)
+
+	var output strings.Builder
+	ids := []string{}
+	for _, target := range targets {
+		ids = append(ids, target.id)
+	}
+	fmt.Fprintf(&output, "STRESS: %d clientes escribiendo y %d leyendo sobre %s en %s\n", cmd.workers, cmd.readers,
+		strings.Join(ids, " y "), elapsed.Round(time.Millisecond))
+	if len(targets) == 1 {
+		output.WriteString("-> Sin sesión del mismo usuario en otra partición montada del mismo disco, se escribe en una sola\n")
+	}
+
+	problems := 0
+	for _, target := range targets {
+		after, err := stressSuperBlock(target.id)
+		if err != nil {
+			return "", err
+		}
+		report, err := stressFsck(target.id)
+		if err != nil {
+			return "", err
+		}
+
+		// Cada mkfile reserva exactamente un inodo: si dos tomaron el mismo, el contador baja menos que los creados
+		inodes := target.before.S_free_inodes_count - after.S_free_inodes_count
+		blocks := target.before.S_free_blocks_count - after.S_free_blocks_count
+		if inodes != int32(target.created) {
+			problems++
+		}
+		problems += len(report.Problems)
+
+		fmt.Fprintf(&output, "-> Partición %s: %d archivos creados en %s\n", target.id, target.created, target.folder)
+		fmt.Fprintf(&output, "   Inodos reservados: %d (esperados %d), bloques reservados: %d\n", inodes, target.created, blocks)
+		fmt.Fprintf(&output, "   Problemas de fsck: %d\n", len(report.Problems))
+		for i, problem := range report.Problems {
+			if i == 5 {
+				fmt.Fprintf(&output, "   - ... y %d problemas más\n", len(report.Problems)-i)
+				break
+			}
+			fmt.Fprintf(&output, "   - %s\n", problem)
+		}
+	}
+
+	// Al borrar la carpeta la partición debe quedar con los mismos inodos y bloques libres que antes de la prueba
+	for _, failure := range stressCleanup(targets) {
+		fail("remove", failure)
+	}
+	for _, target := range targets {
+		after, err := stressSuperBlock(target.id)
+		if err != nil {
+			return "", err
+		}
+		if after.S_free_inodes_count != target.initial.S_free_inodes_count || after.S_free_blocks_count != target.initial.S_free_blocks_count {
+			problems++
+			fmt.Fprintf(&output, "-> Partición %s: después de borrar %s quedan %d inodos y %d bloques libres, antes había %d y %d\n",
+				target.id, folder, after.S_free_inodes_count, after.S_free_blocks_count,
+				target.initial.S_free_inodes_count, target.initial.S_free_blocks_count)
+		}
+	}
+
+	fmt.Fprintf(&output, "-> Lecturas con cat, getfs y rep: %d\n", reads)
+	fmt.Fprintf(&output, "-> Errores: %d\n", len(failures))
+	for i, failure := range failures {
+		if i == 5 {
+			fmt.Fprintf(&output, "   - ... y %d errores más\n", len(failures)-i)
+			break
+		}
+		fmt.Fprintf(&output, "   - %s\n", failure)
+	}
+
+	if problems > 0 || len(failures) > 0 {
+		return "", fmt.Errorf("%sla prueba encontró errores o inodos y bloques reservados dos veces", output.String())
+	}
+	output.WriteString("-> Sin errores ni inodos o bloques reservados dos veces, los archivos de la prueba se borraron")
+	return output.String(), nil
+}
+
+// stressOtherSession devuelve una sesión activa del mismo usuario en otra partición montada del mismo disco con
+// un sistema de archivos, o nil si no hay
+func stressOtherSession(session *stores.Session, partitionID string) *stores.Session {
+	user, _, uid, _ := session.Get()
+	path := stores.MountedPath(partitionID)
+	for _, other := range stores.Sessions() {
+		if other.User != user || other.UID != uid || other.Partition == partitionID || stores.MountedPath(other.Partition) != path {
+			continue
+		}
+		sb, err := stressSuperBlock(other.Partition)
+		if err != nil || !sb.Formatted() {
+			continue
+		}
+		// Sessions devuelve copias; los comandos corren con la sesión registrada, que logout puede cerrar
+		if live := stores.FindSession(other.Token); live != nil {
+			return live
+		}
+	}
+	return nil
+}
+
+// stressCleanup borra la carpeta de la corrida en cada partición donde se llegó a crear y devuelve los errores
+func stressCleanup(targets []*stressTarget) []error {
+	var failures []error
+	for _, target := range targets {
+		if target.folder == "" {
+			continue
+		}
+		_, err := Analyzer("remove -path="+target.folder, target.session)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("error al borrar %s en %s: %w", target.folder, target.id, err))
+			continue
+		}
+		target.folder = ""
+	}
+	return failures
+}
+
+// stressContent devuelve el contenido que mkfile escribe en un archivo de size bytes
+func stressContent(size int) string {
+	var content strings.Builder
+	for i := 0; i < size; i++ {
+		content.WriteByte(byte('0' + i%10))
+	}
+	return content.String()
+}
+
+// stressSuperBlock lee el superbloque de la partición sin que ningún comando la esté modificando
+func stressSuperBlock(partitionID string) (*structures.SuperBlock, error) {
+	unlock := stores.LockPartition(partitionID, false)
+	defer unlock()
+
+	sb, _, _, err := stores.GetMountedPartitionSuperblock(partitionID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la partición montada: %w", err)
+	}
+	return sb, nil
+}
+
+// stressFsck revisa la partición sin reparar: un bloque de dos archivos o un inodo con más entradas que
+// enlaces son las marcas de una reserva doble
+func stressFsck(partitionID string) (*structures.FsckReport, error) {
+	unlock := stores.LockPartition(partitionID, false)
+	defer unlock()
+
+	sb, partition, path, err := stores.GetMountedPartitionSuperblock(partitionID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la partición montada: %w", err)
+	}
+	report, err := sb.Fsck(path, partition, false)
+	if err != nil {
+		return nil, fmt.Errorf("error al revisar la partición: %w", err)
+	}
+	return report, nil
+}
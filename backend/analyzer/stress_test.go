@@ -0,0 +1,74 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	structures "backend/structures"
+	"strings"
+	"testing"
+)
+
+func TestStressUsesOnlyOwnSessions(t *testing.T) {
+	path := structures.MemoryPathPrefix + "carga.mia"
+	first := &stores.Session{}
+	second := &stores.Session{}
+	run(t, first, "mkdisk -size=5 -unit=M -path="+path)
+	run(t, first, "fdisk -size=2 -unit=M -name=P1 -path="+path)
+	run(t, first, "fdisk -size=2 -unit=M -name=P2 -path="+path)
+	run(t, first, "mount -name=P1 -path="+path)
+	run(t, first, "mount -name=P2 -path="+path)
+	var ids []string
+	for _, id := range stores.GetMountedPartitions() {
+		if stores.MountedPath(id) == path {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) != 2 {
+		t.Fatalf("se montaron %d particiones", len(ids))
+	}
+	t.Cleanup(func() {
+		Analyzer("logout", first)
+		Analyzer("logout", second)
+		for _, id := range ids {
+			Analyzer("unmount -id="+id, first)
+		}
+		Analyzer("rmdisk -path="+path, first)
+	})
+	for _, id := range ids {
+		run(t, first, "mkfs -id="+id+" -type=full")
+	}
+	run(t, first, "login -user=root -pass=123 -id="+ids[0])
+
+	// Sin sesión en la otra partición la prueba escribe solo en la propia
+	inodes, blocks := freeCounts(t, ids[1])
+	output := run(t, first, "stress -workers=3 -readers=2 -files=4 -size=30")
+	if strings.Contains(output, ids[1]) {
+		t.Errorf("la prueba usó la partición %s sin una sesión en ella:\n%s", ids[1], output)
+	}
+	if i, b := freeCounts(t, ids[1]); i != inodes || b != blocks {
+		t.Errorf("la partición sin sesión cambió: %d inodos y %d bloques libres, antes %d y %d", i, b, inodes, blocks)
+	}
+
+	run(t, second, "login -user=root -pass=123 -id="+ids[1])
+	before := make(map[string][2]int32)
+	for _, id := range ids {
+		i, b := freeCounts(t, id)
+		before[id] = [2]int32{i, b}
+	}
+	output = run(t, first, "stress -workers=4 -readers=2 -files=5 -size=30")
+	for _, id := range ids {
+		if !strings.Contains(output, "Partición "+id) {
+			t.Errorf("la prueba no escribió en %s:\n%s", id, output)
+		}
+		// Lo creado se borra al terminar
+		if i, b := freeCounts(t, id); i != before[id][0] || b != before[id][1] {
+			t.Errorf("la prueba dejó archivos en %s: %d inodos y %d bloques libres, antes %d y %d", id, i, b, before[id][0], before[id][1])
+		}
+		fsckClean(t, id)
+	}
+	if user, partition, _, _ := second.Get(); user != "root" || partition != ids[1] {
+		t.Errorf("la sesión de la otra partición cambió a %s en %s", user, partition)
+	}
+	if strings.Contains(run(t, first, "getfs"), `"stress`) {
+		t.Error("la carpeta de la prueba sigue en la partición")
+	}
+}
@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	stores "backend/stores"
+	"strings"
+	"testing"
+)
+
+// fsType devuelve el tipo de sistema de archivos de la partición montada
+func fsType(t *testing.T, id string) int32 {
+	t.Helper()
+	sb, _, _, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sb.S_filesystem_type
+}
+
+func TestTunefsKeepsFiles(t *testing.T) {
+	session, id := newMemoryPartition(t, "ff", "2fs")
+	run(t, session, "mkdir -p -path=/home/docs/nombre_de_carpeta_bastante_largo")
+	run(t, session, "mkfile -size=3000 -path=/home/docs/grande.txt")
+	run(t, session, "mkfile -size=40 -path=/home/docs/nombre_de_carpeta_bastante_largo/chico.txt")
+
+	check := func(fs int32) {
+		t.Helper()
+		if got := fsType(t, id); got != fs {
+			t.Fatalf("el sistema de archivos es ext%d, se esperaba ext%d", got, fs)
+		}
+		output := run(t, session, "cat -file1=/home/docs/grande.txt")
+		if !strings.Contains(output, stressContent(3000)) {
+			t.Error("grande.txt cambió al convertir la partición")
+		}
+		output = run(t, session, "cat -file1=/home/docs/nombre_de_carpeta_bastante_largo/chico.txt")
+		if !strings.Contains(output, stressContent(40)) {
+			t.Error("chico.txt cambió al convertir la partición")
+		}
+		fsckClean(t, id)
+	}
+
+	run(t, session, "tunefs -id="+id+" -journal=on")
+	check(3)
+	run(t, session, "mkfile -size=20 -path=/home/nuevo.txt")
+	run(t, session, "tunefs -id="+id+" -journal=off")
+	check(2)
+}
+
+func TestTunefsRejectsSmallJournal(t *testing.T) {
+	session, id := newMemoryPartition(t, "ff", "2fs")
+	reformat(t, session, id, "-fs=2fs -inode_ratio=100000")
+	run(t, session, "mkfile -size=20 -path=/a.txt")
+
+	// Con tan pocos inodos el journal tendría menos registros que el mínimo
+	_, err := Analyzer("tunefs -id="+id+" -journal=on", session)
+	if err == nil {
+		t.Fatal("tunefs pasó a ext3 una partición sin lugar para el journal")
+	}
+	if got := fsType(t, id); got != 2 {
+		t.Errorf("el sistema de archivos quedó como ext%d", got)
+	}
+	output := run(t, session, "cat -file1=/a.txt")
+	if !strings.Contains(output, stressContent(20)) {
+		t.Error("a.txt cambió al rechazar tunefs")
+	}
+	fsckClean(t, id)
+}
@@ -19,7 +19,7 @@ type CAT struct {
 	files []string
 }
 
-func ParseCat(tokens []string) (string, error) {
+func ParseCat(tokens []string, session *stores.Session) (string, error) {
 	cmd := &CAT{} // create the mkdisk command
 
 	args := strings.Join(tokens, " ") // join the tokens to get the arguments
@@ -49,7 +49,7 @@ func ParseCat(tokens []string) (string, error) {
 	fmt.Println("CAT")
 	fmt.Println(cmd.files)
 
-	message, err := commandCat(cmd)
+	message, err := commandCat(cmd, session)
 
 	if err != nil {
 		return "", err
@@ -60,10 +60,10 @@ func ParseCat(tokens []string) (string, error) {
 
 // el cmd.files es una ruta, ir desapilando la ruta hasta llegar al archivo
 // usando recursividad
-func commandCat(cmd *CAT) (string, error) {
+func commandCat(cmd *CAT, session *stores.Session) (string, error) {
 
 	// obtener la sesión activa
-	username, idPartition,_, _ := stores.GetSession()
+	username, idPartition,_, _ := session.Get()
 	if username == "" || idPartition == "" {
 		return "", errors.New("no hay sesión activa")
 	}
@@ -13,7 +13,7 @@ type CHGRP struct {
 	grp string
 }
 
-func ParseChgrp(tokens []string) (string, error) {
+func ParseChgrp(tokens []string, session *stores.Session) (string, error) {
 	cmd := &CHGRP{}
 
 	args := strings.Join(tokens, " ")
@@ -60,12 +60,12 @@ func ParseChgrp(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -user y -grp")
 	}
 
-	return changeGroup(cmd.user, cmd.grp), nil
+	return changeGroup(cmd.user, cmd.grp, session), nil
 }
 
-func changeGroup(user string, grp string) string {
+func changeGroup(user string, grp string, session *stores.Session) string {
 	// get the current session
-	userName, idPartition, _, _ := stores.GetSession()
+	userName, idPartition, _, _ := session.Get()
 	if userName == "" {
 		return "No hay sesión activa"
 	}
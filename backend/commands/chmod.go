@@ -15,7 +15,7 @@ type CHMOD struct {
 	r    bool
 }
 
-func ParseCHMOD(tokens []string) (string, error) {
+func ParseCHMOD(tokens []string, session *stores.Session) (string, error) {
 	cmd := &CHMOD{}
 
 	args := strings.Join(tokens, " ")
@@ -68,7 +68,7 @@ func ParseCHMOD(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -ugo")
 	}
 
-	err := commandCHMOD(cmd)
+	err := commandCHMOD(cmd, session)
 	if err != nil {
 		return "", err
 	}
@@ -82,9 +82,9 @@ func ParseCHMOD(tokens []string) (string, error) {
 		cmd.r), nil
 }
 
-func commandCHMOD(cmd *CHMOD) error {
+func commandCHMOD(cmd *CHMOD, session *stores.Session) error {
 	// obtener la sesion
-	username, idPartition, uid, gid := stores.GetSession()
+	username, idPartition, uid, gid := session.Get()
 	if username == "" || idPartition == "" || uid == 0 || gid == 0 {
 		return errors.New("no hay sesión activa")
 	}
@@ -15,7 +15,7 @@ type CHOWN struct {
 	usuario string
 }
 
-func ParseCHOWN(tokens []string) (string, error) {
+func ParseCHOWN(tokens []string, session *stores.Session) (string, error) {
 	cmd := &CHOWN{}
 
 	args := strings.Join(tokens, " ")
@@ -68,7 +68,7 @@ func ParseCHOWN(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -usuario")
 	}
 
-	err := commandCHOWN(cmd)
+	err := commandCHOWN(cmd, session)
 	if err != nil {
 		return "", err
 	}
@@ -82,9 +82,9 @@ func ParseCHOWN(tokens []string) (string, error) {
 		cmd.r), nil
 }
 
-func commandCHOWN(cmd *CHOWN) error {
+func commandCHOWN(cmd *CHOWN, session *stores.Session) error {
 	// obtener la sesion
-	username, idPartition, _, _ := stores.GetSession()
+	username, idPartition, _, _ := session.Get()
 	if username == "" || idPartition == "" {
 		return errors.New("no hay sesión activa")
 	}
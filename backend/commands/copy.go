@@ -14,7 +14,7 @@ type COPY struct {
 	destino string
 }
 
-func ParseCopy(tokens []string) (string, error) {
+func ParseCopy(tokens []string, session *stores.Session) (string, error) {
 	cmd := &COPY{}
 
 	args := strings.Join(tokens, " ")
@@ -65,7 +65,7 @@ func ParseCopy(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -destino")
 	}
 
-	err := commandCopy(cmd)
+	err := commandCopy(cmd, session)
 	if err != nil {
 		return "", err
 	}
@@ -73,10 +73,10 @@ func ParseCopy(tokens []string) (string, error) {
 	return fmt.Sprintf("COPY: Archivo %s copiado exitosamente.", cmd.path), nil
 }
 
-func commandCopy(cmd *COPY) error {
+func commandCopy(cmd *COPY, session *stores.Session) error {
 	parentDirs, destDir := utils.GetParentDirectories(cmd.path)
 	destinoParentDirs, destinoDir := utils.GetParentDirectories(cmd.destino)
-	username, idPartition, uid, gid := stores.GetSession()
+	username, idPartition, uid, gid := session.Get()
 	if username == "" || idPartition == "" {
 		return errors.New("no hay sesión activa")
 	}
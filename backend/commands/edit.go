@@ -15,7 +15,7 @@ type EDIT struct {
 	contenido string // path del archivo con el contenido
 }
 
-func ParseEdit(tokens []string) (string, error) {
+func ParseEdit(tokens []string, session *stores.Session) (string, error) {
 	cmd := &EDIT{}
 
 	args := strings.Join(tokens, " ")
@@ -66,7 +66,7 @@ func ParseEdit(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -cont")
 	}
 
-	err := commandEdit(cmd)
+	err := commandEdit(cmd, session)
 	if err != nil {
 		return "", err
 	}
@@ -74,9 +74,9 @@ func ParseEdit(tokens []string) (string, error) {
 	return fmt.Sprintf("EDIT: Archivo %s editado correctamente.", cmd.path), nil // Devuelve el comando EDIT creado
 }
 
-func commandEdit(cmd *EDIT) error {
+func commandEdit(cmd *EDIT, session *stores.Session) error {
 	// obtener la sesion
-	username, idPartition, uid, gid := stores.GetSession()
+	username, idPartition, uid, gid := session.Get()
 	if username == "" || idPartition == "" {
 		return errors.New("no hay sesión activa")
 	}
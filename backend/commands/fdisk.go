@@ -1,11 +1,11 @@
 package commands
 
 import (
+	stores "backend/stores"
 	structures "backend/structures"
 	utils "backend/utils"
 	"errors"
 	"fmt"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -27,7 +27,7 @@ func ParseFdisk(tokens []string) (string, error) {
 
 	args := strings.Join(tokens, " ") // join the tokens to get the arguments
 
-	re := regexp.MustCompile(`-size=\d+|-unit=[kKmMbB]|-fit=[bBfF]{2}|-path="[^"]+"|-path=[^\s]+|-type=[pPeElL]|-name="[^"]+"|-name=[^\s]+|-delete="(fast|full)"|-delete=(fast|full)|-add=[-+]?\d+(\.\d+)?`)
+	re := regexp.MustCompile(`-size=\d+|-unit=[kKmMbB]|-fit=[bBfFwW]{2}|-path="[^"]+"|-path=[^\s]+|-type=[pPeElL]|-name="[^"]+"|-name=[^\s]+|-delete="(fast|full)"|-delete=(fast|full)|-add=[-+]?\d+(\.\d+)?`)
 
 	matches := re.FindAllString(args, -1) // find all the matches
 
@@ -105,10 +105,6 @@ func ParseFdisk(tokens []string) (string, error) {
 		cmd.unit = "M"
 	}
 
-	if cmd.fit == "" {
-		cmd.fit = "FF"
-	}
-
 	if cmd.type_ == "" {
 		cmd.type_ = "P"
 	}
@@ -150,6 +146,22 @@ func commandFdisk(fdisk *FDISK) error {
 		return err
 	}
 
+	// A GPT disk has its own partition table instead of the MBR
+	if structures.IsGPT(fdisk.path) {
+		return fdiskGPT(fdisk, sizeBytes)
+	}
+
+	// Without -fit the partition uses the fit of the disk
+	if fdisk.fit == "" {
+		var mbr structures.MBR
+		err = mbr.DeserializeMBR(fdisk.path)
+		if err != nil {
+			fmt.Println("error reading MBR:", err)
+			return err
+		}
+		fdisk.fit = string(mbr.Mbr_disk_fit[0]) + "F"
+	}
+
 	if fdisk.delete != "" {
 		if fdisk.delete == "fast" {
 			err = deletePartition(fdisk)
@@ -181,6 +193,12 @@ func commandFdisk(fdisk *FDISK) error {
 		return nil
 	}
 
+	// Partitions are found by name, so a name cannot repeat in the disk
+	err = checkPartitionName(fdisk)
+	if err != nil {
+		return err
+	}
+
 	if fdisk.type_ == "P" {
 		err = createPrimaryPartition(fdisk, sizeBytes)
 		if err != nil {
@@ -206,6 +224,26 @@ func commandFdisk(fdisk *FDISK) error {
 	return nil
 }
 
+// checkPartitionName fails if a primary, extended or logical partition of the disk already has the name
+func checkPartitionName(fdisk *FDISK) error {
+	var mbr structures.MBR
+	err := mbr.DeserializeMBR(fdisk.path)
+	if err != nil {
+		fmt.Println("error reading MBR:", err)
+		return err
+	}
+
+	partition, _ := mbr.GetPartitionByName(fdisk.name)
+	logical, err := mbr.GetLogicalPartitionByName(fdisk.path, fdisk.name)
+	if err != nil {
+		return err
+	}
+	if partition != nil || logical != nil {
+		return fmt.Errorf("a partition named %s already exists in the disk", fdisk.name)
+	}
+	return nil
+}
+
 func createPrimaryPartition(fdisk *FDISK, sizeBytes int) error {
 	var mbr structures.MBR // create the MBR struct
 
@@ -215,20 +253,14 @@ func createPrimaryPartition(fdisk *FDISK, sizeBytes int) error {
 		return err
 	}
 
-	// check if is there is space for the partition
-	freeSpace := mbr.GetFreeSpace()
-	if freeSpace < int32(sizeBytes) {
-		fmt.Println("not enough space in the MBR")
-		return errors.New("not enough space in the disk")
-	}
 	fmt.Println("MBR before creating primary partition")
 	mbr.PrintMBR()
 
-	// Get the first free partition
-	availablePartition, startPartition, index := mbr.GetFreePartition()
-	if availablePartition == nil {
-		fmt.Println("no available partition")
-		return errors.New("no available partition")
+	// Get the first free slot and the gap chosen by the fit
+	availablePartition, startPartition, index, err := mbr.GetFreePartition(fdisk.path, int32(sizeBytes), fdisk.fit[0])
+	if err != nil {
+		fmt.Println("no available partition:", err)
+		return err
 	}
 
 	fmt.Println("Available partition")
@@ -243,6 +275,12 @@ func createPrimaryPartition(fdisk *FDISK, sizeBytes int) error {
 		mbr.Mbr_partitions[index] = *availablePartition // update the partition
 	}
 
+	err = mbr.ValidateLayout(fdisk.path)
+	if err != nil {
+		fmt.Println("invalid partition layout:", err)
+		return err
+	}
+
 	// Print the partitions
 	fmt.Println("\nParticiones del MBR:")
 	mbr.PrintPartitions()
@@ -269,20 +307,14 @@ func createExtendedPartition(fdisk *FDISK, sizeBytes int) error {
 		fmt.Println("extended partition already exists")
 		return errors.New("extended partition already exists")
 	}
-	// Check if there is space for the extended partition
-	availablePartition, _, _ := mbr.GetFreePartition()
-	if availablePartition == nil {
-		fmt.Println("no available partition")
-		return errors.New("no available partition")
-	}
-
 	fmt.Println("MBR before creating extended partition")
 	mbr.PrintMBR()
 
-	availablePartition, startPartition, index := mbr.GetFreePartition()
-	if availablePartition == nil {
-		fmt.Println("no available partition")
-		return errors.New("no available partition")
+	// Get the first free slot and the gap chosen by the fit
+	availablePartition, startPartition, index, err := mbr.GetFreePartition(fdisk.path, int32(sizeBytes), fdisk.fit[0])
+	if err != nil {
+		fmt.Println("no available partition:", err)
+		return err
 	}
 
 	fmt.Println("Available partition")
@@ -297,15 +329,21 @@ func createExtendedPartition(fdisk *FDISK, sizeBytes int) error {
 		mbr.Mbr_partitions[index] = *availablePartition
 	}
 
+	err = mbr.ValidateLayout(fdisk.path)
+	if err != nil {
+		fmt.Println("invalid partition layout:", err)
+		return err
+	}
+
+	// The first EBR is always at the start of the extended partition; it stays empty until a logical partition takes it
 	ebr := &structures.EBR{
 		Ebr_part_mount: [1]byte{'N'},
 		Ebr_part_fit:   [1]byte{fdisk.fit[0]},
-		Ebr_part_start: int32(startPartition),
+		Ebr_part_start: int32(startPartition) + structures.EBRSize,
 		Ebr_part_size:  -1,
 		Ebr_part_next:  -1,
 		Ebr_part_name:  [16]byte{},
 	}
-	copy(ebr.Ebr_part_name[:], fdisk.name)
 	fmt.Println("EBR has been created")
 	ebr.PrintEBR()
 
@@ -353,70 +391,61 @@ func createLogicalPartition(fdisk *FDISK, logicalSize int) error {
 	fmt.Println("Extended partition")
 	extendedPartition.PrintPartition()
 
-	// Get the first ebr in the extendened partition, the first ebr is in the extendedPartition.Part_start + 32
-	ebr := &structures.EBR{}
-	err = ebr.DeserializeEBR(fdisk.path, int64(extendedPartition.Part_start))
+	// 3. Choose with the fit the gap of the extended partition where the new EBR goes
+	ebrStart, err := mbr.FindLogicalGap(fdisk.path, int32(logicalSize), fdisk.fit[0])
 	if err != nil {
-		fmt.Println("error reading EBR:", err)
+		fmt.Println("not enough space in the extended partition")
+		return fmt.Errorf("not enough space in the extended partition: %w", err)
 	}
+	fmt.Println("New EBR start:", ebrStart)
 
-	fmt.Println("First EBR")
-	ebr.PrintEBR()
-
-	lastEBR, _, err := extendedPartition.GetLastEBR(ebr, fdisk.path)
-	fmt.Println("Last EBR")
-	lastEBR.PrintEBR()
-
-	// Calculate the start of the new EBR
-	newEbrStart := lastEBR.Ebr_part_start + structures.EBRSize + int32(logicalSize)
-	fmt.Println("New EBR start:", newEbrStart)
-
-	// Ensure that the new EBR does not overlap with other partitions or EBRs
-	if newEbrStart > extendedPartition.Part_start+extendedPartition.Part_size {
-		fmt.Println("not enough space in the extended partition")
-		return errors.New("not enough space in the extended partition")
+	logicals, err := mbr.GetLogicalPartitions(fdisk.path)
+	if err != nil {
+		fmt.Println("error reading the EBR chain:", err)
+		return err
 	}
 
-	// Create the new EBR
+	// The data of the logical partition starts right after its EBR
 	newEbr := &structures.EBR{
 		Ebr_part_mount: [1]byte{'N'},
 		Ebr_part_fit:   [1]byte{fdisk.fit[0]},
-		Ebr_part_start: newEbrStart,
+		Ebr_part_start: ebrStart + structures.EBRSize,
 		Ebr_part_size:  int32(logicalSize),
 		Ebr_part_next:  -1,
 		Ebr_part_name:  [16]byte{},
 	}
-
 	copy(newEbr.Ebr_part_name[:], fdisk.name)
 
+	// 4. Link it in the chain, which is kept in disk order; the first EBR is reused when it is empty
+	var previous *structures.LogicalPartition
+	if ebrStart == extendedPartition.Part_start {
+		newEbr.Ebr_part_next = logicals[0].EBR.Ebr_part_next
+	} else {
+		previous = &logicals[0]
+		for i := range logicals {
+			if logicals[i].Offset < ebrStart {
+				previous = &logicals[i]
+			}
+		}
+		newEbr.Ebr_part_next = previous.EBR.Ebr_part_next
+		previous.EBR.Ebr_part_next = ebrStart
+	}
+
 	fmt.Println("New EBR")
 	newEbr.PrintEBR()
 
-	// Update the last EBR
-	lastEBR.Ebr_part_next = newEbr.Ebr_part_start
-	lastEBR.Ebr_part_size = int32(logicalSize)
-	fmt.Println("Last EBR after update")
-	lastEBR.PrintEBR()
-
-	// Serialize the new EBR
-	err = newEbr.SerializeEBR(fdisk.path, int64(newEbr.Ebr_part_start))
+	// The new EBR is written before the one that points to it, so the chain is never left pointing to garbage
+	err = newEbr.SerializeEBR(fdisk.path, int64(ebrStart))
 	if err != nil {
 		fmt.Println("error serializing new EBR:", err)
 		return err
 	}
-
-	// Serialize the last EBR
-	err = lastEBR.SerializeEBR(fdisk.path, int64(lastEBR.Ebr_part_start))
-	if err != nil {
-		fmt.Println("error serializing last EBR:", err)
-		return err
-	}
-
-	// Serialize the MBR
-	err = mbr.SerializeMBR(fdisk.path)
-	if err != nil {
-		fmt.Println("error serializing MBR", err)
-		return err
+	if previous != nil {
+		err = previous.EBR.SerializeEBR(fdisk.path, int64(previous.Offset))
+		if err != nil {
+			fmt.Println("error serializing previous EBR:", err)
+			return err
+		}
 	}
 
 	fmt.Println("Logical partition created successfully")
@@ -440,18 +469,38 @@ func addPartition(fdisk *FDISK, sizeBytes int) error {
 
 	partitionFound, index := mbr.GetPartitionByName(fdisk.name)
 	if partitionFound == nil {
-		fmt.Println("partition not found")
-		return errors.New("partition not found")
+		logical, err := mbr.GetLogicalPartitionByName(fdisk.path, fdisk.name)
+		if err != nil {
+			fmt.Println("error reading the EBR chain:", err)
+			return err
+		}
+		if logical == nil {
+			fmt.Println("partition not found")
+			return errors.New("partition not found")
+		}
+		return addLogicalPartition(fdisk, &mbr, logical, sizeBytes)
 	}
 
-	freeSpace := mbr.GetFreeSpace()
+	newSize := partitionFound.Part_size + int32(sizeBytes)
+	if newSize <= 0 {
+		fmt.Println("the partition would have no space left")
+		return fmt.Errorf("cannot remove %d bytes from a partition of %d bytes", -sizeBytes, partitionFound.Part_size)
+	}
 
-	if freeSpace < int32(sizeBytes) {
-		fmt.Println("not enough space in the disk")
-		return errors.New("not enough space in the disk")
+	// The partition grows in place, so it must still fit in the disk without reaching the next one
+	mbr.Mbr_partitions[index-1].Part_size = newSize
+	err = mbr.ValidateLayout(fdisk.path)
+	if err != nil {
+		fmt.Println("not enough space after the partition")
+		return fmt.Errorf("not enough space after the partition: %w", err)
 	}
 
-	mbr.Mbr_partitions[index-1].Part_size += int32(sizeBytes)
+	// A formatted partition takes its filesystem to the new size; shrinking is refused if the content does not fit
+	err = resizePartitionFileSystem(fdisk.path, partitionFound, &mbr.Mbr_partitions[index-1])
+	if err != nil {
+		fmt.Println("error resizing filesystem:", err)
+		return err
+	}
 
 	fmt.Println("MBR after adding space")
 	mbr.PrintPartitions()
@@ -467,6 +516,84 @@ func addPartition(fdisk *FDISK, sizeBytes int) error {
 	return nil
 }
 
+// addLogicalPartition grows or shrinks a logical partition in place, up to the next EBR or the end of the extended partition
+func addLogicalPartition(fdisk *FDISK, mbr *structures.MBR, logical *structures.LogicalPartition, sizeBytes int) error {
+	newSize := logical.EBR.Ebr_part_size + int32(sizeBytes)
+	if newSize <= 0 {
+		fmt.Println("the partition would have no space left")
+		return fmt.Errorf("cannot remove %d bytes from a partition of %d bytes", -sizeBytes, logical.EBR.Ebr_part_size)
+	}
+
+	extendedPartition, _ := mbr.GetExtendedPartition()
+	limit := extendedPartition.Part_start + extendedPartition.Part_size
+	if logical.EBR.Ebr_part_next != -1 {
+		limit = logical.EBR.Ebr_part_next
+	}
+	if logical.EBR.Ebr_part_start+newSize > limit {
+		fmt.Println("not enough space after the partition")
+		return fmt.Errorf("not enough space after the partition: %d bytes free", max(0, limit-logical.EBR.Ebr_part_start-logical.EBR.Ebr_part_size))
+	}
+
+	// A formatted partition takes its filesystem to the new size; shrinking is refused if the content does not fit
+	partition := logical.Partition()
+	resized := *partition
+	resized.Part_size = newSize
+	err := resizePartitionFileSystem(fdisk.path, partition, &resized)
+	if err != nil {
+		fmt.Println("error resizing filesystem:", err)
+		return err
+	}
+
+	logical.EBR.Ebr_part_size = newSize
+	err = logical.EBR.SerializeEBR(fdisk.path, int64(logical.Offset))
+	if err != nil {
+		fmt.Println("error serializing EBR:", err)
+		return err
+	}
+
+	fmt.Println("Logical partition resized successfully")
+	return nil
+}
+
+// resizePartitionFileSystem runs resizefs on the partition if it holds a filesystem
+func resizePartitionFileSystem(path string, partition *structures.Partition, resized *structures.Partition) error {
+	sb := &structures.SuperBlock{}
+	err := sb.Deserialize(path, int64(partition.Part_start))
+	if err != nil {
+		return err
+	}
+	if !sb.Formatted() || !sb.ValidFor(partition) {
+		return nil
+	}
+
+	// A partition that is not mounted is only checked against its checksums during the resize
+	if stores.MountedID(path, partition) == "" {
+		sb.TrackChecksums(path, partition.Part_start, partition.Part_size)
+		sb.TrackLayout(path, partition)
+		defer structures.UntrackChecksums(path, partition.Part_start)
+		defer structures.UntrackLayout(path, partition.Part_start)
+	}
+
+	// A filesystem of the previous layout is not relayouted: it stays as it is while the partition still holds it
+	if sb.Legacy() {
+		end := int64(resized.Part_start) + int64(resized.Part_size)
+		if sb.End() > end {
+			return fmt.Errorf("the filesystem uses the previous layout and takes up to byte %d, the partition cannot end at byte %d", sb.End(), end)
+		}
+		fmt.Println("filesystem of the previous layout kept as is, only the partition was resized")
+		return nil
+	}
+
+	target, result, err := resizeFileSystem(sb, resized, path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("filesystem resized: %d -> %d inodes (%d moved), %d -> %d blocks (%d moved)\n",
+		sb.TotalInodes(), target.TotalInodes(), result.MovedInodes,
+		sb.TotalBlocks(), target.TotalBlocks(), result.MovedBlocks)
+	return nil
+}
+
 // Delete a partition from the MBR
 func deletePartition(fdisk *FDISK) error {
 	var mbr structures.MBR
@@ -479,8 +606,33 @@ func deletePartition(fdisk *FDISK) error {
 
 	partitionFound, index := mbr.GetPartitionByName(fdisk.name)
 	if partitionFound == nil {
-		fmt.Println("partition not found")
-		return errors.New("partition not found")
+		logical, err := mbr.GetLogicalPartitionByName(fdisk.path, fdisk.name)
+		if err != nil {
+			fmt.Println("error reading the EBR chain:", err)
+			return err
+		}
+		if logical == nil {
+			fmt.Println("partition not found")
+			return errors.New("partition not found")
+		}
+		return deleteLogicalPartition(fdisk, &mbr, logical)
+	}
+
+	// A mounted partition, or an extended one with a mounted logical partition, must be unmounted first
+	if id := stores.MountedID(fdisk.path, partitionFound); id != "" {
+		return fmt.Errorf("partition %s is mounted as %s", fdisk.name, id)
+	}
+	if partitionFound.Part_type[0] == 'E' {
+		logicals, err := mbr.GetLogicalPartitions(fdisk.path)
+		if err != nil {
+			fmt.Println("error reading the EBR chain:", err)
+			return err
+		}
+		for _, logical := range logicals {
+			if id := stores.MountedID(fdisk.path, logical.Partition()); !logical.Empty() && id != "" {
+				return fmt.Errorf("logical partition %s is mounted as %s", logical.Name(), id)
+			}
+		}
 	}
 
 	// Save partition info before deletion
@@ -491,59 +643,111 @@ func deletePartition(fdisk *FDISK) error {
 	partitionFound.DeletePartition()
 
 	if fdisk.delete == "full" {
-		fmt.Println("filling space with 0 character")
-
-		// Open the disk file
-		file, err := os.OpenFile(fdisk.path, os.O_WRONLY, 0644)
+		err = zeroFill(fdisk.path, partStart, partSize)
 		if err != nil {
-			fmt.Println("error opening disk file:", err)
 			return err
 		}
-		defer file.Close()
+	}
+
+	mbr.Mbr_partitions[index-1] = *partitionFound
 
-		// Seek to the start of the partition
-		_, err = file.Seek(int64(partStart), 0)
+	// Serialize the MBR
+	err = mbr.SerializeMBR(fdisk.path)
+	if err != nil {
+		fmt.Println("error serializing MBR", err)
+		return err
+	}
+
+	fmt.Println("Partition deleted successfully")
+	return nil
+}
+
+// deleteLogicalPartition unlinks the logical partition from the EBR chain; the first EBR is only emptied
+func deleteLogicalPartition(fdisk *FDISK, mbr *structures.MBR, logical *structures.LogicalPartition) error {
+	if id := stores.MountedID(fdisk.path, logical.Partition()); id != "" {
+		return fmt.Errorf("partition %s is mounted as %s", fdisk.name, id)
+	}
+
+	logicals, err := mbr.GetLogicalPartitions(fdisk.path)
+	if err != nil {
+		fmt.Println("error reading the EBR chain:", err)
+		return err
+	}
+	extendedPartition, _ := mbr.GetExtendedPartition()
+	partStart, partSize := logical.EBR.Ebr_part_start, logical.EBR.Ebr_part_size
+
+	if logical.Offset == extendedPartition.Part_start {
+		logical.EBR = structures.EBR{
+			Ebr_part_mount: [1]byte{'N'},
+			Ebr_part_fit:   logical.EBR.Ebr_part_fit,
+			Ebr_part_start: logical.Offset + structures.EBRSize,
+			Ebr_part_size:  -1,
+			Ebr_part_next:  logical.EBR.Ebr_part_next,
+		}
+		err = logical.EBR.SerializeEBR(fdisk.path, int64(logical.Offset))
 		if err != nil {
-			fmt.Println("error seeking to partition start:", err)
+			fmt.Println("error serializing EBR:", err)
 			return err
 		}
-
-		// Create a buffer filled with zeros
-		bufferSize := 1024 * 1024 // 1MB buffer
-		if partSize < int32(bufferSize) {
-			bufferSize = int(partSize)
-		}
-		zeroBuffer := make([]byte, bufferSize)
-
-		// Write zeros in chunks
-		remaining := int(partSize)
-		for remaining > 0 {
-			writeSize := bufferSize
-			if remaining < bufferSize {
-				writeSize = remaining
+	} else {
+		for i := range logicals {
+			previous := &logicals[i]
+			if previous.EBR.Ebr_part_next != logical.Offset {
+				continue
 			}
-
-			_, err = file.Write(zeroBuffer[:writeSize])
+			previous.EBR.Ebr_part_next = logical.EBR.Ebr_part_next
+			err = previous.EBR.SerializeEBR(fdisk.path, int64(previous.Offset))
 			if err != nil {
-				fmt.Println("error writing zeros:", err)
+				fmt.Println("error serializing EBR:", err)
 				return err
 			}
+		}
+		// Without its EBR the space is free again
+		partSize += partStart - logical.Offset
+		partStart = logical.Offset
+	}
 
-			remaining -= writeSize
+	if fdisk.delete == "full" {
+		err = zeroFill(fdisk.path, partStart, partSize)
+		if err != nil {
+			return err
 		}
+	}
 
-		fmt.Printf("Successfully overwrote %d bytes with zeros\n", partSize)
+	fmt.Println("Logical partition deleted successfully")
+	return nil
+}
+
+// zeroFill overwrites size bytes of the disk with zeros starting at start
+func zeroFill(path string, start int32, size int32) error {
+	fmt.Println("filling space with 0 character")
+
+	// Create a buffer filled with zeros
+	bufferSize := 1024 * 1024 // 1MB buffer
+	if size < int32(bufferSize) {
+		bufferSize = int(size)
 	}
+	zeroBuffer := make([]byte, bufferSize)
 
-	mbr.Mbr_partitions[index-1] = *partitionFound
+	// Write zeros in chunks from the start of the partition
+	position := int64(start)
+	remaining := int(size)
+	for remaining > 0 {
+		writeSize := bufferSize
+		if remaining < bufferSize {
+			writeSize = remaining
+		}
 
-	// Serialize the MBR
-	err = mbr.SerializeMBR(fdisk.path)
-	if err != nil {
-		fmt.Println("error serializing MBR", err)
-		return err
+		err := structures.DeviceWriteAt(path, zeroBuffer[:writeSize], position)
+		if err != nil {
+			fmt.Println("error writing zeros:", err)
+			return err
+		}
+
+		position += int64(writeSize)
+		remaining -= writeSize
 	}
 
-	fmt.Println("Partition deleted successfully")
+	fmt.Printf("Successfully overwrote %d bytes with zeros\n", size)
 	return nil
 }
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	stores "backend/stores"
+	structures "backend/structures"
+	utils "backend/utils"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// fdiskGPT runs fdisk on a GPT disk: up to 128 primary partitions identified by GUID, no extended or logical ones
+func fdiskGPT(fdisk *FDISK, sizeBytes int) error {
+	gpt, err := structures.ReadGPT(fdisk.path)
+	if err != nil {
+		fmt.Println("error reading GPT:", err)
+		return err
+	}
+
+	// A GPT has no disk fit to fall back to
+	if fdisk.fit == "" {
+		fdisk.fit = "FF"
+	}
+
+	if fdisk.delete != "" {
+		err = deleteGPTPartition(fdisk, gpt)
+		if err != nil {
+			fmt.Println("error deleting partition:", err)
+		}
+		return err
+	}
+
+	if fdisk.add != 0 {
+		sizeToAdd, err := utils.ConvertToBytes(fdisk.add, fdisk.unit)
+		if err != nil {
+			fmt.Println("error converting size to bytes:", err)
+			return err
+		}
+		err = addGPTPartition(fdisk, gpt, sizeToAdd)
+		if err != nil {
+			fmt.Println("error adding partition:", err)
+		}
+		return err
+	}
+
+	if fdisk.type_ != "P" {
+		return errors.New("a GPT disk only has primary partitions")
+	}
+	if len(utf16.Encode([]rune(fdisk.name))) > 36 {
+		return errors.New("a GPT partition name has at most 36 characters")
+	}
+	index, err := gpt.AddPartition(fdisk.name, int32(sizeBytes), fdisk.fit[0])
+	if err != nil {
+		fmt.Println("no available partition:", err)
+		return err
+	}
+
+	entry := &gpt.Entries[index]
+	fmt.Printf("GPT partition %d: %s, GUID %s, LBA %d - %d\n", index+1, entry.Name(), entry.UniquePartitionGUID, entry.StartingLBA, entry.EndingLBA)
+
+	err = gpt.Write(fdisk.path)
+	if err != nil {
+		fmt.Println("error writing GPT:", err)
+		return err
+	}
+	return nil
+}
+
+// addGPTPartition grows or shrinks a GPT partition in place, up to the next partition or the last usable LBA
+func addGPTPartition(fdisk *FDISK, gpt *structures.GPT, sizeBytes int) error {
+	index := gpt.FindByName(fdisk.name)
+	if index == -1 {
+		fmt.Println("partition not found")
+		return errors.New("partition not found")
+	}
+
+	partition := gpt.Partition(index)
+	newSize := partition.Part_size + int32(sizeBytes)
+	if newSize <= 0 {
+		fmt.Println("the partition would have no space left")
+		return fmt.Errorf("cannot remove %d bytes from a partition of %d bytes", -sizeBytes, partition.Part_size)
+	}
+
+	err := gpt.Resize(index, newSize)
+	if err != nil {
+		fmt.Println("not enough space after the partition")
+		return fmt.Errorf("not enough space after the partition: %w", err)
+	}
+
+	// A formatted partition takes its filesystem to the new size; shrinking is refused if the content does not fit
+	err = resizePartitionFileSystem(fdisk.path, partition, gpt.Partition(index))
+	if err != nil {
+		fmt.Println("error resizing filesystem:", err)
+		return err
+	}
+
+	err = gpt.Write(fdisk.path)
+	if err != nil {
+		fmt.Println("error writing GPT:", err)
+		return err
+	}
+
+	fmt.Println("Partition resized successfully")
+	return nil
+}
+
+// deleteGPTPartition clears the entry of the partition in both copies of the array
+func deleteGPTPartition(fdisk *FDISK, gpt *structures.GPT) error {
+	index := gpt.FindByName(fdisk.name)
+	if index == -1 {
+		fmt.Println("partition not found")
+		return errors.New("partition not found")
+	}
+
+	partition := gpt.Partition(index)
+	if id := stores.MountedID(fdisk.path, partition); id != "" {
+		return fmt.Errorf("partition %s is mounted as %s", fdisk.name, id)
+	}
+
+	gpt.Entries[index] = structures.GPTEntry{}
+
+	if fdisk.delete == "full" {
+		err := zeroFill(fdisk.path, partition.Part_start, partition.Part_size)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := gpt.Write(fdisk.path)
+	if err != nil {
+		fmt.Println("error writing GPT:", err)
+		return err
+	}
+
+	fmt.Println("Partition deleted successfully")
+	return nil
+}
@@ -14,11 +14,11 @@ type FIND struct {
 	name string
 }
 
-func ParseFIND(tokens []string) (string, error) {
+func ParseFIND(tokens []string, session *stores.Session) (string, error) {
 	cmd := &FIND{} // create the mkdisk command
 
 	args := strings.Join(tokens, " ") // join the tokens to get the arguments
-	re := regexp.MustCompile(`-path=[^\s]+`)
+	re := regexp.MustCompile(`-path=[^\s]+|-name=[^\s]+`)
 	matches := re.FindAllString(args, -1) // find all the matches
 
 	if len(matches) != len(tokens) {
@@ -64,24 +64,24 @@ func ParseFIND(tokens []string) (string, error) {
 		return "", errors.New("falta el nombre")
 	}
 
-	err := commandFind(cmd)
+	files, err := commandFind(cmd, session)
 	if err != nil {
 		return "", err
 	}
 
-	return fmt.Sprintf("Se han encontrado los archivos: %s", cmd.name), nil
+	return fmt.Sprintf("Se han encontrado los archivos: %s\n%s", cmd.name, strings.Join(files, "\n")), nil
 }
 
-func commandFind(cmd *FIND) error {
+func commandFind(cmd *FIND, session *stores.Session) ([]string, error) {
 	// obtener la sesion
-	username, idPartition, uid, gid := stores.GetSession()
+	username, idPartition, uid, gid := session.Get()
 	if username == "" || idPartition == "" || uid == 0 || gid == 0 {
-		return errors.New("no hay sesión activa")
+		return nil, errors.New("no hay sesión activa")
 	}
 
 	partitionSuperblock, _, partitionPath, err := stores.GetMountedPartitionSuperblock(idPartition)
 	if err != nil {
-		return fmt.Errorf("error al obtener la partición montada: %w", err)
+		return nil, fmt.Errorf("error al obtener la partición montada: %w", err)
 	}
 
 	parentsDir, destDir := utils.GetParentDirectories(cmd.path)
@@ -89,12 +89,12 @@ func commandFind(cmd *FIND) error {
 	// Buscar archivos
 	files, err := partitionSuperblock.Find(partitionPath, parentsDir, destDir, cmd.name)
 	if err != nil {
-		return fmt.Errorf("error al buscar archivos: %w", err)
+		return nil, fmt.Errorf("error al buscar archivos: %w", err)
 	}
 
 	if len(files) == 0 {
-		return errors.New("no se encontraron archivos")
+		return nil, errors.New("no se encontraron archivos")
 	}
 
-	return nil
+	return files, nil
 }
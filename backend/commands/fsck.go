@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"backend/stores"
+	"backend/structures"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type FSCK struct {
+	id     string
+	repair bool
+}
+
+/*
+   fsck -id=501A
+   fsck -id=501A -repair
+*/
+
+func ParseFsck(tokens []string) (string, error) {
+	cmd := &FSCK{}
+
+	args := strings.Join(tokens, " ")
+	re := regexp.MustCompile(`-id=[^\s]+|-repair`)
+	matches := re.FindAllString(args, -1)
+
+	if len(matches) != len(tokens) {
+		for _, token := range tokens {
+			if !re.MatchString(token) {
+				return "", fmt.Errorf("parámetro inválido: %s", token)
+			}
+		}
+	}
+
+	for _, match := range matches {
+		kv := strings.SplitN(match, "=", 2)
+		key := strings.ToLower(kv[0])
+
+		switch key {
+		case "-id":
+			if len(kv) != 2 {
+				return "", fmt.Errorf("formato de parámetro inválido: %s", match)
+			}
+			value := kv[1]
+			if strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+				value = strings.Trim(value, "\"")
+			}
+			cmd.id = value
+		case "-repair":
+			cmd.repair = true
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.id == "" {
+		return "", errors.New("faltan parámetros requeridos: -id")
+	}
+
+	return commandFsck(cmd)
+}
+
+func commandFsck(cmd *FSCK) (string, error) {
+	mountedPartition, partitionPath, err := stores.GetMountedPartition(cmd.id)
+	if err != nil {
+		return "", fmt.Errorf("error al obtener la partición montada: %w", err)
+	}
+
+	// Si el superbloque principal está dañado se revisa con una copia de respaldo
+	sb, backupOffset, err := structures.LoadSuperBlock(partitionPath, mountedPartition)
+	if err != nil {
+		return "", fmt.Errorf("error al leer el superbloque: %w", err)
+	}
+	sb.TrackChecksums(partitionPath, mountedPartition.Part_start, mountedPartition.Part_size)
+	sb.TrackLayout(partitionPath, mountedPartition)
+
+	report, err := sb.Fsck(partitionPath, mountedPartition, cmd.repair)
+	if err != nil {
+		return "", fmt.Errorf("error al revisar la partición: %w", err)
+	}
+
+	// Guardar los contadores corregidos
+	if cmd.repair {
+		err = sb.Serialize(partitionPath, int64(mountedPartition.Part_start))
+		if err != nil {
+			return "", fmt.Errorf("error al serializar el superbloque: %w", err)
+		}
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "FSCK: Partición %s revisada\n", cmd.id)
+	if backupOffset != -1 {
+		fmt.Fprintf(&output, "-> Superbloque leído de la copia en %d\n", backupOffset)
+	}
+	fmt.Fprintf(&output, "-> Inodos en uso: %d\n", report.UsedInodes)
+	fmt.Fprintf(&output, "-> Bloques en uso: %d\n", report.UsedBlocks)
+	fmt.Fprintf(&output, "-> Problemas encontrados: %d\n", len(report.Problems))
+	for _, problem := range report.Problems {
+		fmt.Fprintf(&output, "   - %s\n", problem)
+	}
+	if cmd.repair {
+		fmt.Fprintf(&output, "-> Reparaciones: %d\n", len(report.Repairs))
+		for _, repair := range report.Repairs {
+			fmt.Fprintf(&output, "   - %s\n", repair)
+		}
+	} else if len(report.Problems) > 0 {
+		output.WriteString("-> Use -repair para corregir los que se puedan\n")
+	}
+
+	return strings.TrimRight(output.String(), "\n"), nil
+}
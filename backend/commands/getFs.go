@@ -11,9 +11,11 @@ import (
 
 type FileSystemNodeWithRef struct {
 	Name       string        `json:"name"`
-	Type       int           `json:"type"`
+	Type       int           `json:"type"`             // 0: directorio, 1: archivo, 2: enlace simbólico
+	Target     string        `json:"target,omitempty"` // Ruta a la que apunta un enlace simbólico
 	Content    []interface{} `json:"content,omitempty"`
 	InodeRef   int32         `json:"inodeRef"`
+	Links      int32         `json:"links"` // Cantidad de nombres (enlaces duros) del inodo
 	IsRef      bool          `json:"isRef,omitempty"`
 	RefContent []interface{} `json:"refContent,omitempty"` // Contenido del nodo referenciado
 }
@@ -27,28 +29,13 @@ func ParseGetfs(tokens []string) (string, error) {
 	seenPaths := make(map[string]bool) // Mapa para trackear paths ya procesados
 
 	for _, id := range stores.GetMountedPartitions() {
-		mountedMbr, mountedSb, mountedDiskPath, err := stores.GetMountedPartitionRep(id)
+		disk, err := getMountedDiskInfo(id, seenPaths)
 		if err != nil {
-			return "", fmt.Errorf("error al obtener la partición montada: %v", err)
+			return "", err
 		}
-
-		// Verificar si ya hemos procesado este path de disco
-		if _, exists := seenPaths[mountedDiskPath]; exists {
-			continue // Saltar este disco, ya fue procesado
-		}
-
-		// Marcar este path como procesado
-		seenPaths[mountedDiskPath] = true
-
-		disk := map[string]interface{}{
-			"diskPath":      mountedDiskPath,
-			"diskSize":      mountedMbr.Mbr_size,
-			"diskSignature": mountedMbr.Mbr_disk_signature,
-			"diskFit":       string(mountedMbr.Mbr_disk_fit[0]),
-			"partitions":    getPartitionsInfo(mountedMbr, mountedSb, mountedDiskPath, id),
+		if disk != nil {
+			disks = append(disks, disk)
 		}
-
-		disks = append(disks, disk)
 	}
 
 	jsonData, err := json.MarshalIndent(disks, "", "  ")
@@ -59,7 +46,58 @@ func ParseGetfs(tokens []string) (string, error) {
 	return string(jsonData), nil
 }
 
-func getPartitionsInfo(mbr *structures.MBR, sb *structures.SuperBlock, diskPath string, mountedID string) []map[string]interface{} {
+// getMountedDiskInfo describe el disco de la partición montada con el id, o devuelve nil si ya se describió.
+// Mientras lo lee tiene la partición tomada para lectura, así no ve a medias lo que otra petición escribe.
+func getMountedDiskInfo(id string, seenPaths map[string]bool) (map[string]interface{}, error) {
+	unlock := stores.LockPartition(id, false)
+	defer unlock()
+
+	mountedMbr, mountedSb, mountedDiskPath, err := stores.GetMountedPartitionRep(id)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la partición montada: %v", err)
+	}
+
+	// Verificar si ya hemos procesado este path de disco
+	if _, exists := seenPaths[mountedDiskPath]; exists {
+		return nil, nil // Saltar este disco, ya fue procesado
+	}
+
+	// Marcar este path como procesado
+	seenPaths[mountedDiskPath] = true
+
+	return getDiskInfo(mountedMbr, mountedSb, mountedDiskPath, mountedDiskPath, id), nil
+}
+
+// getDiskInfo describe el disco y su tabla de particiones, MBR o GPT. El sistema de archivos de la partición
+// montada con mountedID se lee de sb en fsPath, que puede ser una vista reconstruida desde el journal.
+func getDiskInfo(mbr *structures.MBR, sb *structures.SuperBlock, diskPath string, fsPath string, mountedID string) map[string]interface{} {
+	if structures.IsGPT(diskPath) {
+		gpt, err := structures.ReadGPT(diskPath)
+		if err != nil {
+			fmt.Println("Error al leer la GPT:", err)
+			return map[string]interface{}{"diskPath": diskPath, "table": "GPT"}
+		}
+		return map[string]interface{}{
+			"diskPath":      diskPath,
+			"diskSize":      gpt.DiskSize(),
+			"diskSignature": gpt.Header.DiskGUID.String(),
+			"diskFit":       "F",
+			"table":         "GPT",
+			"partitions":    getGPTPartitionsInfo(gpt, sb, diskPath, fsPath, mountedID),
+		}
+	}
+
+	return map[string]interface{}{
+		"diskPath":      diskPath,
+		"diskSize":      mbr.Mbr_size,
+		"diskSignature": mbr.Mbr_disk_signature,
+		"diskFit":       string(mbr.Mbr_disk_fit[0]),
+		"table":         "MBR",
+		"partitions":    getPartitionsInfo(mbr, sb, diskPath, fsPath, mountedID),
+	}
+}
+
+func getPartitionsInfo(mbr *structures.MBR, sb *structures.SuperBlock, diskPath string, fsPath string, mountedID string) []map[string]interface{} {
 	var partitions []map[string]interface{}
 
 	for i, part := range mbr.Mbr_partitions {
@@ -77,21 +115,89 @@ func getPartitionsInfo(mbr *structures.MBR, sb *structures.SuperBlock, diskPath
 			"partitionStart":  part.Part_start,
 			"partitionName":   strings.TrimRight(string(part.Part_name[:]), "\x00"),
 			"partitionID":     partitionID,
+			"fs":              nil,
 		}
 
 		// Solo agregar filesystem si es la partición montada
 		if partitionID == mountedID {
-			partition["fs"] = getFileSystemStructure(sb, diskPath)
-		} else {
-			partition["fs"] = nil
+			addFileSystemInfo(partition, sb, fsPath, part.Part_start)
+		}
+
+		partitions = append(partitions, partition)
+	}
+
+	// Las particiones lógicas se numeran después de las cuatro primarias y su id viene de la tabla de montajes
+	logicals, err := mbr.GetLogicalPartitions(diskPath)
+	if err != nil {
+		fmt.Println("Error al recorrer los EBR:", err)
+	}
+	for _, logical := range logicals {
+		if logical.Empty() {
+			continue
+		}
+		partitionID := stores.MountedID(diskPath, logical.Partition())
+		partition := map[string]interface{}{
+			"partitionNumber": logical.Number,
+			"partitionSize":   logical.EBR.Ebr_part_size,
+			"partitionType":   "L",
+			"partitionFit":    string(logical.EBR.Ebr_part_fit[0]),
+			"partitionStart":  logical.EBR.Ebr_part_start,
+			"partitionName":   logical.Name(),
+			"partitionID":     partitionID,
+			"fs":              nil,
+		}
+		if partitionID != "" && partitionID == mountedID {
+			addFileSystemInfo(partition, sb, fsPath, logical.EBR.Ebr_part_start)
 		}
+		partitions = append(partitions, partition)
+	}
+
+	return partitions
+}
+
+// getGPTPartitionsInfo lista las entradas usadas de la GPT, numeradas por su posición en el arreglo
+func getGPTPartitionsInfo(gpt *structures.GPT, sb *structures.SuperBlock, diskPath string, fsPath string, mountedID string) []map[string]interface{} {
+	var partitions []map[string]interface{}
 
+	for i := range gpt.Entries {
+		entry := &gpt.Entries[i]
+		if !entry.Used() {
+			continue
+		}
+		partitionID := stores.MountedID(diskPath, gpt.Partition(i))
+		partition := map[string]interface{}{
+			"partitionNumber": i + 1,
+			"partitionSize":   entry.Size(),
+			"partitionType":   "P",
+			"partitionFit":    string(entry.Fit()),
+			"partitionStart":  entry.Start(),
+			"partitionName":   entry.Name(),
+			"partitionID":     partitionID,
+			"partitionGUID":   entry.UniquePartitionGUID.String(),
+			"fs":              nil,
+		}
+		if partitionID != "" && partitionID == mountedID {
+			addFileSystemInfo(partition, sb, fsPath, entry.Start())
+		}
 		partitions = append(partitions, partition)
 	}
 
 	return partitions
 }
 
+// addFileSystemInfo agrega a la partición montada su árbol y, si el sistema de archivos los tiene, la etiqueta y el UUID
+func addFileSystemInfo(partition map[string]interface{}, sb *structures.SuperBlock, fsPath string, partStart int32) {
+	partition["fs"] = getFileSystemStructure(sb, fsPath)
+	if sb == nil {
+		return
+	}
+	ext, err := sb.ReadExtended(fsPath, partStart)
+	if err == nil && ext != nil {
+		partition["label"] = ext.Label()
+		partition["uuid"] = ext.UUID()
+	}
+}
+
 func getFileSystemStructure(sb *structures.SuperBlock, diskPath string) *FileSystemNodeWithRef {
 	if sb == nil {
 		return nil
@@ -131,7 +237,9 @@ func generateNodeContent(superblock *structures.SuperBlock, diskPath string, ino
 		refNode := &FileSystemNodeWithRef{
 			Name:       node.Name,
 			Type:       node.Type,
+			Target:     node.Target,
 			InodeRef:   inodeIndex,
+			Links:      node.Links,
 			IsRef:      true,
 			RefContent: make([]interface{}, 0), // Inicializar con un slice vacío
 		}
@@ -159,6 +267,7 @@ func generateNodeContent(superblock *structures.SuperBlock, diskPath string, ino
 		Name:     "/",
 		Type:     0,
 		InodeRef: inodeIndex,
+		Links:    superblock.InodeLinks(inode),
 		Content:  make([]interface{}, 0), // Inicializar con un slice vacío
 	}
 
@@ -166,14 +275,27 @@ func generateNodeContent(superblock *structures.SuperBlock, diskPath string, ino
 		node.Type = 1
 	}
 
-	// Procesamiento de bloques del inodo
-	for _, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			break
+	// Los enlaces simbólicos se muestran con su destino y no se siguen
+	if inode.I_type[0] == '2' {
+		target, err := superblock.LinkTarget(diskPath, inode)
+		if err != nil {
+			delete(processingNodes, inodeIndex) // Limpiar el estado de procesamiento
+			return nil, err
 		}
+		node.Type = 2
+		node.Target = target
+	}
+
+	// Procesamiento de bloques del inodo (directos e indirectos)
+	dataBlocks, err := superblock.InodeDataBlocks(diskPath, inode)
+	if err != nil {
+		delete(processingNodes, inodeIndex) // Limpiar el estado de procesamiento
+		return nil, err
+	}
+	for _, blockIndex := range dataBlocks {
 
 		if inode.I_type[0] == '0' { // Directorio
-			folderBlock := &structures.FolderBlock{}
+			folderBlock := superblock.NewFolderBlock()
 			err := folderBlock.Deserialize(diskPath, int64(superblock.S_block_start+(blockIndex*superblock.S_block_size)))
 			if err != nil {
 				delete(processingNodes, inodeIndex) // Limpiar el estado de procesamiento
@@ -181,7 +303,11 @@ func generateNodeContent(superblock *structures.SuperBlock, diskPath string, ino
 			}
 
 			for _, content := range folderBlock.B_content {
-				name := strings.TrimRight(string(content.B_name[:]), "\x00")
+				name, err := superblock.EntryName(diskPath, content)
+				if err != nil {
+					delete(processingNodes, inodeIndex) // Limpiar el estado de procesamiento
+					return nil, err
+				}
 				if content.B_inodo == -1 || name == "." || name == ".." {
 					continue
 				}
@@ -199,7 +325,7 @@ func generateNodeContent(superblock *structures.SuperBlock, diskPath string, ino
 			}
 
 		} else if inode.I_type[0] == '1' { // Archivo
-			fileBlock := &structures.FileBlock{}
+			fileBlock := superblock.NewFileBlock()
 			err := fileBlock.Deserialize(diskPath, int64(superblock.S_block_start+(blockIndex*superblock.S_block_size)))
 			if err != nil {
 				delete(processingNodes, inodeIndex) // Limpiar el estado de procesamiento
@@ -229,7 +355,7 @@ func generateNodeContent(superblock *structures.SuperBlock, diskPath string, ino
 func updateTemporaryMarkers(completeNode *FileSystemNodeWithRef, nodesCache map[int32]*FileSystemNodeWithRef) {
 	for _, nodePtr := range nodesCache {
 		// Si es un directorio, buscar en su contenido los marcadores temporales
-		if nodePtr.Type == 1 && nodePtr.Content != nil {
+		if nodePtr.Type == 0 && nodePtr.Content != nil {
 			for i := range nodePtr.Content {
 				// Convertir el elemento de la interfaz a FileSystemNodeWithRef
 				if contentNode, ok := nodePtr.Content[i].(FileSystemNodeWithRef); ok {
@@ -239,7 +365,9 @@ func updateTemporaryMarkers(completeNode *FileSystemNodeWithRef, nodesCache map[
 						nodePtr.Content[i] = FileSystemNodeWithRef{
 							Name:       contentNode.Name, // Mantener el nombre asignado
 							Type:       completeNode.Type,
+							Target:     completeNode.Target,
 							InodeRef:   completeNode.InodeRef,
+							Links:      completeNode.Links,
 							IsRef:      true,
 							RefContent: completeNode.Content,
 						}
@@ -7,27 +7,47 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type JOURNAL struct {
-	id string
+	id         string
+	status     bool
+	checkpoint bool
+	payload    int    // transacción cuyo contenido completo se muestra, 0 si no se pidió
+	until      string // fecha o transacción hasta la que se reconstruye la partición
 }
 
+/*
+   journaling -id=501A
+   journaling -id=501A -status
+   journaling -id=501A -checkpoint
+   journaling -id=501A -payload=12
+   journaling -id=501A -until=12
+   journaling -id=501A -until="2025-03-01 18:30:00"
+*/
+
 func ParseJournal(tokens []string) (string, error) {
 	cmd := &JOURNAL{}
 
 	args := strings.Join(tokens, " ")
-	re := regexp.MustCompile(`-id=[^\s]+`)
+	re := regexp.MustCompile(`-id=[^\s]+|-payload=[^\s]+|-until="[^"]+"|-until=[^\s]+|-status|-checkpoint`)
 	matches := re.FindAllString(args, -1)
 
 	for _, match := range matches {
 		kv := strings.SplitN(match, "=", 2)
+		key := strings.ToLower(kv[0])
+		if key == "-status" || key == "-checkpoint" {
+			cmd.status = cmd.status || key == "-status"
+			cmd.checkpoint = cmd.checkpoint || key == "-checkpoint"
+			continue
+		}
 		if len(kv) != 2 {
 			return "", errors.New("formato de parámetro inválido")
 		}
-		key, value := strings.ToLower(kv[0]), kv[1]
+		value := kv[1]
 
 		if strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
 			value = strings.Trim(value, "\"")
@@ -39,6 +59,17 @@ func ParseJournal(tokens []string) (string, error) {
 				return "", errors.New("el id no puede estar vacío")
 			}
 			cmd.id = value
+		case "-payload":
+			payload, err := strconv.Atoi(value)
+			if err != nil || payload <= 0 {
+				return "", errors.New("el número de transacción debe ser un entero positivo")
+			}
+			cmd.payload = payload
+		case "-until":
+			if value == "" {
+				return "", errors.New("el momento de -until no puede estar vacío")
+			}
+			cmd.until = value
 		default:
 			return "", fmt.Errorf("parámetro desconocido: %s", key)
 		}
@@ -48,6 +79,18 @@ func ParseJournal(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -id")
 	}
 
+	if cmd.status || cmd.checkpoint {
+		return commandJournalStatus(cmd)
+	}
+
+	if cmd.payload != 0 {
+		return commandJournalPayload(cmd)
+	}
+
+	if cmd.until != "" {
+		return commandJournalUntil(cmd)
+	}
+
 	jsonContent, err := commandJournal(cmd)
 	if err != nil {
 		return "", err
@@ -57,7 +100,7 @@ func ParseJournal(tokens []string) (string, error) {
 }
 
 func commandJournal(cmd *JOURNAL) (string, error) {
-	partitionSuperblock, mountedPartition, partitionPath, err := stores.GetMountedPartitionSuperblock(cmd.id)
+	partitionSuperblock, _, partitionPath, err := stores.GetMountedPartitionSuperblock(cmd.id)
 	if err != nil {
 		return "", fmt.Errorf("error al obtener la partición montada: %w", err)
 	}
@@ -67,63 +110,234 @@ func commandJournal(cmd *JOURNAL) (string, error) {
 		return "", errors.New("sistema de archivos no es ext3")
 	}
 
-	// Recolectará todos los journals
+	transactions, _, err := partitionSuperblock.ReadJournal(partitionPath)
+	if err != nil {
+		return "", fmt.Errorf("error al leer el journal: %w", err)
+	}
+
+	// Una entrada por transacción
 	journals := []map[string]string{}
+	for _, transaction := range transactions {
+		date := transaction.Date
 
-	count := 2
+		journalEntry := map[string]string{
+			"transaction": strconv.Itoa(int(transaction.Number)),
+			"operation":   transaction.Info.Operation(),
+			"date":        date.Format(time.RFC3339),
+			"path":        transaction.Info.Path(),
+			"content":     transaction.Info.Content(),
+			"updates":     strconv.Itoa(len(transaction.Updates)),
+			"status":      journalStatus(transaction.Status),
+		}
+		// El contenido completo de los archivos puede ser largo, se muestra su tamaño y hash
+		if len(transaction.Payloads) > 0 {
+			journalEntry["payload_size"] = strconv.Itoa(transaction.PayloadSize())
+			journalEntry["payload_sha256"] = transaction.PayloadHash()
+		}
+		journals = append(journals, journalEntry)
+	}
 
-	for {
-		// obtener el journal
-		journal := &structures.Journal{}
+	// Convertir la estructura a JSON usando encoding/json para garantizar una sintaxis correcta
+	jsonBytes, err := json.MarshalIndent(journals, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error al generar JSON: %w", err)
+	}
 
-		fmt.Println("Deserializando en:", int64(mountedPartition.Part_start)+68+114*int64(count))
-		// Deserializar el journal
-		err = journal.Deserialize(partitionPath, int64(mountedPartition.Part_start)+68+114*int64(count))
-		if err != nil {
-			return "", fmt.Errorf("error al deserializar el journal: %w", err)
+	return string(jsonBytes), nil
+}
+
+// commandJournalPayload muestra el contenido completo de los archivos que escribió una transacción
+func commandJournalPayload(cmd *JOURNAL) (string, error) {
+	partitionSuperblock, _, partitionPath, err := stores.GetMountedPartitionSuperblock(cmd.id)
+	if err != nil {
+		return "", fmt.Errorf("error al obtener la partición montada: %w", err)
+	}
+	if partitionSuperblock.S_filesystem_type != 3 {
+		return "", errors.New("sistema de archivos no es ext3")
+	}
+
+	transactions, _, err := partitionSuperblock.ReadJournal(partitionPath)
+	if err != nil {
+		return "", fmt.Errorf("error al leer el journal: %w", err)
+	}
+
+	for _, transaction := range transactions {
+		if int(transaction.Number) != cmd.payload {
+			continue
+		}
+		if len(transaction.Payloads) == 0 {
+			return "", fmt.Errorf("la transacción %d no guardó contenido de archivos", cmd.payload)
 		}
-		if journal.J_count == 0 {
-			break
+		var output strings.Builder
+		fmt.Fprintf(&output, "JOURNALING: Transacción %d (%s %s), %d bytes, sha256 %s\n", transaction.Number,
+			transaction.Info.Operation(), transaction.Info.Path(), transaction.PayloadSize(), transaction.PayloadHash())
+		for i, payload := range transaction.Payloads {
+			fmt.Fprintf(&output, "-> Contenido %d (%d bytes):\n%s\n", i+1, len(payload), payload)
 		}
-		fmt.Println("obtenido el journal")
-		journal.Print()
-		date := time.Unix(int64(journal.J_content.I_date), 0)
+		return output.String(), nil
+	}
+	return "", fmt.Errorf("la transacción %d no está en el journal", cmd.payload)
+}
 
-		operation := strings.TrimRight(string(journal.J_content.I_operation[:]), "\x00")
-		path := strings.TrimRight(string(journal.J_content.I_path[:]), "\x00")
-		content := strings.TrimRight(string(journal.J_content.I_content[:]), "\x00")
+// commandJournalUntil devuelve, con la misma forma que getfs, el árbol de la partición tal como estaba en el
+// momento pedido. El árbol se reconstruye en memoria desde el journal y la partición no se modifica.
+func commandJournalUntil(cmd *JOURNAL) (string, error) {
+	mbr, _, diskPath, err := stores.GetMountedPartitionRep(cmd.id)
+	if err != nil {
+		return "", fmt.Errorf("error al obtener la partición montada: %w", err)
+	}
 
-		// Crear un mapa para el entry actual
-		journalEntry := map[string]string{
-			"operation": operation,
-			"date":      date.Format(time.RFC3339),
-			"path":      path,
-			"content":   content,
+	snapshotSb, snapshotPath, replayed, err := journalSnapshot(cmd.id, cmd.until)
+	if err != nil {
+		return "", err
+	}
+	defer structures.DropDevice(snapshotPath)
+	fmt.Printf("Vista de la partición %s reconstruida con %d transacciones\n", cmd.id, replayed)
+
+	disk := getDiskInfo(mbr, snapshotSb, diskPath, snapshotPath, cmd.id)
+
+	jsonBytes, err := json.MarshalIndent([]map[string]interface{}{disk}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error al generar JSON: %w", err)
+	}
+	return string(jsonBytes), nil
+}
+
+// journalSnapshot reconstruye en memoria la partición montada con el id hasta el momento until, que puede ser
+// el número de la última transacción incluida o una fecha. Quien llama debe liberar el disco con DropDevice.
+func journalSnapshot(id string, until string) (*structures.SuperBlock, string, int, error) {
+	partitionSuperblock, partition, partitionPath, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("error al obtener la partición montada: %w", err)
+	}
+	if partitionSuperblock.S_filesystem_type != 3 {
+		return nil, "", 0, errors.New("sistema de archivos no es ext3")
+	}
+
+	include, err := journalUntil(until)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	snapshotPath, snapshotSb, replayed, err := partitionSuperblock.JournalSnapshot(partitionPath, partition, include)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("error al reconstruir la partición desde el journal: %w", err)
+	}
+	return snapshotSb, snapshotPath, replayed, nil
+}
+
+// Formatos de fecha aceptados por -until, en la zona horaria local
+var untilLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// journalUntil convierte el valor de -until en el criterio de las transacciones que entran en la vista:
+// un número es la última transacción incluida y una fecha incluye las transacciones hechas hasta ese momento
+func journalUntil(until string) (func(*structures.JournalTransaction) bool, error) {
+	if sequence, err := strconv.Atoi(until); err == nil {
+		return func(transaction *structures.JournalTransaction) bool {
+			return int(transaction.Number) <= sequence
+		}, nil
+	}
+
+	for _, layout := range untilLayouts {
+		moment, err := time.ParseInLocation(layout, until, time.Local)
+		if err != nil {
+			continue
 		}
+		return func(transaction *structures.JournalTransaction) bool {
+			return !transaction.Date.After(moment)
+		}, nil
+	}
+	return nil, fmt.Errorf("momento inválido: %s, debe ser un número de transacción o una fecha como 2006-01-02 15:04:05", until)
+}
 
-		if operation == "mkdir" {
-			fmt.Println("Operación mkdir detectada")
-		} else if operation == "mkfile" {
-			fmt.Println("Operación mkfile detectada")
+// commandJournalStatus muestra el estado del anillo del journal y, si se pidió, fuerza un checkpoint
+func commandJournalStatus(cmd *JOURNAL) (string, error) {
+	partitionSuperblock, _, partitionPath, err := stores.GetMountedPartitionSuperblock(cmd.id)
+	if err != nil {
+		return "", fmt.Errorf("error al obtener la partición montada: %w", err)
+	}
+	if partitionSuperblock.S_filesystem_type != 3 {
+		return "", errors.New("sistema de archivos no es ext3")
+	}
+
+	var output strings.Builder
+	if cmd.checkpoint {
+		freed, err := partitionSuperblock.CheckpointJournal(partitionPath, -1)
+		if err != nil {
+			return "", fmt.Errorf("error al hacer checkpoint del journal: %w", err)
 		}
+		fmt.Fprintf(&output, "JOURNALING: Checkpoint realizado, %d transacciones liberadas\n", freed)
+	} else {
+		fmt.Fprintf(&output, "JOURNALING: Estado del journal de la partición %s\n", cmd.id)
+	}
 
-		// Añadir al slice de journals
-		journals = append(journals, journalEntry)
+	transactions, js, err := partitionSuperblock.ReadJournal(partitionPath)
+	if err != nil {
+		return "", fmt.Errorf("error al leer el journal: %w", err)
+	}
+	date := time.Unix(int64(js.JS_checkpoint), 0)
+	fmt.Fprintf(&output, "-> Head: %d\n", js.JS_head)
+	fmt.Fprintf(&output, "-> Tail: %d\n", js.JS_tail)
+	fmt.Fprintf(&output, "-> Registros en uso: %d de %d (%.1f%%)\n", js.JS_used, js.JS_slots, float64(js.JS_used)*100/float64(js.JS_slots))
+	fmt.Fprintf(&output, "-> Transacciones: %d\n", len(transactions))
+	fmt.Fprintf(&output, "-> Siguiente transacción: %d\n", js.JS_transaction)
+	fmt.Fprintf(&output, "-> Checkpoints: %d (último %s)", js.JS_checkpoints, date.Format(time.RFC3339))
+	return output.String(), nil
+}
+
+// journalStatus describe el estado de una transacción del journal
+func journalStatus(status byte) string {
+	switch status {
+	case structures.JournalApplied:
+		return "aplicada"
+	case structures.JournalCommit:
+		return "confirmada"
+	default:
+		return "incompleta"
+	}
+}
 
-		count++
+// Journaled ejecuta un comando como una transacción del journal de la partición de la sesión.
+// Si el comando falla sus cambios se descartan; en ext2 o sin sesión se ejecuta sin journal.
+func Journaled(operation string, tokens []string, session *stores.Session, run func([]string, *stores.Session) (string, error)) (string, error) {
+	_, partitionID, _, _ := session.Get()
+	if partitionID == "" {
+		return run(tokens, session)
+	}
+	sb, mountedPartition, partitionPath, err := stores.GetMountedPartitionSuperblock(partitionID)
+	if err != nil || sb.S_filesystem_type != 3 {
+		return run(tokens, session)
 	}
 
-	// Serializar el superbloque
-	err = partitionSuperblock.Serialize(partitionPath, int64(mountedPartition.Part_start))
+	path, content := journalArguments(tokens)
+	tx, err := sb.BeginTransaction(partitionPath, mountedPartition, structures.NewInformation(operation, path, content))
 	if err != nil {
-		return "", fmt.Errorf("error al serializar el superbloque: %w", err)
+		return "", err
 	}
 
-	// Convertir la estructura a JSON usando encoding/json para garantizar una sintaxis correcta
-	jsonBytes, err := json.MarshalIndent(journals, "", "  ")
+	output, err := run(tokens, session)
 	if err != nil {
-		return "", fmt.Errorf("error al generar JSON: %w", err)
+		tx.Abort()
+		return "", err
 	}
 
-	return string(jsonBytes), nil
+	err = tx.Commit()
+	if err != nil {
+		return "", fmt.Errorf("error al confirmar la transacción en el journal: %w", err)
+	}
+	return output, nil
+}
+
+// journalArguments separa el -path de un comando del resto de sus parámetros para describirlo en el journal
+func journalArguments(tokens []string) (string, string) {
+	path := ""
+	rest := []string{}
+	for _, token := range tokens {
+		if strings.HasPrefix(strings.ToLower(token), "-path=") && path == "" {
+			path = strings.Trim(token[len("-path="):], "\"")
+			continue
+		}
+		rest = append(rest, token)
+	}
+	return path, strings.Join(rest, " ")
 }
@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"backend/stores"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type LN struct {
+	path    string
+	destino string
+	s       bool
+}
+
+/*
+   ln -s -path=/home/docs -destino=/home/user/documentos
+   ln -s -path=/home/actual.txt -destino=../archivos/v2.txt
+   ln -path=/home/copia.txt -destino=/home/user/a.txt
+*/
+
+func ParseLn(tokens []string, session *stores.Session) (string, error) {
+	cmd := &LN{}
+
+	args := strings.Join(tokens, " ")
+	re := regexp.MustCompile(`-path=[^\s]+|-destino=[^\s]+|-s`)
+	matches := re.FindAllString(args, -1)
+
+	if len(matches) != len(tokens) {
+		for _, token := range tokens {
+			if !re.MatchString(token) {
+				return "", fmt.Errorf("parámetro inválido: %s", token)
+			}
+		}
+	}
+
+	for _, match := range matches {
+		kv := strings.SplitN(match, "=", 2)
+		key := strings.ToLower(kv[0])
+
+		switch key {
+		case "-path":
+			if len(kv) != 2 {
+				return "", fmt.Errorf("formato de parámetro inválido: %s", match)
+			}
+			value := kv[1]
+			if strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+				value = strings.Trim(value, "\"")
+			}
+			cmd.path = value
+		case "-destino":
+			if len(kv) != 2 {
+				return "", fmt.Errorf("formato de parámetro inválido: %s", match)
+			}
+			value := kv[1]
+			if strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+				value = strings.Trim(value, "\"")
+			}
+			cmd.destino = value
+		case "-s":
+			cmd.s = true
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.path == "" {
+		return "", errors.New("faltan parámetros requeridos: -path")
+	}
+
+	if cmd.destino == "" {
+		return "", errors.New("faltan parámetros requeridos: -destino")
+	}
+
+	err := commandLn(cmd, session)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("LN: Enlace %s -> %s creado exitosamente.", cmd.path, cmd.destino), nil
+}
+
+func commandLn(cmd *LN, session *stores.Session) error {
+	parentDirs, destDir := utils.GetParentDirectories(cmd.path)
+	username, idPartition, uid, gid := session.Get()
+	if username == "" || idPartition == "" {
+		return errors.New("no hay sesión activa")
+	}
+	partitionSuperblock, mountedPartition, partitionPath, err := stores.GetMountedPartitionSuperblock(idPartition)
+	if err != nil {
+		return fmt.Errorf("error al obtener la partición montada: %w", err)
+	}
+
+	if cmd.s {
+		// El destino se guarda tal cual se escribió, sin resolverlo
+		err = partitionSuperblock.CreateSymlink(partitionPath, parentDirs, destDir, cmd.destino, uid, gid)
+	} else {
+		// Enlace duro: un nombre más para el inodo existente
+		targetParentDirs, targetDir := utils.GetParentDirectories(cmd.destino)
+		err = partitionSuperblock.CreateHardLink(partitionPath, parentDirs, destDir, targetParentDirs, targetDir)
+	}
+	if err != nil {
+		return fmt.Errorf("error al crear el enlace: %w", err)
+	}
+
+	err = partitionSuperblock.Serialize(partitionPath, int64(mountedPartition.Part_start))
+	if err != nil {
+		return fmt.Errorf("error al serializar el superbloque: %w", err)
+	}
+
+	return nil
+}
@@ -14,7 +14,7 @@ type LOGIN struct {
 	id  string
 }
 
-func ParseLogin(tokens []string) (string, error) {
+func ParseLogin(tokens []string, session *stores.Session) (string, error) {
 	cmd := &LOGIN{}
 
 	args := strings.Join(tokens, " ")
@@ -78,16 +78,17 @@ func ParseLogin(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -id")
 	}
 
-	err := commandLogin(cmd)
+	err := commandLogin(cmd, session)
 	if err != nil {
 		return "", err
 	}
+	saveState()
 
 	return fmt.Sprintf("Login exitoso para el usuario %s en la partición %s", cmd.user, cmd.id), nil
 }
 
-func commandLogin(cmd *LOGIN) error {
-	userLogged, _, _, _ := stores.GetSession();
+func commandLogin(cmd *LOGIN, session *stores.Session) error {
+	userLogged, _, _, _ := session.Get()
 	if userLogged != "" {
 		return errors.New("ya hay un usuario logueado")
 	}
@@ -103,8 +104,6 @@ func commandLogin(cmd *LOGIN) error {
 		return fmt.Errorf("error al loguear el usuario: %w", err)
 	}
 
-	// si no hay error, loguear el usuario
-	stores.SetSession(cmd.user, cmd.id, uid, gid)
-
-	return nil
+	// si no hay error, loguear el usuario con una sesión nueva para este cliente
+	return stores.StartSession(session, cmd.user, cmd.id, uid, gid)
 }
\ No newline at end of file
@@ -8,7 +8,7 @@ import (
 
 type LOGOUT struct {} // no requires parameters
 
-func ParseLogout(tokens []string) (string, error) {
+func ParseLogout(tokens []string, session *stores.Session) (string, error) {
 	fmt.Println("LOGOUT")
 
 	if len(tokens) != 0 {
@@ -16,13 +16,14 @@ func ParseLogout(tokens []string) (string, error) {
 	}
 
 	// check if a session is active
-	username, _, _, _ := stores.GetSession()
+	username, _, _, _ := session.Get()
 	if username == "" {
 		return "No hay sesión activa", nil
 	}
 
 	// clear the session
-	stores.SetSession("", "", -1, -1)
+	stores.EndSession(session)
+	saveState()
 
 	return "Sesión cerrada", nil
 
@@ -5,7 +5,6 @@ import (
 	"backend/structures"
 	"errors"
 	"fmt"
-	"os"
 	"regexp"
 	"strings"
 )
@@ -76,25 +75,11 @@ func commandLoss(cmd *LOSS) error {
 }
 
 func Loss(path string, sb *structures.SuperBlock, id string) error {
-	var mbr structures.MBR
-
-	err := mbr.DeserializeMBR(path)
+	partitionFound, _, err := stores.GetMountedPartition(id)
 	if err != nil {
-		return fmt.Errorf("error al deserializar el MBR: %w", err)
-	}
-
-	partitionFound, _ := mbr.GetPartitionByID(id)
-	if partitionFound == nil {
-		return fmt.Errorf("partición con ID %s no encontrada", id)
+		return fmt.Errorf("partición con ID %s no encontrada: %w", id, err)
 	}
 
-	// Abrir el archivo para lectura y escritura
-	file, err := os.OpenFile(path, os.O_RDWR, 0666)
-	if err != nil {
-		return fmt.Errorf("error al abrir el archivo: %w", err)
-	}
-	defer file.Close()
-
 	// Calcular el inicio y tamaño de la partición
 	partitionStart := partitionFound.Part_start
 	partitionSize := partitionFound.Part_size
@@ -105,20 +90,19 @@ func Loss(path string, sb *structures.SuperBlock, id string) error {
 		length int32
 		name   string
 	}{
-		// Limpiar el superbloque mismo (primeros 68 bytes)
-		{0, 68, "superbloque"},
-
-		// Bitmaps y tablas
-		{sb.S_bm_inode_start, sb.S_inodes_count, "bitmap de inodos"},
-		{sb.S_bm_block_start, sb.S_blocks_count, "bitmap de bloques"},
-		{sb.S_inode_start, sb.S_inodes_count * sb.S_inode_size, "área de inodos"},
-		{sb.S_block_start, sb.S_blocks_count * sb.S_block_size, "área de bloques"},
+		// Limpiar el superbloque mismo
+		{partitionStart, int32(sb.Size()), "superbloque"},
+
+		// Bitmaps y tablas (las posiciones del superbloque ya son absolutas)
+		{sb.S_bm_inode_start, sb.TotalInodes(), "bitmap de inodos"},
+		{sb.S_bm_block_start, sb.TotalBlocks(), "bitmap de bloques"},
+		{sb.S_inode_start, sb.TotalInodes() * sb.S_inode_size, "área de inodos"},
+		{sb.S_block_start, sb.TotalBlocks() * sb.S_block_size, "área de bloques"},
 	}
 
 	// Limpiar cada área
 	for _, area := range cleanAreas {
-		// Posición absoluta en el archivo = inicio de partición + posición relativa
-		position := partitionStart + area.start
+		position := area.start
 
 		// Asegurarnos de no salirnos de la partición
 		if position >= partitionStart+partitionSize {
@@ -134,14 +118,8 @@ func Loss(path string, sb *structures.SuperBlock, id string) error {
 		// Crear un buffer de ceros del tamaño adecuado
 		nullBuffer := make([]byte, length)
 
-		// Posicionar el cursor en el archivo
-		_, err := file.Seek(int64(position), 0)
-		if err != nil {
-			return fmt.Errorf("error al posicionar el cursor para %s: %w", area.name, err)
-		}
-
 		// Escribir los bytes nulos
-		_, err = file.Write(nullBuffer)
+		err := structures.DeviceWriteAt(path, nullBuffer, int64(position))
 		if err != nil {
 			return fmt.Errorf("error al escribir %s: %w", area.name, err)
 		}
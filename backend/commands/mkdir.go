@@ -4,7 +4,6 @@ import (
 	stores "backend/stores"
 	structures "backend/structures"
 	utils "backend/utils"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"regexp"
@@ -21,7 +20,7 @@ type MKDIR struct {
    mkdir -path="/home/mis documentos/archivos clases"
 */
 
-func ParseMkdir(tokens []string) (string, error) {
+func ParseMkdir(tokens []string, session *stores.Session) (string, error) {
 	cmd := &MKDIR{}
 
 	args := strings.Join(tokens, " ")
@@ -61,7 +60,7 @@ func ParseMkdir(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -path")
 	}
 
-	err := commandMkdir(cmd)
+	err := commandMkdir(cmd, session)
 	if err != nil {
 		return "", err
 	}
@@ -69,9 +68,9 @@ func ParseMkdir(tokens []string) (string, error) {
 	return fmt.Sprintf("MKDIR: Directorio %s creado correctamente.", cmd.path), nil // Devuelve el comando MKDIR creado
 }
 
-func commandMkdir(mkdir *MKDIR) error {
+func commandMkdir(mkdir *MKDIR, session *stores.Session) error {
 	// obtener la sesión activa
-	username, idPartitinUser, uid, gid := stores.GetSession()
+	username, idPartitinUser, uid, gid := session.Get()
 	if username == "" {
 		return errors.New("no hay sesión activa")
 	}
@@ -148,10 +147,8 @@ func createDirectory(dirPath string, p bool, sb *structures.SuperBlock, partitio
 			}
 		}
 	}
-	// calcular el journal start
-
 	// Crear el directorio segun el path proporcionado
-	err := sb.CreateFolder(partitionPath, parentDirs, destDir, uid, gid, dirPath, int64(mountedPartition.Part_start+int32(binary.Size(structures.SuperBlock{}))))
+	err := sb.CreateFolder(partitionPath, parentDirs, destDir, uid, gid)
 	if err != nil {
 		return fmt.Errorf("error al crear el directorio: %w", err)
 	}
@@ -19,6 +19,8 @@ type MKDISK struct {
 	unit string
 	fit string  // types: FF, BF, WF
 	path string
+	table string // partition table: MBR or GPT
+	compat bool // standard MBR in LBA 0 for host tools, the project's MBR in LBA 1
 }
 
 func ParseMkdisk(tokens []string) (string, error) {
@@ -30,7 +32,7 @@ func ParseMkdisk(tokens []string) (string, error) {
 	paramRe := regexp.MustCompile(`-\w+=[^\s"]+|-\w+="[^"]+"`)
 
 	// regular expression to get the valid parameters we want to process
-	validParamRe := regexp.MustCompile(`-size=\d+|-unit=[kKmM]|-fit=[bBfFwW]{2}|-path="[^"]+"|-path=[^\s]+`)
+	validParamRe := regexp.MustCompile(`-size=\d+|-unit=[kKmM]|-fit=[bBfFwW]{2}|-path="[^"]+"|-path=[^\s]+|-table=[a-zA-Z]+|-compat`)
 
 	// First, check if there are any parameters that don't match our valid pattern
 	allParams := paramRe.FindAllString(args, -1)
@@ -49,6 +51,12 @@ func ParseMkdisk(tokens []string) (string, error) {
 	matches := validParamRe.FindAllString(args, -1)
 
 	for _, match := range matches {
+		// -compat is a flag without value
+		if match == "-compat" {
+			cmd.compat = true
+			continue
+		}
+
 		// divide the argument in the key and value
 		kv := strings.SplitN(match, "=", 2)
 		if len(kv) != 2 {
@@ -84,6 +92,12 @@ func ParseMkdisk(tokens []string) (string, error) {
 				return "", errors.New("invalid path")
 			}
 			cmd.path = value
+		case "-table":
+			value = strings.ToUpper(value)
+			if value != "MBR" && value != "GPT" {
+				return "", errors.New("invalid table, must be mbr or gpt")
+			}
+			cmd.table = value
 		}
 	}
 
@@ -99,6 +113,19 @@ func ParseMkdisk(tokens []string) (string, error) {
 		cmd.unit = "M"
 	}
 
+	if cmd.table == "" {
+		cmd.table = "MBR"
+	}
+
+	// A GPT has no field for the fit of the disk, partitions there use first fit unless fdisk says otherwise
+	if cmd.table == "GPT" && cmd.fit != "" && cmd.fit != "FF" {
+		return "", errors.New("a GPT disk has no disk fit, use fdisk -fit for each partition")
+	}
+
+	if cmd.table == "GPT" && cmd.compat {
+		return "", errors.New("a GPT disk already starts with a standard protective MBR, -compat is only for MBR disks")
+	}
+
 	if cmd.fit == "" {
 		cmd.fit = "FF"
 	}
@@ -108,7 +135,11 @@ func ParseMkdisk(tokens []string) (string, error) {
 		return "", err
 	}
 
-	return fmt.Sprintf("Disk created with size %d%s, fit %s, and path %s", cmd.Size, cmd.unit, cmd.fit, cmd.path), nil
+	table := cmd.table
+	if cmd.compat {
+		table += " (compat)"
+	}
+	return fmt.Sprintf("Disk created with size %d%s, fit %s, table %s, and path %s", cmd.Size, cmd.unit, cmd.fit, table, cmd.path), nil
 }
 
 func commandMkdisk(mkdisk *MKDISK) error {
@@ -125,6 +156,15 @@ func commandMkdisk(mkdisk *MKDISK) error {
 		return err
 	}
 
+	if mkdisk.table == "GPT" {
+		err = createGPT(mkdisk, sizeBytes)
+		if err != nil {
+			fmt.Println("error creating GPT:", err)
+			return err
+		}
+		return nil
+	}
+
 	err = createMBR(mkdisk, sizeBytes)
 	if err != nil {
 		fmt.Println("error creating MBR:", err)
@@ -135,7 +175,18 @@ func commandMkdisk(mkdisk *MKDISK) error {
 }
 
 func createDisk(mkdisk *MKDISK, sizeBytes int) error {
-	err := os.MkdirAll(filepath.Dir(mkdisk.path), os.ModePerm) // create the directory
+	// in-memory disks never touch the host filesystem
+	if structures.IsMemoryPath(mkdisk.path) {
+		return structures.CreateMemoryDevice(mkdisk.path, int64(sizeBytes))
+	}
+
+	// forget any open device of a previous disk with the same path
+	err := structures.DropDevice(mkdisk.path)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(mkdisk.path), os.ModePerm) // create the directory
 	if err != nil {
 		fmt.Println("error creating directory:", err)
 		return err
@@ -195,10 +246,28 @@ func createMBR(mkdisk *MKDISK, sizeBytes int) error {
 	fmt.Println("MBR has been created")
 	mbr.PrintMBR() // print the MBR
 
+	// a compat disk keeps the MBR in LBA 1, behind a standard MBR that host tools can read
+	if mkdisk.compat {
+		return mbr.SerializeCompatMBR(mkdisk.path)
+	}
+
 	err := mbr.SerializeMBR(mkdisk.path) // serialize the MBR
 	if err != nil {
 		fmt.Println("error serializing MBR:", err)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// createGPT writes a protective MBR, an empty GUID partition table and its backup at the end of the disk
+func createGPT(mkdisk *MKDISK, sizeBytes int) error {
+	gpt, err := structures.CreateGPT(mkdisk.path, int64(sizeBytes))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("GPT has been created")
+	fmt.Println("Disk GUID:", gpt.Header.DiskGUID)
+	fmt.Println("Usable LBAs:", gpt.Header.FirstUsableLBA, "-", gpt.Header.LastUsableLBA)
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	stores "backend/stores"
 	structures "backend/structures"
 	utils "backend/utils"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"regexp"
@@ -24,7 +23,7 @@ type MKFILE struct {
    mkfile -path=/home/user/docs/b.txt -r -cont=/home/Documents/b.txt
 */
 
-func ParseMKfile(tokens []string) (string, error) {
+func ParseMKfile(tokens []string, session *stores.Session) (string, error) {
 	cmd := &MKFILE{}
 
 	args := strings.Join(tokens, " ")
@@ -89,7 +88,7 @@ func ParseMKfile(tokens []string) (string, error) {
 	fmt.Println("Contenido:", cmd.cont)
 	fmt.Println("")
 
-	err := commandMkfile(cmd)
+	err := commandMkfile(cmd, session)
 	if err != nil {
 		return "", err
 	}
@@ -97,9 +96,9 @@ func ParseMKfile(tokens []string) (string, error) {
 	return fmt.Sprintf("MKFILE: Archivo %s creado correctamente.", cmd.path), nil // Devuelve el comando MKDIR creado
 }
 
-func commandMkfile(mkfile *MKFILE) error {
+func commandMkfile(mkfile *MKFILE, session *stores.Session) error {
 	// obtener la sesion
-	username, idPartition, uid, gid := stores.GetSession()
+	username, idPartition, uid, gid := session.Get()
 	if username == "" || idPartition == "" {
 		return errors.New("no hay sesión activa")
 	}
@@ -196,7 +195,7 @@ func createFile(dirPath string, r bool, size int, contentPath string, sb *struct
 
 	fmt.Println("CONTENTFILE", contentFile)
 	// Crear el directorio segun el path proporcionado
-	err = sb.CreateFile(partitionPath, parentDirs, destDir, r, size, contentFile, uid, gid, dirPath, int64(mountedPartition.Part_start+int32(binary.Size(structures.SuperBlock{}))))
+	err = sb.CreateFile(partitionPath, parentDirs, destDir, r, size, contentFile, uid, gid)
 	if err != nil {
 		return fmt.Errorf("error al crear el directorio: %w", err)
 	}
@@ -8,24 +8,54 @@ import (
 	"fmt"
 	"math"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type MKFS struct {
-	id  string
-	typ string
-	fs  string
+	id         string
+	typ        string
+	fs         string
+	csum       bool   // checksums CRC32C en los metadatos
+	blockSize  int32  // tamaño de bloque en bytes
+	inodeRatio int32  // bytes de partición por inodo, 0 para la proporción clásica de 3 bloques por inodo
+	label      string // etiqueta del volumen
+	uuid       string // UUID generado al formatear
 }
 
+// fsGeometry son las opciones que definen cómo se reparte la partición entre inodos, bloques y journal
+type fsGeometry struct {
+	fs         string
+	csum       bool
+	blockSize  int32
+	inodeRatio int32
+}
+
+// Tamaños de bloque permitidos: potencias de dos entre estos límites
+const (
+	minBlockSize     = 64
+	maxBlockSize     = 4096
+	defaultBlockSize = 64
+)
+
+/*
+   mkfs -id=501A -fs=3fs -block_size=1024 -inode_ratio=4096 -label=datos
+*/
+
 func ParseMkfs(tokens []string) (string, error) {
 	cmd := &MKFS{}
 
 	args := strings.Join(tokens, " ")
-	re := regexp.MustCompile(`-id=[^\s]+|-type=[^\s]+|-fs=[23]fs`)
+	re := regexp.MustCompile(`-id=[^\s]+|-type=[^\s]+|-fs=[23]fs|-csum|-block_size=[^\s]+|-inode_ratio=[^\s]+|-label="[^"]+"|-label=[^\s]+`)
 	matches := re.FindAllString(args, -1)
 
 	for _, match := range matches {
+		if strings.ToLower(match) == "-csum" {
+			cmd.csum = true
+			continue
+		}
+
 		kv := strings.SplitN(match, "=", 2)
 		if len(kv) != 2 {
 			return "", fmt.Errorf("formato de parámetro inválido: %s", match)
@@ -52,6 +82,23 @@ func ParseMkfs(tokens []string) (string, error) {
 				return "", errors.New("el tipo de sistema de archivos debe ser 2 o 3")
 			}
 			cmd.fs = value
+		case "-block_size":
+			size, err := strconv.Atoi(value)
+			if err != nil || size < minBlockSize || size > maxBlockSize || size&(size-1) != 0 {
+				return "", fmt.Errorf("el tamaño de bloque debe ser una potencia de dos entre %d y %d", minBlockSize, maxBlockSize)
+			}
+			cmd.blockSize = int32(size)
+		case "-inode_ratio":
+			ratio, err := strconv.Atoi(value)
+			if err != nil || ratio <= 0 {
+				return "", errors.New("la proporción de inodos debe ser un número entero positivo de bytes")
+			}
+			cmd.inodeRatio = int32(ratio)
+		case "-label":
+			if value == "" || len(value) > 16 {
+				return "", errors.New("la etiqueta debe tener entre 1 y 16 caracteres")
+			}
+			cmd.label = value
 		default:
 			return "", fmt.Errorf("parámetro desconocido: %s", key)
 		}
@@ -70,18 +117,30 @@ func ParseMkfs(tokens []string) (string, error) {
 		cmd.fs = "2fs"
 	}
 
+	if cmd.blockSize == 0 {
+		cmd.blockSize = defaultBlockSize
+	}
+
 	err := commandMkfs(cmd)
 	if err != nil {
-		fmt.Println("Error:", err)
+		return "", err
 	}
 
 	return fmt.Sprintf("MKFS: Sistema de archivos creado exitosamente\n"+
 		"-> ID: %s\n"+
 		"-> Tipo: %s\n"+
-		"-> Sistema de archivos: %s",
+		"-> Sistema de archivos: %s\n"+
+		"-> Checksums: %t\n"+
+		"-> Tamaño de bloque: %d\n"+
+		"-> Etiqueta: %s\n"+
+		"-> UUID: %s",
 		cmd.id,
 		cmd.typ,
-		map[string]string{"2fs": "EXT2", "3fs": "EXT3"}[cmd.fs]), nil
+		map[string]string{"2fs": "EXT2", "3fs": "EXT3"}[cmd.fs],
+		cmd.csum,
+		cmd.blockSize,
+		cmd.label,
+		cmd.uuid), nil
 }
 func commandMkfs(mkfs *MKFS) error {
 	fmt.Println("Creando sistema de archivos...", mkfs.fs)
@@ -92,16 +151,64 @@ func commandMkfs(mkfs *MKFS) error {
 		return err
 	}
 
-	// Calcular el valor de n
-	n := calculateN(mountedPartition, mkfs.fs)
+	geometry := fsGeometry{fs: mkfs.fs, csum: mkfs.csum, blockSize: mkfs.blockSize, inodeRatio: mkfs.inodeRatio}
+
+	// Calcular el valor de n y la cantidad de bloques
+	n, blocks := calculateN(mountedPartition, geometry)
+	err = validGeometry(geometry, n, blocks)
+	if err != nil {
+		return err
+	}
 
 	fmt.Printf("Valor de N: %d\n", n)
+	fmt.Printf("Bloques: %d\n", blocks)
 
 	// Inicializar un nuevo superbloque
-	superBlock := createSuperBlock(mountedPartition, n, mkfs.fs)
+	superBlock := createSuperBlock(mountedPartition, n, blocks, geometry)
+
+	// El superbloque extendido guarda la etiqueta, el UUID y la proporción de inodos
+	ext, err := structures.NewExtendedSuperBlock(mkfs.label, mkfs.inodeRatio)
+	if err != nil {
+		return err
+	}
+	mkfs.uuid = ext.UUID()
+
+	// Desde aquí todo lo que se escriba en la partición lleva checksum si se pidió
+	superBlock.TrackChecksums(partitionPath, mountedPartition.Part_start, mountedPartition.Part_size)
+	superBlock.TrackLayout(partitionPath, mountedPartition)
 
+	// En ext3 el formateo es la primera transacción del journal, así recovery puede reconstruir desde aquí
+	if superBlock.S_filesystem_type == 3 {
+		err = superBlock.ClearJournal(partitionPath)
+		if err != nil {
+			return err
+		}
+		var tx *structures.Transaction
+		tx, err = superBlock.BeginTransaction(partitionPath, mountedPartition, structures.NewInformation("mkfs", "/", "-fs="+mkfs.fs))
+		if err != nil {
+			return err
+		}
+		err = createFileSystem(superBlock, ext, mountedPartition, partitionPath)
+		if err != nil {
+			tx.Abort()
+			return err
+		}
+		err = tx.Commit()
+	} else {
+		err = createFileSystem(superBlock, ext, mountedPartition, partitionPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Escribir las copias de respaldo del superbloque
+	return superBlock.WriteBackups(partitionPath, mountedPartition)
+}
+
+// createFileSystem escribe los bitmaps, la raíz con users.txt, el superbloque extendido y el superbloque
+func createFileSystem(superBlock *structures.SuperBlock, ext *structures.ExtendedSuperBlock, mountedPartition *structures.Partition, partitionPath string) error {
 	// Crear los bitmaps
-	err = superBlock.CreateBitMaps(partitionPath)
+	err := superBlock.CreateBitMaps(partitionPath)
 	if err != nil {
 		return err
 	}
@@ -109,7 +216,7 @@ func commandMkfs(mkfs *MKFS) error {
 	// Validar que sistema de archivos es
 	if superBlock.S_filesystem_type == 3 {
 		// Crear archivo users.txt ext3
-		err = superBlock.CreateUsersFileExt3(partitionPath, int64(mountedPartition.Part_start+int32(binary.Size(structures.SuperBlock{}))))
+		err = superBlock.CreateUsersFileExt3(partitionPath)
 		if err != nil {
 			return err
 		}
@@ -121,40 +228,64 @@ func commandMkfs(mkfs *MKFS) error {
 		}
 	}
 
-	// Serializar el superbloque
-	err = superBlock.Serialize(partitionPath, int64(mountedPartition.Part_start))
+	// Serializar el superbloque extendido justo después del superbloque
+	err = ext.Serialize(partitionPath, structures.ExtendedOffset(mountedPartition.Part_start))
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Serializar el superbloque
+	return superBlock.Serialize(partitionPath, int64(mountedPartition.Part_start))
 }
 
-func calculateN(partition *structures.Partition, fs string) int32 {
-	// Numerador: tamaño de la partición menos el tamaño del superblock
-	numerator := int(partition.Part_size) - binary.Size(structures.SuperBlock{})
-
-	// Denominador base: 4 + tamaño de inodos + 3 * tamaño de bloques de archivo
-	baseDenominator := 4 + binary.Size(structures.Inode{}) + 3*binary.Size(structures.FileBlock{})
+// validGeometry comprueba que en la geometría quepan al menos dos inodos y dos bloques y, en ext3, un journal de
+// MinJournalSlots registros, que tiene un registro por inodo
+func validGeometry(geometry fsGeometry, n int32, blocks int32) error {
+	if n < 2 || blocks < 2 {
+		return errors.New("la partición es demasiado pequeña para el tamaño de bloque y la proporción de inodos pedidos")
+	}
+	if geometry.fs == "3fs" && n < structures.MinJournalSlots {
+		return fmt.Errorf("el journal tendría %d registros y necesita al menos %d, use una proporción de inodos menor o una partición más grande",
+			n, structures.MinJournalSlots)
+	}
+	return nil
+}
 
-	// Si el sistema de archivos es "3fs", se añade el tamaño del journaling al denominador
-	temp := 0
-	if fs == "3fs" {
-		temp = binary.Size(structures.Journal{})
+// calculateN devuelve la cantidad de inodos y de bloques que caben en la partición con la geometría pedida.
+// Sin proporción de inodos se usan 3 bloques por inodo; con ella hay un inodo por cada inodeRatio bytes y
+// el resto de la partición se llena de bloques.
+func calculateN(partition *structures.Partition, geometry fsGeometry) (int32, int32) {
+	// Numerador: tamaño de la partición menos el superbloque, sus dos copias de respaldo y el superbloque extendido
+	numerator := int(partition.Part_size) - 3*binary.Size(structures.SuperBlock{}) - binary.Size(structures.ExtendedSuperBlock{})
+
+	// Cada inodo ocupa su byte del bitmap y su lugar en la tabla; en "3fs" también un registro del journal
+	perInode := 1 + binary.Size(structures.Inode{})
+	if geometry.fs == "3fs" {
+		perInode += binary.Size(structures.Journal{})
 	}
 
-	// Denominador final
-	denominator := baseDenominator + temp
+	// Cada bloque ocupa su byte del bitmap y, con checksums, su entrada de 4 bytes en la tabla
+	perBlock := 1 + int(geometry.blockSize)
+	if geometry.csum {
+		perBlock += 4
+	}
 
-	// Calcular n
-	n := math.Floor(float64(numerator) / float64(denominator))
+	if geometry.inodeRatio == 0 {
+		n := math.Floor(float64(numerator) / float64(perInode+3*perBlock))
+		return int32(n), int32(n) * 3
+	}
 
-	return int32(n)
+	n := numerator / int(geometry.inodeRatio)
+	blocks := (numerator - n*perInode) / perBlock
+	if blocks < 0 {
+		blocks = 0
+	}
+	return int32(n), int32(blocks)
 }
 
-func createSuperBlock(partition *structures.Partition, n int32, fs string) *structures.SuperBlock {
+func createSuperBlock(partition *structures.Partition, n int32, blocks int32, geometry fsGeometry) *structures.SuperBlock {
 	// Calcular punteros de las estructuras
-	journal_start, bm_inode_start, bm_block_start, inode_start, block_start := calculateStartPositions(partition, fs, n)
+	journal_start, bm_inode_start, bm_block_start, inode_start, block_start := calculateStartPositions(partition, geometry.fs, n, blocks)
 
 	fmt.Printf("Journal Start: %d\n", journal_start)
 	fmt.Printf("Bitmap Inode Start: %d\n", bm_inode_start)
@@ -165,55 +296,68 @@ func createSuperBlock(partition *structures.Partition, n int32, fs string) *stru
 	// Tipo de sistema de archivos
 	var fsType int32
 
-	if fs == "2fs" {
+	if geometry.fs == "2fs" {
 		fsType = 2
 	} else {
 		fsType = 3
 	}
 
+	features := structures.FeatureLongNames | structures.FeatureLinkCount | structures.FeatureExtended
+	csumStart := int32(0)
+	if geometry.csum {
+		// La tabla de checksums de bloques va después del área de bloques
+		features |= structures.FeatureMetadataCsum
+		csumStart = block_start + (blocks * geometry.blockSize)
+		fmt.Printf("Checksum Table Start: %d\n", csumStart)
+	}
+
 	// Crear un nuevo superbloque
 	superBlock := &structures.SuperBlock{
 		S_filesystem_type:   fsType,
 		S_inodes_count:      0,
 		S_blocks_count:      0,
 		S_free_inodes_count: int32(n),
-		S_free_blocks_count: int32(n * 3),
+		S_free_blocks_count: blocks,
 		S_mtime:             float32(time.Now().Unix()),
 		S_umtime:            float32(time.Now().Unix()),
 		S_mnt_count:         1,
-		S_magic:             0xEF53,
+		S_magic:             structures.SuperBlockMagic,
 		S_inode_size:        int32(binary.Size(structures.Inode{})),
-		S_block_size:        int32(binary.Size(structures.FileBlock{})),
+		S_block_size:        geometry.blockSize,
 		S_first_ino:         inode_start,
 		S_first_blo:         block_start,
 		S_bm_inode_start:    bm_inode_start,
 		S_bm_block_start:    bm_block_start,
 		S_inode_start:       inode_start,
 		S_block_start:       block_start,
+		S_fit:               partition.Part_fit,
+		S_features:          features,
+		S_csum_start:        csumStart,
 	}
 	return superBlock
 }
 
-func calculateStartPositions(partition *structures.Partition, fs string, n int32) (int32, int32, int32, int32, int32) {
+func calculateStartPositions(partition *structures.Partition, fs string, n int32, blocks int32) (int32, int32, int32, int32, int32) {
 	superblockSize := int32(binary.Size(structures.SuperBlock{}))
+	extendedSize := int32(binary.Size(structures.ExtendedSuperBlock{}))
 	journalSize := int32(binary.Size(structures.Journal{}))
 	inodeSize := int32(binary.Size(structures.Inode{}))
 
 	// Inicializar posiciones
 	// EXT2
 	journalStart := int32(0)
-	bmInodeStart := partition.Part_start + superblockSize
+	bmInodeStart := partition.Part_start + superblockSize + extendedSize
 	bmBlockStart := bmInodeStart + n
-	inodeStart := bmBlockStart + (3 * n)
-	blockStart := inodeStart + (inodeSize * n)
+	inodeStart := bmBlockStart + blocks
+	blockStart := inodeStart + (inodeSize * n) + superblockSize // copia de respaldo del superbloque
 
 	// Ajustar para EXT3
 	if fs == "3fs" {
-		journalStart = partition.Part_start + superblockSize
+		journalStart = partition.Part_start + superblockSize + extendedSize
 		bmInodeStart = journalStart + (journalSize * n)
 		bmBlockStart = bmInodeStart + n
-		inodeStart = bmBlockStart + (3 * n)
-		blockStart = inodeStart + (inodeSize * n)
+		inodeStart = bmBlockStart + blocks
+		blockStart = inodeStart + (inodeSize * n) + superblockSize
 	}
 
 	return journalStart, bmInodeStart, bmBlockStart, inodeStart, blockStart
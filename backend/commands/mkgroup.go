@@ -12,7 +12,7 @@ type MKGROUP struct {
 	name string
 }
 
-func ParseMkgroup(tokens []string) (string, error) {
+func ParseMkgroup(tokens []string, session *stores.Session) (string, error) {
 	cmd := &MKGROUP{}
 
 	args := strings.Join(tokens, " ")
@@ -50,12 +50,12 @@ func ParseMkgroup(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -name")
 	}
 
-	return createGroup(cmd.name), nil
+	return createGroup(cmd.name, session), nil
 }
 
-func createGroup(name string) string {
+func createGroup(name string, session *stores.Session) string {
 	// get the current session
-	userName, idPartition, _, _ := stores.GetSession()
+	userName, idPartition, _, _ := session.Get()
 	if userName == "" {
 		return "No hay sesión activa"
 	}
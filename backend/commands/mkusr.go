@@ -13,7 +13,7 @@ type MKUSR struct {
 	group string
 }
 
-func ParseMkuser(tokens []string) (string, error) {
+func ParseMkuser(tokens []string, session *stores.Session) (string, error) {
 	cmd := &MKUSR{}
 
 	args := strings.Join(tokens, " ")
@@ -72,12 +72,12 @@ func ParseMkuser(tokens []string) (string, error) {
 		return "", errors.New("los parámetros -user, -pass y -group deben tener como máximo 10 caracteres")
 	}
 
-	return createUser(cmd.user, cmd.pass, cmd.group), nil
+	return createUser(cmd.user, cmd.pass, cmd.group, session), nil
 }
 
-func createUser(user string, pass string, group string) string {
+func createUser(user string, pass string, group string, session *stores.Session) string {
 	// get the current session
-	userName, idPartition, _, _ := stores.GetSession()
+	userName, idPartition, _, _ := session.Get()
 	if userName == "" {
 		return "No hay sesión activa"
 	}
@@ -11,8 +11,9 @@ import (
 )
 
 type MOUNT struct {
-	path string
-	name string
+	path  string
+	name  string
+	label string // volume label of the filesystem, alternative to name
 }
 
 func ParseMount(tokens []string) (string, error) {
@@ -20,7 +21,7 @@ func ParseMount(tokens []string) (string, error) {
 
 	args := strings.Join(tokens, " ") // join the tokens to get the arguments
 
-	re := regexp.MustCompile(`-path="[^"]+"|-path=[^\s]+|-name="[^"]+"|-name=[^\s]+`)
+	re := regexp.MustCompile(`-path="[^"]+"|-path=[^\s]+|-name="[^"]+"|-name=[^\s]+|-label="[^"]+"|-label=[^\s]+`)
 
 	matches := re.FindAllString(args, -1) // find all the matches
 
@@ -46,6 +47,11 @@ func ParseMount(tokens []string) (string, error) {
 					return "", errors.New("invalid name")
 				}
 				cmd.name = value
+			case "-label":
+				if value == "" {
+					return "", errors.New("invalid label")
+				}
+				cmd.label = value
 			default:
 				return "", fmt.Errorf("invalid argument: %s", key)
 		}
@@ -55,44 +61,91 @@ func ParseMount(tokens []string) (string, error) {
 		return "", errors.New("missing path")
 	}
 
-	if cmd.name == "" {
-		return "", errors.New("missing name")
+	if cmd.name == "" && cmd.label == "" {
+		return "", errors.New("missing name or label")
 	}
 
-	err := commandMount(cmd)
+	name, err := commandMount(cmd)
 	if err != nil {
 		return "", err
 	}
+	saveState()
 
-	return fmt.Sprintf("mounting partition %s in %s", cmd.name, cmd.path), nil
+	return fmt.Sprintf("mounting partition %s in %s", name, cmd.path), nil
 }
 
-func commandMount(mount *MOUNT) error {
+// commandMount mounts the partition and returns its name, which is looked up when it is given by label
+func commandMount(mount *MOUNT) (string, error) {
 	var mbr structures.MBR
-	
-	err := mbr.DeserializeMBR(mount.path)
+	var gpt *structures.GPT // only for GPT disks, which have no MBR struct
+	var err error
+
+	if structures.IsGPT(mount.path) {
+		gpt, err = structures.ReadGPT(mount.path)
+	} else {
+		err = mbr.DeserializeMBR(mount.path)
+	}
 	if err != nil {
-		fmt.Println("error deserializing mbr: ", err)
-		return err
+		fmt.Println("error reading the partition table: ", err)
+		return "", err
 	}
 	fmt.Println("name: ", mount.name)
 	fmt.Println("path: ", mount.path)
 
-	partition, indexPartition := mbr.GetPartitionByName(mount.name)
+	// With a label the partition is found by the volume label of its filesystem
+	if mount.label != "" && gpt != nil {
+		index := gpt.FindByLabel(mount.path, mount.label)
+		if index == -1 {
+			fmt.Println("label not found")
+			return "", fmt.Errorf("no partition with label %s", mount.label)
+		}
+		mount.name = gpt.Entries[index].Name()
+	} else if mount.label != "" {
+		partition, indexPartition := mbr.GetPartitionByLabel(mount.path, mount.label)
+		if indexPartition == -1 {
+			fmt.Println("label not found")
+			return "", fmt.Errorf("no partition with label %s", mount.label)
+		}
+		mount.name = strings.Trim(string(partition.Part_name[:]), "\x00")
+	}
+
+	// GPT partitions are numbered by their entry in the array
+	var partition *structures.Partition
+	indexPartition := -1
+	if gpt != nil {
+		if index := gpt.FindByName(mount.name); index != -1 {
+			partition, indexPartition = gpt.Partition(index), index+1
+		}
+	} else {
+		partition, indexPartition = mbr.GetPartitionByName(mount.name)
+	}
+
+	// Logical partitions are searched through the EBR chain and numbered after the primary slots
+	var logical *structures.LogicalPartition
+	if indexPartition == -1 && gpt == nil {
+		logical, err = mbr.GetLogicalPartitionByName(mount.path, mount.name)
+		if err != nil {
+			fmt.Println("error reading the EBR chain: ", err)
+			return "", err
+		}
+		if logical != nil {
+			partition, indexPartition = logical.Partition(), logical.Number
+		}
+	}
 	if indexPartition == -1 {
 		fmt.Println("partition not found")
-		return errors.New("partition not found")
+		return "", errors.New("partition not found")
 	}
 	fmt.Println("indexPartition: ", indexPartition)	
 
-	if stores.MountedPartitions[string(partition.Part_id[:])] != "" {
+	if stores.MountedID(mount.path, partition) != "" {
 		fmt.Println("partition already mounted")
-		return errors.New("partition already mounted")
+		return "", errors.New("partition already mounted")
 	}
 
 	if partition == nil {
 		fmt.Println("partition not found")
-		return errors.New("partition not found")
+		return "", errors.New("partition not found")
 	}
 
 	fmt.Println("Partition Available:")
@@ -102,11 +155,50 @@ func commandMount(mount *MOUNT) error {
 	idPartition, err := generatePartitionID(mount, indexPartition)
 	if err != nil {
 		fmt.Println("error generating partition id: ", err)
-		return err 
+		return "", err
 	}
 
-	stores.MountedPartitions[idPartition] = mount.path  // mount the partition
+	// The number of a logical partition changes when an earlier one is deleted, so it may be taken
+	for logical != nil && stores.MountedPath(idPartition) != "" {
+		indexPartition++
+		idPartition, err = generatePartitionID(mount, indexPartition)
+		if err != nil {
+			return "", err
+		}
+	}
 
+	// Keep the disk open, track the checksums and replay the journal while the partition is mounted
+	err = structures.ActivatePartition(mount.path, partition)
+	if err != nil {
+		fmt.Println("error opening disk: ", err)
+		return "", err
+	}
+
+	// Stamp the mount time and count the mount in the superblock, if the partition is formatted
+	err = structures.RecordMount(mount.path, partition)
+	if err != nil {
+		fmt.Println("error updating the superblock: ", err)
+	}
+
+	// Logical and GPT partitions keep their id only in the mount table; a logical one also marks its EBR
+	if logical != nil || gpt != nil {
+		stores.AddMount(idPartition, mount.path, partition.Part_start)
+		if gpt != nil {
+			fmt.Println("partition mounted successfully")
+			return mount.name, nil
+		}
+		logical.EBR.Ebr_part_mount[0] = '1'
+		err = logical.EBR.SerializeEBR(mount.path, int64(logical.Offset))
+		if err != nil {
+			fmt.Println("error serializing ebr: ", err)
+			stores.RemoveMount(idPartition)
+			structures.DeactivatePartition(mount.path, partition.Part_start)
+			return "", err
+		}
+		return mount.name, nil
+	}
+
+	stores.AddMount(idPartition, mount.path, 0)
 	partition.MountPartition(indexPartition, idPartition) // mount the partition
 
 	fmt.Println("partition mounted successfully")
@@ -118,10 +210,12 @@ func commandMount(mount *MOUNT) error {
 	err = mbr.SerializeMBR(mount.path)
 	if err != nil {
 		fmt.Println("error serializing mbr: ", err)
-		return err
+		stores.RemoveMount(idPartition)
+		structures.DeactivatePartition(mount.path, partition.Part_start)
+		return "", err
 	}
 
-	return nil
+	return mount.name, nil
 }
 
 func generatePartitionID(mount *MOUNT, indexPartition int) (string, error) {
@@ -134,4 +228,12 @@ func generatePartitionID(mount *MOUNT, indexPartition int) (string, error) {
 	idPartition := fmt.Sprintf("%s%d%s", stores.Carnet, indexPartition, letter)
 
 	return idPartition, nil
-}
\ No newline at end of file
+}
+
+// saveState persists the mount table and the session; the command already succeeded, so a failure is only reported
+func saveState() {
+	err := stores.SaveState()
+	if err != nil {
+		fmt.Println("error saving the state: ", err)
+	}
+}
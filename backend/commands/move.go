@@ -14,7 +14,7 @@ type MOVE struct {
 	destino string
 }
 
-func ParseMove(tokens []string) (string, error) {
+func ParseMove(tokens []string, session *stores.Session) (string, error) {
 	cmd := &MOVE{}
 
 	args := strings.Join(tokens, " ")
@@ -65,7 +65,7 @@ func ParseMove(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -destino")
 	}
 
-	err := commandMove(cmd)
+	err := commandMove(cmd, session)
 	if err != nil {
 		return "", err
 	}
@@ -73,10 +73,10 @@ func ParseMove(tokens []string) (string, error) {
 	return fmt.Sprintf("MOVE: Archivo %s movido exitosamente.", cmd.path), nil
 }
 
-func commandMove(cmd *MOVE) error {
+func commandMove(cmd *MOVE, session *stores.Session) error {
 	parentDirs, destDir := utils.GetParentDirectories(cmd.path)
 	destinoParentDirs, destinoDir := utils.GetParentDirectories(cmd.destino)
-	username, idPartition, uid, gid := stores.GetSession()
+	username, idPartition, uid, gid := session.Get()
 	if username == "" || idPartition == "" {
 		return errors.New("no hay sesión activa")
 	}
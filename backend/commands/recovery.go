@@ -3,7 +3,6 @@ package commands
 import (
 	"backend/stores"
 	"backend/structures"
-	"backend/utils"
 	"errors"
 	"fmt"
 	"regexp"
@@ -12,13 +11,19 @@ import (
 
 type RECOVERY struct {
 	id string
+	sb string
 }
 
+/*
+   recovery -id=501A
+   recovery -id=501A -sb=backup
+*/
+
 func ParseRecovery(tokens []string) (string, error) {
 	cmd := &RECOVERY{}
 
 	args := strings.Join(tokens, " ")
-	re := regexp.MustCompile(`-id=[^\s]+`)
+	re := regexp.MustCompile(`-id=[^\s]+|-sb=[^\s]+`)
 	matches := re.FindAllString(args, -1)
 
 	for _, match := range matches {
@@ -34,6 +39,12 @@ func ParseRecovery(tokens []string) (string, error) {
 				return "", errors.New("el id no puede estar vacío")
 			}
 			cmd.id = value
+		case "-sb":
+			// Por ahora la única fuente alternativa del superbloque son sus copias
+			if strings.ToLower(value) != "backup" {
+				return "", errors.New("el valor de -sb debe ser backup")
+			}
+			cmd.sb = "backup"
 		default:
 			return "", fmt.Errorf("parámetro desconocido: %s", key)
 		}
@@ -43,79 +54,114 @@ func ParseRecovery(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -id")
 	}
 
-	err := commandRecovery(cmd)
+	if cmd.sb == "backup" {
+		return commandRecoveryBackup(cmd)
+	}
+
+	replayed, partial, err := commandRecovery(cmd)
 	if err != nil {
 		return "", err
 	}
 
-	return fmt.Sprintf("recovery partition %s", cmd.id), nil
+	output := fmt.Sprintf("recovery partition %s\n-> Transacciones rehechas: %d", cmd.id, replayed)
+	if partial {
+		output += "\n-> Advertencia: el journal ya no conserva el formateo de la partición, la recuperación puede ser parcial"
+	}
+	return output, nil
 }
 
-// commandRecovery es una función ficticia que simula la recuperación de una partición
-func commandRecovery(cmd *RECOVERY) error {
-	partitionSuperblock, mountedPartition, partitionPath, err := stores.GetMountedPartitionSuperblock(cmd.id)
+// commandRecoveryBackup restaura el superbloque principal desde una de sus copias de respaldo.
+// Si la raíz sigue intacta solo se recalculan los contadores con los bitmaps; si no, en ext3 se reconstruye con el journal.
+func commandRecoveryBackup(cmd *RECOVERY) (string, error) {
+	mountedPartition, partitionPath, err := stores.GetMountedPartition(cmd.id)
 	if err != nil {
-		return fmt.Errorf("error al obtener el superbloque de la partición: %w", err)
+		return "", fmt.Errorf("error al obtener la partición montada: %w", err)
 	}
 
-	if partitionSuperblock.S_filesystem_type != 3 {
-		return errors.New("sistema de archivos no es ext3")
+	backup, offset, err := structures.FindBackupSuperBlock(partitionPath, mountedPartition)
+	if err != nil {
+		return "", err
 	}
+	fmt.Println("Copia del superbloque encontrada en:", offset)
 
-	count := 2
-	// Iniciar el sistmea de archivos
-	error := partitionSuperblock.CreateUsersFileExt3(partitionPath, -1)
-	if error != nil {
-		return fmt.Errorf("error al crear el sistema de archivos: %w", error)
-	}
-	for {
-		// obtener el journal
-		journal := &structures.Journal{}
+	// Con la geometría restaurada se vuelven a registrar los checksums de la partición
+	backup.TrackChecksums(partitionPath, mountedPartition.Part_start, mountedPartition.Part_size)
+	backup.TrackLayout(partitionPath, mountedPartition)
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "RECOVERY: Superbloque de la partición %s restaurado desde la copia en %d\n", cmd.id, offset)
 
-		fmt.Println("Deserializando en:", int64(mountedPartition.Part_start)+68+114*int64(count))
-		// Deserializar el journal
-		err = journal.Deserialize(partitionPath, int64(mountedPartition.Part_start)+68+114*int64(count))
+	root := &structures.Inode{}
+	rootErr := root.Deserialize(partitionPath, int64(backup.S_inode_start))
+	if rootErr == nil && root.I_type[0] == '0' {
+		err = backup.RecountFromBitmaps(partitionPath)
 		if err != nil {
-			return fmt.Errorf("error al deserializar el journal: %w", err)
+			return "", fmt.Errorf("error al recalcular los contadores: %w", err)
 		}
-		if journal.J_count == 0 {
-			break
+		output.WriteString("-> Contadores recalculados a partir de los bitmaps\n")
+	} else if backup.S_filesystem_type == 3 {
+		// Sin raíz no hay nada que conservar, el contenido se reconstruye con el journal
+		err = backup.Serialize(partitionPath, int64(mountedPartition.Part_start))
+		if err != nil {
+			return "", fmt.Errorf("error al serializar el superbloque: %w", err)
+		}
+		_, _, err = commandRecovery(cmd)
+		if err != nil {
+			return "", err
 		}
-		fmt.Println("obtenido el journal")
-		journal.Print()
+		backup, _, err = structures.LoadSuperBlock(partitionPath, mountedPartition)
+		if err != nil {
+			return "", err
+		}
+		output.WriteString("-> La raíz estaba dañada, contenido reconstruido con el journal\n")
+	} else {
+		output.WriteString("-> La raíz está dañada y ext2 no tiene journal, el contenido no se pudo recuperar\n")
+	}
+
+	err = backup.Serialize(partitionPath, int64(mountedPartition.Part_start))
+	if err != nil {
+		return "", fmt.Errorf("error al serializar el superbloque: %w", err)
+	}
+	err = backup.WriteBackups(partitionPath, mountedPartition)
+	if err != nil {
+		return "", err
+	}
 
-		operation := strings.TrimRight(string(journal.J_content.I_operation[:]), "\x00")
-		path := strings.TrimRight(string(journal.J_content.I_path[:]), "\x00")
-		content := strings.TrimRight(string(journal.J_content.I_content[:]), "\x00")
+	return strings.TrimRight(output.String(), "\n"), nil
+}
+
+// commandRecovery rehace todas las transacciones confirmadas del journal, desde el formateo de la partición.
+// Como las actualizaciones son imágenes de lo escrito, rehacerlas sobre una partición dañada o intacta deja el mismo resultado.
+func commandRecovery(cmd *RECOVERY) (int, bool, error) {
+	partitionSuperblock, mountedPartition, partitionPath, err := stores.GetMountedPartitionSuperblock(cmd.id)
+	if err != nil {
+		return 0, false, fmt.Errorf("error al obtener el superbloque de la partición: %w", err)
+	}
 
-		fmt.Println("Operación:", operation, "Ruta:", path, "Contenido:", content)
+	if partitionSuperblock.S_filesystem_type != 3 {
+		return 0, false, errors.New("sistema de archivos no es ext3")
+	}
 
-		parentDirs, destiDir := utils.GetParentDirectories(path)
-		size := utils.StringToInt(content)
-		if operation == "mkdir" {
-			err := partitionSuperblock.CreateFolder(partitionPath, parentDirs, destiDir, 0, 0, path, -1)
-			if err != nil {
-				return fmt.Errorf("error al crear el directorio: %w", err)
-			}
-			fmt.Println("MKDIR: Directorio creado correctamente")
-		} else if operation == "mkfile" {
-			err := partitionSuperblock.CreateFile(partitionPath, parentDirs, destiDir, false, size, content, 0, 0, path, -1)
-			if err != nil {
-				return fmt.Errorf("error al crear el archivo: %w", err)
-			}
-			fmt.Println("MKFILE: Archivo creado correctamente")
-		} else {
-			fmt.Println("Operación desconocida detectada")
-		}
+	// Si un checkpoint ya liberó el formateo, solo se puede rehacer lo que conserva el journal
+	transactions, _, err := partitionSuperblock.ReadJournal(partitionPath)
+	if err != nil {
+		return 0, false, fmt.Errorf("error al leer el journal: %w", err)
+	}
+	partial := len(transactions) == 0 || transactions[0].Info.Operation() != "mkfs"
 
-		count++
+	replayed, err := partitionSuperblock.ReplayJournal(partitionPath, true)
+	if err != nil {
+		return replayed, false, fmt.Errorf("error al rehacer el journal: %w", err)
 	}
 
-	// Serializar el superbloque
-	err = partitionSuperblock.Serialize(partitionPath, int64(mountedPartition.Part_start))
+	// El superbloque rehecho puede traer otra configuración de checksums
+	restored := &structures.SuperBlock{}
+	err = restored.Deserialize(partitionPath, int64(mountedPartition.Part_start))
 	if err != nil {
-		return fmt.Errorf("error al serializar el superbloque: %w", err)
+		return replayed, false, fmt.Errorf("error al leer el superbloque rehecho: %w", err)
 	}
+	restored.TrackChecksums(partitionPath, mountedPartition.Part_start, mountedPartition.Part_size)
+	restored.TrackLayout(partitionPath, mountedPartition)
 
-	return nil
+	return replayed, partial, nil
 }
@@ -14,7 +14,7 @@ type REMOVE struct {
 	path string
 }
 
-func ParseRemove(tokens []string) (string, error) {
+func ParseRemove(tokens []string, session *stores.Session) (string, error) {
 	cmd := &REMOVE{}
 
 	args := strings.Join(tokens, " ")
@@ -44,7 +44,7 @@ func ParseRemove(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -path")
 	}
 
-	err := commandRemove(cmd)
+	err := commandRemove(cmd, session)
 	if err != nil {
 		return "", err
 	}
@@ -52,9 +52,9 @@ func ParseRemove(tokens []string) (string, error) {
 	return fmt.Sprintf("REMOVE: Directorio %s eliminado correctamente.", cmd.path), nil // Devuelve el comando REMOVE creado
 }
 
-func commandRemove(cmd *REMOVE) error {
+func commandRemove(cmd *REMOVE, session *stores.Session) error {
 	// obtener la sesión activa
-	username, idPartitinUser, _, _ := stores.GetSession()
+	username, idPartitinUser, _, _ := session.Get()
 	if username == "" {
 		return errors.New("no hay sesión activa")
 	}
@@ -14,7 +14,7 @@ type RENAME struct {
 	name string
 }
 
-func ParseRename(tokens []string) (string, error) {
+func ParseRename(tokens []string, session *stores.Session) (string, error) {
 	cmd := &RENAME{}
 
 	args := strings.Join(tokens, " ")
@@ -65,7 +65,7 @@ func ParseRename(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -name")
 	}
 
-	err := commandRename(cmd)
+	err := commandRename(cmd, session)
 	if err != nil {
 		return "", err
 	}
@@ -73,9 +73,9 @@ func ParseRename(tokens []string) (string, error) {
 	return fmt.Sprintf("RENAME: Archivo %s renombrado exitosamente.", cmd.path), nil
 }
 
-func commandRename(cmd *RENAME) error {
+func commandRename(cmd *RENAME, session *stores.Session) error {
 	parentDirs, destDir := utils.GetParentDirectories(cmd.path)
-	username, idPartition, uid, gid := stores.GetSession()
+	username, idPartition, uid, gid := session.Get()
 	if username == "" || idPartition == "" {
 		return errors.New("no hay sesión activa")
 	}
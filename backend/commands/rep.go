@@ -3,6 +3,7 @@ package commands
 import (
 	reports "backend/reports"
 	stores "backend/stores"
+	structures "backend/structures"
 	"errors"
 	"fmt"
 	"regexp"
@@ -14,6 +15,7 @@ type REP struct {
 	path         string
 	name         string
 	path_file_ls string
+	until        string // momento del journal para el reporte tree histórico
 }
 
 func ParseRep(tokens []string) (string, error) {
@@ -21,7 +23,7 @@ func ParseRep(tokens []string) (string, error) {
 
 	args := strings.Join(tokens, " ")
 
-	re := regexp.MustCompile(`(?i)-id=[^\s]+|-path="[^"]+"|-path=[^\s]+|-name=[^\s]+|-path_file_ls="[^"]+"|-path_file_ls=[^\s]+`)
+	re := regexp.MustCompile(`(?i)-id=[^\s]+|-path="[^"]+"|-path=[^\s]+|-name=[^\s]+|-path_file_ls="[^"]+"|-path_file_ls=[^\s]+|-until="[^"]+"|-until=[^\s]+`)
 
 	matches := re.FindAllString(args, -1)
 
@@ -48,13 +50,18 @@ func ParseRep(tokens []string) (string, error) {
 			}
 			cmd.path = value
 		case "-name":
-			validNames := []string{"mbr", "disk", "inode", "block", "bm_inode", "bm_block", "sb", "file", "ls", "tree", "fs"}
+			validNames := []string{"mbr", "ebr", "disk", "inode", "block", "bm_inode", "bm_block", "sb", "file", "ls", "tree", "fs"}
 			if !contains(validNames, value) {
-				return "", errors.New("nombre inválido, debe ser uno de los siguientes: mbr, disk, inode, block, bm_inode, bm_block, sb, file, ls")
+				return "", errors.New("nombre inválido, debe ser uno de los siguientes: mbr, ebr, disk, inode, block, bm_inode, bm_block, sb, file, ls")
 			}
 			cmd.name = value
 		case "-path_file_ls":
 			cmd.path_file_ls = value
+		case "-until":
+			if value == "" {
+				return "", errors.New("invalid until")
+			}
+			cmd.until = value
 		default:
 			return "", fmt.Errorf("invalid argument: %s", key)
 		}
@@ -62,6 +69,9 @@ func ParseRep(tokens []string) (string, error) {
 	if cmd.id == "" || cmd.path == "" || cmd.name == "" {
 		return "", errors.New("faltan parámetros requeridos: -id, -path, -name")
 	}
+	if cmd.until != "" && cmd.name != "tree" {
+		return "", errors.New("-until solo se puede usar con el reporte tree")
+	}
 
 	message, err := commandRep(cmd)
 	if err != nil {
@@ -87,13 +97,41 @@ func commandRep(rep *REP) (string, error) {
 		return "", fmt.Errorf("Error al obtener la partición montada: %v", err)
 	}
 
+	// Los reportes de la tabla de particiones siempre leen el disco real, aunque se pida una vista del journal
+	diskPath := mountedDiskPath
+	gptDisk := structures.IsGPT(diskPath)
+
+	// El árbol histórico se dibuja sobre la partición reconstruida desde el journal
+	if rep.until != "" {
+		snapshotSb, snapshotPath, _, err := journalSnapshot(rep.id, rep.until)
+		if err != nil {
+			return "", err
+		}
+		defer structures.DropDevice(snapshotPath)
+		mountedSb, mountedDiskPath = snapshotSb, snapshotPath
+	}
+
 	switch rep.name {
 	case "mbr":
-		err = reports.ReportMBR(mountedMbr, mountedDiskPath, rep.path)
+		// Un disco GPT no tiene el MBR del proyecto: se reportan su MBR protector, el encabezado y las entradas
+		if gptDisk {
+			err = reports.ReportGPT(diskPath, rep.path)
+		} else {
+			err = reports.ReportMBR(mountedMbr, diskPath, rep.path)
+		}
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 		}
 		return fmt.Sprintf("MBR report generated at %s", rep.path), nil
+	case "ebr":
+		if gptDisk {
+			return "", errors.New("un disco GPT no tiene partición extendida")
+		}
+		err = reports.ReportEBR(mountedMbr, diskPath, rep.path)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("EBR report generated at %s", rep.path), nil
 	case "inode":
 		err = reports.ReportInode(mountedSb, mountedDiskPath, rep.path)
 		if err != nil {
@@ -131,7 +169,11 @@ func commandRep(rep *REP) (string, error) {
 		}
 		return fmt.Sprintf("Superblock report generated at %s", rep.path), nil
 	case "disk":
-		err = reports.ReportDisk(mountedMbr, mountedDiskPath, rep.path)
+		if gptDisk {
+			err = reports.ReportGPTDisk(diskPath, rep.path)
+		} else {
+			err = reports.ReportDisk(mountedMbr, diskPath, rep.path)
+		}
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 		}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"backend/stores"
+	"backend/structures"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type RESIZEFS struct {
+	id string
+}
+
+/*
+   resizefs -id=501A
+*/
+
+func ParseResizefs(tokens []string) (string, error) {
+	cmd := &RESIZEFS{}
+
+	args := strings.Join(tokens, " ")
+	re := regexp.MustCompile(`-id=[^\s]+`)
+	matches := re.FindAllString(args, -1)
+
+	for _, match := range matches {
+		kv := strings.SplitN(match, "=", 2)
+		if len(kv) != 2 {
+			return "", fmt.Errorf("formato de parámetro inválido: %s", match)
+		}
+		key, value := strings.ToLower(kv[0]), kv[1]
+
+		if strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+			value = strings.Trim(value, "\"")
+		}
+
+		switch key {
+		case "-id":
+			if value == "" {
+				return "", errors.New("el id no puede estar vacío")
+			}
+			cmd.id = value
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.id == "" {
+		return "", errors.New("faltan parámetros requeridos: -id")
+	}
+
+	return commandResizefs(cmd)
+}
+
+// commandResizefs ajusta el sistema de archivos de la partición a su tamaño actual en el MBR
+func commandResizefs(cmd *RESIZEFS) (string, error) {
+	sb, partition, partitionPath, err := stores.GetMountedPartitionSuperblock(cmd.id)
+	if err != nil {
+		return "", fmt.Errorf("error al obtener la partición montada: %w", err)
+	}
+	if !sb.Formatted() {
+		return "", errors.New("la partición no tiene un sistema de archivos")
+	}
+
+	target, result, err := resizeFileSystem(sb, partition, partitionPath)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("RESIZEFS: Sistema de archivos de la partición %s ajustado a %d bytes\n"+
+		"-> Inodos: %d -> %d (%d reubicados)\n"+
+		"-> Bloques: %d -> %d (%d reubicados)",
+		cmd.id, partition.Part_size,
+		sb.TotalInodes(), target.TotalInodes(), result.MovedInodes,
+		sb.TotalBlocks(), target.TotalBlocks(), result.MovedBlocks), nil
+}
+
+// currentGeometry devuelve las opciones con que se formateó el sistema de archivos de sb
+func currentGeometry(sb *structures.SuperBlock, path string, partStart int32) (fsGeometry, error) {
+	geometry := fsGeometry{fs: "2fs", csum: sb.HasFeature(structures.FeatureMetadataCsum), blockSize: sb.S_block_size}
+	if sb.S_filesystem_type == 3 {
+		geometry.fs = "3fs"
+	}
+	ext, err := sb.ReadExtended(path, partStart)
+	if err != nil {
+		return geometry, fmt.Errorf("error al leer el superbloque extendido: %w", err)
+	}
+	if ext != nil {
+		geometry.inodeRatio = ext.S_inode_ratio
+	}
+	return geometry, nil
+}
+
+// resizeFileSystem lleva el sistema de archivos de sb a la geometría que le corresponde al tamaño de partition,
+// con las mismas opciones de formato. Al achicar, lo que queda fuera se reubica y si no cabe no se toca nada.
+func resizeFileSystem(sb *structures.SuperBlock, partition *structures.Partition, path string) (*structures.SuperBlock, *structures.RelayoutResult, error) {
+	if sb.Legacy() {
+		return nil, nil, errors.New("el sistema de archivos tiene el formato anterior y no se puede redimensionar, vuelva a formatear la partición con mkfs")
+	}
+
+	geometry, err := currentGeometry(sb, path, partition.Part_start)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	n, blocks := calculateN(partition, geometry)
+	err = validGeometry(geometry, n, blocks)
+	if err != nil {
+		return nil, nil, fmt.Errorf("la partición de %d bytes no alcanza para el sistema de archivos: %w", partition.Part_size, err)
+	}
+	target := createSuperBlock(partition, n, blocks, geometry)
+
+	result, err := sb.Relayout(path, partition, target)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error al redimensionar el sistema de archivos: %w", err)
+	}
+	return target, result, nil
+}
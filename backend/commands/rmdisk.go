@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"backend/structures"
 	"errors"
 	"os"
 	"path/filepath"
@@ -58,15 +59,25 @@ func ParseRmdisk(tokens []string) (string, error) {
 func commandRmdisk(cmd *RMDISK) error {
 	path := cmd.Path
 
-	if !filepath.IsAbs(path) {
+	if !filepath.IsAbs(path) && !structures.IsMemoryPath(path) {
 		return errors.New("invalid path")
 	}
 
-	if _, err := os.Stat(path); os.IsNotExist(err) {
+	if !structures.DeviceExists(path) {
 		return errors.New("disk not found")
 	}
 
-	err := os.RemoveAll(path)
+	// discard the open device so nothing is written back to the removed disk
+	err := structures.DropDevice(path)
+	if err != nil {
+		return err
+	}
+
+	if structures.IsMemoryPath(path) {
+		return nil
+	}
+
+	err = os.RemoveAll(path)
 	if err != nil {
 		return err
 	}
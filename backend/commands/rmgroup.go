@@ -12,7 +12,7 @@ type RMGROUP struct {
 	name string
 }
 
-func ParseRmgroup(tokens []string) (string, error) {
+func ParseRmgroup(tokens []string, session *stores.Session) (string, error) {
 	cmd := &RMGROUP{}
 
 	args := strings.Join(tokens, " ")
@@ -50,12 +50,12 @@ func ParseRmgroup(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -name")
 	}
 
-	return removeGroup(cmd.name), nil
+	return removeGroup(cmd.name, session), nil
 }
 
-func removeGroup(name string) string {
+func removeGroup(name string, session *stores.Session) string {
 	// get the current session
-	userName, idPartition, _, _  := stores.GetSession()
+	userName, idPartition, _, _  := session.Get()
 	if userName == "" {
 		return "No hay sesión activa"
 	}
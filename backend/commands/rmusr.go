@@ -12,7 +12,7 @@ type RMUSR struct {
 	user  string
 }
 
-func ParseRmuser(tokens []string) (string, error) {
+func ParseRmuser(tokens []string, session *stores.Session) (string, error) {
 	cmd := &RMUSR{}
 
 	args := strings.Join(tokens, " ")
@@ -50,12 +50,12 @@ func ParseRmuser(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -user")
 	}
 
-	return removeUser(cmd.user), nil
+	return removeUser(cmd.user, session), nil
 }
 
-func removeUser(user string) string {
+func removeUser(user string, session *stores.Session) string {
 	// get the current session
-	userName, idPartition, _, _  := stores.GetSession()
+	userName, idPartition, _, _  := session.Get()
 	if userName == "" {
 		return "No hay sesión activa"
 	}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	stores "backend/stores"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SESSIONS struct {
+	kill int // número de la sesión a cerrar; 0 solo lista
+}
+
+// ParseSessions lista las sesiones activas de todos los clientes o cierra una con -kill; solo lo puede usar root
+func ParseSessions(tokens []string, session *stores.Session) (string, error) {
+	cmd := &SESSIONS{}
+
+	args := strings.Join(tokens, " ")
+	re := regexp.MustCompile(`-kill=[^\s]+`)
+	matches := re.FindAllString(args, -1)
+
+	if len(matches) != len(tokens) {
+		for _, token := range tokens {
+			if !re.MatchString(token) {
+				return "", fmt.Errorf("parámetro inválido: %s", token)
+			}
+		}
+	}
+
+	for _, match := range matches {
+		kv := strings.SplitN(match, "=", 2)
+		key := strings.ToLower(kv[0])
+
+		switch key {
+		case "-kill":
+			id, err := strconv.Atoi(strings.Trim(kv[1], "\""))
+			if err != nil || id <= 0 {
+				return "", errors.New("el número de la sesión debe ser un entero positivo")
+			}
+			cmd.kill = id
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	userName, _, _, _ := session.Get()
+	if userName == "" {
+		return "", errors.New("no hay sesión activa")
+	}
+	if userName != "root" {
+		return "", errors.New("solo el usuario root puede ver o cerrar las sesiones")
+	}
+
+	if cmd.kill != 0 {
+		return commandKillSession(cmd)
+	}
+	return commandSessions(), nil
+}
+
+func commandSessions() string {
+	output := "Sesiones activas:\n"
+	for _, active := range stores.Sessions() {
+		expires := active.LastUsed.Add(stores.SessionTimeout)
+		output += fmt.Sprintf("#%d %s en %s, desde %s, expira %s\n", active.ID, active.User, active.Partition,
+			active.Started.Format(time.DateTime), expires.Format(time.DateTime))
+	}
+	return strings.TrimSuffix(output, "\n")
+}
+
+func commandKillSession(cmd *SESSIONS) (string, error) {
+	user, err := stores.KillSession(cmd.kill)
+	if err != nil {
+		return "", err
+	}
+	saveState()
+
+	return fmt.Sprintf("Sesión #%d de %s cerrada", cmd.kill, user), nil
+}
@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"backend/stores"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type TUNEFS struct {
+	id      string
+	journal string // on: pasar a ext3, off: pasar a ext2
+}
+
+/*
+   tunefs -id=501A -journal=on
+   tunefs -id=501A -journal=off
+*/
+
+func ParseTunefs(tokens []string) (string, error) {
+	cmd := &TUNEFS{}
+
+	args := strings.Join(tokens, " ")
+	re := regexp.MustCompile(`-id=[^\s]+|-journal=[^\s]+`)
+	matches := re.FindAllString(args, -1)
+
+	for _, match := range matches {
+		kv := strings.SplitN(match, "=", 2)
+		if len(kv) != 2 {
+			return "", fmt.Errorf("formato de parámetro inválido: %s", match)
+		}
+		key, value := strings.ToLower(kv[0]), kv[1]
+
+		if strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+			value = strings.Trim(value, "\"")
+		}
+
+		switch key {
+		case "-id":
+			if value == "" {
+				return "", errors.New("el id no puede estar vacío")
+			}
+			cmd.id = value
+		case "-journal":
+			value = strings.ToLower(value)
+			if value != "on" && value != "off" {
+				return "", errors.New("el journal debe ser on u off")
+			}
+			cmd.journal = value
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.id == "" || cmd.journal == "" {
+		return "", errors.New("faltan parámetros requeridos: -id, -journal")
+	}
+
+	return commandTunefs(cmd)
+}
+
+// commandTunefs convierte la partición entre ext2 y ext3 conservando los archivos: la nueva geometría es
+// la misma que daría mkfs con el otro sistema de archivos y el contenido se reubica dentro de ella
+func commandTunefs(cmd *TUNEFS) (string, error) {
+	sb, partition, partitionPath, err := stores.GetMountedPartitionSuperblock(cmd.id)
+	if err != nil {
+		return "", fmt.Errorf("error al obtener la partición montada: %w", err)
+	}
+	if !sb.Formatted() {
+		return "", errors.New("la partición no tiene un sistema de archivos")
+	}
+	if sb.Legacy() {
+		return "", errors.New("el sistema de archivos tiene el formato anterior y no se puede convertir, vuelva a formatear la partición con mkfs")
+	}
+
+	fs := "2fs"
+	if cmd.journal == "on" {
+		fs = "3fs"
+	}
+	if (sb.S_filesystem_type == 3) == (fs == "3fs") {
+		return "", fmt.Errorf("la partición %s ya es %s", cmd.id, map[string]string{"2fs": "EXT2", "3fs": "EXT3"}[fs])
+	}
+
+	// Se conservan el tamaño de bloque y la proporción de inodos con que se formateó
+	geometry, err := currentGeometry(sb, partitionPath, partition.Part_start)
+	if err != nil {
+		return "", err
+	}
+	geometry.fs = fs
+	n, blocks := calculateN(partition, geometry)
+	err = validGeometry(geometry, n, blocks)
+	if err != nil {
+		return "", err
+	}
+	target := createSuperBlock(partition, n, blocks, geometry)
+
+	result, err := sb.Relayout(partitionPath, partition, target)
+	if err != nil {
+		return "", fmt.Errorf("error al cambiar el sistema de archivos: %w", err)
+	}
+
+	action := "desactivado"
+	if fs == "3fs" {
+		action = "activado"
+	}
+	return fmt.Sprintf("TUNEFS: Journal %s en la partición %s\n"+
+		"-> Sistema de archivos: %s\n"+
+		"-> Inodos: %d -> %d (%d reubicados)\n"+
+		"-> Bloques: %d -> %d (%d reubicados)",
+		action, cmd.id,
+		map[string]string{"2fs": "EXT2", "3fs": "EXT3"}[fs],
+		sb.TotalInodes(), target.TotalInodes(), result.MovedInodes,
+		sb.TotalBlocks(), target.TotalBlocks(), result.MovedBlocks), nil
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"backend/stores"
+	"backend/structures"
 	"errors"
 	"fmt"
 	"regexp"
@@ -9,17 +10,24 @@ import (
 )
 
 type UNMOUNTED struct {
-	id string
+	id    string
+	force bool // desmontar aunque haya una sesión activa en la partición, cerrándola
 }
 
 func ParseUnmounted(tokens []string) (string, error) {
 	cmd := &UNMOUNTED{}
 
 	args := strings.Join(tokens, " ")
-	re := regexp.MustCompile(`-id=[^\s]+`)
+	re := regexp.MustCompile(`-id=[^\s]+|-force`)
 	matches := re.FindAllString(args, -1)
 
 	for _, match := range matches {
+		// -force es una bandera sin valor
+		if strings.ToLower(match) == "-force" {
+			cmd.force = true
+			continue
+		}
+
 		kv := strings.SplitN(match, "=", 2)
 		if len(kv) != 2 {
 			return "", fmt.Errorf("formato de parámetro inválido: %s", match)
@@ -49,16 +57,78 @@ func ParseUnmounted(tokens []string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	saveState()
 
 	return fmt.Sprintf("unmounting partition %s", cmd.id), nil
 }
 
 func commandUnmount(unmounted *UNMOUNTED) error {
 	fmt.Println("unmounting partition", unmounted.id)
-	if stores.MountedPartitions[string(unmounted.id)] == "" {
+	if stores.MountedPath(unmounted.id) == "" {
 		return errors.New("partition not mounted")
 	}
 
-	delete(stores.MountedPartitions, string(unmounted.id))
-	return nil
+	// Las sesiones activas en la partición la siguen usando; solo -force las cierra
+	users := stores.PartitionSessions(unmounted.id)
+	if len(users) > 0 {
+		if !unmounted.force {
+			return fmt.Errorf("hay sesiones activas en la partición %s (%s), use logout o -force", unmounted.id, strings.Join(users, ", "))
+		}
+		stores.EndPartitionSessions(unmounted.id)
+		fmt.Println("sesiones cerradas:", strings.Join(users, ", "))
+	}
+
+	partition, path, err := stores.GetMountedPartition(unmounted.id)
+	if err != nil {
+		return err
+	}
+
+	// La hora de desmontaje se escribe mientras se siguen llevando los checksums de la partición
+	err = structures.RecordUnmount(path, partition)
+	if err != nil {
+		fmt.Println("error al actualizar el superbloque:", err)
+	}
+
+	err = releasePartition(unmounted.id, path)
+	if err != nil {
+		return err
+	}
+
+	stores.RemoveMount(unmounted.id)
+
+	// Liberar el disco escribiendo lo que quede en caché; si ya no hay particiones montadas en él se cierra
+	return structures.DeactivatePartition(path, partition.Part_start)
+}
+
+// releasePartition deja la tabla de particiones como si la partición nunca se hubiera montado: en el MBR vuelve el
+// estado creado sin correlativo ni id, una lógica desmarca su EBR y una GPT no guarda nada del montaje
+func releasePartition(id string, path string) error {
+	start, external := stores.PartitionStart(id)
+	if external && structures.IsGPT(path) {
+		return nil
+	}
+
+	var mbr structures.MBR
+	err := mbr.DeserializeMBR(path)
+	if err != nil {
+		return err
+	}
+
+	if external {
+		logical, err := mbr.GetLogicalPartitionAt(path, start-structures.EBRSize)
+		if err != nil {
+			return err
+		}
+		logical.EBR.Ebr_part_mount[0] = 'N'
+		return logical.EBR.SerializeEBR(path, int64(logical.Offset))
+	}
+
+	for i := range mbr.Mbr_partitions {
+		partID := strings.TrimRight(string(mbr.Mbr_partitions[i].Part_id[:]), "\x00")
+		if partID == id {
+			mbr.Mbr_partitions[i].UnmountPartition()
+			return mbr.SerializeMBR(path)
+		}
+	}
+	return fmt.Errorf("el MBR no tiene la partición %s", id)
 }
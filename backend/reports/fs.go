@@ -10,7 +10,8 @@ import (
 // FileSystemNode representa un nodo en el sistema de archivos (archivo o directorio)
 type FileSystemNode struct {
 	Name    string        `json:"name"`
-	Type    int           `json:"type"`              // 0: directorio, 1: archivo
+	Type    int           `json:"type"`              // 0: directorio, 1: archivo, 2: enlace simbólico
+	Target  string        `json:"target,omitempty"`  // Ruta a la que apunta un enlace simbólico
 	Content []interface{} `json:"content,omitempty"` // Para directorios: []FileSystemNode, para archivos: []string
 }
 
@@ -33,11 +34,6 @@ func GenerateFileSystemJSON(superblock *structures.SuperBlock, diskPath string,
 
 // Función recursiva para generar la estructura de nodos
 func generateNodeContent(superblock *structures.SuperBlock, diskPath string, inodeIndex int32, visited map[int32]bool) (*FileSystemNode, error) {
-	if visited[inodeIndex] {
-		return nil, nil
-	}
-	visited[inodeIndex] = true
-
 	// Deserializar el inodo actual
 	inode := &structures.Inode{}
 	err := inode.Deserialize(diskPath, int64(superblock.S_inode_start+(inodeIndex*superblock.S_inode_size)))
@@ -45,25 +41,40 @@ func generateNodeContent(superblock *structures.SuperBlock, diskPath string, ino
 		return nil, err
 	}
 
+	// Las carpetas se visitan una sola vez; un archivo con enlaces duros aparece con cada uno de sus nombres
+	if visited[inodeIndex] && inode.I_type[0] == '0' {
+		return nil, nil
+	}
+	visited[inodeIndex] = true
+
 	node := &FileSystemNode{}
 
-	// Determinar si es directorio (0) o archivo (1)
+	// Determinar si es directorio (0), enlace simbólico (2) o archivo (1)
 	if inode.I_type[0] == '0' {
 		node.Type = 0
 		node.Content = []interface{}{}
+	} else if inode.I_type[0] == '2' {
+		target, err := superblock.LinkTarget(diskPath, inode)
+		if err != nil {
+			return nil, err
+		}
+		node.Type = 2
+		node.Target = target
+		return node, nil
 	} else {
 		node.Type = 1
 		node.Content = []interface{}{}
 	}
 
-	// Procesar bloques
-	for _, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			break
-		}
+	// Procesar bloques (directos e indirectos)
+	dataBlocks, err := superblock.InodeDataBlocks(diskPath, inode)
+	if err != nil {
+		return nil, err
+	}
+	for _, blockIndex := range dataBlocks {
 
 		if inode.I_type[0] == '0' { // Directorio
-			folderBlock := &structures.FolderBlock{}
+			folderBlock := superblock.NewFolderBlock()
 			err := folderBlock.Deserialize(diskPath, int64(superblock.S_block_start+(blockIndex*superblock.S_block_size)))
 			if err != nil {
 				return nil, err
@@ -71,7 +82,10 @@ func generateNodeContent(superblock *structures.SuperBlock, diskPath string, ino
 
 			// Procesar contenido del directorio
 			for _, content := range folderBlock.B_content {
-				name := strings.TrimRight(string(content.B_name[:]), "\x00")
+				name, err := superblock.EntryName(diskPath, content)
+				if err != nil {
+					return nil, err
+				}
 				if content.B_inodo == -1 || name == "." || name == ".." {
 					continue
 				}
@@ -89,7 +103,7 @@ func generateNodeContent(superblock *structures.SuperBlock, diskPath string, ino
 			}
 
 		} else if inode.I_type[0] == '1' { // Archivo
-			fileBlock := &structures.FileBlock{}
+			fileBlock := superblock.NewFileBlock()
 			err := fileBlock.Deserialize(diskPath, int64(superblock.S_block_start+(blockIndex*superblock.S_block_size)))
 			if err != nil {
 				return nil, err
@@ -74,13 +74,14 @@ func generateDotContent(sb *structures.SuperBlock, diskPath string, inode *struc
 				<tr><td bgcolor="lightgray"><b>Nombre</b></td><td bgcolor="lightgray"><b>UID</b></td><td bgcolor="lightgray"><b>GID</b></td><td bgcolor="lightgray"><b>Size</b></td><td bgcolor="lightgray"><b>Tipo</b></td><td bgcolor="lightgray"><b>Fecha</b></td><td bgcolor="lightgray"><b>Hora</b></td><td bgcolor="lightgray"><b>Permisos</b></td></tr>
 	`)
 
-	// Procesar bloques del inodo
-	for _, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			continue
-		}
+	// Procesar bloques del inodo (directos e indirectos)
+	dataBlocks, err := sb.InodeDataBlocks(diskPath, inode)
+	if err != nil {
+		fmt.Println("Error al leer los bloques del inodo:", err)
+	}
+	for _, blockIndex := range dataBlocks {
 
-		block := &structures.FolderBlock{}
+		block := sb.NewFolderBlock()
 		if err := block.Deserialize(diskPath, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) 
 		err != nil {
 			continue
@@ -100,11 +101,23 @@ func generateDotContent(sb *structures.SuperBlock, diskPath string, inode *struc
 			}
 
 			// Limpiar nombre y determinar tipo
-			name := strings.TrimRight(string(content.B_name[:]), "\x00")
+			name, err := sb.EntryName(diskPath, content)
+			if err != nil {
+				continue
+			}
 			fileType := "Archivo"
 			if inodeContent.I_type[0] == '0' {
 				fileType = "Carpeta"
 			}
+			if inodeContent.I_type[0] == '2' {
+				// Mostrar hacia dónde apunta el enlace
+				target, err := sb.LinkTarget(diskPath, inodeContent)
+				if err != nil {
+					continue
+				}
+				fileType = "Enlace"
+				name = fmt.Sprintf("%s -&gt; %s", name, target)
+			}
 			ctime := time.Unix(int64(inode.I_ctime), 0)
 
 			date, time := utils.FormatDate(ctime.Format(time.RFC3339))
@@ -37,8 +37,12 @@ func ReportBlock(superblock *structures.SuperBlock, diskPath string, path string
 	// Variable para almacenar los nombres de los bloques y conectarlos
 	var blockNames []string
 
-	// Iterar sobre cada inodo
-	for i := int32(0); i < superblock.S_inodes_count; i++ {
+	// Iterar sobre cada inodo en uso
+	usedInodes, err := superblock.UsedInodes(diskPath)
+	if err != nil {
+		return err
+	}
+	for _, i := range usedInodes {
 		inode := &structures.Inode{}
 
 		err := inode.Deserialize(diskPath, int64(superblock.S_inode_start+(i*superblock.S_inode_size)))
@@ -48,12 +52,40 @@ func ReportBlock(superblock *structures.SuperBlock, diskPath string, path string
 
 		inode.Print()
 
-		// Iterar sobre cada bloque del inodo
-		for i, blockIndex := range inode.I_block {
-			if blockIndex == -1 {
-				break
+		// Graficar los bloques de apuntadores (indirectos simples, dobles y triples)
+		pointerBlocks, err := superblock.InodePointerBlocks(diskPath, inode)
+		if err != nil {
+			return err
+		}
+		for _, blockIndex := range pointerBlocks {
+			fmt.Println("Pointer block")
+			blockName := fmt.Sprintf("block%d", blockIndex)
+			blockNames = append(blockNames, blockName)
+
+			block := superblock.NewPointerBlock()
+			err := block.Deserialize(diskPath, int64(superblock.S_block_start+(blockIndex*superblock.S_block_size)))
+			if err != nil {
+				return err
+			}
+
+			// Agregar la información del bloque
+			dotContent += fmt.Sprintf("%s [label=<\n", blockName)
+			dotContent += "<table border='0' cellborder='1' cellspacing='0'>\n"
+			dotContent += fmt.Sprintf("<tr><td bgcolor='lightblue'><b>Block %d</b></td></tr>\n", blockIndex)
+
+			for j, pointer := range block.P_pointers {
+				dotContent += fmt.Sprintf("<tr><td>%d: %d</td></tr>\n", j+1, pointer)
 			}
 
+			dotContent += "</table>>];\n"
+		}
+
+		// Iterar sobre cada bloque de datos del inodo
+		dataBlocks, err := superblock.InodeDataBlocks(diskPath, inode)
+		if err != nil {
+			return err
+		}
+		for _, blockIndex := range dataBlocks {
 			// Nombre del bloque
 			blockName := fmt.Sprintf("block%d", blockIndex)
 			fmt.Println("Block name:", blockName)
@@ -61,56 +93,8 @@ func ReportBlock(superblock *structures.SuperBlock, diskPath string, path string
 
 			// Manejar bloques de carpeta
 			if inode.I_type[0] == '0' {
-				// Manejar bloques de apuntadores
-				if i == 12 || i == 13 || i == 14 {
-					fmt.Println("Pointer block")
-					block := &structures.PointerBlock{}
-					err := block.Deserialize(diskPath, int64(superblock.S_block_start+(blockIndex*superblock.S_block_size)))
-					if err != nil {
-						return err
-					}
-
-					fmt.Println("Block pointers:")
-					block.Print()
-
-					// Agregar la información del bloque
-					dotContent += fmt.Sprintf("%s [label=<\n", blockName)
-					dotContent += "<table border='0' cellborder='1' cellspacing='0'>\n"
-					dotContent += fmt.Sprintf("<tr><td bgcolor='lightblue'><b>Block %d</b></td></tr>\n", blockIndex)
-					
-					for j, pointer := range block.P_pointers {
-						dotContent += fmt.Sprintf("<tr><td>%d: %d</td></tr>\n", j+1, pointer)
-					}
-
-					dotContent += "</table>>];\n"
-
-					// graficar los bloques a los que apunta el bloque de apuntadores
-					for _, pointer := range block.P_pointers {
-						if pointer == -1 {
-							break
-						}
-						blockName := fmt.Sprintf("block%d", pointer)
-						dotContent += fmt.Sprintf("%s -> %s;\n", blockName, blockName)
-						blockNames = append(blockNames, blockName)
-						block := &structures.FolderBlock{}
-						err := block.Deserialize(diskPath, int64(superblock.S_block_start+(pointer*superblock.S_block_size)))
-						if err != nil {
-							return err
-						}
-						dotContent += fmt.Sprintf("%s [label=<\n", blockName)
-						dotContent += "<table border='0' cellborder='1' cellspacing='0'>\n"
-						dotContent += fmt.Sprintf("<tr><td bgcolor='lightblue'><b>Block %d</b></td></tr>\n", pointer)
-						for j, content := range block.B_content {
-							name := strings.TrimRight(string(content.B_name[:]), "\x00")
-							dotContent += fmt.Sprintf("<tr><td>%d: %s</td></tr>\n", j+1, escapeHTML(name))
-						}
-						dotContent += "</table>>];\n"
-						dotContent += fmt.Sprintf("%s -> %s;\n", blockName, blockName)
-					}
-					continue
-				}
 				fmt.Println("Folder block")
-				block := &structures.FolderBlock{}
+				block := superblock.NewFolderBlock()
 
 				err := block.Deserialize(diskPath, int64(superblock.S_block_start+(blockIndex*superblock.S_block_size)))
 				if err != nil {
@@ -123,7 +107,10 @@ func ReportBlock(superblock *structures.SuperBlock, diskPath string, path string
 				dotContent += fmt.Sprintf("<tr><td bgcolor='lightblue'><b>Block %d</b></td></tr>\n", blockIndex)
 
 				for j, content := range block.B_content {
-					name := strings.TrimRight(string(content.B_name[:]), "\x00")
+					name, err := superblock.EntryName(diskPath, content)
+					if err != nil {
+						return err
+					}
 					dotContent += fmt.Sprintf("<tr><td>%d: %s</td></tr>\n", j+1, escapeHTML(name))
 				}
 
@@ -131,10 +118,10 @@ func ReportBlock(superblock *structures.SuperBlock, diskPath string, path string
 				continue
 			}
 
-			// Manejar bloques de archivo
-			if inode.I_type[0] == '1' {
+			// Manejar bloques de archivo (los enlaces simbólicos guardan su destino igual que un archivo)
+			if inode.I_type[0] == '1' || inode.I_type[0] == '2' {
 				fmt.Println("File block")
-				block := &structures.FileBlock{}
+				block := superblock.NewFileBlock()
 
 				err := block.Deserialize(diskPath, int64(superblock.S_block_start+(blockIndex*superblock.S_block_size)))
 				if err != nil {
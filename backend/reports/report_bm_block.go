@@ -15,27 +15,16 @@ func ReportBMBlock(superblock *structures.SuperBlock, diskPath string, path stri
 		return err
 	}
 
-	file, err := os.Open(diskPath)
-	if err != nil {
-		return fmt.Errorf("error al abrir el archivo de disco: %v", err)
-	}
-	defer file.Close()
-
 	totalBlocks := superblock.S_blocks_count + superblock.S_free_blocks_count
 
 	var bitmapContent strings.Builder
 
 	for i := int32(0); i < totalBlocks; i++ {
-		_, err := file.Seek(int64(superblock.S_bm_block_start+i), 0)
-		if err != nil {
-			return fmt.Errorf("error al establecer el puntero en el archivo: %v", err)
-		}
-
-		// Leer un byte (carácter '0' o '1')
+		// Leer un byte del bitmap
 		char := make([]byte, 1)
-		_, err = file.Read(char)
+		err := structures.DeviceReadAt(diskPath, char, int64(superblock.S_bm_block_start+i))
 		if err != nil {
-			return fmt.Errorf("error al leer el byte del archivo: %v", err)
+			return fmt.Errorf("error al leer el byte del disco: %v", err)
 		}
 
 		// Agregar el carácter al contenido del bitmap
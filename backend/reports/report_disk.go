@@ -55,60 +55,45 @@ func ReportDisk(mbr *structures.MBR, diskPath string, path string) error {
 				</tr>
 			`, i+1, partitionName, partitionName, partSize, (float32(partSize)/float32(diskSize))*100)
 
-			// Si es una partición extendida, recorrer los EBRs
+			// Si es una partición extendida, recorrer sus particiones lógicas y sus huecos libres
 			if partType == 'E' {
-				ebr := structures.EBR{}
-				err := ebr.DeserializeEBR(diskPath, int64(part.Part_start))
+				logicals, err := mbr.GetLogicalPartitions(diskPath)
 				if err != nil {
-					return fmt.Errorf("error al deserializar el EBR: %v", err)
+					return fmt.Errorf("error al recorrer los EBR: %v", err)
 				}
 
-				storageUsedExtended := int(ebr.Ebr_part_size)
-				dotContent += fmt.Sprintf(`
+				for _, logical := range logicals {
+					if logical.Empty() {
+						continue
+					}
+					dotContent += fmt.Sprintf(`
 					<tr>
-						<td colspan="4" bgcolor="orange"><b>EBR INICIAL</b></td>
+						<td colspan="4" bgcolor="orange"><b>EBR (byte %d)</b></td>
 					</tr>
 					<tr>
-						<td>EBR</td>
+						<td>Lógica %s</td>
 						<td>%d bytes</td>
 						<td>%.2f%%</td>
 					</tr>
-				`, ebr.Ebr_part_size, (float32(ebr.Ebr_part_size)/float32(diskSize)*100))
-
-				ebrNext := ebr.Ebr_part_next
-				for ebrNext != -1 {
-					err := ebr.DeserializeEBR(diskPath, int64(ebrNext))
-					if err != nil {
-						return fmt.Errorf("error al deserializar el EBR: %v", err)
-					}
-
-					storageUsedExtended += int(ebr.Ebr_part_size)
-					dotContent += fmt.Sprintf(`
-						<tr>
-							<td colspan="4" bgcolor="orange"><b>EBR SIGUIENTE</b></td>
-						</tr>
-						<tr>
-							<td>EBR</td>
-							<td>%d bytes</td>
-							<td>%.2f%%</td>
-						</tr>
-					`, ebr.Ebr_part_size, (float32(ebr.Ebr_part_size)/float32(diskSize)*100))
-
-					ebrNext = ebr.Ebr_part_next
+				`, logical.Offset, logical.Name(), logical.EBR.Ebr_part_size, (float32(logical.EBR.Ebr_part_size)/float32(diskSize)*100))
 				}
 
-				// Espacio libre en la partición extendida
-				freeSpaceExtended := partSize - int32(storageUsedExtended)
-				dotContent += fmt.Sprintf(`
+				gaps, err := mbr.GetLogicalGaps(diskPath)
+				if err != nil {
+					return fmt.Errorf("error al recorrer los EBR: %v", err)
+				}
+				for _, gap := range gaps {
+					dotContent += fmt.Sprintf(`
 					<tr>
 						<td colspan="4" bgcolor="lightgreen"><b>ESPACIO LIBRE (Extendida)</b></td>
 					</tr>
 					<tr>
-						<td>Libre</td>
+						<td>Libre desde el byte %d</td>
 						<td>%d bytes</td>
 						<td>%.2f%%</td>
 					</tr>
-				`, freeSpaceExtended, (float32(freeSpaceExtended)/float32(diskSize))*100)
+				`, gap.Start, gap.Size, (float32(gap.Size)/float32(diskSize))*100)
+				}
 			}
 		}
 	}
@@ -126,6 +111,17 @@ func ReportDisk(mbr *structures.MBR, diskPath string, path string) error {
 		</tr>
 	`, freeSpace, (float32(freeSpace)/float32(diskSize)*100))
 
+	// Huecos libres del disco, en el orden en que aparecen
+	for _, gap := range mbr.GetFreeGaps(diskPath) {
+		dotContent += fmt.Sprintf(`
+		<tr>
+			<td>Libre desde el byte %d</td>
+			<td>%d bytes</td>
+			<td>%.2f%%</td>
+		</tr>
+	`, gap.Start, gap.Size, (float32(gap.Size)/float32(diskSize)*100))
+	}
+
 	dotContent += `</table>>]; }`
 
 	// Crear el archivo DOT
@@ -3,32 +3,56 @@ package reports
 import (
 	structures "backend/structures"
 	utils "backend/utils"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"strings"
 )
 
-func ReportEBR(ebr *structures.EBR, path string) error {
-	err := utils.CreateParentDirs(path)
+// ReportEBR dibuja una tabla por cada EBR de la cadena de la partición extendida, en el orden del disco
+func ReportEBR(mbr *structures.MBR, diskPath string, path string) error {
+	logicals, err := mbr.GetLogicalPartitions(diskPath)
+	if err != nil {
+		return fmt.Errorf("error al recorrer los EBR: %v", err)
+	}
+	if logicals == nil {
+		return errors.New("el disco no tiene partición extendida")
+	}
+
+	err = utils.CreateParentDirs(path)
 	if err != nil {
 		return err
 	}
 
-	dotFileName, outputImage := utils.GetFileNames(path)	
-	
-	dotContent := fmt.Sprintf(`digraph G {
+	dotFileName, outputImage := utils.GetFileNames(path)
+
+	dotContent := `digraph G {
 		node [shape=plaintext]
-		tabla [label=<
+		rankdir=LR;
+	`
+	for i, logical := range logicals {
+		title := fmt.Sprintf("EBR en el byte %d", logical.Offset)
+		if logical.Empty() {
+			title += " (vacío)"
+		}
+		dotContent += fmt.Sprintf(`
+		tabla_%d [label=<
 			<table border="0" cellborder="1" cellspacing="0">
-				<tr><td colspan="2" bgcolor="blue"><font color="white"><b>REPORTE EBR</b></font></td></tr>
+				<tr><td colspan="2" bgcolor="blue"><font color="white"><b>%s</b></font></td></tr>
 				<tr><td bgcolor="lightgray"><b>ebr_part_mount</b></td><td>%c</td></tr>
 				<tr><td bgcolor="lightgray"><b>ebr_part_fit</b></td><td>%c</td></tr>
 				<tr><td bgcolor="lightgray"><b>ebr_part_start</b></td><td>%d</td></tr>
 				<tr><td bgcolor="lightgray"><b>ebr_part_size</b></td><td>%d</td></tr>
 				<tr><td bgcolor="lightgray"><b>ebr_part_next</b></td><td>%d</td></tr>
 				<tr><td bgcolor="lightgray"><b>ebr_part_name</b></td><td>%s</td></tr>
-			</table>>] }`, ebr.Ebr_part_mount[0], ebr.Ebr_part_fit[0], ebr.Ebr_part_start, ebr.Ebr_part_size, ebr.Ebr_part_next, strings.TrimRight(string(ebr.Ebr_part_name[:]), "\x00"))
+			</table>>];
+		`, i, title, logical.EBR.Ebr_part_mount[0], logical.EBR.Ebr_part_fit[0], logical.EBR.Ebr_part_start, logical.EBR.Ebr_part_size, logical.EBR.Ebr_part_next, logical.Name())
+		if i > 0 {
+			dotContent += fmt.Sprintf("tabla_%d -> tabla_%d;\n", i-1, i)
+		}
+	}
+	dotContent += "}"
+
 	file, err := os.Create(dotFileName)
 	if err != nil {
 		return fmt.Errorf("error al crear el archivo: %v", err)
@@ -48,7 +72,4 @@ func ReportEBR(ebr *structures.EBR, path string) error {
 
 	fmt.Printf("Reporte EBR generado: %s\n", outputImage)
 	return nil
-
-
-
-}
\ No newline at end of file
+}
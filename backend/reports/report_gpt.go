@@ -0,0 +1,187 @@
+package reports
+
+import (
+	structures "backend/structures"
+	utils "backend/utils"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// ReportGPT es el reporte mbr de un disco GPT: el MBR protector, el encabezado primario, el estado del respaldo
+// y una sección por cada entrada usada del arreglo de particiones
+func ReportGPT(diskPath string, path string) error {
+	fmt.Println("Reportando GPT")
+	gpt, err := structures.ReadGPT(diskPath)
+	if err != nil {
+		return fmt.Errorf("error al leer la GPT: %v", err)
+	}
+	protective, err := structures.ReadStandardMBR(diskPath)
+	if err != nil {
+		return fmt.Errorf("error al leer el MBR protector: %v", err)
+	}
+
+	backupState := "válido"
+	if _, err := gpt.ReadBackup(diskPath); err != nil {
+		backupState = fmt.Sprintf("dañado (%v)", err)
+	}
+
+	err = utils.CreateParentDirs(path)
+	if err != nil {
+		return err
+	}
+
+	dotFileName, outputImage := utils.GetFileNames(path)
+
+	record := protective.Partitions[0]
+	header := gpt.Header
+	dotContent := fmt.Sprintf(`digraph G {
+        node [shape=plaintext]
+        tabla [label=<
+            <table border="0" cellborder="1" cellspacing="0">
+                <tr><td colspan="2" bgcolor="blue"><font color="white"><b>REPORTE GPT</b></font></td></tr>
+                <tr><td colspan="2" bgcolor="yellow"><b>MBR PROTECTOR</b></td></tr>
+                <tr><td bgcolor="lightgray"><b>tipo</b></td><td>0x%02X</td></tr>
+                <tr><td bgcolor="lightgray"><b>lba_inicio</b></td><td>%d</td></tr>
+                <tr><td bgcolor="lightgray"><b>lba_tamano</b></td><td>%d</td></tr>
+                <tr><td bgcolor="lightgray"><b>firma</b></td><td>0x%02X%02X</td></tr>
+                <tr><td colspan="2" bgcolor="yellow"><b>ENCABEZADO GPT</b></td></tr>
+                <tr><td bgcolor="lightgray"><b>revision</b></td><td>0x%08X</td></tr>
+                <tr><td bgcolor="lightgray"><b>crc_encabezado</b></td><td>0x%08X</td></tr>
+                <tr><td bgcolor="lightgray"><b>lba_propio</b></td><td>%d</td></tr>
+                <tr><td bgcolor="lightgray"><b>lba_respaldo</b></td><td>%d</td></tr>
+                <tr><td bgcolor="lightgray"><b>primer_lba_usable</b></td><td>%d</td></tr>
+                <tr><td bgcolor="lightgray"><b>ultimo_lba_usable</b></td><td>%d</td></tr>
+                <tr><td bgcolor="lightgray"><b>disk_guid</b></td><td>%s</td></tr>
+                <tr><td bgcolor="lightgray"><b>lba_entradas</b></td><td>%d</td></tr>
+                <tr><td bgcolor="lightgray"><b>entradas</b></td><td>%d de %d bytes</td></tr>
+                <tr><td bgcolor="lightgray"><b>crc_entradas</b></td><td>0x%08X</td></tr>
+                <tr><td bgcolor="lightgray"><b>respaldo</b></td><td>%s</td></tr>
+            `, record.OSType, record.StartingLBA, record.SizeInLBA, protective.Signature[0], protective.Signature[1],
+		header.Revision, header.HeaderCRC32, header.MyLBA, header.AlternateLBA, header.FirstUsableLBA, header.LastUsableLBA,
+		header.DiskGUID, header.PartitionEntryLBA, header.NumberOfPartitionEntries, header.SizeOfPartitionEntry,
+		header.PartitionEntryArrayCRC32, backupState)
+
+	for i := range gpt.Entries {
+		entry := &gpt.Entries[i]
+		if !entry.Used() {
+			continue
+		}
+		dotContent += fmt.Sprintf(`
+				<tr><td colspan="2" bgcolor="yellow"><b>PARTICIÓN %d</b></td></tr>
+				<tr><td bgcolor="lightblue"><b>part_name</b></td><td>%s</td></tr>
+				<tr><td bgcolor="lightblue"><b>part_type_guid</b></td><td>%s</td></tr>
+				<tr><td bgcolor="lightblue"><b>part_guid</b></td><td>%s</td></tr>
+				<tr><td bgcolor="lightblue"><b>part_lba</b></td><td>%d - %d</td></tr>
+				<tr><td bgcolor="lightblue"><b>part_start</b></td><td>%d</td></tr>
+				<tr><td bgcolor="lightblue"><b>part_size</b></td><td>%d</td></tr>
+				<tr><td bgcolor="lightblue"><b>part_fit</b></td><td>%c</td></tr>
+			`, i+1, entry.Name(), entry.PartitionTypeGUID, entry.UniquePartitionGUID, entry.StartingLBA, entry.EndingLBA,
+			entry.Start(), entry.Size(), entry.Fit())
+	}
+
+	dotContent += "</table>>] }"
+
+	return writeDot(dotFileName, outputImage, dotContent)
+}
+
+// ReportGPTDisk es el reporte disk de un disco GPT: las estructuras de la tabla, las particiones y los huecos
+// libres en el orden en que aparecen en el disco
+func ReportGPTDisk(diskPath string, path string) error {
+	fmt.Println("Reportando Disco GPT")
+	gpt, err := structures.ReadGPT(diskPath)
+	if err != nil {
+		return fmt.Errorf("error al leer la GPT: %v", err)
+	}
+
+	err = utils.CreateParentDirs(path)
+	if err != nil {
+		return err
+	}
+
+	dotFileName, outputImage := utils.GetFileNames(path)
+	diskSize := float32(gpt.DiskSize())
+	tableSize := int32(gpt.Header.FirstUsableLBA-1) * structures.SectorSize
+
+	row := func(color string, title string, description string, size int32) string {
+		return fmt.Sprintf(`
+				<tr>
+					<td colspan="4" bgcolor="%s"><b>%s</b></td>
+				</tr>
+				<tr>
+					<td>%s</td>
+					<td>%d bytes</td>
+					<td>%.2f%%</td>
+				</tr>
+			`, color, title, description, size, float32(size)/diskSize*100)
+	}
+
+	dotContent := `digraph G {
+		rankdir=LR; // Orientación horizontal
+		node [shape=plaintext]
+		disco [label=<
+			<table border="1" cellborder="1" cellspacing="0">
+				<tr><td colspan="4" bgcolor="blue"><font color="white"><b>DISCO GPT</b></font></td></tr>
+	`
+	dotContent += row("lightgray", "MBR PROTECTOR", "LBA 0", structures.SectorSize)
+	dotContent += row("lightgray", "GPT PRIMARIA", fmt.Sprintf("LBA 1 - %d", gpt.Header.FirstUsableLBA-1), tableSize)
+
+	// Particiones y huecos se intercalan por su posición en el disco
+	gaps := gpt.GetFreeGaps()
+	nextGap := 0
+	for _, index := range gptEntriesByStart(gpt) {
+		entry := &gpt.Entries[index]
+		for nextGap < len(gaps) && gaps[nextGap].Start < entry.Start() {
+			dotContent += row("lightgreen", "ESPACIO LIBRE", fmt.Sprintf("Libre desde el byte %d", gaps[nextGap].Start), gaps[nextGap].Size)
+			nextGap++
+		}
+		dotContent += row("yellow", fmt.Sprintf("PARTICIÓN %d (%s)", index+1, entry.Name()), entry.UniquePartitionGUID.String(), entry.Size())
+	}
+	for ; nextGap < len(gaps); nextGap++ {
+		dotContent += row("lightgreen", "ESPACIO LIBRE", fmt.Sprintf("Libre desde el byte %d", gaps[nextGap].Start), gaps[nextGap].Size)
+	}
+
+	dotContent += row("lightgray", "GPT DE RESPALDO", fmt.Sprintf("LBA %d - %d", gpt.Header.LastUsableLBA+1, gpt.Header.AlternateLBA), tableSize)
+	dotContent += `</table>>]; }`
+
+	return writeDot(dotFileName, outputImage, dotContent)
+}
+
+// gptEntriesByStart devuelve los índices de las entradas usadas ordenados por su primer LBA
+func gptEntriesByStart(gpt *structures.GPT) []int {
+	indexes := []int{}
+	for i := range gpt.Entries {
+		if !gpt.Entries[i].Used() {
+			continue
+		}
+		position := len(indexes)
+		for position > 0 && gpt.Entries[indexes[position-1]].StartingLBA > gpt.Entries[i].StartingLBA {
+			position--
+		}
+		indexes = append(indexes[:position], append([]int{i}, indexes[position:]...)...)
+	}
+	return indexes
+}
+
+// writeDot guarda el contenido DOT y genera la imagen con Graphviz
+func writeDot(dotFileName string, outputImage string, dotContent string) error {
+	file, err := os.Create(dotFileName)
+	if err != nil {
+		return fmt.Errorf("error al crear el archivo: %v", err)
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(dotContent)
+	if err != nil {
+		return fmt.Errorf("error al escribir en el archivo: %v", err)
+	}
+
+	cmd := exec.Command("dot", "-Tpng", dotFileName, "-o", outputImage)
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("error al ejecutar el comando Graphviz: %v", err)
+	}
+
+	fmt.Println("Imagen generada:", outputImage)
+	return nil
+}
@@ -22,8 +22,12 @@ func ReportInode(superblock *structures.SuperBlock, diskPath string, path string
         node [shape=plaintext]
     `
 
-	// Iterar sobre cada inodo
-	for i := int32(0); i < superblock.S_inodes_count; i++ {
+	// Iterar sobre cada inodo en uso
+	usedInodes, err := superblock.UsedInodes(diskPath)
+	if err != nil {
+		return err
+	}
+	for n, i := range usedInodes {
 		inode := &structures.Inode{}
 		// Deserializar el inodo
 		err := inode.Deserialize(diskPath, int64(superblock.S_inode_start+(i*superblock.S_inode_size)))
@@ -48,8 +52,9 @@ func ReportInode(superblock *structures.SuperBlock, diskPath string, path string
                 <tr><td bgcolor="lightgray"><b>i_mtime</b></td><td>%s</td></tr>
                 <tr><td bgcolor="lightgray"><b>i_type</b></td><td>%c</td></tr>
                 <tr><td bgcolor="lightgray"><b>i_perm</b></td><td>%s</td></tr>
+                <tr><td bgcolor="lightgray"><b>i_links</b></td><td>%d</td></tr>
                 <tr><td colspan="2" bgcolor="green"><b>BLOQUES DIRECTOS</b></td></tr>
-            `, i, i, inode.I_uid, inode.I_gid, inode.I_size, atime, ctime, mtime, rune(inode.I_type[0]), string(inode.I_perm[:]))
+            `, i, i, inode.I_uid, inode.I_gid, inode.I_size, atime, ctime, mtime, rune(inode.I_type[0]), string(inode.I_perm[:]), superblock.InodeLinks(inode))
 
 		// Agregar los bloques directos a la tabla hasta el índice 11
 		for j, block := range inode.I_block {
@@ -69,8 +74,8 @@ func ReportInode(superblock *structures.SuperBlock, diskPath string, path string
         `, inode.I_block[12], inode.I_block[13], inode.I_block[14])
 
 		// Agregar enlace al siguiente inodo si no es el último
-		if i < superblock.S_inodes_count-1 {
-			dotContent += fmt.Sprintf("inode%d -> inode%d [color=red, penwidth=2];\n", i, i+1)
+		if n < len(usedInodes)-1 {
+			dotContent += fmt.Sprintf("inode%d -> inode%d [color=red, penwidth=2];\n", i, usedInodes[n+1])
 		}
 	}
 
@@ -124,6 +124,30 @@ func ReportMBR(mbr *structures.MBR, diskPath string, path string) error {
 								
 		}
 	
+	// Un disco compat tiene además el MBR estándar del LBA 0, que es el que leen las herramientas del sistema
+	if structures.IsCompatMBR(diskPath) {
+		standard, err := structures.ReadStandardMBR(diskPath)
+		if err != nil {
+			return fmt.Errorf("error al leer el MBR estándar: %v", err)
+		}
+		dotContent += fmt.Sprintf(`
+				<tr><td colspan="2" bgcolor="blue"><font color="white"><b>MBR ESTÁNDAR (LBA 0)</b></font></td></tr>
+				<tr><td bgcolor="lightgray"><b>disk_signature</b></td><td>0x%08X</td></tr>
+				<tr><td bgcolor="lightgray"><b>firma</b></td><td>0x%02X%02X</td></tr>
+			`, standard.DiskSignature, standard.Signature[0], standard.Signature[1])
+		for i, record := range standard.Partitions {
+			if record.OSType == 0 {
+				continue
+			}
+			dotContent += fmt.Sprintf(`
+				<tr><td colspan="2" bgcolor="yellow"><b>ENTRADA %d</b></td></tr>
+				<tr><td bgcolor="lightblue"><b>tipo</b></td><td>0x%02X</td></tr>
+				<tr><td bgcolor="lightblue"><b>lba_inicio</b></td><td>%d</td></tr>
+				<tr><td bgcolor="lightblue"><b>lba_tamano</b></td><td>%d</td></tr>
+			`, i+1, record.OSType, record.StartingLBA, record.SizeInLBA)
+		}
+	}
+
 	dotContent += "</table>>] }"
 
 	file, err := os.Create(dotFileName)
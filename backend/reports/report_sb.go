@@ -23,7 +23,7 @@ func ReportSuperBlock(sb *structures.SuperBlock, path string) error {
 		tabla [label=<
 			<table border="0" cellborder="1" cellspacing="0">
 				<tr><td colspan="2" bgcolor="blue"><font color="white"><b>REPORTE SUPER BLOQUE</b></font></td></tr>
-				<tr><td bgcolor="lightgray"><b>s_filesystem_type</b></td><td>%s</td></tr>
+				<tr><td bgcolor="lightgray"><b>s_filesystem_type</b></td><td>%d</td></tr>
 				<tr><td bgcolor="lightgray"><b>s_inodes_count</b></td><td>%d</td></tr>
 				<tr><td bgcolor="lightgray"><b>s_blocks_count</b></td><td>%d</td></tr>
 				<tr><td bgcolor="lightgray"><b>s_free_blocks_count</b></td><td>%d</td></tr>
@@ -45,6 +45,15 @@ func generateInodeTreeContent(superblock *structures.SuperBlock, diskPath string
 	`, inodeIndex, inodeIndex, inode.I_uid, inode.I_gid, inode.I_size,
 		atime, ctime, mtime, rune(inode.I_type[0]), string(inode.I_perm[:]))
 
+	// Show where a symbolic link points to
+	if inode.I_type[0] == '2' {
+		target, err := superblock.LinkTarget(diskPath, inode)
+		if err != nil {
+			return "", "", err
+		}
+		nodeContent += fmt.Sprintf(`<tr><td bgcolor="orange"><b>destino</b></td><td>%s</td></tr>`, escape(target))
+	}
+
 	// Add direct blocks (0-11)
 	for i := 0; i < 12; i++ {
 		nodeContent += fmt.Sprintf("<tr><td>%d</td><td>%d</td></tr>", i, inode.I_block[i])
@@ -62,14 +71,15 @@ func generateInodeTreeContent(superblock *structures.SuperBlock, diskPath string
 	var connections string
 	var blockNodes string
 
-	// Process blocks
-	for _, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			break
-		}
+	// Process data blocks, following the indirect pointers
+	dataBlocks, err := superblock.InodeDataBlocks(diskPath, inode)
+	if err != nil {
+		return "", "", err
+	}
+	for _, blockIndex := range dataBlocks {
 
 		if inode.I_type[0] == '0' { // Folder
-			folderBlock := &structures.FolderBlock{}
+			folderBlock := superblock.NewFolderBlock()
 			err := folderBlock.Deserialize(diskPath, int64(superblock.S_block_start+(blockIndex*superblock.S_block_size)))
 			if err != nil {
 				return "", "", err
@@ -89,7 +99,10 @@ func generateInodeTreeContent(superblock *structures.SuperBlock, diskPath string
 
 			// Add folder content (excluding parent references)
 			for i, content := range folderBlock.B_content {
-				name := strings.TrimRight(string(content.B_name[:]), "\x00")
+				name, err := superblock.EntryName(diskPath, content)
+				if err != nil {
+					return "", "", err
+				}
 				if name == "" || content.B_inodo == -1 {
 					continue
 				}
@@ -106,6 +119,9 @@ func generateInodeTreeContent(superblock *structures.SuperBlock, diskPath string
 					if err == nil && childInode.I_type[0] == '0' {
 						entryType = "Carpeta"
 					}
+					if err == nil && childInode.I_type[0] == '2' {
+						entryType = "Enlace"
+					}
 				}
 
 				blockNode += fmt.Sprintf(`
@@ -121,7 +137,10 @@ func generateInodeTreeContent(superblock *structures.SuperBlock, diskPath string
 
 			// Generate connections from block to inodes (excluding parent references)
 			for _, content := range folderBlock.B_content {
-				name := strings.TrimRight(string(content.B_name[:]), "\x00")
+				name, err := superblock.EntryName(diskPath, content)
+				if err != nil {
+					return "", "", err
+				}
 				if content.B_inodo == -1 || name == ".." {
 					continue
 				}
@@ -129,7 +148,7 @@ func generateInodeTreeContent(superblock *structures.SuperBlock, diskPath string
 			}
 
 		} else if inode.I_type[0] == '1' { // File
-			fileBlock := &structures.FileBlock{}
+			fileBlock := superblock.NewFileBlock()
 			err := fileBlock.Deserialize(diskPath, int64(superblock.S_block_start+(blockIndex*superblock.S_block_size)))
 			if err != nil {
 				return "", "", err
@@ -143,6 +162,21 @@ func generateInodeTreeContent(superblock *structures.SuperBlock, diskPath string
 					<tr><td>%s</td></tr>
 				</table>>];
 			`, blockIndex, blockIndex, escapeHTML(content))
+		} else if inode.I_type[0] == '2' { // Symbolic link
+			fileBlock := superblock.NewFileBlock()
+			err := fileBlock.Deserialize(diskPath, int64(superblock.S_block_start+(blockIndex*superblock.S_block_size)))
+			if err != nil {
+				return "", "", err
+			}
+
+			// Generate link block node, the target path is shown as stored
+			content := strings.TrimRight(string(fileBlock.B_content[:]), "\x00")
+			blockNodes += fmt.Sprintf(`block%d [label=<
+				<table border="0" cellborder="1" cellspacing="0">
+					<tr><td bgcolor="orange"><b>Bloque Enlace %d</b></td></tr>
+					<tr><td>%s</td></tr>
+				</table>>];
+			`, blockIndex, blockIndex, escapeHTML(content))
 		}
 
 		// Connect inode to its block (all in black)
@@ -151,19 +185,19 @@ func generateInodeTreeContent(superblock *structures.SuperBlock, diskPath string
 
 	// Process child inodes recursively (for folder inodes)
 	if inode.I_type[0] == '0' {
-		for _, blockIndex := range inode.I_block {
-			if blockIndex == -1 {
-				continue
-			}
+		for _, blockIndex := range dataBlocks {
 
-			folderBlock := &structures.FolderBlock{}
+			folderBlock := superblock.NewFolderBlock()
 			err := folderBlock.Deserialize(diskPath, int64(superblock.S_block_start+(blockIndex*superblock.S_block_size)))
 			if err != nil {
 				return "", "", err
 			}
 
 			for _, content := range folderBlock.B_content {
-				name := strings.TrimRight(string(content.B_name[:]), "\x00")
+				name, err := superblock.EntryName(diskPath, content)
+				if err != nil {
+					return "", "", err
+				}
 				if content.B_inodo == -1 || content.B_inodo == inodeIndex || name == ".." {
 					continue
 				}
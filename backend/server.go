@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	analyzer "backend/analyzer" // Importar el paquete analyzer
+	stores "backend/stores"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors" // Importar el middleware de CORS
@@ -17,18 +19,49 @@ type CommandRequest struct {
 
 type CommandResponse struct {
 	Output string `json:"output"`
+	Token  string `json:"token,omitempty"` // token de la sesión del cliente, si tiene una
+}
+
+// El cliente manda el token de su sesión en este header o, si no, en la cookie
+const (
+	sessionHeader = "X-Session-Token"
+	sessionCookie = "session_token"
+)
+
+// clientSession busca la sesión del token de la petición; sin token o con uno expirado el cliente no tiene sesión
+func clientSession(c *fiber.Ctx) (*stores.Session, string) {
+	token := c.Get(sessionHeader)
+	if token == "" {
+		token = c.Cookies(sessionCookie)
+	}
+	if token != "" {
+		if session := stores.FindSession(token); session != nil {
+			return session, token
+		}
+	}
+	return &stores.Session{}, token
 }
 
 func main() {
+	// Restaurar los montajes, las letras de los discos y la sesión de la ejecución anterior
+	dropped, err := stores.RestoreState()
+	if err != nil {
+		log.Println("Error al restaurar el estado:", err)
+	}
+	for _, message := range dropped {
+		log.Println(message)
+	}
+
 	// Crear una nueva instancia de Fiber
 	app := fiber.New()
 
 	// Configurar el middleware de CORS
 	app.Use(cors.New(cors.Config{
 		// permitir todas las solicitudes de origen
-		AllowOrigins: "*",                            // Cambia esto a tu dominio en producción
-		AllowHeaders: "Origin, Content-Type, Accept", // Headers permitidos
-		AllowMethods: "GET, POST, PUT, DELETE",       // Métodos HTTP permitidos
+		AllowOrigins:  "*",                                              // Cambia esto a tu dominio en producción
+		AllowHeaders:  "Origin, Content-Type, Accept, " + sessionHeader, // Headers permitidos
+		ExposeHeaders: sessionHeader,
+		AllowMethods:  "GET, POST, PUT, DELETE", // Métodos HTTP permitidos
 	}))
 
 	// Ruta de prueba
@@ -50,6 +83,9 @@ func main() {
 			})
 		}
 
+		// Todos los comandos de la petición corren con la sesión del cliente; un login en medio la crea
+		session, token := clientSession(c)
+
 		commands := strings.Split(requestBody.Command, "\n")
 		output := ""
 
@@ -58,7 +94,7 @@ func main() {
 				continue
 			}
 
-			result, err := analyzer.Analyzer(cmd)
+			result, err := analyzer.Analyzer(cmd, session)
 			if err != nil {
 				output += fmt.Sprintf("Error: %s\n", err.Error())
 			} else {
@@ -70,8 +106,16 @@ func main() {
 			output = "No se ejecutó ningún comando"
 		}
 
+		// Un login deja un token nuevo y un logout o una sesión expirada lo quitan
+		current := session.GetToken()
+		if current != token {
+			c.Cookie(&fiber.Cookie{Name: sessionCookie, Value: current, HTTPOnly: true, Expires: cookieExpiry(current)})
+		}
+		c.Set(sessionHeader, current)
+
 		return c.JSON(CommandResponse{
 			Output: output,
+			Token:  current,
 		})
 
 	})
@@ -79,3 +123,11 @@ func main() {
 	// Iniciar el servidor en el puerto 8000
 	log.Fatal(app.Listen(":8000"))
 }
+
+// cookieExpiry borra la cookie cuando el cliente se quedó sin sesión
+func cookieExpiry(token string) time.Time {
+	if token == "" {
+		return time.Unix(0, 0)
+	}
+	return time.Time{}
+}
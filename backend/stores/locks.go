@@ -0,0 +1,68 @@
+package stores
+
+import "sync"
+
+// Cada disco y cada partición montada tienen un candado de lectura/escritura. Un comando que cambia la estructura
+// de un disco (su tabla de particiones, los montajes) toma el del disco para escritura y espera a que nadie esté
+// trabajando en sus particiones. Uno que trabaja dentro de una partición toma el del disco para lectura y el de la
+// partición para escritura si la modifica o para lectura si solo la lee, así varios leen a la vez y el que escribe
+// lee y reescribe el superbloque, los bitmaps y las carpetas sin que otro reserve los mismos inodos y bloques.
+// El del disco siempre se toma primero, por eso dos comandos nunca se esperan entre sí.
+var (
+	locksMu        sync.Mutex
+	diskLocks      = make(map[string]*sync.RWMutex) // por disco
+	partitionLocks = make(map[string]*sync.RWMutex) // por id de montaje
+)
+
+func lockFor(locks map[string]*sync.RWMutex, key string) *sync.RWMutex {
+	locksMu.Lock()
+	defer locksMu.Unlock()
+
+	lock, ok := locks[key]
+	if !ok {
+		lock = &sync.RWMutex{}
+		locks[key] = lock
+	}
+	return lock
+}
+
+// LockDisk toma el disco en path para escritura y devuelve la función que lo suelta
+func LockDisk(path string) func() {
+	lock := lockFor(diskLocks, path)
+	lock.Lock()
+	return lock.Unlock
+}
+
+// LockPartition toma la partición montada con el id, para escritura si write o para lectura si no, junto con su
+// disco para lectura. Devuelve la función que suelta los dos.
+func LockPartition(id string, write bool) func() {
+	for {
+		path := MountedPath(id)
+		unlockDisk := func() {}
+		if path != "" {
+			disk := lockFor(diskLocks, path)
+			disk.RLock()
+			unlockDisk = disk.RUnlock
+		}
+
+		// Mientras esperaba el disco la partición se pudo desmontar o volver a montar en otro
+		if MountedPath(id) != path {
+			unlockDisk()
+			continue
+		}
+
+		partition := lockFor(partitionLocks, id)
+		if write {
+			partition.Lock()
+			return func() {
+				partition.Unlock()
+				unlockDisk()
+			}
+		}
+		partition.RLock()
+		return func() {
+			partition.RUnlock()
+			unlockDisk()
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package stores
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SessionTimeout es cuánto puede pasar una sesión sin usarse antes de expirar
+var SessionTimeout = 30 * time.Minute
+
+// Session es la sesión de un cliente. El servidor la busca con el token que llega en cada petición y la pasa a
+// los comandos; un cliente sin sesión recibe una vacía que login registra y logout vacía.
+type Session struct {
+	ID        int // número corto para listar y terminar sesiones sin mostrar el token
+	Token     string
+	User      string
+	Partition string
+	UID       int32
+	GID       int32
+	Started   time.Time
+	LastUsed  time.Time
+	hash      string // hash del token, la llave en sessions y lo único del token que se guarda en el estado
+}
+
+var (
+	sessions      = make(map[string]*Session) // por hash del token
+	sessionsMu    sync.Mutex
+	nextSessionID = 1
+)
+
+// Get devuelve el usuario, la partición, el uid y el gid de la sesión; sin sesión el usuario es ""
+func (s *Session) Get() (string, string, int32, int32) {
+	if s == nil {
+		return "", "", -1, -1
+	}
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if s.User == "" {
+		return "", "", -1, -1
+	}
+	return s.User, s.Partition, s.UID, s.GID
+}
+
+// GetToken devuelve el token de la sesión, "" si no hay sesión. Login, logout o sessions -kill lo pueden cambiar
+// desde otra petición, por eso se lee con el candado de las sesiones
+func (s *Session) GetToken() string {
+	if s == nil {
+		return ""
+	}
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	return s.Token
+}
+
+// FindSession devuelve la sesión del token, o nil si no existe o ya expiró. Usarla la renueva.
+func FindSession(token string) *Session {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	session, ok := sessions[hashToken(token)]
+	if !ok {
+		return nil
+	}
+	// Una sesión restaurada solo conoce el hash hasta que el cliente vuelve a presentar su token
+	if session.Token == "" {
+		session.Token = token
+	}
+	now := time.Now()
+	if now.Sub(session.LastUsed) > SessionTimeout {
+		endSession(session)
+		return nil
+	}
+	session.LastUsed = now
+	return session
+}
+
+// StartSession registra la sesión del cliente con un token nuevo
+func StartSession(session *Session, user string, partition string, uid int32, gid int32) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	now := time.Now()
+	*session = Session{ID: nextSessionID, Token: token, User: user, Partition: partition, UID: uid, GID: gid, Started: now, LastUsed: now,
+		hash: hashToken(token)}
+	nextSessionID++
+	sessions[session.hash] = session
+	return nil
+}
+
+// EndSession cierra la sesión; quien todavía la tenga la ve vacía
+func EndSession(session *Session) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	endSession(session)
+}
+
+func endSession(session *Session) {
+	delete(sessions, session.hash)
+	*session = Session{}
+}
+
+// Sessions devuelve una copia de las sesiones activas ordenadas por su número, descartando las expiradas
+func Sessions() []Session {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	now := time.Now()
+	list := []Session{}
+	for _, session := range sessions {
+		if now.Sub(session.LastUsed) > SessionTimeout {
+			endSession(session)
+			continue
+		}
+		list = append(list, *session)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// KillSession cierra la sesión con el número dado y devuelve su usuario
+func KillSession(id int) (string, error) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	for _, session := range sessions {
+		if session.ID == id {
+			user := session.User
+			endSession(session)
+			return user, nil
+		}
+	}
+	return "", fmt.Errorf("no hay una sesión activa con el número %d", id)
+}
+
+// PartitionSessions devuelve los usuarios con sesión activa en la partición
+func PartitionSessions(partition string) []string {
+	users := []string{}
+	for _, session := range Sessions() {
+		if session.Partition == partition {
+			users = append(users, session.User)
+		}
+	}
+	return users
+}
+
+// EndPartitionSessions cierra todas las sesiones de la partición, como al desmontarla
+func EndPartitionSessions(partition string) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	for _, session := range sessions {
+		if session.Partition == partition {
+			endSession(session)
+		}
+	}
+}
+
+// restoreSession vuelve a registrar una sesión guardada con el hash de su token
+func restoreSession(saved Session) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	session := saved
+	session.ID = nextSessionID
+	nextSessionID++
+	sessions[session.hash] = &session
+}
+
+func newToken() (string, error) {
+	buffer := make([]byte, 32)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", fmt.Errorf("error al generar el token de la sesión: %w", err)
+	}
+	return hex.EncodeToString(buffer), nil
+}
+
+// hashToken devuelve el sha256 del token en hexadecimal
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
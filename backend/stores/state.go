@@ -0,0 +1,210 @@
+package stores
+
+import (
+	structures "backend/structures"
+	utils "backend/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StateFile es donde se guardan la tabla de montajes, las letras de los discos y las sesiones para que sobrevivan
+// a un reinicio del servidor. Los discos en memoria no se guardan porque desaparecen con el proceso.
+var StateFile = "data/state.json"
+
+// stateMu evita que dos peticiones escriban el archivo temporal al mismo tiempo
+var stateMu sync.Mutex
+
+type mountState struct {
+	ID          string `json:"id"`
+	Path        string `json:"path"`
+	Correlative int32  `json:"correlative"`
+	Start       int32  `json:"start,omitempty"` // solo lógicas y GPT, ver partitionStarts
+}
+
+// Del token solo se guarda su hash: quien lea el archivo no puede usar las sesiones
+type sessionState struct {
+	TokenHash string    `json:"tokenHash"`
+	User      string    `json:"user"`
+	Partition string    `json:"partition"`
+	UID       int32     `json:"uid"`
+	GID       int32     `json:"gid"`
+	Started   time.Time `json:"started"`
+	LastUsed  time.Time `json:"lastUsed"`
+}
+
+type persistedState struct {
+	Mounts     []mountState      `json:"mounts"`
+	Letters    map[string]string `json:"letters"`
+	NextLetter int               `json:"nextLetter"`
+	Sessions   []sessionState    `json:"sessions"`
+}
+
+// SaveState escribe el estado actual en StateFile. Se escribe a un archivo temporal que luego reemplaza al
+// anterior, así un corte a medias no deja un estado ilegible.
+func SaveState() error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	state := persistedState{Mounts: []mountState{}, Letters: map[string]string{}, Sessions: []sessionState{}}
+	mountsMu.RLock()
+	for id, path := range mountedPartitions {
+		if !structures.IsMemoryPath(path) {
+			state.Mounts = append(state.Mounts, mountState{ID: id, Path: path, Start: partitionStarts[id]})
+		}
+	}
+	mountsMu.RUnlock()
+
+	for i := range state.Mounts {
+		partition, _, err := GetMountedPartition(state.Mounts[i].ID)
+		if err == nil {
+			state.Mounts[i].Correlative = partition.Part_correlative
+		}
+	}
+
+	letters, next := utils.Letters()
+	for path, letter := range letters {
+		if !structures.IsMemoryPath(path) {
+			state.Letters[path] = letter
+		}
+	}
+	state.NextLetter = next
+
+	for _, session := range Sessions() {
+		if structures.IsMemoryPath(MountedPath(session.Partition)) {
+			continue
+		}
+		state.Sessions = append(state.Sessions, sessionState{TokenHash: session.hash, User: session.User, Partition: session.Partition,
+			UID: session.UID, GID: session.GID, Started: session.Started, LastUsed: session.LastUsed})
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(StateFile), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	temp := StateFile + ".tmp"
+	err = os.WriteFile(temp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp, StateFile)
+}
+
+// RestoreState carga StateFile al iniciar el servidor. Cada montaje se valida contra su disco: en el MBR la
+// partición debe seguir con Part_status montado, el mismo Part_id y el mismo correlativo; una lógica debe tener
+// el EBR marcado como montado y una GPT debe seguir en su entrada. Los montajes que no pasan se descartan, igual
+// que las sesiones cuya partición ya no está montada. Devuelve un mensaje por cada cosa descartada.
+func RestoreState() ([]string, error) {
+	data, err := os.ReadFile(StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state persistedState
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, fmt.Errorf("el archivo de estado %s está dañado: %w", StateFile, err)
+	}
+
+	utils.RestoreLetters(state.Letters, state.NextLetter)
+
+	var dropped []string
+	for _, mount := range state.Mounts {
+		partition, err := validateMount(mount)
+		if err == nil {
+			err = structures.ActivatePartition(mount.Path, partition)
+		}
+		if err != nil {
+			dropped = append(dropped, fmt.Sprintf("montaje %s (%s) descartado: %v", mount.ID, mount.Path, err))
+			continue
+		}
+		AddMount(mount.ID, mount.Path, mount.Start)
+	}
+
+	for _, session := range state.Sessions {
+		if MountedPath(session.Partition) == "" {
+			dropped = append(dropped, fmt.Sprintf("sesión de %s descartada: la partición %s no está montada", session.User, session.Partition))
+			continue
+		}
+		// Las expiradas se descartan sin avisar, igual que si el servidor no se hubiera detenido, y también las
+		// guardadas con el token en claro, que ya no se aceptan
+		if time.Since(session.LastUsed) > SessionTimeout || session.TokenHash == "" {
+			continue
+		}
+		restoreSession(Session{hash: session.TokenHash, User: session.User, Partition: session.Partition,
+			UID: session.UID, GID: session.GID, Started: session.Started, LastUsed: session.LastUsed})
+	}
+
+	// Lo descartado tampoco debe volver en el próximo reinicio
+	if len(dropped) > 0 {
+		err = SaveState()
+	}
+	return dropped, err
+}
+
+// validateMount comprueba que el disco todavía tiene la partición montada tal como se guardó
+func validateMount(mount mountState) (*structures.Partition, error) {
+	if !structures.DeviceExists(mount.Path) {
+		return nil, errors.New("el disco ya no existe")
+	}
+
+	// Las GPT y las lógicas no guardan el id en el disco: se buscan por el byte donde empiezan sus datos
+	if mount.Start != 0 {
+		if structures.IsGPT(mount.Path) {
+			gpt, err := structures.ReadGPT(mount.Path)
+			if err != nil {
+				return nil, err
+			}
+			index := gpt.FindByStart(mount.Start)
+			if index == -1 || int32(index+1) != mount.Correlative {
+				return nil, fmt.Errorf("la GPT no tiene la partición %d en el byte %d", mount.Correlative, mount.Start)
+			}
+			return gpt.Partition(index), nil
+		}
+
+		var mbr structures.MBR
+		err := mbr.DeserializeMBR(mount.Path)
+		if err != nil {
+			return nil, err
+		}
+		logical, err := mbr.GetLogicalPartitionAt(mount.Path, mount.Start-structures.EBRSize)
+		if err != nil {
+			return nil, err
+		}
+		if logical.EBR.Ebr_part_mount[0] != '1' {
+			return nil, errors.New("el EBR de la partición lógica no está marcado como montado")
+		}
+		return logical.Partition(), nil
+	}
+
+	var mbr structures.MBR
+	err := mbr.DeserializeMBR(mount.Path)
+	if err != nil {
+		return nil, err
+	}
+	partition, err := mbr.GetPartitionByID(mount.ID)
+	if err != nil {
+		return nil, err
+	}
+	if partition.Part_status[0] != '1' {
+		return nil, fmt.Errorf("el MBR tiene la partición con estado %c y no montada", partition.Part_status[0])
+	}
+	if partition.Part_correlative != mount.Correlative {
+		return nil, fmt.Errorf("el correlativo en el MBR es %d y no %d", partition.Part_correlative, mount.Correlative)
+	}
+	if id := strings.TrimRight(string(partition.Part_id[:]), "\x00"); id != mount.ID {
+		return nil, fmt.Errorf("el MBR tiene el id %s", id)
+	}
+	return partition, nil
+}
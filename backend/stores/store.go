@@ -3,33 +3,106 @@ package stores
 import (
 	structures "backend/structures"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 )
 
 const Carnet string = "50" // 202300350
 
+// La tabla de montajes la leen y cambian peticiones concurrentes, así que solo se usa a través de las funciones
+// de abajo, que toman mountsMu
 var (
-	MountedPartitions map[string]string = make(map[string]string)
-	userNameLogged string = ""
-	idMountedPartition string = ""
-	userid int32 = -1
-	groupid int32 = -1
+	mountsMu          sync.RWMutex
+	mountedPartitions = make(map[string]string) // id -> disco
 
+	// partitionStarts guarda, por id, el byte donde empiezan los datos de cada partición montada cuya tabla no
+	// tiene dónde guardar el id como el MBR lo hace en Part_id: las lógicas (EBR) y las de un disco GPT
+	partitionStarts = make(map[string]int32)
 )
 
-func SetSession(user string, idPartition string, uid int32, gid int32) {
-	userNameLogged = user
-	idMountedPartition = idPartition
-	userid = uid
-	groupid = gid
+// MountedPath devuelve el disco de la partición montada con el id, o "" si no está montada
+func MountedPath(id string) string {
+	mountsMu.RLock()
+	defer mountsMu.RUnlock()
+	return mountedPartitions[id]
 }
 
-func GetSession() (string, string, int32, int32) {	
-	return userNameLogged, idMountedPartition, userid, groupid
+// PartitionStart devuelve el inicio guardado de una partición lógica o GPT montada; false para las del MBR
+func PartitionStart(id string) (int32, bool) {
+	mountsMu.RLock()
+	defer mountsMu.RUnlock()
+	start, ok := partitionStarts[id]
+	return start, ok
+}
+
+// AddMount registra el montaje; start es 0 para las particiones del MBR, que guardan el id en Part_id
+func AddMount(id string, path string, start int32) {
+	mountsMu.Lock()
+	defer mountsMu.Unlock()
+	mountedPartitions[id] = path
+	if start != 0 {
+		partitionStarts[id] = start
+	}
+}
+
+// RemoveMount quita el montaje de la tabla
+func RemoveMount(id string) {
+	mountsMu.Lock()
+	defer mountsMu.Unlock()
+	delete(mountedPartitions, id)
+	delete(partitionStarts, id)
+}
+
+// findPartition busca la partición montada con el id en el MBR o, si no está ahí, en la cadena de EBRs o en la GPT
+func findPartition(mbr *structures.MBR, path string, id string) (*structures.Partition, error) {
+	start, ok := PartitionStart(id)
+	if !ok {
+		return mbr.GetPartitionByID(id)
+	}
+
+	var partition *structures.Partition
+	if structures.IsGPT(path) {
+		gpt, err := structures.ReadGPT(path)
+		if err != nil {
+			return nil, err
+		}
+		index := gpt.FindByStart(start)
+		if index == -1 {
+			return nil, fmt.Errorf("la partición %s ya no está en la GPT", id)
+		}
+		partition = gpt.Partition(index)
+	} else {
+		logical, err := mbr.GetLogicalPartitionAt(path, start-structures.EBRSize)
+		if err != nil {
+			return nil, err
+		}
+		partition = logical.Partition()
+	}
+	copy(partition.Part_id[:], id)
+	return partition, nil
+}
+
+// MountedID devuelve el id con que está montada la partición del disco en path, o "" si no está montada
+func MountedID(path string, partition *structures.Partition) string {
+	mountsMu.RLock()
+	defer mountsMu.RUnlock()
+
+	for id, start := range partitionStarts {
+		if mountedPartitions[id] == path && start == partition.Part_start {
+			return id
+		}
+	}
+	id := strings.TrimRight(string(partition.Part_id[:]), "\x00")
+	if _, external := partitionStarts[id]; !external && mountedPartitions[id] == path {
+		return id
+	}
+	return ""
 }
 
 
 func GetMountedPartition(id string) (*structures.Partition, string, error) {
-	path := MountedPartitions[id]
+	path := MountedPath(id)
 	if path == "" {
 		return nil, "", errors.New("la partición no está montada")
 	}
@@ -41,7 +114,7 @@ func GetMountedPartition(id string) (*structures.Partition, string, error) {
 		return nil, "", err
 	}
 
-	partition, err := mbr.GetPartitionByID(id)
+	partition, err := findPartition(&mbr, path, id)
 	if partition == nil {
 		return nil, "", err
 	}
@@ -50,7 +123,7 @@ func GetMountedPartition(id string) (*structures.Partition, string, error) {
 }
 
 func GetMountedPartitionRep(id string) (*structures.MBR, *structures.SuperBlock, string, error) {
-	path := MountedPartitions[id]
+	path := MountedPath(id)
 	if path == "" {
 		return nil, nil, "", errors.New("la partición no está montada")
 	}
@@ -62,7 +135,7 @@ func GetMountedPartitionRep(id string) (*structures.MBR, *structures.SuperBlock,
 		return nil, nil, "", err
 	}
 
-	partition, err := mbr.GetPartitionByID(id)
+	partition, err := findPartition(&mbr, path, id)
 	if partition == nil {
 		return nil, nil, "", err
 	}
@@ -79,23 +152,27 @@ func GetMountedPartitionRep(id string) (*structures.MBR, *structures.SuperBlock,
 
 // print the mounted partitions id
 func PrintMountedPartitions() {
-	for id, path := range MountedPartitions {
+	mountsMu.RLock()
+	defer mountsMu.RUnlock()
+	for id, path := range mountedPartitions {
 		println("id:", id, "path:", path)
 	}
 }
 
 // return string[] with the mounted partitions
 func GetMountedPartitions() []string {
-	var mountedPartitions []string
-	for id := range MountedPartitions {
-		mountedPartitions = append(mountedPartitions, id)
+	mountsMu.RLock()
+	defer mountsMu.RUnlock()
+	var ids []string
+	for id := range mountedPartitions {
+		ids = append(ids, id)
 	}
-	return mountedPartitions
+	return ids
 }
 
 // GetMountedPartitionSuperblock obtiene el SuperBlock de la partición montada con el id especificado
 func GetMountedPartitionSuperblock(id string) (*structures.SuperBlock, *structures.Partition, string, error) {
-	path := MountedPartitions[id]
+	path := MountedPath(id)
 	if path == "" {
 		return nil, nil, "", errors.New("la partición no está montada")
 	}
@@ -107,7 +184,7 @@ func GetMountedPartitionSuperblock(id string) (*structures.SuperBlock, *structur
 		return nil, nil, "", err
 	}
 
-	partition, err := mbr.GetPartitionByID(id)
+	partition, err := findPartition(&mbr, path, id)
 	if partition == nil {
 		return nil, nil, "", err
 	}
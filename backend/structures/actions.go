@@ -4,6 +4,7 @@ import (
 	utils "backend/utils"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // RemoveInode elimina un inodo
@@ -16,21 +17,21 @@ func (sb *SuperBlock) RemoveInode(path string, inodeIndex int32, parentsDir []st
 		return fmt.Errorf("error al deserializar el inodo: %w", err)
 	}
 
-	// Verificar si el inodo es de tipo carpeta
-	if inode.I_type[0] == '1' {
-		return fmt.Errorf("el inodo %d es de tipo carpeta", inodeIndex)
+	// Verificar que el inodo sea de tipo carpeta
+	if inode.I_type[0] != '0' {
+		return fmt.Errorf("el inodo %d no es de tipo carpeta", inodeIndex)
 	}
 
-	// Iterar sobre cada bloque del inodo (apuntadores)
-	for _, blockIndex := range inode.I_block {
-		// Si el bloque no existe, salir
+	// Iterar sobre cada bloque de la carpeta (directos e indirectos)
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+	for _, blockIndex := range dataBlocks {
 		fmt.Println("Bloque actual:", blockIndex)
-		if blockIndex == -1 {
-			break
-		}
 
 		// Crear un nuevo bloque de carpeta
-		block := &FolderBlock{}
+		block := sb.NewFolderBlock()
 
 		// Deserializar el bloque
 		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
@@ -48,7 +49,7 @@ func (sb *SuperBlock) RemoveInode(path string, inodeIndex int32, parentsDir []st
 
 			if len(parentsDir) != 0 {
 				if content.B_inodo == -1 {
-					return fmt.Errorf("el inodo %d no tiene contenido", blockIndex)
+					continue
 				}
 				// Obtenemos la carpeta padre más cercana
 				parentDir, err := utils.First(parentsDir)
@@ -57,7 +58,11 @@ func (sb *SuperBlock) RemoveInode(path string, inodeIndex int32, parentsDir []st
 				}
 
 				// Convertir B_name a string y eliminar los caracteres nulos
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
+				contentName = strings.Trim(contentName, "\x00 ")
 				// Convertir parentDir a string y eliminar los caracteres nulos
 				parentDirName := strings.Trim(parentDir, "\x00 ")
 
@@ -79,10 +84,20 @@ func (sb *SuperBlock) RemoveInode(path string, inodeIndex int32, parentsDir []st
 				fmt.Println("Carpeta destino: ", destDir)
 
 				// convertir content.B_name a string
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
+				contentName = strings.Trim(contentName, "\x00 ")
 				fmt.Println("Nombre de la carpeta: ", contentName)
 				if contentName == destDir {
 					fmt.Println("---------LA ENCONTRÉ-------")
+					removedInode := content.B_inodo
+					// liberar los bloques del nombre si es largo
+					err = sb.releaseEntryName(path, content)
+					if err != nil {
+						return fmt.Errorf("error al liberar el nombre: %w", err)
+					}
 					// borrar la referencia del inodo en el bloque
 					block.B_content[indexContent] = FolderContent{B_name: [12]byte{'-'}, B_inodo: -1}
 					// serializar el bloque
@@ -90,6 +105,11 @@ func (sb *SuperBlock) RemoveInode(path string, inodeIndex int32, parentsDir []st
 					if err != nil {
 						return fmt.Errorf("error al serializar el bloque: %w", err)
 					}
+					// quitar el enlace; si era el último se libera el inodo, sus bloques y todo el subárbol
+					err = sb.unlinkInode(path, removedInode)
+					if err != nil {
+						return fmt.Errorf("error al liberar el inodo %d: %w", removedInode, err)
+					}
 					return nil
 				}
 			}
@@ -100,6 +120,55 @@ func (sb *SuperBlock) RemoveInode(path string, inodeIndex int32, parentsDir []st
 	return nil
 }
 
+// releaseInode libera un inodo y todos sus bloques; si es carpeta, quita antes los enlaces de su contenido
+func (sb *SuperBlock) releaseInode(path string, inodeIndex int32) error {
+	inode := &Inode{}
+	err := inode.Deserialize(path, int64(sb.S_inode_start+(inodeIndex*sb.S_inode_size)))
+	if err != nil {
+		return err
+	}
+
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+
+	// Si es carpeta, liberar primero cada hijo (sin tocar . y ..)
+	if inode.I_type[0] == '0' {
+		for _, blockIndex := range dataBlocks {
+			block := sb.NewFolderBlock()
+			err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+			if err != nil {
+				return err
+			}
+
+			for indexContent := 2; indexContent < len(block.B_content); indexContent++ {
+				child := block.B_content[indexContent].B_inodo
+				if child == -1 || child == inodeIndex {
+					continue
+				}
+				err := sb.releaseEntryName(path, block.B_content[indexContent])
+				if err != nil {
+					return err
+				}
+				err = sb.unlinkInode(path, child)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Liberar los bloques de datos y los bloques de apuntadores
+	err = sb.truncateInodeBlocks(path, inode, 0)
+	if err != nil {
+		return err
+	}
+
+	// Liberar el inodo
+	return sb.FreeBitmapInode(path, inodeIndex)
+}
+
 // EditFileInInode edita el contenido de un archivo en el sistema de archivos
 func (sb *SuperBlock) EditFileInInode(path string, inodeIndex int32, parentsDir []string, destDir string, contentFile string, uid int32, gid int32) error {
 	// Crear un nuevo inodo
@@ -109,18 +178,19 @@ func (sb *SuperBlock) EditFileInInode(path string, inodeIndex int32, parentsDir
 	if err != nil {
 		return err
 	}
-	// Verificar si el inodo es de tipo carpeta
-	if inode.I_type[0] == '1' {
-		return fmt.Errorf("el inodo %d es de tipo carpeta", inodeIndex)
+	// Verificar que el inodo sea de tipo carpeta
+	if inode.I_type[0] != '0' {
+		return fmt.Errorf("el inodo %d no es de tipo carpeta", inodeIndex)
 	}
 
 	// Iterar sobre cada bloque del inodo (apuntadores)
-	for _, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			break
-		}
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+	for _, blockIndex := range dataBlocks {
 
-		block := &FolderBlock{}
+		block := sb.NewFolderBlock()
 
 		// Deserializar el bloque
 		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
@@ -138,7 +208,7 @@ func (sb *SuperBlock) EditFileInInode(path string, inodeIndex int32, parentsDir
 
 			if len(parentsDir) != 0 {
 				if content.B_inodo == -1 {
-					break
+					continue
 				}
 
 				parentDir, err := utils.First(parentsDir)
@@ -147,7 +217,11 @@ func (sb *SuperBlock) EditFileInInode(path string, inodeIndex int32, parentsDir
 				}
 
 				// Convertir B_name a string y eliminar los caracteres nulos
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
+				contentName = strings.Trim(contentName, "\x00 ")
 				// Convertir parentDir a string y eliminar los caracteres nulos
 				parentDirName := strings.Trim(parentDir, "\x00 ")
 
@@ -164,10 +238,12 @@ func (sb *SuperBlock) EditFileInInode(path string, inodeIndex int32, parentsDir
 				if content.B_inodo == -1 {
 					continue
 				}
-				destDirByte := [12]byte{}
-				copy(destDirByte[:], destDir)
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
 
-				if content.B_name == destDirByte {
+				if contentName == destDir {
 					fmt.Println("---------LA ENCONTRÉ-------")
 					inodeFile := &Inode{}
 					err := inodeFile.Deserialize(path, int64(sb.S_inode_start+(content.B_inodo*sb.S_inode_size)))
@@ -176,80 +252,16 @@ func (sb *SuperBlock) EditFileInInode(path string, inodeIndex int32, parentsDir
 					}
 
 					if inodeFile.I_type[0] == '1' {
-						// modificar el contenido del archivo
-						contentBlocks := make([]string, 0)
-						for i := 0; i < len(contentFile); i += 64 {
-							if i+64 > len(contentFile) {
-								contentBlocks = append(contentBlocks, contentFile[i:])
-							} else {
-								contentBlocks = append(contentBlocks, contentFile[i:i+64])
-							}
+						// modificar el contenido del archivo, reservando o liberando bloques según el nuevo tamaño
+						err = sb.writeInodeContent(path, inodeFile, contentFile)
+						if err != nil {
+							return err
 						}
 
-						// iterar sobre cada bloque del inodo y settear el contenido
-						for i, blockIndex := range inodeFile.I_block {
-							// Si ya no hay más contenido para escribir, podemos terminar
-							if i >= len(contentBlocks) {
-								break
-							}
-
-							if blockIndex == -1 {
-								// todavía hay contenido por asignar pero el bloque no existe
-								// actualizar el inodo para que apunte al nuevo bloque
-								inodeFile.I_block[i] = sb.S_blocks_count
-								// serializar el inodo
-								err := inodeFile.Serialize(path, int64(sb.S_inode_start+(content.B_inodo*sb.S_inode_size)))
-								if err != nil {
-									return err
-								}
-
-								newBlock := &FileBlock{
-									B_content: [64]byte{},
-								}
-								// copiar el contenido del bloque
-								copy(newBlock.B_content[:], contentBlocks[i])
-								// serializar el bloque
-								err = newBlock.Serialize(path, int64(sb.S_block_start+(sb.S_blocks_count*sb.S_block_size)))
-								if err != nil {
-									return err
-								}
-
-								// Actualizar el bitmap de bloques
-								err = sb.UpdateBitmapBlock(path)
-								if err != nil {
-									return err
-								}
-
-								// Actualizar el superbloque
-								sb.S_blocks_count++
-								sb.S_free_blocks_count--
-								sb.S_first_blo += sb.S_block_size
-								continue // Ya hemos procesado este bloque, continuamos con el siguiente
-							}
-
-							// obtener el bloque
-							block := &FileBlock{}
-							err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
-							if err != nil {
-								return err
-							}
-
-							// limpiar el bloque
-							for j := range block.B_content {
-								block.B_content[j] = 0
-							}
-
-							contentBytes := []byte(contentBlocks[i])
-							// Copiar byte por byte el nuevo contenido
-							for j := 0; j < len(contentBytes); j++ {
-								block.B_content[j] = contentBytes[j]
-							}
-
-							// serializar el bloque
-							err = block.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
-							if err != nil {
-								return err
-							}
+						// serializar el inodo
+						err = inodeFile.Serialize(path, int64(sb.S_inode_start+(content.B_inodo*sb.S_inode_size)))
+						if err != nil {
+							return err
 						}
 						return nil
 					}
@@ -271,18 +283,19 @@ func (sb *SuperBlock) RenameFileInInode(path string, inodeIndex int32, parentsDi
 	if err != nil {
 		return err
 	}
-	// Verificar si el inodo es de tipo carpeta
-	if inode.I_type[0] == '1' {
-		return fmt.Errorf("el inodo %d es de tipo carpeta", inodeIndex)
+	// Verificar que el inodo sea de tipo carpeta
+	if inode.I_type[0] != '0' {
+		return fmt.Errorf("el inodo %d no es de tipo carpeta", inodeIndex)
 	}
 
 	// Iterar sobre cada bloque del inodo (apuntadores)
-	for _, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			break
-		}
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+	for _, blockIndex := range dataBlocks {
 
-		block := &FolderBlock{}
+		block := sb.NewFolderBlock()
 
 		// Deserializar el bloque
 		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
@@ -300,7 +313,7 @@ func (sb *SuperBlock) RenameFileInInode(path string, inodeIndex int32, parentsDi
 
 			if len(parentsDir) != 0 {
 				if content.B_inodo == -1 {
-					break
+					continue
 				}
 
 				parentDir, err := utils.First(parentsDir)
@@ -309,7 +322,11 @@ func (sb *SuperBlock) RenameFileInInode(path string, inodeIndex int32, parentsDi
 				}
 
 				// Convertir B_name a string y eliminar los caracteres nulos
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
+				contentName = strings.Trim(contentName, "\x00 ")
 				// Convertir parentDir a string y eliminar los caracteres nulos
 				parentDirName := strings.Trim(parentDir, "\x00 ")
 
@@ -326,16 +343,27 @@ func (sb *SuperBlock) RenameFileInInode(path string, inodeIndex int32, parentsDi
 				if content.B_inodo == -1 {
 					continue
 				}
-				destDirByte := [12]byte{}
-				copy(destDirByte[:], destDir)
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
 
-				if content.B_name == destDirByte {
+				if contentName == destDir {
 					fmt.Println("---------LA ENCONTRÉ-------")
+					// Preparar el nuevo nombre (reserva sus bloques si es largo)
+					newNameBytes, err := sb.encodeEntryName(path, newName)
+					if err != nil {
+						return err
+					}
+					// Liberar los bloques del nombre anterior
+					err = sb.releaseEntryName(path, content)
+					if err != nil {
+						return err
+					}
 					// Cambiar el nombre del archivo o carpeta en el bloque
-					block.B_content[indexContent].B_name = [12]byte{}
-					copy(block.B_content[indexContent].B_name[:], newName)
+					block.B_content[indexContent].B_name = newNameBytes
 					// Serializar el bloque
-					err := block.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+					err = block.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
 					if err != nil {
 						return err
 					}
@@ -358,18 +386,19 @@ func (sb *SuperBlock) CopyFileInInode(path string, inodeIndex int32, parentsDir
 	if err != nil {
 		return err
 	}
-	// Verificar si el inodo es de tipo carpeta
-	if inode.I_type[0] == '1' {
-		return fmt.Errorf("el inodo %d es de tipo carpeta", inodeIndex)
+	// Verificar que el inodo sea de tipo carpeta
+	if inode.I_type[0] != '0' {
+		return fmt.Errorf("el inodo %d no es de tipo carpeta", inodeIndex)
 	}
 
 	// Iterar sobre cada bloque del inodo (apuntadores)
-	for _, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			break
-		}
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+	for _, blockIndex := range dataBlocks {
 
-		block := &FolderBlock{}
+		block := sb.NewFolderBlock()
 
 		// Deserializar el bloque
 		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
@@ -387,7 +416,7 @@ func (sb *SuperBlock) CopyFileInInode(path string, inodeIndex int32, parentsDir
 
 			if len(parentsDir) != 0 {
 				if content.B_inodo == -1 {
-					break
+					continue
 				}
 
 				parentDir, err := utils.First(parentsDir)
@@ -396,7 +425,11 @@ func (sb *SuperBlock) CopyFileInInode(path string, inodeIndex int32, parentsDir
 				}
 
 				// Convertir B_name a string y eliminar los caracteres nulos
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
+				contentName = strings.Trim(contentName, "\x00 ")
 				// Convertir parentDir a string y eliminar los caracteres nulos
 				parentDirName := strings.Trim(parentDir, "\x00 ")
 
@@ -413,17 +446,19 @@ func (sb *SuperBlock) CopyFileInInode(path string, inodeIndex int32, parentsDir
 				if content.B_inodo == -1 {
 					continue
 				}
-				destDirByte := [12]byte{}
-				copy(destDirByte[:], destDir)
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
 
-				if content.B_name == destDirByte {
+				if contentName == destDir {
 					fmt.Println("---------LA ENCONTRÉ-------")
 					fmt.Println("Inodo: ", content.B_inodo)
-					fmt.Println("Nombre: ", string(content.B_name[:]))
+					fmt.Println("Nombre: ", contentName)
 					fmt.Println("destinoParentDirs: ", destinoParentDirs)
 					fmt.Println("destinoDir: ", destinoDir)
 
-					err := sb.copyContentTo(path, 0, content.B_inodo, string(content.B_name[:]), destinoParentDirs, destinoDir)
+					err := sb.copyContentTo(path, content.B_inodo, contentName, destinoParentDirs, destinoDir)
 					if err != nil {
 						return err
 					}
@@ -436,178 +471,207 @@ func (sb *SuperBlock) CopyFileInInode(path string, inodeIndex int32, parentsDir
 	return fmt.Errorf("no se encontró el archivo")
 }
 
-// copyContentTo copia el contenido de un archivo o carpeta
-func (sb *SuperBlock) copyContentTo(path string, inodeIndex int32, inodeNumber int32, name string, parentsDir []string, destDir string) error {
-	// Crear un nuevo inodo
-	fmt.Println("Inodo: ", inodeIndex)
-	inode := &Inode{}
-	// Deserializar el inodo
-	err := inode.Deserialize(path, int64(sb.S_inode_start+(inodeIndex*sb.S_inode_size)))
+// copyContentTo copia el archivo o carpeta inodeNumber, con todo su contenido, dentro de la carpeta destino
+func (sb *SuperBlock) copyContentTo(path string, inodeNumber int32, name string, parentsDir []string, destDir string) error {
+	folderIndex, folderInode, err := sb.destinationFolder(path, inodeNumber, parentsDir, destDir)
 	if err != nil {
 		return err
 	}
-	// Verificar si el inodo es de tipo carpeta
-	if inode.I_type[0] == '1' {
-		return fmt.Errorf("el inodo %d no es de tipo carpeta", inodeIndex)
-	}
-
-	// Iterar sobre cada bloque del inodo (apuntadores)
-	for i, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			fmt.Println("Ya no hay más bloques, creando uno nuevo")
-			fmt.Println("i:", i)
-
-			// 1. Crear y serializar el nuevo bloque de carpeta
-			newBlock := &FolderBlock{
-				B_content: [4]FolderContent{
-					{B_name: [12]byte{'.'}, B_inodo: inodeIndex},
-					{B_name: [12]byte{'.', '.'}, B_inodo: inodeIndex},
-					{B_name: [12]byte{'-'}, B_inodo: -1},
-					{B_name: [12]byte{'-'}, B_inodo: -1},
-				},
-			}
-
-			destDirByte := [12]byte{}
-			copy(destDirByte[:], name)
-			newBlock.B_content[2] = FolderContent{B_name: destDirByte, B_inodo: inodeNumber}
 
-			// Serializar el nuevo bloque
-			err := newBlock.Serialize(path, int64(sb.S_block_start+(sb.S_blocks_count*sb.S_block_size)))
-			if err != nil {
-				return err
-			}
-
-			// Actualizar el bitmap de bloques
-			err = sb.UpdateBitmapBlock(path)
-			if err != nil {
-				return err
-			}
+	fmt.Println("Copiando en el inodo")
+	// Copiar el inodo y sus bloques; la copia tiene sus propios datos y un único enlace
+	newInodeIndex, err := sb.copyInode(path, inodeNumber, folderIndex)
+	if err != nil {
+		return err
+	}
 
-			inode.I_block[i] = sb.S_blocks_count
+	// insertar el nuevo contenido, creando un bloque de carpeta si hace falta
+	return sb.addFolderEntry(path, folderIndex, folderInode, strings.Trim(name, "\x00 "), newInodeIndex)
+}
 
-			// Serializar el inodo actualizado
-			err = inode.Serialize(path, int64(sb.S_inode_start+(inodeIndex*sb.S_inode_size)))
-			if err != nil {
-				return err
-			}
+// moveEntryTo agrega una entrada name -> inodeNumber en la carpeta destino sin copiar el contenido
+func (sb *SuperBlock) moveEntryTo(path string, inodeNumber int32, name string, parentsDir []string, destDir string) error {
+	folderIndex, folderInode, err := sb.destinationFolder(path, inodeNumber, parentsDir, destDir)
+	if err != nil {
+		return err
+	}
 
-			// Actualizar el superbloque
-			sb.S_blocks_count++
-			sb.S_free_blocks_count--
-			sb.S_first_blo += sb.S_block_size
+	err = sb.addFolderEntry(path, folderIndex, folderInode, strings.Trim(name, "\x00 "), inodeNumber)
+	if err != nil {
+		return err
+	}
 
-			return nil
+	// Si se mueve una carpeta, su .. ahora apunta a la carpeta destino
+	movedInode := &Inode{}
+	err = movedInode.Deserialize(path, int64(sb.S_inode_start+(inodeNumber*sb.S_inode_size)))
+	if err != nil {
+		return err
+	}
+	if movedInode.I_type[0] != '0' {
+		return nil
+	}
+	dataBlocks, err := sb.InodeDataBlocks(path, movedInode)
+	if err != nil {
+		return err
+	}
+	for _, blockIndex := range dataBlocks {
+		block := sb.NewFolderBlock()
+		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+		if err != nil {
+			return err
 		}
-
-		block := &FolderBlock{}
-
-		// Deserializar el bloque
-		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
+		block.B_content[1].B_inodo = folderIndex
+		err = block.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
 		if err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		fmt.Println("Bloque: ", blockIndex)
-		block.Print()
-
-		// Iterar sobre cada contenido del bloque, desde el index 2 porque los primeros dos son . y ..
-		for indexContent := 2; indexContent < len(block.B_content); indexContent++ {
-			content := block.B_content[indexContent]
-			fmt.Println("Contenido: ", indexContent)
-			fmt.Println("len parentsDir: ", len(parentsDir))
-
-			if len(parentsDir) != 0 {
-				if content.B_inodo == -1 {
-					break
-				}
+// destinationFolder resuelve la carpeta destino de copy y move siguiendo enlaces simbólicos.
+// Rechaza que una carpeta se copie o mueva dentro de sí misma.
+func (sb *SuperBlock) destinationFolder(path string, inodeNumber int32, parentsDir []string, destDir string) (int32, *Inode, error) {
+	// Buscar la carpeta destino
+	folderIndex, err := sb.ResolvePath(path, parentsDir, destDir, true)
+	if err != nil {
+		return -1, nil, err
+	}
+	if folderIndex == -1 {
+		return -1, nil, fmt.Errorf("no se encontró el archivo")
+	}
 
-				parentDir, err := utils.First(parentsDir)
-				if err != nil {
-					return err
-				}
+	folderInode := &Inode{}
+	err = folderInode.Deserialize(path, int64(sb.S_inode_start+(folderIndex*sb.S_inode_size)))
+	if err != nil {
+		return -1, nil, err
+	}
+	if folderInode.I_type[0] != '0' {
+		return -1, nil, fmt.Errorf("el destino %s no es una carpeta", destDir)
+	}
 
-				// Convertir B_name a string y eliminar los caracteres nulos
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
-				// Convertir parentDir a string y eliminar los caracteres nulos
-				parentDirName := strings.Trim(parentDir, "\x00 ")
-				fmt.Println("Nombre del contenido: ", contentName)
-				fmt.Println("Nombre de la carpeta padre: ", parentDirName)
+	// Subir por las entradas .. hasta la raíz buscando el origen
+	current := folderIndex
+	for {
+		if current == inodeNumber {
+			return -1, nil, fmt.Errorf("no se puede copiar o mover una carpeta dentro de sí misma")
+		}
+		if current == 0 {
+			break
+		}
+		currentInode := &Inode{}
+		err := currentInode.Deserialize(path, int64(sb.S_inode_start+(current*sb.S_inode_size)))
+		if err != nil {
+			return -1, nil, err
+		}
+		parent, err := sb.parentFolder(path, currentInode)
+		if err != nil {
+			return -1, nil, err
+		}
+		if parent == -1 || parent == current {
+			break
+		}
+		current = parent
+	}
 
-				// Si el nombre del contenido coincide con el nombre de la carpeta padre
-				if strings.EqualFold(contentName, parentDirName) {
-					fmt.Println("entrando a la carpeta padre")
-					err := sb.copyContentTo(path, content.B_inodo, inodeNumber, name, utils.RemoveElement(parentsDir, 0), destDir)
-					if err != nil {
-						return err
-					}
-					return nil
-				}
-			} else {
-				fmt.Println("content.B_inodo: ", content.B_inodo)
-				if content.B_inodo == -1 {
-					continue
-				}
-				destDirByte := [12]byte{}
-				copy(destDirByte[:], destDir)
-				fmt.Println("Nombre del contenido: ", string(content.B_name[:]))
-				fmt.Println("Nombre de la carpeta: ", string(destDirByte[:]))
+	return folderIndex, folderInode, nil
+}
 
-				if content.B_name == destDirByte {
-					fmt.Println("Copiando en el inodo")
-					// obtener el inodo de content.B_inodo
-					folderInode := &Inode{}
-					err := folderInode.Deserialize(path, int64(sb.S_inode_start+(content.B_inodo*sb.S_inode_size)))
-					if err != nil {
-						return err
-					}
+// copyInode crea una copia del inodo sourceIndex (y de todo su subárbol si es carpeta) colgando de parentIndex
+func (sb *SuperBlock) copyInode(path string, sourceIndex int32, parentIndex int32) (int32, error) {
+	source := &Inode{}
+	err := source.Deserialize(path, int64(sb.S_inode_start+(sourceIndex*sb.S_inode_size)))
+	if err != nil {
+		return -1, err
+	}
 
-					// iterar sobre cada bloque del inodo (apuntadores)
-					for _, blockIndex := range folderInode.I_block {
-						if blockIndex == -1 {
-							// TODO: crear un nuevo bloque
-							fmt.Println("No hay más bloques, creando uno nuevo")
-							break
-						}
-						blockInode := &FolderBlock{}
-						err := blockInode.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
-						if err != nil {
-							return err
-						}
+	newInodeIndex, err := sb.AllocateInode(path)
+	if err != nil {
+		return -1, err
+	}
 
-						// iterar sobre cada contenido del bloque, desde el index 2 porque los primeros dos son . y ..
-						for indexContentInode := 2; indexContentInode < len(blockInode.B_content); indexContentInode++ {
-							contentBlock := blockInode.B_content[indexContentInode]
+	newInode := &Inode{
+		I_uid:   source.I_uid,
+		I_gid:   source.I_gid,
+		I_size:  source.I_size,
+		I_atime: float32(time.Now().Unix()),
+		I_ctime: float32(time.Now().Unix()),
+		I_mtime: float32(time.Now().Unix()),
+		I_block: [15]int32{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
+		I_type:  source.I_type,
+		I_perm:  source.I_perm,
+		I_links: 1,
+	}
 
-							if contentBlock.B_inodo == -1 {
-								// insertar el nuevo contenido
-								nameBytes := [12]byte{}
-								copy(nameBytes[:], name)
-								blockInode.B_content[indexContentInode] = FolderContent{B_name: nameBytes, B_inodo: inodeNumber}
+	// Archivos y enlaces simbólicos: copiar el contenido en bloques nuevos
+	if source.I_type[0] != '0' {
+		content, err := sb.ReadInodeContent(path, source)
+		if err != nil {
+			return -1, err
+		}
+		if int(source.I_size) < len(content) {
+			content = content[:source.I_size]
+		}
+		err = sb.writeInodeContent(path, newInode, content)
+		if err != nil {
+			return -1, err
+		}
+		return newInodeIndex, newInode.Serialize(path, int64(sb.S_inode_start+(newInodeIndex*sb.S_inode_size)))
+	}
 
-								// serializar el bloque
-								err := blockInode.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
-								if err != nil {
-									return err
-								}
+	// Carpetas: crear el primer bloque con . y .. y copiar cada hijo
+	folderBlockPos, err := sb.AllocateBlock(path)
+	if err != nil {
+		return -1, err
+	}
+	newInode.I_block[0] = folderBlockPos
+	folderBlock := sb.folderBlockWith(
+		FolderContent{B_name: [12]byte{'.'}, B_inodo: newInodeIndex},
+		FolderContent{B_name: [12]byte{'.', '.'}, B_inodo: parentIndex},
+	)
+	err = folderBlock.Serialize(path, int64(sb.S_block_start+(folderBlockPos*sb.S_block_size)))
+	if err != nil {
+		return -1, err
+	}
+	err = newInode.Serialize(path, int64(sb.S_inode_start+(newInodeIndex*sb.S_inode_size)))
+	if err != nil {
+		return -1, err
+	}
 
-								// serializar el inodo
-								err = folderInode.Serialize(path, int64(sb.S_inode_start+(content.B_inodo*sb.S_inode_size)))
-								if err != nil {
-									return err
-								}
+	dataBlocks, err := sb.InodeDataBlocks(path, source)
+	if err != nil {
+		return -1, err
+	}
+	for _, blockIndex := range dataBlocks {
+		block := sb.NewFolderBlock()
+		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+		if err != nil {
+			return -1, err
+		}
 
-								return nil
-							}
+		// Desde el index 2 porque los primeros dos son . y ..
+		for indexContent := 2; indexContent < len(block.B_content); indexContent++ {
+			content := block.B_content[indexContent]
+			if content.B_inodo == -1 {
+				continue
+			}
+			contentName, err := sb.EntryName(path, content)
+			if err != nil {
+				return -1, err
+			}
 
-						}
-					}
-					return nil
-				}
+			childIndex, err := sb.copyInode(path, content.B_inodo, newInodeIndex)
+			if err != nil {
+				return -1, err
+			}
+			err = sb.addFolderEntry(path, newInodeIndex, newInode, contentName, childIndex)
+			if err != nil {
+				return -1, err
 			}
 		}
 	}
 
-	return fmt.Errorf("no se encontró el archivo")
+	return newInodeIndex, nil
 }
 
 func (sb *SuperBlock) MoveFileInInode(path string, inodeIndex int32, parentsDir []string, destDir string, destinoParentDirs []string, destinoDir string, uid int32, gid int32) error {
@@ -620,18 +684,19 @@ func (sb *SuperBlock) MoveFileInInode(path string, inodeIndex int32, parentsDir
 	if err != nil {
 		return err
 	}
-	// Verificar si el inodo es de tipo carpeta
-	if inode.I_type[0] == '1' {
-		return fmt.Errorf("el inodo %d es de tipo carpeta", inodeIndex)
+	// Verificar que el inodo sea de tipo carpeta
+	if inode.I_type[0] != '0' {
+		return fmt.Errorf("el inodo %d no es de tipo carpeta", inodeIndex)
 	}
 
 	// Iterar sobre cada bloque del inodo (apuntadores)
-	for _, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			break
-		}
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+	for _, blockIndex := range dataBlocks {
 
-		block := &FolderBlock{}
+		block := sb.NewFolderBlock()
 
 		// Deserializar el bloque
 		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
@@ -649,7 +714,7 @@ func (sb *SuperBlock) MoveFileInInode(path string, inodeIndex int32, parentsDir
 
 			if len(parentsDir) != 0 {
 				if content.B_inodo == -1 {
-					break
+					continue
 				}
 
 				parentDir, err := utils.First(parentsDir)
@@ -658,7 +723,11 @@ func (sb *SuperBlock) MoveFileInInode(path string, inodeIndex int32, parentsDir
 				}
 
 				// Convertir B_name a string y eliminar los caracteres nulos
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
+				contentName = strings.Trim(contentName, "\x00 ")
 				// Convertir parentDir a string y eliminar los caracteres nulos
 				parentDirName := strings.Trim(parentDir, "\x00 ")
 
@@ -675,14 +744,21 @@ func (sb *SuperBlock) MoveFileInInode(path string, inodeIndex int32, parentsDir
 				if content.B_inodo == -1 {
 					continue
 				}
-				destDirByte := [12]byte{}
-				copy(destDirByte[:], destDir)
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
 
-				if content.B_name == destDirByte {
+				if contentName == destDir {
 					fmt.Println("---------LA ENCONTRÉ-------")
 					fmt.Println("Inodo: ", content.B_inodo)
-					fmt.Println("Nombre: ", string(content.B_name[:]))
-					err = sb.copyContentTo(path, 0, content.B_inodo, string(content.B_name[:]), destinoParentDirs, destinoDir)
+					fmt.Println("Nombre: ", contentName)
+					err = sb.moveEntryTo(path, content.B_inodo, contentName, destinoParentDirs, destinoDir)
+					if err != nil {
+						return err
+					}
+					// El destino guardó su propia copia del nombre, liberar la del origen
+					err = sb.releaseEntryName(path, content)
 					if err != nil {
 						return err
 					}
@@ -714,16 +790,17 @@ func (sb *SuperBlock) ChownInInode(path string, inodeNumber int32, parentsDir []
 		return fmt.Errorf("error al deserializar el inodo: %w", err)
 	}
 
-	if inode.I_type[0] == '1' {
-		return fmt.Errorf("el inodo %d es de tipo carpeta", inodeNumber)
+	if inode.I_type[0] != '0' {
+		return fmt.Errorf("el inodo %d no es de tipo carpeta", inodeNumber)
 	}
 
-	for _, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			break
-		}
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+	for _, blockIndex := range dataBlocks {
 		// Crear un nuevo bloque de carpeta
-		block := &FolderBlock{}
+		block := sb.NewFolderBlock()
 
 		// Deserializar el bloque
 		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
@@ -739,7 +816,7 @@ func (sb *SuperBlock) ChownInInode(path string, inodeNumber int32, parentsDir []
 
 			if len(parentsDir) != 0 {
 				if content.B_inodo == -1 {
-					break
+					continue
 				}
 
 				parentDir, err := utils.First(parentsDir)
@@ -747,7 +824,11 @@ func (sb *SuperBlock) ChownInInode(path string, inodeNumber int32, parentsDir []
 					return err
 				}
 
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
+				contentName = strings.Trim(contentName, "\x00 ")
 				// Convertir parentDir a string y eliminar los caracteres nulos
 				parentDirName := strings.Trim(parentDir, "\x00 ")
 
@@ -765,10 +846,12 @@ func (sb *SuperBlock) ChownInInode(path string, inodeNumber int32, parentsDir []
 					continue
 				}
 
-				destDirByte := [12]byte{}
-				copy(destDirByte[:], destDir)
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
 
-				if content.B_name == destDirByte {
+				if contentName == destDir {
 					fmt.Println("---------LA ENCONTRÉ-------")
 					// Si son las mismas, entonces entramos al inodo que apunta el bloque
 					inodeFound := &Inode{}
@@ -803,16 +886,17 @@ func (sb *SuperBlock) ChmodInInode(path string, inodeNumber int32, parentsDir []
 		return fmt.Errorf("error al deserializar el inodo: %w", err)
 	}
 
-	if inode.I_type[0] == '1' {
-		return fmt.Errorf("el inodo %d es de tipo carpeta", inodeNumber)
+	if inode.I_type[0] != '0' {
+		return fmt.Errorf("el inodo %d no es de tipo carpeta", inodeNumber)
 	}
 
-	for _, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			break
-		}
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+	for _, blockIndex := range dataBlocks {
 		// Crear un nuevo bloque de carpeta
-		block := &FolderBlock{}
+		block := sb.NewFolderBlock()
 
 		// Deserializar el bloque
 		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
@@ -828,7 +912,7 @@ func (sb *SuperBlock) ChmodInInode(path string, inodeNumber int32, parentsDir []
 
 			if len(parentsDir) != 0 {
 				if content.B_inodo == -1 {
-					break
+					continue
 				}
 
 				parentDir, err := utils.First(parentsDir)
@@ -836,7 +920,11 @@ func (sb *SuperBlock) ChmodInInode(path string, inodeNumber int32, parentsDir []
 					return err
 				}
 
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
+				contentName = strings.Trim(contentName, "\x00 ")
 				// Convertir parentDir a string y eliminar los caracteres nulos
 				parentDirName := strings.Trim(parentDir, "\x00 ")
 
@@ -854,10 +942,12 @@ func (sb *SuperBlock) ChmodInInode(path string, inodeNumber int32, parentsDir []
 					continue
 				}
 
-				destDirByte := [12]byte{}
-				copy(destDirByte[:], destDir)
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return err
+				}
 
-				if content.B_name == destDirByte {
+				if contentName == destDir {
 					fmt.Println("---------LA ENCONTRÉ-------")
 					// Si son las mismas, entonces entramos al inodo que apunta el bloque
 					inodeFound := &Inode{}
@@ -885,77 +975,3 @@ func (sb *SuperBlock) ChmodInInode(path string, inodeNumber int32, parentsDir []
 	}
 	return nil
 }
-
-func (sb *SuperBlock) FindInInode(path string, inodeNumber int32, parentsDir []string, destDir string, name string) error {
-	inode := &Inode{}
-	// Deserializar el inodo
-	err := inode.Deserialize(path, int64(sb.S_inode_start+(inodeNumber*sb.S_inode_size)))
-	if err != nil {
-		return fmt.Errorf("error al deserializar el inodo: %w", err)
-	}
-
-	if inode.I_type[0] == '1' {
-		return fmt.Errorf("el inodo %d es de tipo carpeta", inodeNumber)
-	}
-
-	for _, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			break
-		}
-		// Crear un nuevo bloque de carpeta
-		block := &FolderBlock{}
-
-		// Deserializar el bloque
-		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
-		if err != nil {
-			return err
-		}
-
-		fmt.Println("Bloque: ", blockIndex)
-		block.Print()
-
-		for indexContent := 2; indexContent < len(block.B_content); indexContent++ {
-			content := block.B_content[indexContent]
-
-			if len(parentsDir) != 0 {
-				if content.B_inodo == -1 {
-					break
-				}
-
-				parentDir, err := utils.First(parentsDir)
-				if err != nil {
-					return err
-				}
-
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
-				// Convertir parentDir a string y eliminar los caracteres nulos
-				parentDirName := strings.Trim(parentDir, "\x00 ")
-
-				if strings.EqualFold(contentName, parentDirName) {
-					fmt.Println("entrando a la carpeta padre")
-					err := sb.FindInInode(path, content.B_inodo, utils.RemoveElement(parentsDir, 0), destDir, name)
-					if err != nil {
-						return err
-					}
-					return nil
-				}
-			} else {
-				fmt.Println("ya no hay más carpetas padre")
-				if content.B_inodo == -1 {
-					continue
-				}
-
-				destDirByte := [12]byte{}
-				copy(destDirByte[:], destDir)
-
-				if content.B_name == destDirByte {
-					fmt.Println("---------LA ENCONTRÉ-------")
-					// TODO: implementar
-					return nil
-				}
-			}
-		}
-
-	}
-	return nil
-}
@@ -0,0 +1,85 @@
+package structures
+
+import (
+	"fmt"
+	"time"
+)
+
+// ActivatePartition prepares a partition that is being mounted: the disk stays open (with its cache) while it
+// is mounted, the checksums are verified from now on if the filesystem was formatted with them, and the EXT3
+// transactions that were committed but not applied are redone while an interrupted one is dropped.
+func ActivatePartition(path string, partition *Partition) error {
+	_, err := OpenDevice(path)
+	if err != nil {
+		return err
+	}
+
+	sb := &SuperBlock{}
+	err = sb.Deserialize(path, int64(partition.Part_start))
+	if err != nil {
+		CloseDevice(path)
+		return err
+	}
+	sb.TrackChecksums(path, partition.Part_start, partition.Part_size)
+	sb.TrackLayout(path, partition)
+
+	// The journal of the previous format has no journal superblock: it is started over empty
+	if sb.Legacy() && sb.S_filesystem_type == 3 {
+		if _, err := sb.ReadJournalSuperBlock(path); err != nil {
+			fmt.Println("warning: the EXT3 journal has the previous format, its entries are dropped and it starts empty")
+			err = sb.ClearJournal(path)
+			if err != nil {
+				DeactivatePartition(path, partition.Part_start)
+				return err
+			}
+		}
+	}
+
+	if sb.Formatted() && sb.S_filesystem_type == 3 {
+		replayed, err := sb.ReplayJournal(path, false)
+		if err != nil {
+			fmt.Println("error replaying the journal: ", err)
+		} else {
+			fmt.Println("journal transactions replayed: ", replayed)
+		}
+	}
+	return nil
+}
+
+// DeactivatePartition undoes ActivatePartition: the partition is no longer tracked and its reference to the disk
+// is released.
+func DeactivatePartition(path string, partStart int32) error {
+	UntrackChecksums(path, partStart)
+	UntrackLayout(path, partStart)
+	return CloseDevice(path)
+}
+
+// RecordMount stamps the mount time and counts the mount in the superblock of a formatted partition
+func RecordMount(path string, partition *Partition) error {
+	return stampSuperBlock(path, partition, func(sb *SuperBlock) {
+		sb.S_mtime = float32(time.Now().Unix())
+		sb.S_mnt_count++
+	})
+}
+
+// RecordUnmount stamps the unmount time in the superblock of a formatted partition
+func RecordUnmount(path string, partition *Partition) error {
+	return stampSuperBlock(path, partition, func(sb *SuperBlock) {
+		sb.S_umtime = float32(time.Now().Unix())
+	})
+}
+
+// stampSuperBlock applies update to the superblock of the partition; a partition without a filesystem is left alone
+func stampSuperBlock(path string, partition *Partition, update func(sb *SuperBlock)) error {
+	sb := &SuperBlock{}
+	err := readSuperBlock(path, int64(partition.Part_start), sb)
+	if err != nil || !sb.Formatted() {
+		return nil
+	}
+	err = sb.Deserialize(path, int64(partition.Part_start))
+	if err != nil {
+		return err
+	}
+	update(sb)
+	return sb.Serialize(path, int64(partition.Part_start))
+}
@@ -0,0 +1,29 @@
+package structures
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestActivatePartitionReleasesDevice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corto.mia")
+	err := os.WriteFile(path, make([]byte, 1024), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The partition table points past the end of the disk, so its superblock cannot be read
+	partition := &Partition{Part_start: 4096, Part_size: 1024}
+	err = ActivatePartition(path, partition)
+	if err == nil {
+		t.Fatal("a partition whose superblock cannot be read was activated")
+	}
+
+	devicesMu.Lock()
+	_, open := devices[path]
+	devicesMu.Unlock()
+	if open {
+		t.Error("the disk is still held open after the activation failed")
+	}
+}
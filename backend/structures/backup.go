@@ -0,0 +1,178 @@
+package structures
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// mkfs keeps two backup copies of the superblock: one right after the inode table and one in the
+// last bytes of the partition. They hold the geometry of the filesystem; their counters are the ones
+// from the last time they were written, so after restoring one the counters are rebuilt from the bitmaps.
+
+// BackupOffsets returns the absolute offsets of the backup superblocks of this filesystem.
+// Partitions formatted before the backups existed have no room for them and get none.
+func (sb *SuperBlock) BackupOffsets(partition *Partition) []int64 {
+	if sb.Legacy() {
+		return nil
+	}
+	size := int64(binary.Size(SuperBlock{}))
+	offsets := make([]int64, 0, 2)
+
+	afterInodes := int64(sb.S_inode_start) + int64(sb.TotalInodes())*int64(sb.S_inode_size)
+	if afterInodes+size <= int64(sb.S_block_start) {
+		offsets = append(offsets, afterInodes)
+	}
+
+	// The last copy must not overlap the block area nor the checksum table
+	dataEnd := int64(sb.S_block_start) + int64(sb.TotalBlocks())*int64(sb.S_block_size)
+	if sb.HasFeature(FeatureMetadataCsum) {
+		dataEnd = max(dataEnd, int64(sb.S_csum_start)+int64(sb.TotalBlocks())*4)
+	}
+	atEnd := int64(partition.Part_start) + int64(partition.Part_size) - size
+	if atEnd >= dataEnd {
+		offsets = append(offsets, atEnd)
+	}
+	return offsets
+}
+
+// WriteBackups writes a copy of the superblock at every backup offset.
+func (sb *SuperBlock) WriteBackups(path string, partition *Partition) error {
+	for _, offset := range sb.BackupOffsets(partition) {
+		backup := *sb
+		err := backup.Serialize(path, offset)
+		if err != nil {
+			return fmt.Errorf("error al escribir la copia del superbloque en %d: %w", offset, err)
+		}
+	}
+	return nil
+}
+
+// ValidFor reports whether the superblock describes a filesystem that fits in the partition:
+// right magic and type and every area in order inside the partition bounds.
+func (sb *SuperBlock) ValidFor(partition *Partition) bool {
+	start := partition.Part_start
+	end := partition.Part_start + partition.Part_size
+	return sb.Formatted() &&
+		(sb.S_filesystem_type == 2 || sb.S_filesystem_type == 3) &&
+		sb.S_inode_size == sb.inodeSize() &&
+		sb.S_block_size > 0 &&
+		sb.S_bm_inode_start > start &&
+		sb.S_bm_inode_start < sb.S_bm_block_start &&
+		sb.S_bm_block_start < sb.S_inode_start &&
+		sb.S_inode_start < sb.S_block_start &&
+		sb.S_block_start < end
+}
+
+// SameGeometry reports whether two superblocks place the filesystem areas in the same positions.
+func (sb *SuperBlock) SameGeometry(other *SuperBlock) bool {
+	return sb.S_filesystem_type == other.S_filesystem_type &&
+		sb.S_inode_size == other.S_inode_size &&
+		sb.S_block_size == other.S_block_size &&
+		sb.S_bm_inode_start == other.S_bm_inode_start &&
+		sb.S_bm_block_start == other.S_bm_block_start &&
+		sb.S_inode_start == other.S_inode_start &&
+		sb.S_block_start == other.S_block_start &&
+		sb.S_csum_start == other.S_csum_start &&
+		sb.S_features == other.S_features
+}
+
+// readBackup reads the superblock stored at offset and checks that it is a usable backup.
+// The copy after the inode table must also point back at its own offset.
+func readBackup(path string, partition *Partition, offset int64) (*SuperBlock, error) {
+	end := int64(partition.Part_start) + int64(partition.Part_size)
+	if offset <= int64(partition.Part_start) || offset+int64(binary.Size(SuperBlock{})) > end {
+		return nil, fmt.Errorf("la posición %d está fuera de la partición", offset)
+	}
+
+	backup := &SuperBlock{}
+	err := backup.Deserialize(path, offset)
+	if err != nil {
+		return nil, err
+	}
+	if !backup.ValidFor(partition) {
+		return nil, fmt.Errorf("no hay una copia válida del superbloque en %d", offset)
+	}
+
+	found := false
+	for _, expected := range backup.BackupOffsets(partition) {
+		found = found || expected == offset
+	}
+	if !found {
+		return nil, fmt.Errorf("la copia del superbloque en %d no corresponde a su posición", offset)
+	}
+	return backup, nil
+}
+
+// FindBackupSuperBlock returns the first valid backup superblock of the partition and its offset.
+// The copy at the end of the partition is always at a known place; the one after the inode table is
+// located with the geometry that the primary superblock still has, if any.
+func FindBackupSuperBlock(path string, partition *Partition) (*SuperBlock, int64, error) {
+	offsets := []int64{int64(partition.Part_start) + int64(partition.Part_size) - int64(binary.Size(SuperBlock{}))}
+
+	primary := &SuperBlock{}
+	err := readSuperBlock(path, int64(partition.Part_start), primary)
+	if err == nil && primary.S_inode_start > partition.Part_start && primary.S_bm_block_start > primary.S_bm_inode_start {
+		offsets = append(offsets, primary.BackupOffsets(partition)...)
+	}
+
+	for _, offset := range offsets {
+		backup, err := readBackup(path, partition, offset)
+		if err != nil {
+			fmt.Println("Copia del superbloque descartada:", err)
+			continue
+		}
+		return backup, offset, nil
+	}
+
+	// Without the primary geometry the copy after the inode table is searched by its magic number
+	return scanBackupSuperBlock(path, partition)
+}
+
+// scanBackupSuperBlock looks through the partition for a superblock magic number that belongs to a
+// valid backup sitting at one of its own offsets.
+func scanBackupSuperBlock(path string, partition *Partition) (*SuperBlock, int64, error) {
+	fmt.Println("Buscando una copia del superbloque en toda la partición")
+	data := make([]byte, partition.Part_size)
+	err := DeviceReadAt(path, data, int64(partition.Part_start))
+	if err != nil {
+		return nil, -1, err
+	}
+
+	magic := make([]byte, 4)
+	binary.LittleEndian.PutUint32(magic, SuperBlockMagic)
+
+	for from := 0; ; {
+		index := bytes.Index(data[from:], magic)
+		if index == -1 {
+			break
+		}
+		from += index + 1
+
+		offset := int64(partition.Part_start) + int64(from-1-magicOffset)
+		backup, err := readBackup(path, partition, offset)
+		if err == nil {
+			return backup, offset, nil
+		}
+	}
+	return nil, -1, fmt.Errorf("no se encontró ninguna copia válida del superbloque")
+}
+
+// LoadSuperBlock reads the primary superblock of the partition. If it is damaged it returns a backup
+// copy instead, together with its offset; the offset is -1 when the primary was used.
+func LoadSuperBlock(path string, partition *Partition) (*SuperBlock, int64, error) {
+	sb := &SuperBlock{}
+	err := sb.Deserialize(path, int64(partition.Part_start))
+	if err == nil && sb.ValidFor(partition) {
+		return sb, -1, nil
+	}
+
+	backup, offset, backupErr := FindBackupSuperBlock(path, partition)
+	if backupErr != nil {
+		if err != nil {
+			return nil, -1, err
+		}
+		return nil, -1, fmt.Errorf("el superbloque principal está dañado y %w", backupErr)
+	}
+	return backup, offset, nil
+}
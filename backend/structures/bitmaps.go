@@ -1,104 +1,282 @@
 package structures
 
 import (
-	"encoding/binary"
-	"os"
+	"bytes"
+	"fmt"
 )
 
 // CreateBitMaps creates the Inode and Block Bitmaps in the specified file.
 func (sb *SuperBlock) CreateBitMaps(path string) error {
-	// Open the file for writing or create it if it doesn't exist
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		return err
+	// Inode Bitmap: a buffer filled with '0's to represent free inodes
+	buffer := make([]byte, sb.S_free_inodes_count)
+	for i := range buffer {
+		buffer[i] = '0'
 	}
-	defer file.Close()
 
-	// Inode Bitmap
-	// Move the file pointer to the start of the Inode Bitmap
-	_, err = file.Seek(int64(sb.S_bm_inode_start), 0)
+	// Write the Inode Bitmap buffer to the disk
+	err := DeviceWriteAt(path, buffer, int64(sb.S_bm_inode_start))
 	if err != nil {
 		return err
 	}
 
-	// Create a buffer filled with '0's to represent free inodes
-	buffer := make([]byte, sb.S_free_inodes_count)
+	// Block Bitmap: a buffer filled with 'O's to represent free blocks
+	buffer = make([]byte, sb.S_free_blocks_count)
 	for i := range buffer {
-		buffer[i] = '0'
+		buffer[i] = 'O'
 	}
 
-	// Write the Inode Bitmap buffer to the file
-	err = binary.Write(file, binary.LittleEndian, buffer)
+	// Write the Block Bitmap buffer to the disk
+	return DeviceWriteAt(path, buffer, int64(sb.S_bm_block_start))
+}
+
+// TotalInodes returns the number of inodes the partition was formatted with.
+// It is derived from the bitmap layout, so it stays valid even if the counters are damaged.
+func (sb *SuperBlock) TotalInodes() int32 {
+	return sb.S_bm_block_start - sb.S_bm_inode_start
+}
+
+// TotalBlocks returns the number of blocks the partition was formatted with.
+func (sb *SuperBlock) TotalBlocks() int32 {
+	return sb.S_inode_start - sb.S_bm_block_start
+}
+
+// readBitmap loads a whole bitmap into memory.
+func readBitmap(path string, start int32, length int32) ([]byte, error) {
+	buffer := make([]byte, length)
+	err := DeviceReadAt(path, buffer, int64(start))
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return buffer, nil
+}
+
+// findFreeRun looks for a run of count consecutive free entries in a bitmap
+// following the fit of the partition: 'B' best fit, 'W' worst fit and first fit otherwise.
+// It returns -1 when there is no run large enough.
+func findFreeRun(bitmap []byte, used byte, count int32, fit byte) int32 {
+	bestStart, bestSize := int32(-1), int32(0)
+
+	runStart := int32(-1)
+	for i := int32(0); i <= int32(len(bitmap)); i++ {
+		// A used entry (or the end of the bitmap) closes the current run
+		if i < int32(len(bitmap)) && bitmap[i] != used {
+			if runStart == -1 {
+				runStart = i
+			}
+			continue
+		}
+		if runStart == -1 {
+			continue
+		}
+
+		runSize := i - runStart
+		if runSize >= count {
+			switch fit {
+			case 'B':
+				if bestStart == -1 || runSize < bestSize {
+					bestStart, bestSize = runStart, runSize
+				}
+			case 'W':
+				if bestStart == -1 || runSize > bestSize {
+					bestStart, bestSize = runStart, runSize
+				}
+			default:
+				return runStart
+			}
+		}
+		runStart = -1
 	}
 
-	// Block Bitmap
-	// Move the file pointer to the start of the Block Bitmap
-	_, err = file.Seek(int64(sb.S_bm_block_start), 0)
+	return bestStart
+}
+
+// firstFree returns the first free entry of a bitmap or -1 if it is full.
+func firstFree(bitmap []byte, used byte) int32 {
+	for i, state := range bitmap {
+		if state != used {
+			return int32(i)
+		}
+	}
+	return -1
+}
+
+// AllocateInode finds a free inode in the Inode Bitmap, marks it as used and returns its index.
+func (sb *SuperBlock) AllocateInode(path string) (int32, error) {
+	bitmap, err := readBitmap(path, sb.S_bm_inode_start, sb.TotalInodes())
 	if err != nil {
-		return err
+		return -1, err
 	}
 
-	// Create a buffer filled with 'O's to represent free blocks
-	buffer = make([]byte, sb.S_free_blocks_count)
-	for i := range buffer {
-		buffer[i] = 'O'
+	inodeIndex := findFreeRun(bitmap, '1', 1, sb.S_fit[0])
+	if inodeIndex == -1 {
+		return -1, fmt.Errorf("no hay inodos libres en la partición")
 	}
 
-	// Write the Block Bitmap buffer to the file
-	err = binary.Write(file, binary.LittleEndian, buffer)
+	// Write '1' to mark the inode as used
+	err = DeviceWriteAt(path, []byte{'1'}, int64(sb.S_bm_inode_start)+int64(inodeIndex))
 	if err != nil {
-		return err
+		return -1, err
 	}
+	bitmap[inodeIndex] = '1'
 
-	return nil
+	// Update the superblock
+	sb.S_inodes_count++
+	sb.S_free_inodes_count--
+	if next := firstFree(bitmap, '1'); next != -1 {
+		sb.S_first_ino = sb.S_inode_start + (next * sb.S_inode_size)
+	}
+
+	return inodeIndex, nil
 }
 
-// UpdateBitmapInode updates the Inode Bitmap to mark an inode as used.
-func (sb *SuperBlock) UpdateBitmapInode(path string) error {
-	// Open the file for reading and writing
-	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+// AllocateBlock finds a free block in the Block Bitmap, marks it as used and returns its index.
+func (sb *SuperBlock) AllocateBlock(path string) (int32, error) {
+	blocks, err := sb.AllocateBlocks(path, 1)
 	if err != nil {
-		return err
+		return -1, err
+	}
+	return blocks[0], nil
+}
+
+// AllocateBlocks reserves count blocks, preferring a contiguous run chosen with the partition fit.
+// When no run is large enough the first free blocks are used instead.
+func (sb *SuperBlock) AllocateBlocks(path string, count int32) ([]int32, error) {
+	bitmap, err := readBitmap(path, sb.S_bm_block_start, sb.TotalBlocks())
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]int32, 0, count)
+	start := findFreeRun(bitmap, 'X', count, sb.S_fit[0])
+	if start != -1 {
+		for i := int32(0); i < count; i++ {
+			blocks = append(blocks, start+i)
+		}
+	} else {
+		for i := int32(0); i < int32(len(bitmap)) && int32(len(blocks)) < count; i++ {
+			if bitmap[i] != 'X' {
+				blocks = append(blocks, i)
+			}
+		}
+		if int32(len(blocks)) < count {
+			return nil, fmt.Errorf("no hay bloques libres suficientes en la partición")
+		}
+	}
+
+	// Write 'X' to mark every block as used
+	for _, blockIndex := range blocks {
+		err = DeviceWriteAt(path, []byte{'X'}, int64(sb.S_bm_block_start)+int64(blockIndex))
+		if err != nil {
+			return nil, err
+		}
+		bitmap[blockIndex] = 'X'
 	}
-	defer file.Close()
 
-	// Move the file pointer to the position of the specific inode in the bitmap
-	_, err = file.Seek(int64(sb.S_bm_inode_start)+int64(sb.S_inodes_count), 0)
+	// Update the superblock
+	sb.S_blocks_count += count
+	sb.S_free_blocks_count -= count
+	if next := firstFree(bitmap, 'X'); next != -1 {
+		sb.S_first_blo = sb.S_block_start + (next * sb.S_block_size)
+	}
+
+	return blocks, nil
+}
+
+// FreeBitmapInode marks an inode as free in the Inode Bitmap and gives it back to the superblock.
+func (sb *SuperBlock) FreeBitmapInode(path string, inodeIndex int32) error {
+	// Read the current state so an inode is never released twice
+	state := make([]byte, 1)
+	err := DeviceReadAt(path, state, int64(sb.S_bm_inode_start)+int64(inodeIndex))
 	if err != nil {
 		return err
 	}
+	if state[0] != '1' {
+		return nil
+	}
 
-	// Write '1' to mark the inode as used
-	_, err = file.Write([]byte{'1'})
+	// Write '0' to mark the inode as free
+	err = DeviceWriteAt(path, []byte{'0'}, int64(sb.S_bm_inode_start)+int64(inodeIndex))
 	if err != nil {
 		return err
 	}
 
+	// Update the superblock
+	sb.S_inodes_count--
+	sb.S_free_inodes_count++
+	if offset := sb.S_inode_start + (inodeIndex * sb.S_inode_size); offset < sb.S_first_ino {
+		sb.S_first_ino = offset
+	}
+
 	return nil
 }
 
-// UpdateBitmapBlock updates the Block Bitmap to mark a block as used.
-func (sb *SuperBlock) UpdateBitmapBlock(path string) error {
-	// Open the file for reading and writing
-	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+// FreeBitmapBlock marks a block as free in the Block Bitmap and gives it back to the superblock.
+func (sb *SuperBlock) FreeBitmapBlock(path string, blockIndex int32) error {
+	// Read the current state so a block is never released twice
+	state := make([]byte, 1)
+	err := DeviceReadAt(path, state, int64(sb.S_bm_block_start)+int64(blockIndex))
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	if state[0] != 'X' {
+		return nil
+	}
 
-	// Move the file pointer to the position of the specific block in the bitmap
-	_, err = file.Seek(int64(sb.S_bm_block_start)+int64(sb.S_blocks_count), 0)
+	// Write 'O' to mark the block as free
+	err = DeviceWriteAt(path, []byte{'O'}, int64(sb.S_bm_block_start)+int64(blockIndex))
 	if err != nil {
 		return err
 	}
 
-	// Write 'X' to mark the block as used
-	_, err = file.Write([]byte{'X'})
+	// Update the superblock
+	sb.S_blocks_count--
+	sb.S_free_blocks_count++
+	if offset := sb.S_block_start + (blockIndex * sb.S_block_size); offset < sb.S_first_blo {
+		sb.S_first_blo = offset
+	}
+
+	return nil
+}
+
+// UsedInodes returns the indexes of the inodes marked as used in the inode bitmap
+func (sb *SuperBlock) UsedInodes(path string) ([]int32, error) {
+	bitmap, err := readBitmap(path, sb.S_bm_inode_start, sb.TotalInodes())
+	if err != nil {
+		return nil, err
+	}
+
+	used := make([]int32, 0, sb.S_inodes_count)
+	for i, b := range bitmap {
+		if b == '1' {
+			used = append(used, int32(i))
+		}
+	}
+	return used, nil
+}
+
+// RecountFromBitmaps rebuilds the usage counters and the first free inode and block from the bitmaps.
+// It is used after restoring a backup superblock, whose counters may be out of date.
+func (sb *SuperBlock) RecountFromBitmaps(path string) error {
+	inodeBitmap, err := readBitmap(path, sb.S_bm_inode_start, sb.TotalInodes())
+	if err != nil {
+		return err
+	}
+	blockBitmap, err := readBitmap(path, sb.S_bm_block_start, sb.TotalBlocks())
 	if err != nil {
 		return err
 	}
 
+	sb.S_inodes_count = int32(bytes.Count(inodeBitmap, []byte{'1'}))
+	sb.S_free_inodes_count = sb.TotalInodes() - sb.S_inodes_count
+	sb.S_blocks_count = int32(bytes.Count(blockBitmap, []byte{'X'}))
+	sb.S_free_blocks_count = sb.TotalBlocks() - sb.S_blocks_count
+
+	if next := firstFree(inodeBitmap, '1'); next != -1 {
+		sb.S_first_ino = sb.S_inode_start + (next * sb.S_inode_size)
+	}
+	if next := firstFree(blockBitmap, 'X'); next != -1 {
+		sb.S_first_blo = sb.S_block_start + (next * sb.S_block_size)
+	}
 	return nil
-}
\ No newline at end of file
+}
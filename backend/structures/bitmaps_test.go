@@ -0,0 +1,209 @@
+package structures
+
+import "testing"
+
+func TestFindFreeRun(t *testing.T) {
+	// Free runs: 3 at 2, 1 at 6, 4 at 9
+	bitmap := []byte("XXOOOXOXXOOOOX")
+
+	tests := []struct {
+		fit   byte
+		count int32
+		want  int32
+	}{
+		{'F', 1, 2},
+		{'B', 1, 6},
+		{'W', 1, 9},
+		{'F', 2, 2},
+		{'B', 2, 2},
+		{'W', 2, 9},
+		{'F', 4, 9},
+		{'B', 4, 9},
+		{'F', 5, -1},
+		{'B', 5, -1},
+		{'W', 5, -1},
+	}
+	for _, test := range tests {
+		got := findFreeRun(bitmap, 'X', test.count, test.fit)
+		if got != test.want {
+			t.Errorf("findFreeRun(%c, %d) = %d, want %d", test.fit, test.count, got, test.want)
+		}
+	}
+}
+
+// newBitmapSuperBlock lays out 16 inodes and 32 blocks on an in-memory disk, with the bitmaps
+// at the start and nothing else, which is all the allocator looks at.
+func newBitmapSuperBlock(t *testing.T, fit byte, blocks string) (*SuperBlock, string) {
+	t.Helper()
+	path := MemoryPathPrefix + t.Name() + string(fit) + ".mia"
+	err := CreateMemoryDevice(path, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { DropDevice(path) })
+
+	sb := &SuperBlock{
+		S_bm_inode_start:    0,
+		S_bm_block_start:    16,
+		S_inode_start:       48,
+		S_inode_size:        96,
+		S_block_start:       1584,
+		S_block_size:        64,
+		S_free_inodes_count: 16,
+		S_fit:               [1]byte{fit},
+	}
+	inodes := []byte("0000000000000000")
+	err = DeviceWriteAt(path, inodes, int64(sb.S_bm_inode_start))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = DeviceWriteAt(path, []byte(blocks), int64(sb.S_bm_block_start))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, state := range blocks {
+		if state == 'X' {
+			sb.S_blocks_count++
+		} else {
+			sb.S_free_blocks_count++
+		}
+	}
+	return sb, path
+}
+
+func TestAllocateBlocksFollowsFit(t *testing.T) {
+	// Free runs: 4 at 2, 2 at 8, 20 at 12
+	blocks := "XXOOOOXXOOXXOOOOOOOOOOOOOOOOOOOO"
+
+	tests := []struct {
+		fit  byte
+		want int32
+	}{
+		{'F', 2},
+		{'B', 8},
+		{'W', 12},
+	}
+	for _, test := range tests {
+		sb, path := newBitmapSuperBlock(t, test.fit, blocks)
+		free := sb.S_free_blocks_count
+
+		got, err := sb.AllocateBlocks(path, 2)
+		if err != nil {
+			t.Fatalf("fit %c: %v", test.fit, err)
+		}
+		if len(got) != 2 || got[0] != test.want || got[1] != test.want+1 {
+			t.Errorf("fit %c: allocated %v, want [%d %d]", test.fit, got, test.want, test.want+1)
+		}
+		if sb.S_free_blocks_count != free-2 {
+			t.Errorf("fit %c: %d free blocks, want %d", test.fit, sb.S_free_blocks_count, free-2)
+		}
+
+		bitmap, err := readBitmap(path, sb.S_bm_block_start, sb.TotalBlocks())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bitmap[test.want] != 'X' || bitmap[test.want+1] != 'X' {
+			t.Errorf("fit %c: blocks %v are not marked in the bitmap", test.fit, got)
+		}
+	}
+}
+
+func TestAllocateBlocksWithoutRun(t *testing.T) {
+	// Three free blocks, none of them next to another
+	blocks := "XOXXOXXXXXXXXXXXXXXXXXXXXXXXXXXO"
+	sb, path := newBitmapSuperBlock(t, 'F', blocks)
+
+	got, err := sb.AllocateBlocks(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0] != 1 || got[1] != 4 || got[2] != 31 {
+		t.Errorf("allocated %v, want [1 4 31]", got)
+	}
+	if sb.S_free_blocks_count != 0 {
+		t.Errorf("%d free blocks, want 0", sb.S_free_blocks_count)
+	}
+
+	_, err = sb.AllocateBlocks(path, 1)
+	if err == nil {
+		t.Error("allocating from a full bitmap did not fail")
+	}
+}
+
+func TestFreeBitmapBlock(t *testing.T) {
+	sb, path := newBitmapSuperBlock(t, 'F', "XXXXOOOOOOOOOOOOOOOOOOOOOOOOOOOO")
+	sb.S_first_blo = sb.S_block_start + 4*sb.S_block_size
+
+	err := sb.FreeBitmapBlock(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sb.S_free_blocks_count != 29 || sb.S_blocks_count != 3 {
+		t.Errorf("counters after free: %d free, %d used", sb.S_free_blocks_count, sb.S_blocks_count)
+	}
+	if sb.S_first_blo != sb.S_block_start+sb.S_block_size {
+		t.Errorf("first free block at %d, want %d", sb.S_first_blo, sb.S_block_start+sb.S_block_size)
+	}
+
+	// Freeing it again must not give the block back twice
+	err = sb.FreeBitmapBlock(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sb.S_free_blocks_count != 29 {
+		t.Errorf("double free left %d free blocks, want 29", sb.S_free_blocks_count)
+	}
+
+	got, err := sb.AllocateBlock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 1 {
+		t.Errorf("first fit reused block %d, want 1", got)
+	}
+}
+
+func TestAllocateAndFreeInode(t *testing.T) {
+	sb, path := newBitmapSuperBlock(t, 'F', "OOOOOOOOOOOOOOOOOOOOOOOOOOOOOOOO")
+
+	first, err := sb.AllocateInode(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := sb.AllocateInode(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != 0 || second != 1 {
+		t.Errorf("allocated inodes %d and %d, want 0 and 1", first, second)
+	}
+	if sb.S_free_inodes_count != 14 || sb.S_inodes_count != 2 {
+		t.Errorf("counters after allocating: %d free, %d used", sb.S_free_inodes_count, sb.S_inodes_count)
+	}
+
+	// Freeing it twice must give the inode back once
+	for i := 0; i < 2; i++ {
+		err = sb.FreeBitmapInode(path, first)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sb.S_free_inodes_count != 15 || sb.S_inodes_count != 1 {
+		t.Errorf("counters after freeing: %d free, %d used", sb.S_free_inodes_count, sb.S_inodes_count)
+	}
+	used, err := sb.UsedInodes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(used) != 1 || used[0] != second {
+		t.Errorf("used inodes %v, want [%d]", used, second)
+	}
+
+	again, err := sb.AllocateInode(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first {
+		t.Errorf("first fit reused inode %d, want %d", again, first)
+	}
+}
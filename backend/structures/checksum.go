@@ -0,0 +1,193 @@
+package structures
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"sync"
+)
+
+// Metadata checksums (FeatureMetadataCsum): the superblock and every inode carry a CRC32C of their
+// own bytes, and folder and pointer blocks have theirs in a table after the block area (S_csum_start),
+// one uint32 per block. They are refreshed in Serialize and verified in Deserialize.
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// CorruptionError is returned by Deserialize when the stored checksum does not match the data read.
+type CorruptionError struct {
+	Structure string // what was being read, as shown to the user
+	Offset    int64  // absolute offset of the structure in the disk
+	Stored    uint32
+	Computed  uint32
+}
+
+func (e *CorruptionError) Error() string {
+	return fmt.Sprintf("corrupción detectada en %s (posición %d): checksum guardado %08x, calculado %08x",
+		e.Structure, e.Offset, e.Stored, e.Computed)
+}
+
+// checksumArea describes a partition with checksums enabled.
+type checksumArea struct {
+	start      int64 // partition bounds
+	end        int64
+	blockStart int64 // block area and its checksum table
+	blockSize  int64
+	blocks     int64
+	csumStart  int64
+}
+
+var (
+	checksumsMu   sync.Mutex
+	checksumAreas = make(map[string][]checksumArea)
+)
+
+// TrackChecksums registers the partition [partStart, partStart+partSize) of the disk at path so its
+// structures are checksummed, or unregisters it if the filesystem does not have FeatureMetadataCsum.
+// It is called when the partition is mounted and when it is formatted.
+func (sb *SuperBlock) TrackChecksums(path string, partStart int32, partSize int32) {
+	UntrackChecksums(path, partStart)
+	if sb.S_magic != SuperBlockMagic || !sb.HasFeature(FeatureMetadataCsum) {
+		return
+	}
+
+	checksumsMu.Lock()
+	defer checksumsMu.Unlock()
+	checksumAreas[path] = append(checksumAreas[path], checksumArea{
+		start:      int64(partStart),
+		end:        int64(partStart) + int64(partSize),
+		blockStart: int64(sb.S_block_start),
+		blockSize:  int64(sb.S_block_size),
+		blocks:     int64(sb.TotalBlocks()),
+		csumStart:  int64(sb.S_csum_start),
+	})
+}
+
+// UntrackChecksums stops checksumming the partition that starts at partStart (unmount).
+func UntrackChecksums(path string, partStart int32) {
+	checksumsMu.Lock()
+	defer checksumsMu.Unlock()
+
+	areas := checksumAreas[path][:0]
+	for _, area := range checksumAreas[path] {
+		if area.start != int64(partStart) {
+			areas = append(areas, area)
+		}
+	}
+	if len(areas) == 0 {
+		delete(checksumAreas, path)
+		return
+	}
+	checksumAreas[path] = areas
+}
+
+// forgetChecksums drops every partition of a disk that is being deleted or recreated.
+func forgetChecksums(path string) {
+	checksumsMu.Lock()
+	defer checksumsMu.Unlock()
+	delete(checksumAreas, path)
+}
+
+// checksumAreaAt returns the checksummed partition that contains offset, if any.
+func checksumAreaAt(path string, offset int64) (checksumArea, bool) {
+	checksumsMu.Lock()
+	defer checksumsMu.Unlock()
+
+	for _, area := range checksumAreas[path] {
+		if offset >= area.start && offset < area.end {
+			return area, true
+		}
+	}
+	return checksumArea{}, false
+}
+
+// checksumOf returns the CRC32C of the little endian encoding of v.
+func checksumOf(v any) (uint32, error) {
+	var buffer bytes.Buffer
+	err := binary.Write(&buffer, binary.LittleEndian, v)
+	if err != nil {
+		return 0, err
+	}
+	return crc32.Checksum(buffer.Bytes(), castagnoli), nil
+}
+
+// sealStruct stores in field the checksum of v computed with field set to zero.
+func sealStruct(path string, offset int64, v any, field *uint32) error {
+	if _, ok := checksumAreaAt(path, offset); !ok {
+		return nil
+	}
+	*field = 0
+	sum, err := checksumOf(v)
+	if err != nil {
+		return err
+	}
+	*field = sum
+	return nil
+}
+
+// verifyStruct checks the checksum stored in field against the rest of v.
+func verifyStruct(structure string, path string, offset int64, v any, field *uint32) error {
+	if _, ok := checksumAreaAt(path, offset); !ok {
+		return nil
+	}
+	stored := *field
+	*field = 0
+	sum, err := checksumOf(v)
+	*field = stored
+	if err != nil {
+		return err
+	}
+	if sum != stored {
+		return &CorruptionError{Structure: structure, Offset: offset, Stored: stored, Computed: sum}
+	}
+	return nil
+}
+
+// blockChecksumSlot returns where the checksum of the block at offset is kept.
+func blockChecksumSlot(path string, offset int64) (int64, bool) {
+	area, ok := checksumAreaAt(path, offset)
+	if !ok || offset < area.blockStart {
+		return 0, false
+	}
+	index := (offset - area.blockStart) / area.blockSize
+	if index >= area.blocks {
+		return 0, false
+	}
+	return area.csumStart + index*4, true
+}
+
+// sealBlock writes the checksum of the block v stored at offset into the checksum table.
+func sealBlock(path string, offset int64, v any) error {
+	slot, ok := blockChecksumSlot(path, offset)
+	if !ok {
+		return nil
+	}
+	sum, err := checksumOf(v)
+	if err != nil {
+		return err
+	}
+	stored := make([]byte, 4)
+	binary.LittleEndian.PutUint32(stored, sum)
+	return DeviceWriteAt(path, stored, slot)
+}
+
+// verifyBlock checks the block v read from offset against the checksum table.
+func verifyBlock(structure string, path string, offset int64, v any) error {
+	slot, ok := blockChecksumSlot(path, offset)
+	if !ok {
+		return nil
+	}
+	stored := make([]byte, 4)
+	err := DeviceReadAt(path, stored, slot)
+	if err != nil {
+		return err
+	}
+	sum, err := checksumOf(v)
+	if err != nil {
+		return err
+	}
+	if sum != binary.LittleEndian.Uint32(stored) {
+		return &CorruptionError{Structure: structure, Offset: offset, Stored: binary.LittleEndian.Uint32(stored), Computed: sum}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package structures
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// A compat disk starts with a standard 512-byte MBR that host tools such as fdisk -l or sfdisk can read.
+// The project's MBR struct moves to LBA 1 and every change to it is mirrored in the standard partition
+// entries. Partitions start and end on sector boundaries so they can be described in LBAs. The extended
+// partition holds the project's EBR chain, so its entry is typed as non-filesystem data: host tools
+// list it as one region and leave it alone.
+
+// MBR partition type codes used in the standard entries
+const (
+	mbrTypeLinux     = 0x83
+	mbrTypeNonFSData = 0xDA
+)
+
+var bootSignature = [2]byte{0x55, 0xAA}
+
+// PartitionRecord is one of the four 16-byte entries of a standard MBR
+type PartitionRecord struct {
+	BootIndicator byte
+	StartCHS      [3]byte
+	OSType        byte
+	EndCHS        [3]byte
+	StartingLBA   uint32
+	SizeInLBA     uint32
+}
+
+// StandardMBR is the master boot record of LBA 0 as the specification defines it
+type StandardMBR struct {
+	BootCode      [440]byte
+	DiskSignature uint32
+	Reserved      [2]byte
+	Partitions    [4]PartitionRecord
+	Signature     [2]byte
+}
+
+// ReadStandardMBR reads LBA 0 of the disk as a standard MBR
+func ReadStandardMBR(path string) (*StandardMBR, error) {
+	standard := &StandardMBR{}
+	return standard, readStruct(path, 0, standard)
+}
+
+// IsCompatMBR reports whether the disk at path has a standard MBR with the project's MBR struct in LBA 1.
+// Both carry the same disk signature, which tells a compat disk apart from data that happens to end in 0x55AA.
+func IsCompatMBR(path string) bool {
+	standard, err := ReadStandardMBR(path)
+	if err != nil || standard.Signature != bootSignature || standard.DiskSignature == 0 {
+		return false
+	}
+	mbr := &MBR{}
+	err = readStruct(path, SectorSize, mbr)
+	return err == nil && uint32(mbr.Mbr_disk_signature) == standard.DiskSignature
+}
+
+// SerializeCompatMBR writes the MBR struct in LBA 1 and the standard MBR that describes it in LBA 0
+func (mbr *MBR) SerializeCompatMBR(path string) error {
+	standard, err := mbr.StandardMBR()
+	if err != nil {
+		return err
+	}
+	err = writeStruct(path, SectorSize, mbr)
+	if err != nil {
+		return err
+	}
+	return writeStruct(path, 0, standard)
+}
+
+// StandardMBR builds the standard MBR with one entry per used slot of the MBR struct
+func (mbr *MBR) StandardMBR() (*StandardMBR, error) {
+	standard := &StandardMBR{DiskSignature: uint32(mbr.Mbr_disk_signature), Signature: bootSignature}
+	for i, partition := range mbr.Mbr_partitions {
+		if partition.Part_start == -1 || partition.Part_size <= 0 {
+			continue
+		}
+		if partition.Part_start%SectorSize != 0 {
+			return nil, fmt.Errorf("partition %d starts at byte %d, which is not a sector boundary", i+1, partition.Part_start)
+		}
+		osType := byte(mbrTypeLinux)
+		if partition.Part_type[0] == 'E' {
+			osType = mbrTypeNonFSData
+		}
+		// CHS is not used: FE FF FF tells the tools to read the LBA fields
+		standard.Partitions[i] = PartitionRecord{
+			StartCHS:    [3]byte{0xFE, 0xFF, 0xFF},
+			OSType:      osType,
+			EndCHS:      [3]byte{0xFE, 0xFF, 0xFF},
+			StartingLBA: uint32(partition.Part_start / SectorSize),
+			SizeInLBA:   uint32(alignUp(partition.Part_size, SectorSize) / SectorSize),
+		}
+	}
+	return standard, nil
+}
+
+// mbrLayout returns the first byte a partition may use and the alignment of partition starts and ends
+func mbrLayout(path string) (int32, int32) {
+	if IsCompatMBR(path) {
+		return 2 * SectorSize, SectorSize
+	}
+	return int32(binary.Size(MBR{})), 1
+}
+
+func alignUp(value int32, align int32) int32 {
+	return (value + align - 1) / align * align
+}
+
+func alignDown(value int32, align int32) int32 {
+	return value / align * align
+}
@@ -0,0 +1,479 @@
+package structures
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// BlockDevice is the storage a disk image lives on. Every Serialize/Deserialize goes through one,
+// so the same structures work on a host file or fully in memory.
+type BlockDevice interface {
+	ReadAt(p []byte, off int64) (int, error)
+	WriteAt(p []byte, off int64) (int, error)
+	Size() (int64, error)
+	Sync() error
+	Close() error
+}
+
+// MemoryPathPrefix marks disk paths that live in memory instead of the host filesystem (e.g. mem://disk1.mia)
+const MemoryPathPrefix = "mem://"
+
+// IsMemoryPath reports whether a disk path refers to an in-memory device
+func IsMemoryPath(path string) bool {
+	return strings.HasPrefix(path, MemoryPathPrefix)
+}
+
+// FileDevice is a BlockDevice backed by a host file that stays open.
+type FileDevice struct {
+	file *os.File
+}
+
+// NewFileDevice opens an existing disk image at path.
+func NewFileDevice(path string) (*FileDevice, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileDevice{file: file}, nil
+}
+
+func (d *FileDevice) ReadAt(p []byte, off int64) (int, error)  { return d.file.ReadAt(p, off) }
+func (d *FileDevice) WriteAt(p []byte, off int64) (int, error) { return d.file.WriteAt(p, off) }
+func (d *FileDevice) Sync() error                              { return d.file.Sync() }
+func (d *FileDevice) Close() error                             { return d.file.Close() }
+
+func (d *FileDevice) Size() (int64, error) {
+	info, err := d.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// MemoryDevice is a BlockDevice kept entirely in memory. It grows on writes past the end, like a file.
+type MemoryDevice struct {
+	mu   sync.RWMutex
+	data []byte
+}
+
+// NewMemoryDevice creates a zero-filled in-memory device of the given size.
+func NewMemoryDevice(size int64) *MemoryDevice {
+	return &MemoryDevice{data: make([]byte, size)}
+}
+
+func (d *MemoryDevice) ReadAt(p []byte, off int64) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if off >= int64(len(d.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, d.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (d *MemoryDevice) WriteAt(p []byte, off int64) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if end := off + int64(len(p)); end > int64(len(d.data)) {
+		grown := make([]byte, end)
+		copy(grown, d.data)
+		d.data = grown
+	}
+	return copy(d.data[off:], p), nil
+}
+
+func (d *MemoryDevice) Size() (int64, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(len(d.data)), nil
+}
+
+func (d *MemoryDevice) Sync() error  { return nil }
+func (d *MemoryDevice) Close() error { return nil }
+
+// Cache geometry: pages are small because most structures are 64 or 92 bytes.
+const (
+	cachePageSize = 512
+	cachePages    = 1024
+)
+
+type cachePage struct {
+	index int64
+	data  []byte
+	dirty bool
+}
+
+// CachedDevice wraps a BlockDevice with an LRU write-back page cache.
+// Inodes, blocks and bitmaps are all cached by the page that contains them;
+// dirty pages reach the underlying device on eviction or on Sync.
+type CachedDevice struct {
+	mu    sync.Mutex
+	dev   BlockDevice
+	size  int64
+	pages map[int64]*list.Element
+	lru   *list.List // front = most recently used
+}
+
+// NewCachedDevice wraps dev with a page cache.
+func NewCachedDevice(dev BlockDevice) (*CachedDevice, error) {
+	size, err := dev.Size()
+	if err != nil {
+		return nil, err
+	}
+	return &CachedDevice{dev: dev, size: size, pages: make(map[int64]*list.Element), lru: list.New()}, nil
+}
+
+// page returns the cached page, loading it (and evicting the least recently used one) if needed.
+func (c *CachedDevice) page(index int64) (*cachePage, error) {
+	if element, ok := c.pages[index]; ok {
+		c.lru.MoveToFront(element)
+		return element.Value.(*cachePage), nil
+	}
+
+	if c.lru.Len() >= cachePages {
+		oldest := c.lru.Back()
+		err := c.writeBack(oldest.Value.(*cachePage))
+		if err != nil {
+			return nil, err
+		}
+		c.lru.Remove(oldest)
+		delete(c.pages, oldest.Value.(*cachePage).index)
+	}
+
+	p := &cachePage{index: index, data: make([]byte, cachePageSize)}
+	_, err := c.dev.ReadAt(p.data, index*cachePageSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	c.pages[index] = c.lru.PushFront(p)
+	return p, nil
+}
+
+// writeBack flushes a dirty page, never writing past the logical end of the device.
+func (c *CachedDevice) writeBack(p *cachePage) error {
+	if !p.dirty {
+		return nil
+	}
+	start := p.index * cachePageSize
+	length := int64(cachePageSize)
+	if start+length > c.size {
+		length = c.size - start
+	}
+	if length > 0 {
+		_, err := c.dev.WriteAt(p.data[:length], start)
+		if err != nil {
+			return err
+		}
+	}
+	p.dirty = false
+	return nil
+}
+
+func (c *CachedDevice) ReadAt(p []byte, off int64) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for n < len(p) {
+		position := off + int64(n)
+		if position >= c.size {
+			return n, io.EOF
+		}
+		page, err := c.page(position / cachePageSize)
+		if err != nil {
+			return n, err
+		}
+		inPage := position % cachePageSize
+		end := int64(cachePageSize)
+		if remaining := c.size - (position - inPage); remaining < end {
+			end = remaining
+		}
+		n += copy(p[n:], page.data[inPage:end])
+	}
+	return n, nil
+}
+
+func (c *CachedDevice) WriteAt(p []byte, off int64) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for n < len(p) {
+		position := off + int64(n)
+		page, err := c.page(position / cachePageSize)
+		if err != nil {
+			return n, err
+		}
+		written := copy(page.data[position%cachePageSize:], p[n:])
+		page.dirty = true
+		n += written
+		if position+int64(written) > c.size {
+			c.size = position + int64(written)
+		}
+	}
+	return n, nil
+}
+
+func (c *CachedDevice) Size() (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size, nil
+}
+
+// Sync writes every dirty page to the underlying device and syncs it.
+func (c *CachedDevice) Sync() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for element := c.lru.Front(); element != nil; element = element.Next() {
+		err := c.writeBack(element.Value.(*cachePage))
+		if err != nil {
+			return err
+		}
+	}
+	return c.dev.Sync()
+}
+
+// Close syncs the cache and closes the underlying device.
+func (c *CachedDevice) Close() error {
+	err := c.Sync()
+	if err != nil {
+		return err
+	}
+	return c.dev.Close()
+}
+
+// Drop discards the cache without writing it back and closes the underlying device.
+func (c *CachedDevice) Drop() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pages = make(map[int64]*list.Element)
+	c.lru.Init()
+	return c.dev.Close()
+}
+
+// openDevice is an entry of the device table: a device stays open while something holds a reference.
+// In-memory devices are pinned, they only go away with DropDevice (rmdisk).
+type openDevice struct {
+	dev    *CachedDevice
+	refs   int
+	pinned bool
+}
+
+var (
+	devicesMu sync.Mutex
+	devices   = make(map[string]*openDevice)
+)
+
+// OpenDevice returns the device for a disk path and takes a reference on it.
+// Mounted partitions hold their disk open through this; release it with CloseDevice.
+func OpenDevice(path string) (BlockDevice, error) {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	if entry, ok := devices[path]; ok {
+		entry.refs++
+		return entry.dev, nil
+	}
+	if IsMemoryPath(path) {
+		return nil, fmt.Errorf("the in-memory disk %s does not exist", path)
+	}
+
+	fileDev, err := NewFileDevice(path)
+	if err != nil {
+		return nil, err
+	}
+	cached, err := NewCachedDevice(fileDev)
+	if err != nil {
+		fileDev.Close()
+		return nil, err
+	}
+	devices[path] = &openDevice{dev: cached, refs: 1}
+	return cached, nil
+}
+
+// CloseDevice releases a reference taken with OpenDevice; the last one syncs and closes the device.
+func CloseDevice(path string) error {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	entry, ok := devices[path]
+	if !ok {
+		return nil
+	}
+	entry.refs--
+	if entry.refs > 0 || entry.pinned {
+		return entry.dev.Sync()
+	}
+	delete(devices, path)
+	return entry.dev.Close()
+}
+
+// CreateMemoryDevice registers a new zero-filled in-memory disk of the given size under path.
+func CreateMemoryDevice(path string, size int64) error {
+	if !IsMemoryPath(path) {
+		return fmt.Errorf("in-memory disk paths must start with %s", MemoryPathPrefix)
+	}
+	cached, err := NewCachedDevice(NewMemoryDevice(size))
+	if err != nil {
+		return err
+	}
+
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	if entry, ok := devices[path]; ok {
+		entry.dev.Drop()
+	}
+	devices[path] = &openDevice{dev: cached, pinned: true}
+	return nil
+}
+
+// DropDevice forgets the device for path without writing its cache back.
+// It is used when the image is deleted or recreated underneath (rmdisk, mkdisk).
+func DropDevice(path string) error {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	forgetChecksums(path)
+	forgetLayouts(path)
+
+	entry, ok := devices[path]
+	if !ok {
+		return nil
+	}
+	delete(devices, path)
+	return entry.dev.Drop()
+}
+
+// DeviceExists reports whether path names an existing disk, in memory or on the host.
+func DeviceExists(path string) bool {
+	devicesMu.Lock()
+	_, ok := devices[path]
+	devicesMu.Unlock()
+	if ok {
+		return true
+	}
+	if IsMemoryPath(path) {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// SyncDevices flushes the cache of every open device.
+func SyncDevices() error {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	for path, entry := range devices {
+		err := entry.dev.Sync()
+		if err != nil {
+			return fmt.Errorf("sync %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// SyncDevice flushes the cache of the device for path, if it is open.
+func SyncDevice(path string) error {
+	devicesMu.Lock()
+	entry, ok := devices[path]
+	devicesMu.Unlock()
+	if !ok {
+		return nil
+	}
+	return entry.dev.Sync()
+}
+
+// acquireDevice returns the open device for path, or opens the file just for this access.
+// The returned function must be called when the access is done.
+func acquireDevice(path string) (BlockDevice, func(), error) {
+	devicesMu.Lock()
+	entry, ok := devices[path]
+	devicesMu.Unlock()
+	if ok {
+		return entry.dev, func() {}, nil
+	}
+	if IsMemoryPath(path) {
+		return nil, nil, fmt.Errorf("the in-memory disk %s does not exist", path)
+	}
+
+	fileDev, err := NewFileDevice(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fileDev, func() { fileDev.Close() }, nil
+}
+
+// DeviceReadAt reads len(p) bytes of the disk at path starting at off.
+// Writes held by an open transaction on the partition are visible to the read.
+func DeviceReadAt(path string, p []byte, off int64) error {
+	dev, release, err := acquireDevice(path)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	n, err := dev.ReadAt(p, off)
+	if n == len(p) {
+		if tx := activeTransaction(path, off); tx != nil {
+			tx.overlay(p, off)
+		}
+		return nil
+	}
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// DeviceWriteAt writes p to the disk at path starting at off.
+// While a transaction is open on the partition the write is held by it until the commit.
+func DeviceWriteAt(path string, p []byte, off int64) error {
+	if tx := activeTransaction(path, off); tx != nil {
+		tx.bufferWrite(p, off)
+		return nil
+	}
+
+	dev, release, err := acquireDevice(path)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = dev.WriteAt(p, off)
+	return err
+}
+
+// writeStruct encodes v in little endian and writes it to the disk at path.
+func writeStruct(path string, offset int64, v any) error {
+	var buffer bytes.Buffer
+	err := binary.Write(&buffer, binary.LittleEndian, v)
+	if err != nil {
+		return err
+	}
+	return DeviceWriteAt(path, buffer.Bytes(), offset)
+}
+
+// readStruct reads binary.Size(v) bytes from the disk at path and decodes them into v.
+func readStruct(path string, offset int64, v any) error {
+	size := binary.Size(v)
+	if size <= 0 {
+		return fmt.Errorf("invalid struct size: %d", size)
+	}
+	buffer := make([]byte, size)
+	err := DeviceReadAt(path, buffer, offset)
+	if err != nil {
+		return err
+	}
+	return binary.Read(bytes.NewReader(buffer), binary.LittleEndian, v)
+}
@@ -1,10 +1,9 @@
 package structures
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
-	"os"
+	"strings"
 )
 
 const EBRSize = 32
@@ -18,64 +17,48 @@ type EBR struct {
 	Ebr_part_name [16]byte // name of the partition
 }
 
-func(ebr *EBR) SerializeEBR(path string, offset int64) error {
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		return err
-	}
-
-	defer file.Close()
-
-	// Move the file pointer to the specified offset
-	_, err = file.Seek(offset, 0)
-	if err != nil {
-		return err
-	}
-
-	// Serialize the ebr structure directly into the file
-	err = binary.Write(file, binary.LittleEndian, ebr)
-	if err != nil {
-		return err
-	}
-
-	return nil
+// LogicalPartition is an EBR of the chain together with the byte of the disk where it is stored.
+// The data of the logical partition starts EBRSize bytes after its EBR.
+type LogicalPartition struct {
+	EBR    EBR
+	Offset int32
+	Number int // position among the logical partitions, numbered after the four primary slots (5, 6, ...)
 }
 
-func(ebr *EBR) DeserializeEBR(path string, offset int64) error {
-	// Open the file for reading
-	file, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+// Empty reports whether the EBR describes no partition: the first EBR of the chain is always
+// there and stays empty until a logical partition is created in its place
+func (lp *LogicalPartition) Empty() bool {
+	return lp.EBR.Ebr_part_size <= 0
+}
 
-	// Move the file pointer to the specified offset
-	_, err = file.Seek(offset, 0)
-	if err != nil {
-		return err
-	}
+// Name returns the name of the logical partition
+func (lp *LogicalPartition) Name() string {
+	return strings.TrimRight(string(lp.EBR.Ebr_part_name[:]), "\x00")
+}
 
-	// Get the size of the ebr structure
-	ebrSize := binary.Size(ebr)
-	if ebrSize <= 0 {
-		return fmt.Errorf("invalid ebr size: %d", ebrSize)
+// Partition returns the logical partition as a Partition so it can be mounted and formatted like a primary one
+func (lp *LogicalPartition) Partition() *Partition {
+	partition := &Partition{
+		Part_status:      [1]byte{'0'},
+		Part_type:        [1]byte{'L'},
+		Part_fit:         lp.EBR.Ebr_part_fit,
+		Part_start:       lp.EBR.Ebr_part_start,
+		Part_size:        lp.EBR.Ebr_part_size,
+		Part_name:        lp.EBR.Ebr_part_name,
+		Part_correlative: int32(lp.Number),
 	}
-
-	// Read only the number of bytes corresponding to the size of the Inode structure
-	buffer := make([]byte, ebrSize)
-	_, err = file.Read(buffer)
-	if err != nil {
-		return err
+	if lp.EBR.Ebr_part_mount[0] == '1' {
+		partition.Part_status[0] = '1'
 	}
+	return partition
+}
 
-	// Deserialize the read bytes into the Inode structure
-	reader := bytes.NewReader(buffer)
-	err = binary.Read(reader, binary.LittleEndian, ebr)
-	if err != nil {
-		return err
-	}
+func(ebr *EBR) SerializeEBR(path string, offset int64) error {
+	return writeStruct(path, offset, ebr)
+}
 
-	return nil
+func(ebr *EBR) DeserializeEBR(path string, offset int64) error {
+	return readStruct(path, offset, ebr)
 }
 
 
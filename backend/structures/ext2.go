@@ -10,6 +10,16 @@ import (
 // Crear users.txt en nuestro sistema de archivos
 func (sb *SuperBlock) CreateUsersFileExt2(path string) error {
 	// ----------- Creamos / -----------
+	// Reservar el inodo raíz y su bloque
+	rootInodeIndex, err := sb.AllocateInode(path)
+	if err != nil {
+		return err
+	}
+	rootBlockIndex, err := sb.AllocateBlock(path)
+	if err != nil {
+		return err
+	}
+
 	// Creamos el inodo raíz
 	rootInode := &Inode{
 		I_uid:   1,
@@ -18,84 +28,36 @@ func (sb *SuperBlock) CreateUsersFileExt2(path string) error {
 		I_atime: float32(time.Now().Unix()),
 		I_ctime: float32(time.Now().Unix()),
 		I_mtime: float32(time.Now().Unix()),
-		I_block: [15]int32{sb.S_blocks_count, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
+		I_block: [15]int32{rootBlockIndex, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
 		I_type:  [1]byte{'0'},
 		I_perm:  [3]byte{'7', '7', '7'},
+		I_links: 1,
 	}
 
 	// Serializar el inodo raíz
-	err := rootInode.Serialize(path, int64(sb.S_first_ino))
-	if err != nil {
-		return err
-	}
-
-	// Actualizar el bitmap de inodos
-	err = sb.UpdateBitmapInode(path)
-	if err != nil {
-		return err
-	}
-
-	// Actualizar el superbloque
-	sb.S_inodes_count++
-	sb.S_free_inodes_count--
-	sb.S_first_ino += sb.S_inode_size
-
-	// Creamos el bloque del Inodo Raíz
-	rootBlock := &FolderBlock{
-		B_content: [4]FolderContent{
-			{B_name: [12]byte{'.'}, B_inodo: 0},
-			{B_name: [12]byte{'.', '.'}, B_inodo: 0},
-			{B_name: [12]byte{'-'}, B_inodo: -1},
-			{B_name: [12]byte{'-'}, B_inodo: -1},
-		},
-	}
-
-	// Actualizar el bitmap de bloques
-	err = sb.UpdateBitmapBlock(path)
+	err = rootInode.Serialize(path, int64(sb.S_inode_start+(rootInodeIndex*sb.S_inode_size)))
 	if err != nil {
 		return err
 	}
 
-	// Serializar el bloque de carpeta raíz
-	err = rootBlock.Serialize(path, int64(sb.S_first_blo))
-	if err != nil {
-		return err
-	}
-
-	// Actualizar el superbloque
-	sb.S_blocks_count++
-	sb.S_free_blocks_count--
-	sb.S_first_blo += sb.S_block_size
-
 	// ----------- Creamos /users.txt -----------
 	usersText := "1,G,root\n1,U,root,root,123\n"
 
-	// Deserializar el inodo raíz
-	err = rootInode.Deserialize(path, int64(sb.S_inode_start+0)) // 0 porque es el inodo raíz
-	if err != nil {
-		return err
-	}
-
-	// Actualizamos el inodo raíz
-	rootInode.I_atime = float32(time.Now().Unix())
-
-	// Serializar el inodo raíz
-	err = rootInode.Serialize(path, int64(sb.S_inode_start+0)) // 0 porque es el inodo raíz
+	// Reservar el inodo de users.txt
+	usersInodeIndex, err := sb.AllocateInode(path)
 	if err != nil {
 		return err
 	}
 
-	// Deserializar el bloque de carpeta raíz
-	err = rootBlock.Deserialize(path, int64(sb.S_block_start+0)) // 0 porque es el bloque de carpeta raíz
-	if err != nil {
-		return err
-	}
-
-	// Actualizamos el bloque de carpeta raíz
-	rootBlock.B_content[2] = FolderContent{B_name: [12]byte{'u', 's', 'e', 'r', 's', '.', 't', 'x', 't'}, B_inodo: sb.S_inodes_count}
+	// Creamos el bloque del Inodo Raíz
+	rootBlock := sb.folderBlockWith(
+		FolderContent{B_name: [12]byte{'.'}, B_inodo: rootInodeIndex},
+		FolderContent{B_name: [12]byte{'.', '.'}, B_inodo: rootInodeIndex},
+		FolderContent{B_name: [12]byte{'u', 's', 'e', 'r', 's', '.', 't', 'x', 't'}, B_inodo: usersInodeIndex},
+	)
 
 	// Serializar el bloque de carpeta raíz
-	err = rootBlock.Serialize(path, int64(sb.S_block_start+0)) // 0 porque es el bloque de carpeta raíz
+	err = rootBlock.Serialize(path, int64(sb.S_block_start+(rootBlockIndex*sb.S_block_size)))
 	if err != nil {
 		return err
 	}
@@ -108,56 +70,23 @@ func (sb *SuperBlock) CreateUsersFileExt2(path string) error {
 		I_atime: float32(time.Now().Unix()),
 		I_ctime: float32(time.Now().Unix()),
 		I_mtime: float32(time.Now().Unix()),
-		I_block: [15]int32{sb.S_blocks_count, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
+		I_block: [15]int32{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
 		I_type:  [1]byte{'1'},
 		I_perm:  [3]byte{'7', '7', '7'},
+		I_links: 1,
 	}
 
-	// Actualizar el bitmap de inodos
-	err = sb.UpdateBitmapInode(path)
+	// Escribir el contenido de users.txt
+	err = sb.writeInodeContent(path, usersInode, usersText)
 	if err != nil {
 		return err
 	}
 
 	// Serializar el inodo users.txt
-	err = usersInode.Serialize(path, int64(sb.S_first_ino))
-	if err != nil {
-		return err
-	}
-
-	// Actualizamos el superbloque
-	sb.S_inodes_count++
-	sb.S_free_inodes_count--
-	sb.S_first_ino += sb.S_inode_size
-
-	// Creamos el bloque de users.txt
-	usersBlock := &FileBlock{
-		B_content: [64]byte{},
-	}
-	// Copiamos el texto de usuarios en el bloque
-	copy(usersBlock.B_content[:], usersText)
-
-	// Serializar el bloque de users.txt
-	err = usersBlock.Serialize(path, int64(sb.S_first_blo))
-	if err != nil {
-		return err
-	}
-
-	// Actualizar el bitmap de bloques
-	err = sb.UpdateBitmapBlock(path)
-	if err != nil {
-		return err
-	}
-
-	// Actualizamos el superbloque
-	sb.S_blocks_count++
-	sb.S_free_blocks_count--
-	sb.S_first_blo += sb.S_block_size
-
-	return nil
+	return usersInode.Serialize(path, int64(sb.S_inode_start+(usersInodeIndex*sb.S_inode_size)))
 }
 
-func (sb *SuperBlock) createFileInodeExt2(path string, inodeIndex int32, parentsDir []string, destDir string, r bool, size int, contentFile string, uid int32, gid int32, folderPath string, journalStart int64) error {
+func (sb *SuperBlock) createFileInodeExt2(path string, inodeIndex int32, parentsDir []string, destDir string, r bool, size int, contentFile string, uid int32, gid int32) error {
 	// crear un nuevo inodo
 	inode := &Inode{}
 	// deserializar el inodo
@@ -167,383 +96,87 @@ func (sb *SuperBlock) createFileInodeExt2(path string, inodeIndex int32, parents
 	}
 
 	// verificar que el inodo sea de tipo carpeta
-	if inode.I_type[0] == '1' {
+	if inode.I_type[0] != '0' {
 		return nil
 	}
 
-	// Iterar sobre cada bloque del inodo (apuntadores)
-	for i, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			fmt.Println("No hay más bloques, creando uno nuevo")
-			fmt.Println("i:", i)
-
-			// 1. Crear y serializar un nuevo bloque de carpeta
-			newBlock := &FolderBlock{
-				B_content: [4]FolderContent{
-					{B_name: [12]byte{'.'}, B_inodo: inodeIndex},
-					{B_name: [12]byte{'.', '.'}, B_inodo: inodeIndex},
-					{B_name: [12]byte{'-'}, B_inodo: -1},
-					{B_name: [12]byte{'-'}, B_inodo: -1},
-				},
-			}
-
-			// actualizar contenido del bloque
-			destDirByte := [12]byte{}
-			copy(destDirByte[:], destDir)
-			newBlock.B_content[2] = FolderContent{B_name: destDirByte, B_inodo: sb.S_inodes_count}
-
-			newBlockPos := sb.S_blocks_count
-
-			// Serializar el nuevo bloque
-			err = newBlock.Serialize(path, int64(sb.S_block_start+(newBlockPos*sb.S_block_size)))
-			if err != nil {
-				return err
-			}
-
-			// Actualizar bitmap de bloques
-			err = sb.UpdateBitmapBlock(path)
-			if err != nil {
-				return err
-			}
-
-			// Actualizar superbloque (nuevo bloque)
-			sb.S_blocks_count++
-			sb.S_free_blocks_count--
-			sb.S_first_blo += sb.S_block_size
-
-			// 2. Crear el inodo del nuevo archivo
-			folderInode := &Inode{
-				I_uid:   uid,
-				I_gid:   gid,
-				I_size:  0,
-				I_atime: float32(time.Now().Unix()),
-				I_ctime: float32(time.Now().Unix()),
-				I_mtime: float32(time.Now().Unix()),
-				I_block: [15]int32{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
-				I_type:  [1]byte{'1'},
-				I_perm:  [3]byte{'6', '6', '4'},
-			}
-
-			content := ""
-			if contentFile != "" {
-				content = contentFile
-			} else {
-				// llenar el contenido con una cadena de numeros del 0 al 9 cuantas veces sea el tamaño
-				for i := 0; i < size; i++ {
-					content += string(i%10 + '0')
-				}
-			}
-			fmt.Println("Contenido del archivo: ", content)
-
-			if sb.S_filesystem_type == 3 {
-				// Jornaling
-				// Crear el journal
-				bytePath := [32]byte{}
-				copy(bytePath[:], folderPath)
-				byteContent := [64]byte{}
-				copy(byteContent[:], content)
-				journal := &Journal{
-					J_count: sb.S_inodes_count,
-					J_content: Information{
-						I_operation: [10]byte{'m', 'k', 'f', 'i', 'l', 'e'},
-						I_path:      bytePath,
-						I_content:   byteContent,
-						I_date:      float32(time.Now().Unix()),
-					},
-				}
-				fmt.Println("Journal:")
-				journal.Print()
-
-				// Serializar el journal
-				err = journal.Serialize(path, journalStart)
-				if err != nil {
-					return err
-				}
-			}
-
-			// crear un arreglo de bloques que almacene el contenido de 64 bytes
-			contentBlocks := make([]string, 0)
-			// dividir el contenido en bloques de 64 bytes
-			for i := 0; i < len(content); i += 64 {
-				if i+64 > len(content) {
-					contentBlocks = append(contentBlocks, content[i:])
-				} else {
-					contentBlocks = append(contentBlocks, content[i:i+64])
-				}
-			}
-			// iterar sobre cada bloque del file inode y asignar el contenido
-			for i := 0; i < len(contentBlocks); i++ {
-				// crear un nuevo bloque de archivo
-				fileBlock := &FileBlock{
-					B_content: [64]byte{},
-				}
-				// copiar el contenido del bloque
-				copy(fileBlock.B_content[:], contentBlocks[i])
-
-				folderInode.I_block[i] = sb.S_blocks_count
-
-				// serializar el bloque
-				err = fileBlock.Serialize(path, int64(sb.S_block_start+(sb.S_blocks_count*sb.S_block_size)))
-				if err != nil {
-					return err
-				}
-
-				// Actualizar el bitmap de bloques
-				err = sb.UpdateBitmapBlock(path)
-				if err != nil {
-					return err
-				}
-
-				// Actualizar el superbloque
-				sb.S_blocks_count++
-				sb.S_free_blocks_count--
-				sb.S_first_blo += sb.S_block_size
-
-			}
-			if len(contentBlocks) == 0 {
-				// crear un bloque de archivo vacío
-				fileBlock := &FileBlock{
-					B_content: [64]byte{},
-				}
-
-				folderInode.I_block[0] = sb.S_blocks_count
-				// serializar el bloque
-				err = fileBlock.Serialize(path, int64(sb.S_block_start+(sb.S_blocks_count*sb.S_block_size)))
-				if err != nil {
-					return err
-				}
-
-				// Actualizar el bitmap de bloques
-				err = sb.UpdateBitmapBlock(path)
-				if err != nil {
-					return err
-				}
-
-				// Actualizar el superbloque
-				sb.S_blocks_count++
-				sb.S_free_blocks_count--
-				sb.S_first_blo += sb.S_block_size
-			}
-			// Serializar el inodo del archivo
-			err = folderInode.Serialize(path, int64(sb.S_first_ino))
-			if err != nil {
-				return err
-			}
-
-			// Actualizar el bitmap de inodos
-			err = sb.UpdateBitmapInode(path)
-			if err != nil {
-				return err
-			}
-
-			// Actualizar el superbloque
-			sb.S_inodes_count++
-			sb.S_free_inodes_count--
-			sb.S_first_ino += sb.S_inode_size
-			fmt.Println("contenido del archivo para añadir al bloque: ", contentFile)
-
-			inode.I_block[i] = newBlockPos
-
-			// Serializar el inodo actualizado
-			err = inode.Serialize(path, int64(sb.S_inode_start+(inodeIndex*sb.S_inode_size)))
-			if err != nil {
-				return err
-			}
-
-			return nil
+	// Si las carpetas padre no están vacías debereamos buscar la carpeta padre más cercana
+	if len(parentsDir) != 0 {
+		// Obtenemos la carpeta padre más cercana
+		parentDir, err := utils.First(parentsDir)
+		if err != nil {
+			return err
 		}
 
-		block := &FolderBlock{}
-		// deserializar el bloque
-		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
+		childIndex, err := sb.findFolderEntry(path, inode, parentDir)
 		if err != nil {
 			return err
 		}
+		if childIndex == -1 {
+			return nil
+		}
 
-		// Iterar sobre cada contenido del bloque, desde el index 2 porque los primeros dos son . y ..
-		for indexContent := 2; indexContent < len(block.B_content); indexContent++ {
-			content := block.B_content[indexContent]
-
-			// Si las carpetas padre no están vacías debereamos buscar la carpeta padre más cercana
-			if len(parentsDir) != 0 {
-				// Si el contenido está vacío, salir
-				if content.B_inodo == -1 {
-					break
-				}
-
-				// Obtenemos la carpeta padre más cercana
-				parentDir, err := utils.First(parentsDir)
-				if err != nil {
-					return err
-				}
-
-				// Convertir B_name a string y eliminar los caracteres nulos
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
-				// Convertir parentDir a string y eliminar los caracteres nulos
-				parentDirName := strings.Trim(parentDir, "\x00 ")
-				// Si el nombre del contenido coincide con el nombre de la carpeta padre
-				if strings.EqualFold(contentName, parentDirName) {
-					err := sb.createFileInodeExt2(path, content.B_inodo, utils.RemoveElement(parentsDir, 0), destDir, r, size, contentFile, uid, gid, folderPath, journalStart)
-					if err != nil {
-						return err
-					}
-					return nil
-				}
-			} else {
-				fmt.Println("---------ESTOY  CREANDO--------")
-				// Si el apuntador al inodo está ocupado, continuar con el siguiente
-				if content.B_inodo != -1 {
-					continue
-				}
-
-				// Actualizar el contenido del bloque
-				copy(content.B_name[:], destDir)
-				content.B_inodo = sb.S_inodes_count
-				// Actualizar el bloque
-				block.B_content[indexContent] = content
-				// Serializar el bloque
-				err = block.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
-				if err != nil {
-					return err
-				}
-
-				// Crear el inodo del archivo
-				// Crear el inodo de la carpeta
-				fileInode := &Inode{
-					I_uid:   uid,
-					I_gid:   gid,
-					I_size:  int32(size),
-					I_atime: float32(time.Now().Unix()),
-					I_ctime: float32(time.Now().Unix()),
-					I_mtime: float32(time.Now().Unix()),
-					I_block: [15]int32{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
-					I_type:  [1]byte{'1'},
-					I_perm:  [3]byte{'6', '6', '4'},
-				}
-
-				content := ""
-				if contentFile != "" {
-					content = contentFile
-				} else {
-					// llenar el contenido con una cadena de numeros del 0 al 9 cuantas veces sea el tamaño
-					for i := 0; i < size; i++ {
-						content += string(i%10 + '0')
-					}
-				}
-				fmt.Println("Contenido del archivo: ", content)
-
-				if sb.S_filesystem_type == 3 {
-					// Jornaling
-					// Crear el journal
-					bytePath := [32]byte{}
-					copy(bytePath[:], folderPath)
-					byteContent := [64]byte{}
-					copy(byteContent[:], content)
-					journal := &Journal{
-						J_count: sb.S_inodes_count,
-						J_content: Information{
-							I_operation: [10]byte{'m', 'k', 'f', 'i', 'l', 'e'},
-							I_path:      bytePath,
-							I_content:   byteContent,
-							I_date:      float32(time.Now().Unix()),
-						},
-					}
-					fmt.Println("Journal:")
-					journal.Print()
-
-					// Serializar el journal
-					err = journal.Serialize(path, journalStart)
-					if err != nil {
-						return err
-					}
-				}
-
-				// crear un arreglo de bloques que almacene el contenido de 64 bytes
-				contentBlocks := make([]string, 0)
-				// dividir el contenido en bloques de 64 bytes
-				for i := 0; i < len(content); i += 64 {
-					if i+64 > len(content) {
-						contentBlocks = append(contentBlocks, content[i:])
-					} else {
-						contentBlocks = append(contentBlocks, content[i:i+64])
-					}
-				}
-
-				// iterar sobre cada bloque del file inode y asignar el contenido
-				for i := 0; i < len(contentBlocks); i++ {
-					// crear un nuevo bloque de archivo
-					fileBlock := &FileBlock{
-						B_content: [64]byte{},
-					}
-					// copiar el contenido del bloque
-					copy(fileBlock.B_content[:], contentBlocks[i])
-
-					fileInode.I_block[i] = sb.S_blocks_count
-
-					// serializar el bloque
-					err = fileBlock.Serialize(path, int64(sb.S_block_start+(sb.S_blocks_count*sb.S_block_size)))
-					if err != nil {
-						return err
-					}
-
-					// Actualizar el bitmap de bloques
-					err = sb.UpdateBitmapBlock(path)
-					if err != nil {
-						return err
-					}
+		return sb.createFileInodeExt2(path, childIndex, utils.RemoveElement(parentsDir, 0), destDir, r, size, contentFile, uid, gid)
+	}
 
-					// Actualizar el superbloque
-					sb.S_blocks_count++
-					sb.S_free_blocks_count--
-					sb.S_first_blo += sb.S_block_size
+	fmt.Println("---------ESTOY  CREANDO--------")
 
-				}
-				if len(contentBlocks) == 0 {
-					// crear un bloque de archivo vacío
-					fileBlock := &FileBlock{
-						B_content: [64]byte{},
-					}
+	// Validar el nombre antes de reservar nada
+	err = sb.validateEntryName(destDir)
+	if err != nil {
+		return err
+	}
 
-					fileInode.I_block[0] = sb.S_blocks_count
-					// serializar el bloque
-					err = fileBlock.Serialize(path, int64(sb.S_block_start+(sb.S_blocks_count*sb.S_block_size)))
-					if err != nil {
-						return err
-					}
+	// Reservar el inodo del archivo
+	fileInodeIndex, err := sb.AllocateInode(path)
+	if err != nil {
+		return err
+	}
 
-					// Actualizar el bitmap de bloques
-					err = sb.UpdateBitmapBlock(path)
-					if err != nil {
-						return err
-					}
+	// Agregar la entrada en la carpeta padre
+	err = sb.addFolderEntry(path, inodeIndex, inode, destDir, fileInodeIndex)
+	if err != nil {
+		return err
+	}
 
-					// Actualizar el superbloque
-					sb.S_blocks_count++
-					sb.S_free_blocks_count--
-					sb.S_first_blo += sb.S_block_size
-				}
-				// Serializar el inodo del archivo
-				err = fileInode.Serialize(path, int64(sb.S_first_ino))
-				if err != nil {
-					return err
-				}
+	content := ""
+	if contentFile != "" {
+		content = contentFile
+	} else {
+		// llenar el contenido con una cadena de numeros del 0 al 9 cuantas veces sea el tamaño
+		for i := 0; i < size; i++ {
+			content += string(rune(i%10 + '0'))
+		}
+	}
+	fmt.Println("Contenido del archivo: ", content)
 
-				// Actualizar el bitmap de inodos
-				err = sb.UpdateBitmapInode(path)
-				if err != nil {
-					return err
-				}
+	// Crear el inodo del archivo
+	fileInode := &Inode{
+		I_uid:   uid,
+		I_gid:   gid,
+		I_size:  int32(len(content)),
+		I_atime: float32(time.Now().Unix()),
+		I_ctime: float32(time.Now().Unix()),
+		I_mtime: float32(time.Now().Unix()),
+		I_block: [15]int32{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
+		I_type:  [1]byte{'1'},
+		I_perm:  [3]byte{'6', '6', '4'},
+		I_links: 1,
+	}
 
-				// Actualizar el superbloque
-				sb.S_inodes_count++
-				sb.S_free_inodes_count--
-				sb.S_first_ino += sb.S_inode_size
-				fmt.Println("contenido del archivo para añadir al bloque: ", contentFile)
+	// Escribir el contenido en bloques nuevos
+	err = sb.writeInodeContent(path, fileInode, content)
+	if err != nil {
+		return err
+	}
 
-				return nil
-			}
-		}
+	// Serializar el inodo del archivo
+	err = fileInode.Serialize(path, int64(sb.S_inode_start+(fileInodeIndex*sb.S_inode_size)))
+	if err != nil {
+		return err
 	}
+	fmt.Println("contenido del archivo para añadir al bloque: ", contentFile)
+
 	return nil
 }
 
@@ -556,19 +189,19 @@ func (sb *SuperBlock) folderExists(path string, inodeIndex int32, parentsDir []s
 		return false, err
 	}
 	// Verificar si el inodo es de tipo carpeta
-	if inode.I_type[0] == '1' {
+	if inode.I_type[0] != '0' {
 		return false, nil
 	}
 
 	// Iterar sobre cada bloque del inodo (apuntadores)
-	for _, blockIndex := range inode.I_block {
-		// Si el bloque no existe, salir
-		if blockIndex == -1 {
-			break
-		}
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return false, err
+	}
+	for _, blockIndex := range dataBlocks {
 
 		// Crear un nuevo bloque de carpeta
-		block := &FolderBlock{}
+		block := sb.NewFolderBlock()
 
 		// Deserializar el bloque
 		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
@@ -584,7 +217,7 @@ func (sb *SuperBlock) folderExists(path string, inodeIndex int32, parentsDir []s
 
 			if len(parentsDir) != 0 {
 				if content.B_inodo == -1 {
-					break
+					continue
 				}
 				// Obtenemos la carpeta padre más cercana
 				parentDir, err := utils.First(parentsDir)
@@ -593,7 +226,11 @@ func (sb *SuperBlock) folderExists(path string, inodeIndex int32, parentsDir []s
 				}
 
 				// Convertir B_name a string y eliminar los caracteres nulos
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return false, err
+				}
+				contentName = strings.Trim(contentName, "\x00 ")
 				// Convertir parentDir a string y eliminar los caracteres nulos
 				parentDirName := strings.Trim(parentDir, "\x00 ")
 
@@ -615,7 +252,11 @@ func (sb *SuperBlock) folderExists(path string, inodeIndex int32, parentsDir []s
 				fmt.Println("Carpeta destino: ", destDir)
 
 				// convertir content.B_name a string
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
+				contentName, err := sb.EntryName(path, content)
+				if err != nil {
+					return false, err
+				}
+				contentName = strings.Trim(contentName, "\x00 ")
 				fmt.Println("Nombre de la carpeta: ", contentName)
 				if contentName == destDir {
 					fmt.Println("---------LA ENCONTRÉ-------")
@@ -627,184 +268,3 @@ func (sb *SuperBlock) folderExists(path string, inodeIndex int32, parentsDir []s
 	}
 	return false, nil
 }
-
-func (sb *SuperBlock) readFileInInode(path string, inodeIndex int32, parentsDir []string, destDir string) (string, error) {
-	// Crear un nuevo inodo
-	inode := &Inode{}
-	// Deserializar el inodo
-	err := inode.Deserialize(path, int64(sb.S_inode_start+(inodeIndex*sb.S_inode_size)))
-	if err != nil {
-		return "", err
-	}
-	// Verificar si el inodo es de tipo carpeta
-	if inode.I_type[0] == '1' {
-		return "", nil
-	}
-
-	fmt.Println("destDir: ", destDir)
-	fmt.Println("Padres: ", parentsDir)
-
-	fmt.Println("Inodo: ", inodeIndex)
-
-	// Iterar sobre cada bloque del inodo (apuntadores)
-	for _, blockIndex := range inode.I_block {
-		// Si el bloque no existe, salir
-		if blockIndex == -1 {
-			break
-		}
-
-		// Crear un nuevo bloque de carpeta
-		block := &FolderBlock{}
-
-		// Deserializar el bloque
-		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
-		if err != nil {
-			return "", err
-		}
-
-		fmt.Println("Bloque: ", blockIndex)
-		block.Print()
-
-		// Iterar sobre cada contenido del bloque, desde el index 2 porque los primeros dos son . y ..
-		for indexContent := 2; indexContent < len(block.B_content); indexContent++ {
-			// Obtener el contenido del bloque
-			content := block.B_content[indexContent]
-
-			if len(parentsDir) != 0 {
-				fmt.Println("---------ESTOY  VISITANDO--------")
-
-				if content.B_inodo == -1 {
-					break
-				}
-				// Obtenemos la carpeta padre más cercana
-				parentDir, err := utils.First(parentsDir)
-				if err != nil {
-					return "", err
-				}
-
-				// Convertir B_name a string y eliminar los caracteres nulos
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
-				// Convertir parentDir a string y eliminar los caracteres nulos
-				parentDirName := strings.Trim(parentDir, "\x00 ")
-
-				fmt.Println("Nombre del contenido: ", contentName)
-				fmt.Println("Nombre del archivo: ", destDir)
-				fmt.Println("Nombre de la carpeta padre: ", parentDirName)
-
-				// Si el nombre del contenido coincide con el nombre de la carpeta padre
-				if strings.EqualFold(contentName, parentDirName) {
-					fmt.Println("entrando a la carpeta padre")
-					fileContent, err := sb.readFileInInode(path, content.B_inodo, utils.RemoveElement(parentsDir, 0), destDir)
-					if err != nil {
-						return "", err
-					}
-					if fileContent != "" {
-						return fileContent, nil
-					}
-					return "", fmt.Errorf("no se encontró el archivo")
-				}
-			} else {
-				fmt.Println("ya no hay más carpetas padre")
-				if content.B_inodo == -1 {
-					continue
-				}
-				// convertir destDir a 12 bytes
-				destDirByte := [12]byte{}
-				copy(destDirByte[:], destDir)
-
-				if content.B_name == destDirByte {
-					fmt.Println("---------LA ENCONTRÉ-------")
-					// Si son las mismas, entonces entramos al inodo que apunta el bloque
-					inodeFile := &Inode{}
-					err := inodeFile.Deserialize(path, int64(sb.S_inode_start+(content.B_inodo*sb.S_inode_size)))
-					if err != nil {
-						return "", err
-					}
-
-					// Verificar si el inodo es de tipo archivo
-					if inodeFile.I_type[0] == '1' {
-						allContent := ""
-						// Iterar sobre cada bloque del inodo (apuntadores)
-						for _, blockIndex := range inodeFile.I_block {
-							// Si el bloque no existe, salir
-							if blockIndex == -1 {
-								break
-							}
-
-							// Crear un nuevo bloque de archivo
-							blockFile := &FileBlock{}
-
-							// Deserializar el bloque
-							err := blockFile.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
-							if err != nil {
-								return "", err
-							}
-							allContent += string(blockFile.B_content[:])
-						}
-
-						return allContent, nil
-
-					}
-					return "", fmt.Errorf("el inodo no es de tipo archivo")
-				}
-			}
-		}
-	}
-	return "", fmt.Errorf("no se encontró el archivo")
-}
-
-// getInodeFromPath busca y devuelve el inodo correspondiente a una ruta específica
-func (sb *SuperBlock) getInodeFromPath(path string, inodeIndex int32, parentsDir []string, targetName string) (int32, error) {
-	// Deserializar el inodo actual
-	inode := &Inode{}
-	err := inode.Deserialize(path, int64(sb.S_inode_start+(inodeIndex*sb.S_inode_size)))
-	if err != nil {
-		return -1, err
-	}
-
-	// Si es un archivo y no hay más padres, verificar si es el target
-	if inode.I_type[0] == '1' && len(parentsDir) == 0 {
-		return inodeIndex, nil
-	}
-
-	// Iterar sobre cada bloque del inodo
-	for _, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			continue
-		}
-
-		// Si es un directorio, buscar en los bloques de carpeta
-		if inode.I_type[0] == '0' {
-			block := &FolderBlock{}
-			err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
-			if err != nil {
-				return -1, err
-			}
-
-			// Buscar en los contenidos del bloque (empezando desde 2 para saltar . y ..)
-			for _, content := range block.B_content[2:] {
-				if content.B_inodo == -1 {
-					continue
-				}
-
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
-				if len(parentsDir) > 0 {
-					// Si hay padres por recorrer, buscar el siguiente nivel
-					parentDir := strings.Trim(parentsDir[0], "\x00 ")
-					if strings.EqualFold(contentName, parentDir) {
-						// Llamada recursiva con el siguiente nivel
-						return sb.getInodeFromPath(path, content.B_inodo, parentsDir[1:], targetName)
-					}
-				} else {
-					// Si no hay más padres, verificar si es el target
-					target := strings.Trim(targetName, "\x00 ")
-					if strings.EqualFold(contentName, target) {
-						return content.B_inodo, nil
-					}
-				}
-			}
-		}
-	}
-
-	return -1, fmt.Errorf("no se encontró el inodo para '%s'", targetName)
-}
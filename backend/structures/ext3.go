@@ -2,15 +2,21 @@ package structures
 
 import (
 	"backend/utils"
-	"fmt"
-	"strings"
 	"time"
 )
 
 // Crear users.txt en nuestro sistema de archivos
-func (sb *SuperBlock) CreateUsersFileExt3(path string, journauling_start int64) error {
+func (sb *SuperBlock) CreateUsersFileExt3(path string) error {
 	// ----------- Creamos / -----------
-	// Crear Journal
+	// Reservar el inodo raíz y su bloque
+	rootInodeIndex, err := sb.AllocateInode(path)
+	if err != nil {
+		return err
+	}
+	rootBlockIndex, err := sb.AllocateBlock(path)
+	if err != nil {
+		return err
+	}
 
 	// Creamos el inodo raíz
 	rootInode := &Inode{
@@ -20,68 +26,14 @@ func (sb *SuperBlock) CreateUsersFileExt3(path string, journauling_start int64)
 		I_atime: float32(time.Now().Unix()),
 		I_ctime: float32(time.Now().Unix()),
 		I_mtime: float32(time.Now().Unix()),
-		I_block: [15]int32{sb.S_blocks_count, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
+		I_block: [15]int32{rootBlockIndex, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
 		I_type:  [1]byte{'0'},
 		I_perm:  [3]byte{'7', '7', '7'},
+		I_links: 1,
 	}
 
 	// Serializar el inodo raíz
-	err := rootInode.Serialize(path, int64(sb.S_first_ino))
-	if err != nil {
-		return err
-	}
-
-	// Actualizar el bitmap de inodos
-	err = sb.UpdateBitmapInode(path)
-	if err != nil {
-		return err
-	}
-
-	// Actualizar el superbloque
-	sb.S_inodes_count++
-	sb.S_free_inodes_count--
-	sb.S_first_ino += sb.S_inode_size
-
-	// Creamos el bloque del Inodo Raíz
-	rootBlock := &FolderBlock{
-		B_content: [4]FolderContent{
-			{B_name: [12]byte{'.'}, B_inodo: 0},
-			{B_name: [12]byte{'.', '.'}, B_inodo: 0},
-			{B_name: [12]byte{'-'}, B_inodo: -1},
-			{B_name: [12]byte{'-'}, B_inodo: -1},
-		},
-	}
-
-	// Actualizar el bitmap de bloques
-	err = sb.UpdateBitmapBlock(path)
-	if err != nil {
-		return err
-	}
-
-	// Serializar el bloque de carpeta raíz
-	err = rootBlock.Serialize(path, int64(sb.S_first_blo))
-	if err != nil {
-		return err
-	}
-
-	// Actualizar el superbloque
-	sb.S_blocks_count++
-	sb.S_free_blocks_count--
-	sb.S_first_blo += sb.S_block_size
-
-	journal := &Journal{
-		J_count: sb.S_inodes_count,
-		J_content: Information{
-			I_operation: [10]byte{'m', 'k', 'd', 'i', 'r'},
-			I_path:      [32]byte{'/'},
-			I_content:   [64]byte{},
-			I_date:      float32(time.Now().Unix()),
-		},
-	}
-	fmt.Println("Journal:")
-	journal.Print()
-	// Serializar el journal
-	err = journal.Serialize(path, journauling_start)
+	err = rootInode.Serialize(path, int64(sb.S_inode_start+(rootInodeIndex*sb.S_inode_size)))
 	if err != nil {
 		return err
 	}
@@ -89,32 +41,21 @@ func (sb *SuperBlock) CreateUsersFileExt3(path string, journauling_start int64)
 	// ----------- Creamos /users.txt -----------
 	usersText := "1,G,root\n1,U,root,root,123\n"
 
-	// Deserializar el inodo raíz
-	err = rootInode.Deserialize(path, int64(sb.S_inode_start+0)) // 0 porque es el inodo raíz
+	// Reservar el inodo de users.txt
+	usersInodeIndex, err := sb.AllocateInode(path)
 	if err != nil {
 		return err
 	}
 
-	// Actualizamos el inodo raíz
-	rootInode.I_atime = float32(time.Now().Unix())
-
-	// Serializar el inodo raíz
-	err = rootInode.Serialize(path, int64(sb.S_inode_start+0)) // 0 porque es el inodo raíz
-	if err != nil {
-		return err
-	}
-
-	// Deserializar el bloque de carpeta raíz
-	err = rootBlock.Deserialize(path, int64(sb.S_block_start+0)) // 0 porque es el bloque de carpeta raíz
-	if err != nil {
-		return err
-	}
-
-	// Actualizamos el bloque de carpeta raíz
-	rootBlock.B_content[2] = FolderContent{B_name: [12]byte{'u', 's', 'e', 'r', 's', '.', 't', 'x', 't'}, B_inodo: sb.S_inodes_count}
+	// Creamos el bloque del Inodo Raíz
+	rootBlock := sb.folderBlockWith(
+		FolderContent{B_name: [12]byte{'.'}, B_inodo: rootInodeIndex},
+		FolderContent{B_name: [12]byte{'.', '.'}, B_inodo: rootInodeIndex},
+		FolderContent{B_name: [12]byte{'u', 's', 'e', 'r', 's', '.', 't', 'x', 't'}, B_inodo: usersInodeIndex},
+	)
 
 	// Serializar el bloque de carpeta raíz
-	err = rootBlock.Serialize(path, int64(sb.S_block_start+0)) // 0 porque es el bloque de carpeta raíz
+	err = rootBlock.Serialize(path, int64(sb.S_block_start+(rootBlockIndex*sb.S_block_size)))
 	if err != nil {
 		return err
 	}
@@ -127,76 +68,20 @@ func (sb *SuperBlock) CreateUsersFileExt3(path string, journauling_start int64)
 		I_atime: float32(time.Now().Unix()),
 		I_ctime: float32(time.Now().Unix()),
 		I_mtime: float32(time.Now().Unix()),
-		I_block: [15]int32{sb.S_blocks_count, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
+		I_block: [15]int32{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
 		I_type:  [1]byte{'1'},
 		I_perm:  [3]byte{'7', '7', '7'},
+		I_links: 1,
 	}
 
-	// Actualizar el bitmap de inodos
-	err = sb.UpdateBitmapInode(path)
+	// Escribir el contenido de users.txt
+	err = sb.writeInodeContent(path, usersInode, usersText)
 	if err != nil {
 		return err
 	}
 
 	// Serializar el inodo users.txt
-	err = usersInode.Serialize(path, int64(sb.S_first_ino))
-	if err != nil {
-		return err
-	}
-
-	// Actualizamos el superbloque
-	sb.S_inodes_count++
-	sb.S_free_inodes_count--
-	sb.S_first_ino += sb.S_inode_size
-
-	// Crear Journal
-	journalFile := &Journal{
-		J_count: sb.S_inodes_count,
-		J_content: Information{
-			I_operation: [10]byte{'m', 'k', 'f', 'i', 'l', 'e'},
-			I_path:      [32]byte{'/', 'u', 's', 'e', 'r', 's', '.', 't', 'x', 't'},
-			I_content:   [64]byte{},
-			I_date:      float32(time.Now().Unix()),
-		},
-	}
-	// Copiamos el texto de usuarios en el journal
-	copy(journalFile.J_content.I_content[:], usersText)
-	fmt.Println("Journal:")
-	journalFile.Print()
-
-	fmt.Println("serializando en:", journauling_start)
-
-	// Serializar el journal
-	err = journalFile.Serialize(path, journauling_start)
-	if err != nil {
-		return err
-	}
-
-	// Creamos el bloque de users.txt
-	usersBlock := &FileBlock{
-		B_content: [64]byte{},
-	}
-	// Copiamos el texto de usuarios en el bloque
-	copy(usersBlock.B_content[:], usersText)
-
-	// Serializar el bloque de users.txt
-	err = usersBlock.Serialize(path, int64(sb.S_first_blo))
-	if err != nil {
-		return err
-	}
-
-	// Actualizar el bitmap de bloques
-	err = sb.UpdateBitmapBlock(path)
-	if err != nil {
-		return err
-	}
-
-	// Actualizamos el superbloque
-	sb.S_blocks_count++
-	sb.S_free_blocks_count--
-	sb.S_first_blo += sb.S_block_size
-
-	return nil
+	return usersInode.Serialize(path, int64(sb.S_inode_start+(usersInodeIndex*sb.S_inode_size)))
 }
 
 // createFolderInInode crea una carpeta en un inodo específico
@@ -209,146 +94,78 @@ func (sb *SuperBlock) createFolderInInodeExt3(path string, inodeIndex int32, par
 		return err
 	}
 	// Verificar si el inodo es de tipo carpeta
-	if inode.I_type[0] == '1' {
+	if inode.I_type[0] != '0' {
 		return nil
 	}
 
-	// Iterar sobre cada bloque del inodo (apuntadores)
-	for _, blockIndex := range inode.I_block {
-		// Si el bloque no existe, salir
-		if blockIndex == -1 {
-			break
+	// Sí las carpetas padre no están vacías debereamos buscar la carpeta padre más cercana
+	if len(parentsDir) != 0 {
+		// Obtenemos la carpeta padre más cercana
+		parentDir, err := utils.First(parentsDir)
+		if err != nil {
+			return err
 		}
 
-		// Crear un nuevo bloque de carpeta
-		block := &FolderBlock{}
-
-		// Deserializar el bloque
-		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
+		childIndex, err := sb.findFolderEntry(path, inode, parentDir)
 		if err != nil {
 			return err
 		}
+		if childIndex == -1 {
+			return nil
+		}
 
-		// Iterar sobre cada contenido del bloque, desde el index 2 porque los primeros dos son . y ..
-		for indexContent := 2; indexContent < len(block.B_content); indexContent++ {
-			// Obtener el contenido del bloque
-			content := block.B_content[indexContent]
-
-			// Sí las carpetas padre no están vacías debereamos buscar la carpeta padre más cercana
-			if len(parentsDir) != 0 {
-				//fmt.Println("---------ESTOY  VISITANDO--------")
-
-				// Si el contenido está vacío, salir
-				if content.B_inodo == -1 {
-					break
-				}
-
-				// Obtenemos la carpeta padre más cercana
-				parentDir, err := utils.First(parentsDir)
-				if err != nil {
-					return err
-				}
-
-				// Convertir B_name a string y eliminar los caracteres nulos
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
-				// Convertir parentDir a string y eliminar los caracteres nulos
-				parentDirName := strings.Trim(parentDir, "\x00 ")
-				// Si el nombre del contenido coincide con el nombre de la carpeta padre
-				if strings.EqualFold(contentName, parentDirName) {
-					//fmt.Println("---------LA ENCONTRÉ-------")
-					// Si son las mismas, entonces entramos al inodo que apunta el bloque
-					err := sb.createFolderInInodeExt3(path, content.B_inodo, utils.RemoveElement(parentsDir, 0), destDir)
-					if err != nil {
-						return err
-					}
-					return nil
-				}
-			} else {
-				//fmt.Println("---------ESTOY  CREANDO--------")
-
-				// Si el apuntador al inodo está ocupado, continuar con el siguiente
-				if content.B_inodo != -1 {
-					continue
-				}
-
-				// Actualizar el contenido del bloque
-				copy(content.B_name[:], destDir)
-				content.B_inodo = sb.S_inodes_count
-
-				// Actualizar el bloque
-				block.B_content[indexContent] = content
-
-				// Serializar el bloque
-				err = block.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
-				if err != nil {
-					return err
-				}
-
-				// HACER EL JOURNALING
-
-				// Crear el inodo de la carpeta
-				folderInode := &Inode{
-					I_uid:   1,
-					I_gid:   1,
-					I_size:  0,
-					I_atime: float32(time.Now().Unix()),
-					I_ctime: float32(time.Now().Unix()),
-					I_mtime: float32(time.Now().Unix()),
-					I_block: [15]int32{sb.S_blocks_count, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
-					I_type:  [1]byte{'0'},
-					I_perm:  [3]byte{'6', '6', '4'},
-				}
-
-				// Serializar el inodo de la carpeta
-				err = folderInode.Serialize(path, int64(sb.S_first_ino))
-				if err != nil {
-					return err
-				}
-
-				// Actualizar el bitmap de inodos
-				err = sb.UpdateBitmapInode(path)
-				if err != nil {
-					return err
-				}
-
-				// Actualizar el superbloque
-				sb.S_inodes_count++
-				sb.S_free_inodes_count--
-				sb.S_first_ino += sb.S_inode_size
+		// Si son las mismas, entonces entramos al inodo que apunta el bloque
+		return sb.createFolderInInodeExt3(path, childIndex, utils.RemoveElement(parentsDir, 0), destDir)
+	}
 
-				// TODO: Ponen su Journal
+	// Validar el nombre antes de reservar nada
+	err = sb.validateEntryName(destDir)
+	if err != nil {
+		return err
+	}
 
-				// Crear el bloque de la carpeta
-				folderBlock := &FolderBlock{
-					B_content: [4]FolderContent{
-						{B_name: [12]byte{'.'}, B_inodo: content.B_inodo},
-						{B_name: [12]byte{'.', '.'}, B_inodo: inodeIndex},
-						{B_name: [12]byte{'-'}, B_inodo: -1},
-						{B_name: [12]byte{'-'}, B_inodo: -1},
-					},
-				}
+	// Reservar el inodo de la carpeta y su bloque
+	folderInodeIndex, err := sb.AllocateInode(path)
+	if err != nil {
+		return err
+	}
+	folderBlockIndex, err := sb.AllocateBlock(path)
+	if err != nil {
+		return err
+	}
 
-				// Serializar el bloque de la carpeta
-				err = folderBlock.Serialize(path, int64(sb.S_first_blo))
-				if err != nil {
-					return err
-				}
+	// Agregar la entrada en la carpeta padre
+	err = sb.addFolderEntry(path, inodeIndex, inode, destDir, folderInodeIndex)
+	if err != nil {
+		return err
+	}
 
-				// Actualizar el bitmap de bloques
-				err = sb.UpdateBitmapBlock(path)
-				if err != nil {
-					return err
-				}
+	// Crear el inodo de la carpeta
+	folderInode := &Inode{
+		I_uid:   1,
+		I_gid:   1,
+		I_size:  0,
+		I_atime: float32(time.Now().Unix()),
+		I_ctime: float32(time.Now().Unix()),
+		I_mtime: float32(time.Now().Unix()),
+		I_block: [15]int32{folderBlockIndex, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
+		I_type:  [1]byte{'0'},
+		I_perm:  [3]byte{'6', '6', '4'},
+		I_links: 1,
+	}
 
-				// Actualizar el superbloque
-				sb.S_blocks_count++
-				sb.S_free_blocks_count--
-				sb.S_first_blo += sb.S_block_size
+	// Serializar el inodo de la carpeta
+	err = folderInode.Serialize(path, int64(sb.S_inode_start+(folderInodeIndex*sb.S_inode_size)))
+	if err != nil {
+		return err
+	}
 
-				return nil
-			}
-		}
+	// Crear el bloque de la carpeta
+	folderBlock := sb.folderBlockWith(
+		FolderContent{B_name: [12]byte{'.'}, B_inodo: folderInodeIndex},
+		FolderContent{B_name: [12]byte{'.', '.'}, B_inodo: inodeIndex},
+	)
 
-	}
-	return nil
+	// Serializar el bloque de la carpeta
+	return folderBlock.Serialize(path, int64(sb.S_block_start+(folderBlockIndex*sb.S_block_size)))
 }
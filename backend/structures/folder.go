@@ -2,13 +2,14 @@ package structures
 
 import (
 	utils "backend/utils"
+	"encoding/binary"
 	"fmt"
 	"strings"
 	"time"
 )
 
 // createFolderInode crea una carpeta en un inodo específico
-func (sb *SuperBlock) createFolderInode(path string, inodeIndex int32, parentsDir []string, destDir string, uid int32, gid int32, folderPath string, journalStart int64) error {
+func (sb *SuperBlock) createFolderInode(path string, inodeIndex int32, parentsDir []string, destDir string, uid int32, gid int32) error {
 	// Crear un nuevo inodo
 	inode := &Inode{}
 	// Deserializar el inodo
@@ -17,541 +18,308 @@ func (sb *SuperBlock) createFolderInode(path string, inodeIndex int32, parentsDi
 		return err
 	}
 	// Verificar si el inodo es de tipo carpeta
-	if inode.I_type[0] == '1' {
+	if inode.I_type[0] != '0' {
 		return nil
 	}
 
-	// Iterar sobre cada bloque del inodo (apuntadores)
-	for i, blockIndex := range inode.I_block {
-		// Si el bloque no existe, salir
-		if i > 14 {
-			fmt.Println("Ya no hay más espacio en el inodo")
-			break
+	// Sí las carpetas padre no están vacías debemos buscar la carpeta padre más cercana
+	if len(parentsDir) != 0 {
+		fmt.Println("---------ESTOY  VISITANDO--------")
+
+		// Obtenemos la carpeta padre más cercana
+		parentDir, err := utils.First(parentsDir)
+		if err != nil {
+			return err
 		}
-		if blockIndex == -1 {
-			if blockIndex == -1 {
-				fmt.Println("Ya no hay más bloques, creando uno nuevo")
-				fmt.Println("i:", i)
-
-				// 1. Crear y serializar el nuevo bloque de carpeta
-				newBlock := &FolderBlock{
-					B_content: [4]FolderContent{
-						{B_name: [12]byte{'.'}, B_inodo: inodeIndex},
-						{B_name: [12]byte{'.', '.'}, B_inodo: inodeIndex},
-						{B_name: [12]byte{'-'}, B_inodo: -1},
-						{B_name: [12]byte{'-'}, B_inodo: -1},
-					},
-				}
-
-				// Actualizar contenido del bloque
-				destDirByte := [12]byte{}
-				copy(destDirByte[:], destDir)
-				newBlock.B_content[2] = FolderContent{B_name: destDirByte, B_inodo: sb.S_inodes_count}
-
-				// Guardar posición del nuevo bloque
-				newBlockPos := sb.S_blocks_count
-
-				// Serializar el nuevo bloque
-				err = newBlock.Serialize(path, int64(sb.S_block_start+(newBlockPos*sb.S_block_size)))
-				if err != nil {
-					return err
-				}
-
-				// Actualizar bitmap de bloques
-				err = sb.UpdateBitmapBlock(path)
-				if err != nil {
-					return err
-				}
-
-				// Actualizar superbloque (nuevo bloque)
-				sb.S_blocks_count++
-				sb.S_free_blocks_count--
-				sb.S_first_blo += sb.S_block_size
-
-				if sb.S_filesystem_type == 3 {
-					// Jornaling
-					// Crear el journal
-					bytePath := [32]byte{}
-					copy(bytePath[:], folderPath)
-					journal := &Journal{
-						J_count: sb.S_inodes_count,
-						J_content: Information{
-							I_operation: [10]byte{'m', 'k', 'd', 'i', 'r'},
-							I_path:      bytePath,
-							I_content:   [64]byte{},
-							I_date:      float32(time.Now().Unix()),
-						},
-					}
-					fmt.Println("Journal:")
-					journal.Print()
-					// Serializar el journal
-					err = journal.Serialize(path, journalStart)
-					if err != nil {
-						return err
-					}
-				}
-
-				// 2. Crear el inodo de la nueva carpeta
-				folderInode := &Inode{
-					I_uid:   uid,
-					I_gid:   gid,
-					I_size:  0,
-					I_atime: float32(time.Now().Unix()),
-					I_ctime: float32(time.Now().Unix()),
-					I_mtime: float32(time.Now().Unix()),
-					I_block: [15]int32{sb.S_blocks_count, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
-					I_type:  [1]byte{'0'},
-					I_perm:  [3]byte{'6', '6', '4'},
-				}
-
-				// Serializar el nuevo inodo
-				err = folderInode.Serialize(path, int64(sb.S_first_ino))
-				if err != nil {
-					return err
-				}
-
-				// Actualizar bitmap de inodos
-				err = sb.UpdateBitmapInode(path)
-				if err != nil {
-					return err
-				}
-
-				// Actualizar superbloque (nuevo inodo)
-				sb.S_inodes_count++
-				sb.S_free_inodes_count--
-				sb.S_first_ino += sb.S_inode_size
-
-				// 3. Crear bloque para el nuevo inodo
-				folderBlock := &FolderBlock{
-					B_content: [4]FolderContent{
-						{B_name: [12]byte{'.'}, B_inodo: sb.S_inodes_count - 1}, // Apunta a sí mismo
-						{B_name: [12]byte{'.', '.'}, B_inodo: inodeIndex},       // Apunta al padre
-						{B_name: [12]byte{'-'}, B_inodo: -1},
-						{B_name: [12]byte{'-'}, B_inodo: -1},
-					},
-				}
-
-				// Serializar el bloque de la nueva carpeta
-				err = folderBlock.Serialize(path, int64(sb.S_block_start+(sb.S_blocks_count*sb.S_block_size)))
-				if err != nil {
-					return err
-				}
-
-				// Actualizar bitmap de bloques
-				err = sb.UpdateBitmapBlock(path)
-				if err != nil {
-					return err
-				}
-				sb.S_blocks_count++
-				sb.S_free_blocks_count--
-				sb.S_first_blo += sb.S_block_size
-
-				// 4. Manejar apuntadores indirectos si es necesario
-				if i >= 12 { // Bloques indirectos
-					var pointerBlock *PointerBlock
-					pointerPos := sb.S_blocks_count
-
-					switch i {
-					case 12: // Indirecto simple
-						fmt.Println("Creando bloque de apuntadores indirecto simple")
-						pointerBlock = &PointerBlock{
-							P_pointers: [16]int32{
-								newBlockPos, // Primer bloque que creamos
-								-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1,
-							},
-						}
-
-					case 13: // Indirecto doble
-						fmt.Println("Creando bloque de apuntadores indirecto doble")
-						// Implementar lógica similar para doble indirecto
-						return fmt.Errorf("apuntadores indirectos dobles no implementados aún")
-
-					case 14: // Indirecto triple
-						fmt.Println("Creando bloque de apuntadores indirecto triple")
-						// Implementar lógica similar para triple indirecto
-						return fmt.Errorf("apuntadores indirectos triples no implementados aún")
-					}
-
-					// Serializar el bloque de apuntadores
-					err = pointerBlock.Serialize(path, int64(sb.S_block_start+(pointerPos*sb.S_block_size)))
-					if err != nil {
-						return err
-					}
-
-					// Actualizar bitmap de bloques
-					err = sb.UpdateBitmapBlock(path)
-					if err != nil {
-						return err
-					}
-
-					// Actualizar superbloque (bloque de apuntadores)
-					sb.S_blocks_count++
-					sb.S_free_blocks_count--
-					sb.S_first_blo += sb.S_block_size
-
-					// Actualizar inodo con referencia al bloque de apuntadores
-					inode.I_block[i] = pointerPos
-				} else {
-					// Para bloques directos, simplemente actualizar la referencia
-					inode.I_block[i] = newBlockPos
-				}
-
-				// Serializar el inodo actualizado
-				err = inode.Serialize(path, int64(sb.S_inode_start+(inodeIndex*sb.S_inode_size)))
-				if err != nil {
-					return err
-				}
-
-				return nil
-			}
+
+		childIndex, err := sb.findFolderEntry(path, inode, parentDir)
+		if err != nil {
+			return err
+		}
+		if childIndex == -1 {
+			return nil
 		}
 
-		// Crear un nuevo bloque de carpeta
-		block := &FolderBlock{}
+		// Entramos al inodo que apunta la entrada
+		return sb.createFolderInode(path, childIndex, utils.RemoveElement(parentsDir, 0), destDir, uid, gid)
+	}
+
+	fmt.Println("---------ESTOY  CREANDO--------")
+
+	// Validar el nombre antes de reservar nada
+	err = sb.validateEntryName(destDir)
+	if err != nil {
+		return err
+	}
+
+	// Reservar el inodo de la nueva carpeta y su primer bloque
+	newInodeIndex, err := sb.AllocateInode(path)
+	if err != nil {
+		return err
+	}
+	folderBlockPos, err := sb.AllocateBlock(path)
+	if err != nil {
+		return err
+	}
+
+	// Agregar la entrada en la carpeta padre
+	err = sb.addFolderEntry(path, inodeIndex, inode, destDir, newInodeIndex)
+	if err != nil {
+		return err
+	}
+
+	// Crear el inodo de la carpeta
+	folderInode := &Inode{
+		I_uid:   uid,
+		I_gid:   gid,
+		I_size:  0,
+		I_atime: float32(time.Now().Unix()),
+		I_ctime: float32(time.Now().Unix()),
+		I_mtime: float32(time.Now().Unix()),
+		I_block: [15]int32{folderBlockPos, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
+		I_type:  [1]byte{'0'},
+		I_perm:  [3]byte{'6', '6', '4'},
+		I_links: 1,
+	}
+
+	// Serializar el inodo de la carpeta
+	err = folderInode.Serialize(path, int64(sb.S_inode_start+(newInodeIndex*sb.S_inode_size)))
+	if err != nil {
+		return err
+	}
+
+	// Crear el bloque de la carpeta
+	folderBlock := sb.folderBlockWith(
+		FolderContent{B_name: [12]byte{'.'}, B_inodo: newInodeIndex},   // Apunta a sí mismo
+		FolderContent{B_name: [12]byte{'.', '.'}, B_inodo: inodeIndex}, // Apunta al padre
+	)
+
+	// Serializar el bloque de la carpeta
+	return folderBlock.Serialize(path, int64(sb.S_block_start+(folderBlockPos*sb.S_block_size)))
+}
+
+// findFolderEntry busca una entrada por nombre dentro de una carpeta y devuelve su inodo, o -1 si no existe
+func (sb *SuperBlock) findFolderEntry(path string, inode *Inode, name string) (int32, error) {
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return -1, err
+	}
 
-		// Deserializar el bloque
-		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size))) // 64 porque es el tamaño de un bloque
+	target := strings.Trim(name, "\x00 ")
+	for _, blockIndex := range dataBlocks {
+		block := sb.NewFolderBlock()
+		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
 		if err != nil {
-			return err
+			return -1, err
 		}
 
-		// Iterar sobre cada contenido del bloque, desde el index 2 porque los primeros dos son . y ..
+		// Desde el index 2 porque los primeros dos son . y ..
 		for indexContent := 2; indexContent < len(block.B_content); indexContent++ {
-			// Obtener el contenido del bloque
 			content := block.B_content[indexContent]
+			if content.B_inodo == -1 {
+				continue
+			}
+			contentName, err := sb.EntryName(path, content)
+			if err != nil {
+				return -1, err
+			}
+			if strings.EqualFold(strings.Trim(contentName, "\x00 "), target) {
+				return content.B_inodo, nil
+			}
+		}
+	}
 
-			// Sí las carpetas padre no están vacías debereamos buscar la carpeta padre más cercana
-			if len(parentsDir) != 0 {
-				fmt.Println("---------ESTOY  VISITANDO--------")
-
-				// Si el contenido está vacío, salir
-				if content.B_inodo == -1 {
-					break
-				}
-
-				// Obtenemos la carpeta padre más cercana
-				parentDir, err := utils.First(parentsDir)
-				if err != nil {
-					return err
-				}
-
-				// Convertir B_name a string y eliminar los caracteres nulos
-				contentName := strings.Trim(string(content.B_name[:]), "\x00 ")
-				// Convertir parentDir a string y eliminar los caracteres nulos
-				parentDirName := strings.Trim(parentDir, "\x00 ")
-				// Si el nombre del contenido coincide con el nombre de la carpeta padre
-				if strings.EqualFold(contentName, parentDirName) {
-					//fmt.Println("---------LA ENCONTRÉ-------")
-					// Si son las mismas, entonces entramos al inodo que apunta el bloque
-					err := sb.createFolderInode(path, content.B_inodo, utils.RemoveElement(parentsDir, 0), destDir, uid, gid, folderPath, journalStart)
-					if err != nil {
-						return err
-					}
-					return nil
-				}
-			} else {
-				fmt.Println("---------ESTOY  CREANDO--------")
-
-				// Si el apuntador al inodo está ocupado, continuar con el siguiente
-				if content.B_inodo != -1 {
-					fmt.Println("Ya no hay espacio en el bloque")
-					continue
-				}
-
-				if i == 12 || i == 13 || i == 14 {
-					fmt.Println("estoy en un bloque indirecto")
-					pointerBlock := &PointerBlock{}
-					err := pointerBlock.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
-					if err != nil {
-						return err
-					}
-					for j := 0; j < len(pointerBlock.P_pointers); j++ {
-						if pointerBlock.P_pointers[j] != -1 {
-							folderBlock := &FolderBlock{}
-							err := folderBlock.Deserialize(path, int64(sb.S_block_start+(pointerBlock.P_pointers[j]*sb.S_block_size)))
-							if err != nil {
-								return err
-							}
-
-							for indexContentpb := 2; indexContentpb < len(folderBlock.B_content); indexContentpb++ {
-								contentpb := folderBlock.B_content[indexContentpb]
-
-								if contentpb.B_inodo != -1 {
-									fmt.Println("Ya no hay espacio en el bloque")
-									continue
-								}
-
-								if contentpb.B_inodo == -1 {
-									// añadir el contenido al bloque
-									copy(contentpb.B_name[:], destDir)
-									contentpb.B_inodo = sb.S_inodes_count
-
-									// Actualizar el bloque
-									folderBlock.B_content[indexContentpb] = contentpb
-									// Serializar el bloque
-									err = folderBlock.Serialize(path, int64(sb.S_block_start+(pointerBlock.P_pointers[j]*sb.S_block_size)))
-									if err != nil {
-										return err
-									}
-
-									// crear el inodo de la carpeta
-									folderInode := &Inode{
-										I_uid:   uid,
-										I_gid:   gid,
-										I_size:  0,
-										I_atime: float32(time.Now().Unix()),
-										I_ctime: float32(time.Now().Unix()),
-										I_mtime: float32(time.Now().Unix()),
-										I_block: [15]int32{sb.S_blocks_count, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
-										I_type:  [1]byte{'0'},
-										I_perm:  [3]byte{'6', '6', '4'},
-									}
-
-									// Serializar el inodo de la carpeta
-									err = folderInode.Serialize(path, int64(sb.S_first_ino))
-									if err != nil {
-										return err
-									}
-
-									// Actualizar el bitmap de inodos
-									err = sb.UpdateBitmapInode(path)
-									if err != nil {
-										return err
-									}
-
-									// Actualizar el superbloque
-									sb.S_inodes_count++
-									sb.S_free_inodes_count--
-									sb.S_first_ino += sb.S_inode_size
-
-									// Crear el bloque de la carpeta
-									newFolderBlock := &FolderBlock{
-										B_content: [4]FolderContent{
-											{B_name: [12]byte{'.'}, B_inodo: contentpb.B_inodo},
-											{B_name: [12]byte{'.', '.'}, B_inodo: content.B_inodo},
-											{B_name: [12]byte{'-'}, B_inodo: -1},
-											{B_name: [12]byte{'-'}, B_inodo: -1},
-										},
-									}
-									// Serializar el bloque de la carpeta
-									err = newFolderBlock.Serialize(path, int64(sb.S_first_blo))
-									if err != nil {
-										return err
-									}
-
-									// Actualizar el bitmap de bloques
-									err = sb.UpdateBitmapBlock(path)
-									if err != nil {
-										return err
-									}
-
-									// Actualizar el superbloque
-									sb.S_blocks_count++
-									sb.S_free_blocks_count--
-									sb.S_first_blo += sb.S_block_size
-
-									return nil
-								}
-							}
-						} else {
-							// se necesita crear otro bloque para el bloque de apuntadores
-							newBlock := &FolderBlock{
-								B_content: [4]FolderContent{
-									{B_name: [12]byte{'.'}, B_inodo: inodeIndex},
-									{B_name: [12]byte{'.', '.'}, B_inodo: inodeIndex},
-									{B_name: [12]byte{'-'}, B_inodo: -1},
-									{B_name: [12]byte{'-'}, B_inodo: -1},
-								},
-							}
-
-							destDirByte := [12]byte{}
-							copy(destDirByte[:], destDir)
-							newBlock.B_content[2] = FolderContent{B_name: destDirByte, B_inodo: sb.S_inodes_count}
-							// Guardar posición del nuevo bloque
-							newBlockPos := sb.S_blocks_count
-							// Serializar el nuevo bloque
-							err = newBlock.Serialize(path, int64(sb.S_block_start+(newBlockPos*sb.S_block_size)))
-							if err != nil {
-								return err
-							}
-							// Actualizar bitmap de bloques
-							err = sb.UpdateBitmapBlock(path)
-							if err != nil {
-								return err
-							}
-
-							// Actualizar superbloque (nuevo bloque)
-							sb.S_blocks_count++
-							sb.S_free_blocks_count--
-							sb.S_first_blo += sb.S_block_size
-
-							// 2. Crear el inodo de la nueva carpeta
-							folderInode := &Inode{
-								I_uid:   uid,
-								I_gid:   gid,
-								I_size:  0,
-								I_atime: float32(time.Now().Unix()),
-								I_ctime: float32(time.Now().Unix()),
-								I_mtime: float32(time.Now().Unix()),
-								I_block: [15]int32{sb.S_blocks_count, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
-								I_type:  [1]byte{'0'},
-								I_perm:  [3]byte{'6', '6', '4'},
-							}
-
-							// Serializar el nuevo inodo
-							err = folderInode.Serialize(path, int64(sb.S_first_ino))
-							if err != nil {
-								return err
-							}
-
-							// Actualizar bitmap de inodos
-							err = sb.UpdateBitmapInode(path)
-							if err != nil {
-								return err
-							}
-
-							// Actualizar superbloque (nuevo inodo)
-							sb.S_inodes_count++
-							sb.S_free_inodes_count--
-							sb.S_first_ino += sb.S_inode_size
-
-							// 3. Crear bloque para el nuevo inodo
-							folderBlock := &FolderBlock{
-								B_content: [4]FolderContent{
-									{B_name: [12]byte{'.'}, B_inodo: sb.S_inodes_count - 1}, // Apunta a sí mismo
-									{B_name: [12]byte{'.', '.'}, B_inodo: inodeIndex},       // Apunta al padre
-									{B_name: [12]byte{'-'}, B_inodo: -1},
-									{B_name: [12]byte{'-'}, B_inodo: -1},
-								},
-							}
-
-							// Serializar el bloque de la nueva carpeta
-							err = folderBlock.Serialize(path, int64(sb.S_block_start+(sb.S_blocks_count*sb.S_block_size)))
-							if err != nil {
-								return err
-							}
-
-							// Actualizar bitmap de bloques
-							err = sb.UpdateBitmapBlock(path)
-							if err != nil {
-								return err
-							}
-							sb.S_blocks_count++
-							sb.S_free_blocks_count--
-							sb.S_first_blo += sb.S_block_size
-
-							// actualizar el bloque de apuntadores
-							pointerBlock.P_pointers[j] = newBlockPos
-							// Serializar el bloque de apuntadores
-							err = pointerBlock.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
-							if err != nil {
-								return err
-							}
-							fmt.Println("Actualizando el bloque de apuntadores")
-							pointerBlock.Print()
-							return nil
-						}
-					}
-				}
-
-				// Actualizar el contenido del bloque
-				copy(content.B_name[:], destDir)
-				content.B_inodo = sb.S_inodes_count
-
-				// Actualizar el bloque
-				block.B_content[indexContent] = content
-
-				// Serializar el bloque
-				err = block.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
-				if err != nil {
-					return err
-				}
-				fmt.Println("Tipoe de sistema de archivos:", sb.S_filesystem_type)
-				fmt.Println(sb.S_filesystem_type == 3)
-				if sb.S_filesystem_type == 3 {
-					// Jornaling
-					// Crear el journal
-					bytePath := [32]byte{}
-					copy(bytePath[:], folderPath)
-					journal := &Journal{
-						J_count: sb.S_inodes_count,
-						J_content: Information{
-							I_operation: [10]byte{'m', 'k', 'd', 'i', 'r'},
-							I_path:      bytePath,
-							I_content:   [64]byte{},
-							I_date:      float32(time.Now().Unix()),
-						},
-					}
-					fmt.Println("Journal:")
-					journal.Print()
-
-					// Serializar el journal
-					err = journal.Serialize(path, journalStart)
-					if err != nil {
-						return err
-					}
-				}
-				// Crear el inodo de la carpeta
-				folderInode := &Inode{
-					I_uid:   uid,
-					I_gid:   gid,
-					I_size:  0,
-					I_atime: float32(time.Now().Unix()),
-					I_ctime: float32(time.Now().Unix()),
-					I_mtime: float32(time.Now().Unix()),
-					I_block: [15]int32{sb.S_blocks_count, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
-					I_type:  [1]byte{'0'},
-					I_perm:  [3]byte{'6', '6', '4'},
-				}
-
-				// Serializar el inodo de la carpeta
-				err = folderInode.Serialize(path, int64(sb.S_first_ino))
-				if err != nil {
-					return err
-				}
-
-				// Actualizar el bitmap de inodos
-				err = sb.UpdateBitmapInode(path)
-				if err != nil {
-					return err
-				}
-
-				// Actualizar el superbloque
-				sb.S_inodes_count++
-				sb.S_free_inodes_count--
-				sb.S_first_ino += sb.S_inode_size
-
-				// Crear el bloque de la carpeta
-				folderBlock := &FolderBlock{
-					B_content: [4]FolderContent{
-						{B_name: [12]byte{'.'}, B_inodo: content.B_inodo},
-						{B_name: [12]byte{'.', '.'}, B_inodo: inodeIndex},
-						{B_name: [12]byte{'-'}, B_inodo: -1},
-						{B_name: [12]byte{'-'}, B_inodo: -1},
-					},
-				}
-
-				// Serializar el bloque de la carpeta
-				err = folderBlock.Serialize(path, int64(sb.S_first_blo))
-				if err != nil {
-					return err
-				}
-
-				// Actualizar el bitmap de bloques
-				err = sb.UpdateBitmapBlock(path)
-				if err != nil {
-					return err
-				}
-
-				// Actualizar el superbloque
-				sb.S_blocks_count++
-				sb.S_free_blocks_count--
-				sb.S_first_blo += sb.S_block_size
-
-				return nil
+	return -1, nil
+}
+
+// addFolderEntry agrega la entrada name -> childIndex a la carpeta inodeIndex.
+// Usa el primer espacio libre; si no hay, crea un nuevo bloque de carpeta.
+func (sb *SuperBlock) addFolderEntry(path string, inodeIndex int32, inode *Inode, name string, childIndex int32) error {
+	nameBytes, err := sb.encodeEntryName(path, name)
+	if err != nil {
+		return err
+	}
+	entry := FolderContent{B_name: nameBytes, B_inodo: childIndex}
+
+	// Buscar un espacio libre en los bloques existentes
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+	parentIndex := inodeIndex
+	for i, blockIndex := range dataBlocks {
+		block := sb.NewFolderBlock()
+		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+		if err != nil {
+			return err
+		}
+		// El .. del primer bloque indica el padre que llevará también el bloque nuevo
+		if i == 0 {
+			parentIndex = block.B_content[1].B_inodo
+		}
+
+		for indexContent := 2; indexContent < len(block.B_content); indexContent++ {
+			if block.B_content[indexContent].B_inodo != -1 {
+				continue
 			}
+			block.B_content[indexContent] = entry
+			return block.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
 		}
+	}
 
+	fmt.Println("Ya no hay espacio en los bloques de la carpeta, creando uno nuevo")
+
+	// Crear un nuevo bloque de carpeta con la entrada
+	newBlockPos, err := sb.AllocateBlock(path)
+	if err != nil {
+		return err
+	}
+	newBlock := sb.folderBlockWith(
+		FolderContent{B_name: [12]byte{'.'}, B_inodo: inodeIndex},
+		FolderContent{B_name: [12]byte{'.', '.'}, B_inodo: parentIndex},
+		entry,
+	)
+	err = newBlock.Serialize(path, int64(sb.S_block_start+(newBlockPos*sb.S_block_size)))
+	if err != nil {
+		return err
+	}
+
+	// Enlazar el bloque nuevo como el siguiente bloque lógico de la carpeta
+	err = sb.setInodeBlock(path, inode, int32(len(dataBlocks)), newBlockPos)
+	if err != nil {
+		return err
+	}
+
+	// Serializar el inodo actualizado
+	return inode.Serialize(path, int64(sb.S_inode_start+(inodeIndex*sb.S_inode_size)))
+}
+
+// Los nombres de más de 12 bytes se guardan en una cadena de NameBlocks. La entrada de la carpeta
+// conserva en B_name: la marca longNameMarker, el primer bloque del nombre, su longitud y un prefijo.
+const (
+	longNameMarker  = 0xFF
+	shortNameLength = 12
+	maxNameLength   = 255
+)
+
+// EntryName devuelve el nombre completo de una entrada de carpeta, sea corto o largo
+func (sb *SuperBlock) EntryName(path string, content FolderContent) (string, error) {
+	if content.B_name[0] != longNameMarker {
+		return strings.TrimRight(string(content.B_name[:]), "\x00"), nil
+	}
+
+	blockIndex := int32(binary.LittleEndian.Uint32(content.B_name[1:5]))
+	length := int(content.B_name[5])
+
+	name := make([]byte, 0, length)
+	for blockIndex != -1 && len(name) < length {
+		nameBlock := sb.NewNameBlock()
+		err := nameBlock.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+		if err != nil {
+			return "", err
+		}
+		name = append(name, nameBlock.B_name[:]...)
+		blockIndex = nameBlock.B_next
+	}
+
+	if len(name) < length {
+		return "", fmt.Errorf("el nombre largo de la entrada del inodo %d está incompleto", content.B_inodo)
+	}
+	return string(name[:length]), nil
+}
+
+// validateEntryName verifica que el nombre se pueda guardar en este sistema de archivos
+func (sb *SuperBlock) validateEntryName(name string) error {
+	if len(name) <= shortNameLength {
+		return nil
+	}
+	if !sb.HasFeature(FeatureLongNames) {
+		return fmt.Errorf("el nombre '%s' excede %d bytes y el sistema de archivos no tiene habilitados los nombres largos", name, shortNameLength)
+	}
+	if len(name) > maxNameLength {
+		return fmt.Errorf("el nombre '%s' excede el máximo de %d bytes", name, maxNameLength)
 	}
 	return nil
 }
+
+// encodeEntryName prepara el B_name de una entrada; si el nombre es largo reserva sus NameBlocks
+func (sb *SuperBlock) encodeEntryName(path string, name string) ([12]byte, error) {
+	encoded := [12]byte{}
+	if len(name) <= shortNameLength {
+		copy(encoded[:], name)
+		return encoded, nil
+	}
+
+	err := sb.validateEntryName(name)
+	if err != nil {
+		return encoded, err
+	}
+
+	// Reservar los bloques del nombre
+	chunkSize := int(sb.S_block_size) - 4
+	blocks, err := sb.AllocateBlocks(path, int32((len(name)+chunkSize-1)/chunkSize))
+	if err != nil {
+		return encoded, err
+	}
+
+	for i, blockIndex := range blocks {
+		nameBlock := sb.NewNameBlock()
+		if i+1 < len(blocks) {
+			nameBlock.B_next = blocks[i+1]
+		}
+		copy(nameBlock.B_name, name[i*chunkSize:])
+
+		err := nameBlock.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+		if err != nil {
+			return encoded, err
+		}
+	}
+
+	encoded[0] = longNameMarker
+	binary.LittleEndian.PutUint32(encoded[1:5], uint32(blocks[0]))
+	encoded[5] = byte(len(name))
+	copy(encoded[6:], name)
+	return encoded, nil
+}
+
+// releaseEntryName libera los NameBlocks de una entrada con nombre largo
+func (sb *SuperBlock) releaseEntryName(path string, content FolderContent) error {
+	blocks, err := sb.EntryNameBlocks(path, content)
+	if err != nil {
+		return err
+	}
+	for _, blockIndex := range blocks {
+		err := sb.FreeBitmapBlock(path, blockIndex)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EntryNameBlocks devuelve los NameBlocks que ocupa una entrada con nombre largo. Una cadena que sale de la
+// partición o que vuelve a un bloque ya visitado está dañada y devuelve error en vez de recorrerse sin fin.
+func (sb *SuperBlock) EntryNameBlocks(path string, content FolderContent) ([]int32, error) {
+	blocks := make([]int32, 0)
+	if content.B_name[0] != longNameMarker {
+		return blocks, nil
+	}
+
+	visited := make(map[int32]bool)
+	blockIndex := int32(binary.LittleEndian.Uint32(content.B_name[1:5]))
+	for blockIndex != -1 {
+		if blockIndex < 0 || blockIndex >= sb.TotalBlocks() || visited[blockIndex] {
+			return nil, fmt.Errorf("la cadena del nombre largo de la entrada del inodo %d está dañada en el bloque %d", content.B_inodo, blockIndex)
+		}
+		visited[blockIndex] = true
+
+		nameBlock := sb.NewNameBlock()
+		err := nameBlock.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, blockIndex)
+		blockIndex = nameBlock.B_next
+	}
+	return blocks, nil
+}
@@ -1,15 +1,12 @@
 package structures
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
-	"os"
 )
 
 type FolderBlock struct {
-	B_content [4]FolderContent // 4 * 16 = 64 bytes
-	// Total size: 64 bytes
+	B_content []FolderContent // S_block_size / 16 entries
 }
 
 type FolderContent struct {
@@ -18,57 +15,35 @@ type FolderContent struct {
 	// Total size: 16 bytes
 }
 
-func (fb *FolderBlock) Serialize(path string, offset int64) error {
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+// NewFolderBlock creates a zeroed folder block sized for the filesystem, ready to be deserialized into
+func (sb *SuperBlock) NewFolderBlock() *FolderBlock {
+	return &FolderBlock{B_content: make([]FolderContent, sb.S_block_size/int32(binary.Size(FolderContent{})))}
+}
 
-	_, err = file.Seek(offset, 0)
-	if err != nil {
-		return err
+// folderBlockWith creates a folder block with the given entries first and the remaining ones free
+func (sb *SuperBlock) folderBlockWith(entries ...FolderContent) *FolderBlock {
+	block := sb.NewFolderBlock()
+	for i := range block.B_content {
+		block.B_content[i] = FolderContent{B_name: [12]byte{'-'}, B_inodo: -1}
 	}
+	copy(block.B_content, entries)
+	return block
+}
 
-	err = binary.Write(file, binary.LittleEndian, fb)
+func (fb *FolderBlock) Serialize(path string, offset int64) error {
+	err := sealBlock(path, offset, fb.B_content)
 	if err != nil {
 		return err
 	}
-
-	return nil
+	return writeStruct(path, offset, fb.B_content)
 }
 
 func (fb *FolderBlock) Deserialize(path string, offset int64) error {
-	// Open the file for reading
-	file, err := os.Open(path)
+	err := readStruct(path, offset, fb.B_content)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	_, err = file.Seek(offset, 0)
-	if err != nil {
-		return err
-	}
-
-	fbSize := binary.Size(fb)
-	if fbSize <= 0 {
-		return fmt.Errorf("invalid FolderBlock size: %d", fbSize)
-	}
-
-	buffer := make([]byte, fbSize)
-	_, err = file.Read(buffer)
-	if err != nil {
-		return err
-	}
-
-	reader := bytes.NewReader(buffer)
-	err = binary.Read(reader, binary.LittleEndian, fb)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return verifyBlock("el bloque de carpeta", path, offset, fb.B_content)
 }
 
 func (fb *FolderBlock) Print() {
@@ -0,0 +1,818 @@
+package structures
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// lostAndFoundName es la carpeta de la raíz donde fsck reconecta los inodos huérfanos
+const lostAndFoundName = "lost+found"
+
+// FsckReport reúne lo que encontró Fsck en una partición y lo que reparó
+type FsckReport struct {
+	Problems     []string // inconsistencias encontradas
+	Repairs      []string // cambios hechos en modo reparación
+	Orphans      []int32  // inodos en uso que ninguna carpeta referencia
+	UsedInodes   int32    // inodos alcanzables desde la raíz
+	UsedBlocks   int32    // bloques alcanzables desde la raíz
+	problemIndex map[string]bool
+}
+
+// problem agrega una inconsistencia al reporte, sin repetirla si se vuelve a encontrar en otra pasada
+func (r *FsckReport) problem(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if r.problemIndex == nil {
+		r.problemIndex = make(map[string]bool)
+	}
+	if r.problemIndex[message] {
+		return
+	}
+	r.problemIndex[message] = true
+	r.Problems = append(r.Problems, message)
+}
+
+// repaired agrega una reparación al reporte
+func (r *FsckReport) repaired(format string, args ...any) {
+	r.Repairs = append(r.Repairs, fmt.Sprintf(format, args...))
+}
+
+// fsckChecker recorre el árbol de carpetas desde un inodo y anota qué inodos y bloques están en uso
+type fsckChecker struct {
+	sb      *SuperBlock
+	path    string
+	repair  bool
+	report  *FsckReport
+	inodes  map[int32]bool  // inodos alcanzados
+	blocks  map[int32]int32 // bloque -> inodo que lo usa
+	refs    map[int32]int32 // cantidad de entradas que apuntan a cada inodo
+	parents map[int32]int32 // carpeta -> carpeta padre según el recorrido
+}
+
+func (sb *SuperBlock) newFsckChecker(path string, repair bool, report *FsckReport) *fsckChecker {
+	return &fsckChecker{
+		sb:      sb,
+		path:    path,
+		repair:  repair,
+		report:  report,
+		inodes:  make(map[int32]bool),
+		blocks:  make(map[int32]int32),
+		refs:    make(map[int32]int32),
+		parents: make(map[int32]int32),
+	}
+}
+
+// Fsck revisa la consistencia del sistema de archivos partiendo del inodo raíz: bitmaps, entradas . y ..,
+// I_size contra los bloques asignados, contadores del superbloque, bloques asignados dos veces y cuenta de enlaces.
+// También revisa el superbloque principal y sus copias de respaldo contra la geometría de sb.
+// Con repair corrige lo que puede y reconecta los inodos huérfanos en /lost+found; quien llama debe serializar el superbloque.
+func (sb *SuperBlock) Fsck(path string, partition *Partition, repair bool) (*FsckReport, error) {
+	report := &FsckReport{}
+
+	root := &Inode{}
+	err := root.Deserialize(path, int64(sb.S_inode_start))
+	if err != nil {
+		return nil, err
+	}
+	if root.I_type[0] != '0' {
+		return nil, fmt.Errorf("el inodo raíz está dañado, no se puede revisar la partición")
+	}
+
+	checker := sb.newFsckChecker(path, repair, report)
+	err = checker.walk(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	orphans, err := checker.orphans()
+	if err != nil {
+		return nil, err
+	}
+
+	// Reconectar los huérfanos y volver a recorrer hasta que no quede ninguno
+	for len(orphans) > 0 {
+		for _, orphan := range orphans {
+			report.problem("el inodo %d está en uso pero ninguna carpeta lo referencia (huérfano)", orphan)
+		}
+		report.Orphans = append(report.Orphans, orphans...)
+		if !repair {
+			break
+		}
+
+		err = checker.reattachOrphans(orphans)
+		if err != nil {
+			return nil, err
+		}
+
+		checker = sb.newFsckChecker(path, repair, report)
+		err = checker.walk(0, 0)
+		if err != nil {
+			return nil, err
+		}
+		remaining, err := checker.orphans()
+		if err != nil {
+			return nil, err
+		}
+		if len(remaining) >= len(orphans) {
+			return nil, fmt.Errorf("no se pudieron reconectar los inodos huérfanos %v", remaining)
+		}
+		orphans = remaining
+	}
+
+	err = checker.checkLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	inodeBitmap, blockBitmap, err := checker.checkBitmaps()
+	if err != nil {
+		return nil, err
+	}
+	checker.checkCounters(inodeBitmap, blockBitmap)
+
+	err = checker.checkSuperBlocks(partition)
+	if err != nil {
+		return nil, err
+	}
+
+	report.UsedInodes = int32(len(checker.inodes))
+	report.UsedBlocks = int32(len(checker.blocks))
+	return report, nil
+}
+
+func (c *fsckChecker) validInode(index int32) bool {
+	return index >= 0 && index < c.sb.TotalInodes()
+}
+
+func (c *fsckChecker) validBlock(index int32) bool {
+	return index >= 0 && index < c.sb.TotalBlocks()
+}
+
+func (c *fsckChecker) readInode(index int32) (*Inode, error) {
+	inode := &Inode{}
+	err := inode.Deserialize(c.path, int64(c.sb.S_inode_start+(index*c.sb.S_inode_size)))
+	if err != nil {
+		return nil, err
+	}
+	return inode, nil
+}
+
+func (c *fsckChecker) writeInode(index int32, inode *Inode) error {
+	return inode.Serialize(c.path, int64(c.sb.S_inode_start+(index*c.sb.S_inode_size)))
+}
+
+// isCorruption indica si err es un checksum que no coincide; fsck lo reporta en lugar de detenerse
+func isCorruption(err error) bool {
+	var corruption *CorruptionError
+	return errors.As(err, &corruption)
+}
+
+// validType indica si el tipo del inodo es carpeta, archivo o enlace simbólico
+func validType(inode *Inode) bool {
+	return inode.I_type[0] == '0' || inode.I_type[0] == '1' || inode.I_type[0] == '2'
+}
+
+// claimBlock anota que owner usa el bloque; si otro inodo ya lo usaba es un bloque asignado dos veces.
+// Esos bloques solo se reportan: no hay forma de saber a cuál de los dos inodos pertenece el contenido.
+func (c *fsckChecker) claimBlock(blockIndex int32, owner int32) bool {
+	if previous, used := c.blocks[blockIndex]; used {
+		c.report.problem("el bloque %d está asignado a los inodos %d y %d", blockIndex, previous, owner)
+		return false
+	}
+	c.blocks[blockIndex] = owner
+	return true
+}
+
+// walk recorre el árbol que cuelga de start, cuyo padre esperado es parent
+func (c *fsckChecker) walk(start int32, parent int32) error {
+	type pending struct{ index, parent int32 }
+	queue := []pending{{start, parent}}
+	c.parents[start] = parent
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		// Un archivo con varios enlaces duros se revisa una sola vez
+		if c.inodes[current.index] {
+			continue
+		}
+		inode, err := c.readInode(current.index)
+		if isCorruption(err) {
+			c.report.problem("el inodo %d está dañado: %v", current.index, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !validType(inode) {
+			c.report.problem("el inodo %d tiene un tipo inválido", current.index)
+			continue
+		}
+		c.inodes[current.index] = true
+
+		dataBlocks, err := c.inodeBlocks(current.index, inode)
+		if err != nil {
+			return err
+		}
+
+		if inode.I_type[0] != '0' {
+			err = c.checkSize(current.index, inode, dataBlocks)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		children, err := c.checkFolder(current.index, current.parent, dataBlocks)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			queue = append(queue, pending{child, current.index})
+		}
+	}
+	return nil
+}
+
+// inodeBlocks devuelve los bloques de datos del inodo anotando también sus bloques de apuntadores.
+// Los apuntadores fuera de la partición se reportan y, al reparar, se quitan.
+func (c *fsckChecker) inodeBlocks(index int32, inode *Inode) ([]int32, error) {
+	dataBlocks := make([]int32, 0)
+	dirty := false
+
+	for i, blockIndex := range inode.I_block {
+		if blockIndex == -1 {
+			continue
+		}
+		if !c.validBlock(blockIndex) {
+			c.report.problem("el inodo %d apunta al bloque inexistente %d", index, blockIndex)
+			if c.repair {
+				inode.I_block[i] = -1
+				dirty = true
+				c.report.repaired("se quitó el apuntador al bloque %d del inodo %d", blockIndex, index)
+			}
+			continue
+		}
+		if !c.claimBlock(blockIndex, index) {
+			continue
+		}
+		if i < directBlocks {
+			dataBlocks = append(dataBlocks, blockIndex)
+			continue
+		}
+		err := c.indirectBlocks(index, blockIndex, i-directBlocks+1, &dataBlocks)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if dirty {
+		err := c.writeInode(index, inode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dataBlocks, nil
+}
+
+// indirectBlocks recorre un bloque de apuntadores del nivel indicado
+func (c *fsckChecker) indirectBlocks(owner int32, blockIndex int32, level int, dataBlocks *[]int32) error {
+	pointerBlock := c.sb.NewPointerBlock()
+	err := pointerBlock.Deserialize(c.path, int64(c.sb.S_block_start+(blockIndex*c.sb.S_block_size)))
+	if isCorruption(err) {
+		c.report.problem("el bloque de apuntadores %d del inodo %d está dañado: %v", blockIndex, owner, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	dirty := false
+	for i, pointer := range pointerBlock.P_pointers {
+		if pointer == -1 {
+			continue
+		}
+		if !c.validBlock(pointer) {
+			c.report.problem("el bloque de apuntadores %d del inodo %d apunta al bloque inexistente %d", blockIndex, owner, pointer)
+			if c.repair {
+				pointerBlock.P_pointers[i] = -1
+				dirty = true
+				c.report.repaired("se quitó el apuntador al bloque %d del bloque de apuntadores %d", pointer, blockIndex)
+			}
+			continue
+		}
+		if !c.claimBlock(pointer, owner) {
+			continue
+		}
+		if level == 1 {
+			*dataBlocks = append(*dataBlocks, pointer)
+			continue
+		}
+		err := c.indirectBlocks(owner, pointer, level-1, dataBlocks)
+		if err != nil {
+			return err
+		}
+	}
+
+	if dirty {
+		return pointerBlock.Serialize(c.path, int64(c.sb.S_block_start+(blockIndex*c.sb.S_block_size)))
+	}
+	return nil
+}
+
+// checkSize compara I_size con los bloques asignados a un archivo o enlace.
+// Un archivo vacío puede conservar un bloque, igual que al crearlo.
+func (c *fsckChecker) checkSize(index int32, inode *Inode, dataBlocks []int32) error {
+	blocks := int32(len(dataBlocks))
+	expected := (inode.I_size + c.sb.S_block_size - 1) / c.sb.S_block_size
+	if inode.I_size >= 0 && (expected == blocks || (expected == 0 && blocks == 1)) {
+		return nil
+	}
+
+	c.report.problem("el inodo %d tiene I_size %d pero %d bloques asignados", index, inode.I_size, blocks)
+	if !c.repair {
+		return nil
+	}
+
+	// El tamaño se ajusta a los bloques que de verdad tiene el inodo
+	inode.I_size = blocks * c.sb.S_block_size
+	c.report.repaired("se ajustó el I_size del inodo %d a %d", index, inode.I_size)
+	return c.writeInode(index, inode)
+}
+
+// checkFolder revisa los bloques de una carpeta y devuelve los inodos de sus entradas que hay que recorrer
+func (c *fsckChecker) checkFolder(index int32, parent int32, dataBlocks []int32) ([]int32, error) {
+	children := make([]int32, 0)
+
+	for _, blockIndex := range dataBlocks {
+		block := c.sb.NewFolderBlock()
+		err := block.Deserialize(c.path, int64(c.sb.S_block_start+(blockIndex*c.sb.S_block_size)))
+		if isCorruption(err) {
+			c.report.problem("el bloque %d de la carpeta %d está dañado: %v", blockIndex, index, err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		dirty := false
+
+		// Todos los bloques de una carpeta empiezan con . y ..
+		for slot, expected := range []struct {
+			name  string
+			inode int32
+		}{{".", index}, {"..", parent}} {
+			content := block.B_content[slot]
+			name := strings.TrimRight(string(content.B_name[:]), "\x00")
+			if name == expected.name && content.B_inodo == expected.inode {
+				continue
+			}
+			c.report.problem("el bloque %d de la carpeta %d no tiene la entrada %s hacia el inodo %d", blockIndex, index, expected.name, expected.inode)
+			if c.repair {
+				block.B_content[slot] = FolderContent{B_inodo: expected.inode}
+				copy(block.B_content[slot].B_name[:], expected.name)
+				dirty = true
+				c.report.repaired("se corrigió la entrada %s del bloque %d", expected.name, blockIndex)
+			}
+		}
+
+		// Desde el index 2 porque los primeros dos son . y ..
+		for slot := 2; slot < len(block.B_content); slot++ {
+			content := block.B_content[slot]
+			if content.B_inodo == -1 {
+				continue
+			}
+
+			child, keep, err := c.checkEntry(index, blockIndex, content)
+			if err != nil {
+				return nil, err
+			}
+			if !keep {
+				if c.repair {
+					block.B_content[slot] = FolderContent{B_name: [12]byte{'-'}, B_inodo: -1}
+					dirty = true
+					c.report.repaired("se quitó la entrada inválida del bloque %d de la carpeta %d", blockIndex, index)
+				}
+				continue
+			}
+			if child != -1 {
+				children = append(children, child)
+			}
+		}
+
+		if dirty {
+			err := block.Serialize(c.path, int64(c.sb.S_block_start+(blockIndex*c.sb.S_block_size)))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return children, nil
+}
+
+// checkEntry revisa una entrada de carpeta. Devuelve el inodo a recorrer (-1 si no hay que entrar)
+// y si la entrada es válida; las inválidas se quitan al reparar.
+func (c *fsckChecker) checkEntry(folder int32, blockIndex int32, content FolderContent) (int32, bool, error) {
+	name, ok, err := c.entryName(folder, content)
+	if err != nil {
+		return -1, false, err
+	}
+	if !ok {
+		return -1, false, nil
+	}
+
+	if !c.validInode(content.B_inodo) {
+		c.report.problem("la entrada '%s' de la carpeta %d apunta al inodo inexistente %d", name, folder, content.B_inodo)
+		return -1, false, nil
+	}
+	child, err := c.readInode(content.B_inodo)
+	if isCorruption(err) {
+		c.report.problem("la entrada '%s' de la carpeta %d apunta al inodo %d, que está dañado: %v", name, folder, content.B_inodo, err)
+		return -1, false, nil
+	}
+	if err != nil {
+		return -1, false, err
+	}
+	if !validType(child) {
+		c.report.problem("la entrada '%s' de la carpeta %d apunta al inodo %d, que no está en uso", name, folder, content.B_inodo)
+		return -1, false, nil
+	}
+	c.refs[content.B_inodo]++
+
+	// Una carpeta solo puede colgar de un padre
+	if child.I_type[0] == '0' {
+		if _, seen := c.parents[content.B_inodo]; seen {
+			c.report.problem("la carpeta %d ('%s') tiene más de un padre", content.B_inodo, name)
+			return -1, true, nil
+		}
+		c.parents[content.B_inodo] = folder
+	}
+	return content.B_inodo, true, nil
+}
+
+// entryName devuelve el nombre de una entrada anotando sus NameBlocks como bloques de la carpeta
+func (c *fsckChecker) entryName(folder int32, content FolderContent) (string, bool, error) {
+	if content.B_name[0] != longNameMarker {
+		return strings.TrimRight(string(content.B_name[:]), "\x00"), true, nil
+	}
+
+	blockIndex := int32(binary.LittleEndian.Uint32(content.B_name[1:5]))
+	length := int(content.B_name[5])
+	name := make([]byte, 0, length)
+	for blockIndex != -1 {
+		if !c.validBlock(blockIndex) {
+			c.report.problem("el nombre largo de la entrada del inodo %d en la carpeta %d apunta al bloque inexistente %d", content.B_inodo, folder, blockIndex)
+			return "", false, nil
+		}
+		if !c.claimBlock(blockIndex, folder) {
+			return "", false, nil
+		}
+
+		nameBlock := c.sb.NewNameBlock()
+		err := nameBlock.Deserialize(c.path, int64(c.sb.S_block_start+(blockIndex*c.sb.S_block_size)))
+		if err != nil {
+			return "", false, err
+		}
+		name = append(name, nameBlock.B_name[:]...)
+		blockIndex = nameBlock.B_next
+	}
+
+	if len(name) < length {
+		c.report.problem("el nombre largo de la entrada del inodo %d en la carpeta %d está incompleto", content.B_inodo, folder)
+		return "", false, nil
+	}
+	return string(name[:length]), true, nil
+}
+
+// orphans devuelve los inodos marcados en el bitmap con un tipo válido que el recorrido no alcanzó
+func (c *fsckChecker) orphans() ([]int32, error) {
+	bitmap, err := readBitmap(c.path, c.sb.S_bm_inode_start, c.sb.TotalInodes())
+	if err != nil {
+		return nil, err
+	}
+
+	orphans := make([]int32, 0)
+	for i, state := range bitmap {
+		index := int32(i)
+		if state != '1' || c.inodes[index] {
+			continue
+		}
+		inode, err := c.readInode(index)
+		if isCorruption(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if validType(inode) {
+			orphans = append(orphans, index)
+		}
+	}
+	return orphans, nil
+}
+
+// reattachOrphans agrega en /lost+found una entrada #<inodo> por cada huérfano que no cuelgue de otro huérfano
+func (c *fsckChecker) reattachOrphans(orphans []int32) error {
+	// Recorrer los huérfanos sin reparar para conocer sus bloques y sus descendientes
+	scratch := c.sb.newFsckChecker(c.path, false, &FsckReport{})
+	for _, orphan := range orphans {
+		err := scratch.walk(orphan, orphan)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Antes de reservar nada, marcar en los bitmaps todo lo que se va a conservar
+	err := c.markUsed(c, true)
+	if err != nil {
+		return err
+	}
+	err = c.markUsed(scratch, false)
+	if err != nil {
+		return err
+	}
+
+	// Solo se reconectan los huérfanos que ningún otro huérfano referencia; si todos forman un ciclo se toma el primero
+	top := make([]int32, 0)
+	for _, orphan := range orphans {
+		if scratch.refs[orphan] == 0 {
+			top = append(top, orphan)
+		}
+	}
+	if len(top) == 0 {
+		top = append(top, orphans[0])
+	}
+
+	folderIndex, err := c.lostAndFound()
+	if err != nil {
+		return err
+	}
+	for _, orphan := range top {
+		folderInode, err := c.readInode(folderIndex)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("#%d", orphan)
+		err = c.sb.addFolderEntry(c.path, folderIndex, folderInode, name, orphan)
+		if err != nil {
+			return err
+		}
+		c.report.repaired("se reconectó el inodo huérfano %d como /%s/%s", orphan, lostAndFoundName, name)
+	}
+	return nil
+}
+
+// markUsed marca en los bitmaps los inodos y bloques que alcanzó checker. Con report anota como
+// problema lo que estaba libre; lo de los huérfanos no se anota porque ya se reportaron.
+func (c *fsckChecker) markUsed(checker *fsckChecker, report bool) error {
+	inodeBitmap, err := readBitmap(c.path, c.sb.S_bm_inode_start, c.sb.TotalInodes())
+	if err != nil {
+		return err
+	}
+	for index := range checker.inodes {
+		if inodeBitmap[index] == '1' {
+			continue
+		}
+		if report {
+			c.report.problem("el inodo %d está en uso pero libre en el bitmap", index)
+			c.report.repaired("se marcó el inodo %d como '1' en el bitmap", index)
+		}
+		err := DeviceWriteAt(c.path, []byte{'1'}, int64(c.sb.S_bm_inode_start)+int64(index))
+		if err != nil {
+			return err
+		}
+	}
+
+	blockBitmap, err := readBitmap(c.path, c.sb.S_bm_block_start, c.sb.TotalBlocks())
+	if err != nil {
+		return err
+	}
+	for blockIndex := range checker.blocks {
+		if blockBitmap[blockIndex] == 'X' {
+			continue
+		}
+		if report {
+			c.report.problem("el bloque %d está en uso pero libre en el bitmap", blockIndex)
+			c.report.repaired("se marcó el bloque %d como 'X' en el bitmap", blockIndex)
+		}
+		err := DeviceWriteAt(c.path, []byte{'X'}, int64(c.sb.S_bm_block_start)+int64(blockIndex))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lostAndFound devuelve el inodo de /lost+found y lo crea si no existe
+func (c *fsckChecker) lostAndFound() (int32, error) {
+	root, err := c.readInode(0)
+	if err != nil {
+		return -1, err
+	}
+	folderIndex, err := c.sb.findFolderEntry(c.path, root, lostAndFoundName)
+	if err != nil {
+		return -1, err
+	}
+
+	if folderIndex == -1 {
+		err = c.sb.createFolderInode(c.path, 0, nil, lostAndFoundName, root.I_uid, root.I_gid)
+		if err != nil {
+			return -1, err
+		}
+		c.report.repaired("se creó la carpeta /%s", lostAndFoundName)
+
+		root, err = c.readInode(0)
+		if err != nil {
+			return -1, err
+		}
+		folderIndex, err = c.sb.findFolderEntry(c.path, root, lostAndFoundName)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	folderInode, err := c.readInode(folderIndex)
+	if err != nil {
+		return -1, err
+	}
+	if folderInode.I_type[0] != '0' {
+		return -1, fmt.Errorf("/%s existe y no es una carpeta", lostAndFoundName)
+	}
+	return folderIndex, nil
+}
+
+// checkLinks compara la cuenta de enlaces de cada inodo con las entradas que lo referencian
+func (c *fsckChecker) checkLinks() error {
+	if !c.sb.HasFeature(FeatureLinkCount) {
+		return nil
+	}
+
+	for index := range c.inodes {
+		inode, err := c.readInode(index)
+		if err != nil {
+			return err
+		}
+
+		// La raíz no tiene entrada en ninguna carpeta y las carpetas no admiten enlaces duros
+		expected := c.refs[index]
+		if index == 0 || inode.I_type[0] == '0' {
+			expected = 1
+		}
+		if inode.I_links == expected {
+			continue
+		}
+
+		c.report.problem("el inodo %d tiene %d enlaces pero %d entradas lo referencian", index, inode.I_links, expected)
+		if c.repair {
+			inode.I_links = expected
+			err = c.writeInode(index, inode)
+			if err != nil {
+				return err
+			}
+			c.report.repaired("se ajustó la cuenta de enlaces del inodo %d a %d", index, expected)
+		}
+	}
+	return nil
+}
+
+// checkBitmaps compara los bitmaps con lo alcanzado en el recorrido y, al reparar, los deja iguales.
+// Devuelve los bitmaps como quedaron.
+func (c *fsckChecker) checkBitmaps() ([]byte, []byte, error) {
+	orphan := make(map[int32]bool)
+	for _, index := range c.report.Orphans {
+		orphan[index] = true
+	}
+
+	inodeBitmap, err := readBitmap(c.path, c.sb.S_bm_inode_start, c.sb.TotalInodes())
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range inodeBitmap {
+		index := int32(i)
+		var expected byte = '0'
+		if c.inodes[index] {
+			expected = '1'
+		}
+		if inodeBitmap[i] == expected {
+			continue
+		}
+
+		// Los huérfanos ya se reportaron aparte
+		if expected == '1' {
+			c.report.problem("el inodo %d está en uso pero libre en el bitmap", index)
+		} else if !orphan[index] {
+			c.report.problem("el inodo %d está marcado en el bitmap pero no está en uso", index)
+		}
+		if c.repair {
+			err := DeviceWriteAt(c.path, []byte{expected}, int64(c.sb.S_bm_inode_start)+int64(index))
+			if err != nil {
+				return nil, nil, err
+			}
+			inodeBitmap[i] = expected
+			c.report.repaired("se marcó el inodo %d como '%c' en el bitmap", index, expected)
+		}
+	}
+
+	blockBitmap, err := readBitmap(c.path, c.sb.S_bm_block_start, c.sb.TotalBlocks())
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range blockBitmap {
+		blockIndex := int32(i)
+		var expected byte = 'O'
+		if _, used := c.blocks[blockIndex]; used {
+			expected = 'X'
+		}
+		if blockBitmap[i] == expected {
+			continue
+		}
+
+		if expected == 'X' {
+			c.report.problem("el bloque %d está en uso pero libre en el bitmap", blockIndex)
+		} else {
+			c.report.problem("el bloque %d está marcado en el bitmap pero ningún inodo lo usa", blockIndex)
+		}
+		if c.repair {
+			err := DeviceWriteAt(c.path, []byte{expected}, int64(c.sb.S_bm_block_start)+int64(blockIndex))
+			if err != nil {
+				return nil, nil, err
+			}
+			blockBitmap[i] = expected
+			c.report.repaired("se marcó el bloque %d como '%c' en el bitmap", blockIndex, expected)
+		}
+	}
+
+	return inodeBitmap, blockBitmap, nil
+}
+
+// checkSuperBlocks verifica que el superbloque principal y sus copias de respaldo existan y tengan la geometría de sb.
+// El principal lo reescribe quien llama al serializar sb; las copias se reescriben aquí.
+func (c *fsckChecker) checkSuperBlocks(partition *Partition) error {
+	primary := &SuperBlock{}
+	err := primary.Deserialize(c.path, int64(partition.Part_start))
+	if err != nil && !isCorruption(err) {
+		return err
+	}
+	if err != nil || !primary.ValidFor(partition) || !primary.SameGeometry(c.sb) {
+		c.report.problem("el superbloque principal está dañado, se revisó con una copia de respaldo")
+		if c.repair {
+			c.report.repaired("se restauró el superbloque principal desde la copia de respaldo")
+		}
+	}
+
+	for _, offset := range c.sb.BackupOffsets(partition) {
+		backup, err := readBackup(c.path, partition, offset)
+		if err == nil && backup.SameGeometry(c.sb) {
+			continue
+		}
+		c.report.problem("la copia del superbloque en %d falta o no coincide con el principal", offset)
+		if c.repair {
+			copySb := *c.sb
+			err := copySb.Serialize(c.path, offset)
+			if err != nil {
+				return err
+			}
+			c.report.repaired("se reescribió la copia del superbloque en %d", offset)
+		}
+	}
+	return nil
+}
+
+// checkCounters compara los contadores del superbloque con los bitmaps
+func (c *fsckChecker) checkCounters(inodeBitmap []byte, blockBitmap []byte) {
+	usedInodes := int32(strings.Count(string(inodeBitmap), "1"))
+	usedBlocks := int32(strings.Count(string(blockBitmap), "X"))
+	freeInodes := c.sb.TotalInodes() - usedInodes
+	freeBlocks := c.sb.TotalBlocks() - usedBlocks
+
+	counters := []struct {
+		name     string
+		value    *int32
+		expected int32
+	}{
+		{"S_inodes_count", &c.sb.S_inodes_count, usedInodes},
+		{"S_free_inodes_count", &c.sb.S_free_inodes_count, freeInodes},
+		{"S_blocks_count", &c.sb.S_blocks_count, usedBlocks},
+		{"S_free_blocks_count", &c.sb.S_free_blocks_count, freeBlocks},
+	}
+	for _, counter := range counters {
+		if *counter.value == counter.expected {
+			continue
+		}
+		c.report.problem("%s vale %d pero los bitmaps indican %d", counter.name, *counter.value, counter.expected)
+		if c.repair {
+			*counter.value = counter.expected
+			c.report.repaired("se ajustó %s a %d", counter.name, counter.expected)
+		}
+	}
+
+	if c.repair {
+		if next := firstFree(inodeBitmap, '1'); next != -1 {
+			c.sb.S_first_ino = c.sb.S_inode_start + (next * c.sb.S_inode_size)
+		}
+		if next := firstFree(blockBitmap, 'X'); next != -1 {
+			c.sb.S_first_blo = c.sb.S_block_start + (next * c.sb.S_block_size)
+		}
+	}
+}
@@ -0,0 +1,468 @@
+package structures
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// A GPT disk keeps a protective MBR in LBA 0, the header in LBA 1 and the partition entry array from LBA 2.
+// The end of the disk holds a copy of the array followed by the backup header in the last LBA.
+// These disks do not have the project's MBR struct: every partition is an entry of the array.
+
+const (
+	SectorSize      = 512
+	GPTEntries      = 128
+	gptEntrySize    = 128
+	gptHeaderSize   = 92
+	gptRevision     = 0x00010000
+	gptArraySectors = GPTEntries * gptEntrySize / SectorSize
+
+	// The fit of a partition is kept in the type-specific bits of its attributes
+	gptFitShift = 48
+)
+
+var gptSignature = [8]byte{'E', 'F', 'I', ' ', 'P', 'A', 'R', 'T'}
+
+// GUID is stored in the mixed-endian layout of the specification
+type GUID [16]byte
+
+// LinuxFilesystemGUID is the partition type 0FC63DAF-8483-4772-8E79-3D69D8477DE4
+var LinuxFilesystemGUID = GUID{0xAF, 0x3D, 0xC6, 0x0F, 0x83, 0x84, 0x72, 0x47, 0x8E, 0x79, 0x3D, 0x69, 0xD8, 0x47, 0x7D, 0xE4}
+
+// NewGUID returns a random version 4 GUID
+func NewGUID() (GUID, error) {
+	var g GUID
+	_, err := rand.Read(g[:])
+	if err != nil {
+		return g, err
+	}
+	g[7] = g[7]&0x0f | 0x40 // version, high nibble of the third (little-endian) group
+	g[8] = g[8]&0x3f | 0x80 // variant
+	return g, nil
+}
+
+// String returns the GUID in its usual text form
+func (g GUID) String() string {
+	return fmt.Sprintf("%08X-%04X-%04X-%X-%X",
+		binary.LittleEndian.Uint32(g[0:4]), binary.LittleEndian.Uint16(g[4:6]), binary.LittleEndian.Uint16(g[6:8]), g[8:10], g[10:16])
+}
+
+// IsZero reports whether the GUID is all zeros, which marks an unused entry
+func (g GUID) IsZero() bool {
+	return g == GUID{}
+}
+
+type GPTHeader struct {
+	Signature                [8]byte
+	Revision                 uint32
+	HeaderSize               uint32
+	HeaderCRC32              uint32
+	Reserved                 uint32
+	MyLBA                    uint64
+	AlternateLBA             uint64
+	FirstUsableLBA           uint64
+	LastUsableLBA            uint64
+	DiskGUID                 GUID
+	PartitionEntryLBA        uint64
+	NumberOfPartitionEntries uint32
+	SizeOfPartitionEntry     uint32
+	PartitionEntryArrayCRC32 uint32
+}
+
+type GPTEntry struct {
+	PartitionTypeGUID   GUID
+	UniquePartitionGUID GUID
+	StartingLBA         uint64
+	EndingLBA           uint64
+	Attributes          uint64
+	PartitionName       [36]uint16 // UTF-16LE
+}
+
+// GPT is the primary header with its partition entry array
+type GPT struct {
+	Header  GPTHeader
+	Entries [GPTEntries]GPTEntry
+}
+
+// Used reports whether the entry holds a partition
+func (e *GPTEntry) Used() bool {
+	return !e.PartitionTypeGUID.IsZero()
+}
+
+// Name returns the partition name without the trailing zeros
+func (e *GPTEntry) Name() string {
+	end := len(e.PartitionName)
+	for end > 0 && e.PartitionName[end-1] == 0 {
+		end--
+	}
+	return string(utf16.Decode(e.PartitionName[:end]))
+}
+
+// SetName stores name as UTF-16, truncated to the 36 code units of the entry
+func (e *GPTEntry) SetName(name string) {
+	e.PartitionName = [36]uint16{}
+	copy(e.PartitionName[:], utf16.Encode([]rune(name)))
+}
+
+// Fit returns the fit the partition was created with
+func (e *GPTEntry) Fit() byte {
+	fit := byte(e.Attributes >> gptFitShift)
+	if fit == 0 {
+		return 'F'
+	}
+	return fit
+}
+
+// Start and Size return the bytes of the disk covered by the entry
+func (e *GPTEntry) Start() int32 { return int32(e.StartingLBA * SectorSize) }
+func (e *GPTEntry) Size() int32  { return int32((e.EndingLBA - e.StartingLBA + 1) * SectorSize) }
+
+// IsGPT reports whether the disk at path has a GPT header in LBA 1 or, if that one was lost, a protective MBR
+func IsGPT(path string) bool {
+	signature := make([]byte, len(gptSignature))
+	err := DeviceReadAt(path, signature, SectorSize)
+	if err == nil && bytes.Equal(signature, gptSignature[:]) {
+		return true
+	}
+	protective, err := ReadStandardMBR(path)
+	return err == nil && protective.Signature == bootSignature && protective.Partitions[0].OSType == 0xEE
+}
+
+// CreateGPT writes an empty GUID partition table on a disk of size bytes
+func CreateGPT(path string, size int64) (*GPT, error) {
+	sectors := uint64(size / SectorSize)
+	// Protective MBR, both headers, both arrays and at least one usable sector
+	if sectors < 3+2*gptArraySectors+1 {
+		return nil, fmt.Errorf("a GPT disk needs at least %d bytes", (3+2*gptArraySectors+1)*SectorSize)
+	}
+	diskGUID, err := NewGUID()
+	if err != nil {
+		return nil, err
+	}
+
+	gpt := &GPT{Header: GPTHeader{
+		Signature:                gptSignature,
+		Revision:                 gptRevision,
+		HeaderSize:               gptHeaderSize,
+		MyLBA:                    1,
+		AlternateLBA:             sectors - 1,
+		FirstUsableLBA:           2 + gptArraySectors,
+		LastUsableLBA:            sectors - 2 - gptArraySectors,
+		DiskGUID:                 diskGUID,
+		PartitionEntryLBA:        2,
+		NumberOfPartitionEntries: GPTEntries,
+		SizeOfPartitionEntry:     gptEntrySize,
+	}}
+
+	// The protective MBR covers the whole disk with a single 0xEE record so that MBR-only tools leave it alone
+	protective := &StandardMBR{Signature: bootSignature}
+	protective.Partitions[0] = PartitionRecord{
+		StartCHS:    [3]byte{0x00, 0x02, 0x00},
+		OSType:      0xEE,
+		EndCHS:      [3]byte{0xFF, 0xFF, 0xFF},
+		StartingLBA: 1,
+		SizeInLBA:   uint32(min(sectors-1, 0xFFFFFFFF)),
+	}
+	err = writeStruct(path, 0, protective)
+	if err != nil {
+		return nil, err
+	}
+
+	return gpt, gpt.Write(path)
+}
+
+// ReadGPT reads the primary header and array and checks their CRCs. If the primary copy is damaged the
+// backup one at the end of the disk is used and the primary is rewritten from it.
+func ReadGPT(path string) (*GPT, error) {
+	gpt, primaryErr := readGPTAt(path, 1)
+	if primaryErr == nil {
+		return gpt, nil
+	}
+
+	size, err := deviceSize(path)
+	if err != nil {
+		return nil, err
+	}
+	gpt, err = readGPTAt(path, uint64(size/SectorSize)-1)
+	if err != nil {
+		return nil, fmt.Errorf("primary GPT: %v; backup GPT: %w", primaryErr, err)
+	}
+	fmt.Println("primary GPT damaged, restored from the backup:", primaryErr)
+
+	// The backup header describes itself; turn it back into the primary one
+	gpt.Header.AlternateLBA, gpt.Header.MyLBA = gpt.Header.MyLBA, 1
+	gpt.Header.PartitionEntryLBA = 2
+	return gpt, gpt.Write(path)
+}
+
+// ReadBackup reads and verifies the backup header and array of the disk
+func (gpt *GPT) ReadBackup(path string) (*GPT, error) {
+	return readGPTAt(path, gpt.Header.AlternateLBA)
+}
+
+// readGPTAt reads and verifies the header stored in lba together with the array it points to
+func readGPTAt(path string, lba uint64) (*GPT, error) {
+	gpt := &GPT{}
+	err := readStruct(path, int64(lba*SectorSize), &gpt.Header)
+	if err != nil {
+		return nil, err
+	}
+	header := gpt.Header
+	if header.Signature != gptSignature {
+		return nil, fmt.Errorf("no GPT header in LBA %d", lba)
+	}
+	if header.HeaderSize != gptHeaderSize || header.NumberOfPartitionEntries != GPTEntries || header.SizeOfPartitionEntry != gptEntrySize {
+		return nil, fmt.Errorf("unsupported GPT header in LBA %d", lba)
+	}
+	if header.MyLBA != lba {
+		return nil, fmt.Errorf("the GPT header in LBA %d says it is in LBA %d", lba, header.MyLBA)
+	}
+	crc, err := header.checksum()
+	if err != nil {
+		return nil, err
+	}
+	if crc != header.HeaderCRC32 {
+		return nil, fmt.Errorf("GPT header CRC mismatch in LBA %d", lba)
+	}
+
+	err = readStruct(path, int64(header.PartitionEntryLBA*SectorSize), &gpt.Entries)
+	if err != nil {
+		return nil, err
+	}
+	crc, err = gpt.arrayChecksum()
+	if err != nil {
+		return nil, err
+	}
+	if crc != header.PartitionEntryArrayCRC32 {
+		return nil, fmt.Errorf("GPT partition entry array CRC mismatch for the header in LBA %d", lba)
+	}
+	return gpt, nil
+}
+
+// Write updates the CRCs and writes the primary header and array and their backups
+func (gpt *GPT) Write(path string) error {
+	crc, err := gpt.arrayChecksum()
+	if err != nil {
+		return err
+	}
+
+	primary := gpt.Header
+	primary.PartitionEntryArrayCRC32 = crc
+	backup := primary
+	backup.MyLBA, backup.AlternateLBA = primary.AlternateLBA, primary.MyLBA
+	backup.PartitionEntryLBA = primary.LastUsableLBA + 1
+
+	for _, header := range []*GPTHeader{&primary, &backup} {
+		header.HeaderCRC32, err = header.checksum()
+		if err != nil {
+			return err
+		}
+		err = writeStruct(path, int64(header.PartitionEntryLBA*SectorSize), &gpt.Entries)
+		if err != nil {
+			return err
+		}
+		err = writeStruct(path, int64(header.MyLBA*SectorSize), header)
+		if err != nil {
+			return err
+		}
+	}
+	gpt.Header = primary
+	return nil
+}
+
+// checksum returns the CRC32 of the header computed with its own CRC field set to zero
+func (header GPTHeader) checksum() (uint32, error) {
+	header.HeaderCRC32 = 0
+	var buffer bytes.Buffer
+	err := binary.Write(&buffer, binary.LittleEndian, &header)
+	if err != nil {
+		return 0, err
+	}
+	return crc32.ChecksumIEEE(buffer.Bytes()), nil
+}
+
+// arrayChecksum returns the CRC32 of the whole partition entry array
+func (gpt *GPT) arrayChecksum() (uint32, error) {
+	var buffer bytes.Buffer
+	err := binary.Write(&buffer, binary.LittleEndian, &gpt.Entries)
+	if err != nil {
+		return 0, err
+	}
+	return crc32.ChecksumIEEE(buffer.Bytes()), nil
+}
+
+// DiskSize returns the size of the disk described by the header
+func (gpt *GPT) DiskSize() int64 {
+	return int64(max(gpt.Header.MyLBA, gpt.Header.AlternateLBA)+1) * SectorSize
+}
+
+// Partition returns the entry at index as a Partition; partitions are numbered from 1 by their entry
+func (gpt *GPT) Partition(index int) *Partition {
+	entry := &gpt.Entries[index]
+	partition := &Partition{
+		Part_status:      [1]byte{'0'},
+		Part_type:        [1]byte{'P'},
+		Part_fit:         [1]byte{entry.Fit()},
+		Part_start:       entry.Start(),
+		Part_size:        entry.Size(),
+		Part_correlative: int32(index + 1),
+	}
+	copy(partition.Part_name[:], entry.Name())
+	return partition
+}
+
+// FindByName returns the index of the entry with the given name, or -1
+func (gpt *GPT) FindByName(name string) int {
+	for i := range gpt.Entries {
+		if gpt.Entries[i].Used() && gpt.Entries[i].Name() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// FindByStart returns the index of the entry whose data starts at the byte start, or -1
+func (gpt *GPT) FindByStart(start int32) int {
+	for i := range gpt.Entries {
+		if gpt.Entries[i].Used() && gpt.Entries[i].Start() == start {
+			return i
+		}
+	}
+	return -1
+}
+
+// FindByLabel returns the index of the entry whose filesystem has the given volume label, or -1
+func (gpt *GPT) FindByLabel(path string, label string) int {
+	for i := range gpt.Entries {
+		if !gpt.Entries[i].Used() {
+			continue
+		}
+		start := gpt.Entries[i].Start()
+		sb := &SuperBlock{}
+		err := sb.Deserialize(path, int64(start))
+		if err != nil || !sb.Formatted() {
+			continue
+		}
+		ext, err := sb.ReadExtended(path, start)
+		if err == nil && ext != nil && strings.EqualFold(ext.Label(), label) {
+			return i
+		}
+	}
+	return -1
+}
+
+// FreeEntry returns the index of the first unused entry, or -1 if the array is full
+func (gpt *GPT) FreeEntry() int {
+	for i := range gpt.Entries {
+		if !gpt.Entries[i].Used() {
+			return i
+		}
+	}
+	return -1
+}
+
+// usedEntries returns the indexes of the used entries sorted by their first LBA
+func (gpt *GPT) usedEntries() []int {
+	used := []int{}
+	for i := range gpt.Entries {
+		if gpt.Entries[i].Used() {
+			used = append(used, i)
+		}
+	}
+	sort.Slice(used, func(a, b int) bool {
+		return gpt.Entries[used[a]].StartingLBA < gpt.Entries[used[b]].StartingLBA
+	})
+	return used
+}
+
+// GetFreeGaps returns the free space between the first and last usable LBAs, in order
+func (gpt *GPT) GetFreeGaps() []FreeGap {
+	gaps := []FreeGap{}
+	cursor := gpt.Header.FirstUsableLBA
+	for _, i := range gpt.usedEntries() {
+		entry := &gpt.Entries[i]
+		if entry.StartingLBA > cursor {
+			gaps = append(gaps, FreeGap{Start: int32(cursor * SectorSize), Size: int32((entry.StartingLBA - cursor) * SectorSize)})
+		}
+		cursor = max(cursor, entry.EndingLBA+1)
+	}
+	if cursor <= gpt.Header.LastUsableLBA {
+		gaps = append(gaps, FreeGap{Start: int32(cursor * SectorSize), Size: int32((gpt.Header.LastUsableLBA + 1 - cursor) * SectorSize)})
+	}
+	return gaps
+}
+
+// AddPartition fills a free entry with a partition of at least size bytes, placed with the fit, and returns its index.
+// The size is rounded up to whole sectors. The table is only changed in memory.
+func (gpt *GPT) AddPartition(name string, size int32, fit byte) (int, error) {
+	// Partitions are mounted and resized by name, like in the MBR two of them cannot share it
+	if gpt.FindByName(name) != -1 {
+		return -1, fmt.Errorf("a partition named %s already exists in the disk", name)
+	}
+	index := gpt.FreeEntry()
+	if index == -1 {
+		return -1, fmt.Errorf("the partition entry array is full (%d partitions)", GPTEntries)
+	}
+	sectors := (int64(size) + SectorSize - 1) / SectorSize
+	start, err := pickGap(gpt.GetFreeGaps(), int32(sectors*SectorSize), fit)
+	if err != nil {
+		return -1, err
+	}
+	unique, err := NewGUID()
+	if err != nil {
+		return -1, err
+	}
+
+	entry := &gpt.Entries[index]
+	*entry = GPTEntry{
+		PartitionTypeGUID:   LinuxFilesystemGUID,
+		UniquePartitionGUID: unique,
+		StartingLBA:         uint64(start) / SectorSize,
+		Attributes:          uint64(fit) << gptFitShift,
+	}
+	entry.EndingLBA = entry.StartingLBA + uint64(sectors) - 1
+	entry.SetName(name)
+	return index, gpt.ValidateLayout()
+}
+
+// Resize sets the size of the entry at index to size bytes, rounded up to whole sectors
+func (gpt *GPT) Resize(index int, size int32) error {
+	if size <= 0 {
+		return fmt.Errorf("invalid partition size: %d", size)
+	}
+	entry := &gpt.Entries[index]
+	entry.EndingLBA = entry.StartingLBA + uint64((int64(size)+SectorSize-1)/SectorSize) - 1
+	return gpt.ValidateLayout()
+}
+
+// ValidateLayout checks that every partition lies inside the usable LBAs and that none overlap
+func (gpt *GPT) ValidateLayout() error {
+	used := gpt.usedEntries()
+	for n, i := range used {
+		entry := &gpt.Entries[i]
+		if entry.EndingLBA < entry.StartingLBA || entry.StartingLBA < gpt.Header.FirstUsableLBA || entry.EndingLBA > gpt.Header.LastUsableLBA {
+			return fmt.Errorf("partition %s [LBA %d, %d] is outside the usable LBAs [%d, %d]",
+				entry.Name(), entry.StartingLBA, entry.EndingLBA, gpt.Header.FirstUsableLBA, gpt.Header.LastUsableLBA)
+		}
+		if n > 0 && gpt.Entries[used[n-1]].EndingLBA >= entry.StartingLBA {
+			return fmt.Errorf("partitions %s and %s overlap", gpt.Entries[used[n-1]].Name(), entry.Name())
+		}
+	}
+	return nil
+}
+
+// deviceSize returns the size in bytes of the disk at path
+func deviceSize(path string) (int64, error) {
+	dev, release, err := acquireDevice(path)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	return dev.Size()
+}
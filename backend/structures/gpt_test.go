@@ -0,0 +1,93 @@
+package structures
+
+import (
+	"testing"
+)
+
+// newGPTDisk writes an empty GPT on an in-memory disk of size bytes
+func newGPTDisk(t *testing.T, size int64) (*GPT, string) {
+	t.Helper()
+	path := MemoryPathPrefix + t.Name() + ".mia"
+	err := CreateMemoryDevice(path, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { DropDevice(path) })
+	gpt, err := CreateGPT(path, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gpt, path
+}
+
+func TestGPTAddPartition(t *testing.T) {
+	gpt, path := newGPTDisk(t, 1024*1024)
+
+	first, err := gpt.AddPartition("datos", 100*1024, 'F')
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := gpt.AddPartition("respaldo", 1000, 'F')
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := &gpt.Entries[second]
+	if entry.Start() != gpt.Entries[first].Start()+100*1024 || entry.Size() != 2*SectorSize {
+		t.Errorf("second partition at %d with %d bytes", entry.Start(), entry.Size())
+	}
+
+	_, err = gpt.AddPartition("datos", 1000, 'F')
+	if err == nil {
+		t.Error("a second partition named datos was added")
+	}
+	_, err = gpt.AddPartition("enorme", 1024*1024, 'F')
+	if err == nil {
+		t.Error("a partition larger than the free space was added")
+	}
+
+	err = gpt.Write(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadGPT(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.FindByName("respaldo") != second || read.Entries[second].Fit() != 'F' {
+		t.Errorf("the partitions read back differ from the written ones")
+	}
+}
+
+func TestGPTRestoresPrimaryFromBackup(t *testing.T) {
+	gpt, path := newGPTDisk(t, 1024*1024)
+	_, err := gpt.AddPartition("datos", 64*1024, 'B')
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = gpt.Write(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Damage the primary header
+	err = DeviceWriteAt(path, make([]byte, SectorSize), SectorSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsGPT(path) {
+		t.Fatal("the protective MBR no longer identifies the disk as GPT")
+	}
+	read, err := ReadGPT(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Header.MyLBA != 1 || read.FindByName("datos") == -1 {
+		t.Fatalf("the table restored from the backup is wrong: LBA %d", read.Header.MyLBA)
+	}
+
+	// The primary copy was rewritten, so it reads without falling back again
+	_, err = readGPTAt(path, 1)
+	if err != nil {
+		t.Errorf("the primary GPT was not rewritten: %v", err)
+	}
+}
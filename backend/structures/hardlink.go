@@ -0,0 +1,108 @@
+package structures
+
+import (
+	"fmt"
+	"time"
+)
+
+// CreateHardLink agrega la entrada destDir dentro de parentsDir apuntando al mismo inodo que targetParents/targetName
+// y aumenta su cuenta de enlaces. No se permiten enlaces duros a carpetas.
+func (sb *SuperBlock) CreateHardLink(path string, parentsDir []string, destDir string, targetParents []string, targetName string) error {
+	if !sb.HasFeature(FeatureLinkCount) {
+		return fmt.Errorf("el sistema de archivos no lleva la cuenta de enlaces, vuelva a formatear la partición")
+	}
+
+	// Buscar el inodo existente, un enlace simbólico se enlaza a sí mismo y no a su destino
+	targetIndex, err := sb.ResolvePath(path, targetParents, targetName, false)
+	if err != nil {
+		return err
+	}
+	if targetIndex == -1 {
+		return fmt.Errorf("no existe el archivo %s", targetName)
+	}
+
+	targetInode := &Inode{}
+	err = targetInode.Deserialize(path, int64(sb.S_inode_start+(targetIndex*sb.S_inode_size)))
+	if err != nil {
+		return err
+	}
+	if targetInode.I_type[0] == '0' {
+		return fmt.Errorf("no se pueden crear enlaces duros a carpetas")
+	}
+
+	// Buscar la carpeta donde se creará el nuevo nombre
+	folderIndex, folderInode, err := sb.newEntryFolder(path, parentsDir, destDir)
+	if err != nil {
+		return err
+	}
+
+	err = sb.addFolderEntry(path, folderIndex, folderInode, destDir, targetIndex)
+	if err != nil {
+		return err
+	}
+
+	// El inodo tiene un nombre más
+	targetInode.I_links = sb.InodeLinks(targetInode) + 1
+	targetInode.I_ctime = float32(time.Now().Unix())
+	return targetInode.Serialize(path, int64(sb.S_inode_start+(targetIndex*sb.S_inode_size)))
+}
+
+// newEntryFolder resuelve la carpeta donde se agregará destDir y verifica que el nombre no exista ni sea inválido
+func (sb *SuperBlock) newEntryFolder(path string, parentsDir []string, destDir string) (int32, *Inode, error) {
+	folderIndex, err := sb.resolveFolder(path, parentsDir)
+	if err != nil {
+		return -1, nil, err
+	}
+	if folderIndex == -1 {
+		return -1, nil, fmt.Errorf("no existe la carpeta padre de %s", destDir)
+	}
+
+	folderInode := &Inode{}
+	err = folderInode.Deserialize(path, int64(sb.S_inode_start+(folderIndex*sb.S_inode_size)))
+	if err != nil {
+		return -1, nil, err
+	}
+
+	// Verificar que no exista ya una entrada con ese nombre
+	existing, err := sb.findFolderEntry(path, folderInode, destDir)
+	if err != nil {
+		return -1, nil, err
+	}
+	if existing != -1 {
+		return -1, nil, fmt.Errorf("ya existe una entrada con el nombre %s", destDir)
+	}
+
+	// Validar el nombre antes de reservar nada
+	err = sb.validateEntryName(destDir)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	return folderIndex, folderInode, nil
+}
+
+// InodeLinks devuelve cuántas entradas apuntan al inodo; sin FeatureLinkCount cada inodo tiene un único nombre
+func (sb *SuperBlock) InodeLinks(inode *Inode) int32 {
+	if !sb.HasFeature(FeatureLinkCount) || inode.I_links < 1 {
+		return 1
+	}
+	return inode.I_links
+}
+
+// unlinkInode quita una entrada que apuntaba al inodo; solo lo libera cuando ya no le quedan enlaces
+func (sb *SuperBlock) unlinkInode(path string, inodeIndex int32) error {
+	inode := &Inode{}
+	err := inode.Deserialize(path, int64(sb.S_inode_start+(inodeIndex*sb.S_inode_size)))
+	if err != nil {
+		return err
+	}
+
+	links := sb.InodeLinks(inode)
+	if links > 1 {
+		inode.I_links = links - 1
+		inode.I_ctime = float32(time.Now().Unix())
+		return inode.Serialize(path, int64(sb.S_inode_start+(inodeIndex*sb.S_inode_size)))
+	}
+
+	return sb.releaseInode(path, inodeIndex)
+}
@@ -0,0 +1,360 @@
+package structures
+
+import (
+	"fmt"
+	"time"
+)
+
+// Direccionamiento de bloques estilo ext2:
+// I_block[0..11] directos, I_block[12] indirecto simple, I_block[13] doble, I_block[14] triple.
+const directBlocks = 12
+
+// pointersPerBlock devuelve cuántos apuntadores caben en un bloque del sistema de archivos
+func (sb *SuperBlock) pointersPerBlock() int32 {
+	return sb.S_block_size / 4
+}
+
+// MaxFileBlocks es la cantidad máxima de bloques de datos que puede direccionar un inodo
+func (sb *SuperBlock) MaxFileBlocks() int32 {
+	return directBlocks + sb.levelSpan(1) + sb.levelSpan(2) + sb.levelSpan(3)
+}
+
+// levelSpan devuelve cuántos bloques de datos cubre un bloque de apuntadores del nivel indicado
+func (sb *SuperBlock) levelSpan(level int) int32 {
+	span := int32(1)
+	for i := 0; i < level; i++ {
+		span *= sb.pointersPerBlock()
+	}
+	return span
+}
+
+// newPointerBlock reserva un bloque de apuntadores vacío
+func (sb *SuperBlock) newPointerBlock(path string) (int32, error) {
+	blockIndex, err := sb.AllocateBlock(path)
+	if err != nil {
+		return -1, err
+	}
+
+	pointerBlock := sb.NewPointerBlock()
+	for i := range pointerBlock.P_pointers {
+		pointerBlock.P_pointers[i] = -1
+	}
+
+	err = pointerBlock.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+	if err != nil {
+		return -1, err
+	}
+	return blockIndex, nil
+}
+
+// InodeDataBlocks devuelve, en orden lógico, los bloques de datos de un inodo
+func (sb *SuperBlock) InodeDataBlocks(path string, inode *Inode) ([]int32, error) {
+	dataBlocks, _, err := sb.collectInodeBlocks(path, inode)
+	if err != nil {
+		return nil, err
+	}
+	return dataBlocks, nil
+}
+
+// InodePointerBlocks devuelve los bloques de apuntadores (simples, dobles y triples) de un inodo
+func (sb *SuperBlock) InodePointerBlocks(path string, inode *Inode) ([]int32, error) {
+	_, pointerBlocks, err := sb.collectInodeBlocks(path, inode)
+	if err != nil {
+		return nil, err
+	}
+	return pointerBlocks, nil
+}
+
+// collectInodeBlocks devuelve los bloques de datos de un inodo y los bloques de apuntadores que los referencian
+func (sb *SuperBlock) collectInodeBlocks(path string, inode *Inode) ([]int32, []int32, error) {
+	dataBlocks := make([]int32, 0)
+	pointerBlocks := make([]int32, 0)
+
+	for i, blockIndex := range inode.I_block {
+		if blockIndex == -1 {
+			continue
+		}
+		// Apuntadores directos
+		if i < directBlocks {
+			dataBlocks = append(dataBlocks, blockIndex)
+			continue
+		}
+		// Apuntadores indirectos: 12 simple, 13 doble, 14 triple
+		err := sb.collectIndirectBlocks(path, blockIndex, i-directBlocks+1, &dataBlocks, &pointerBlocks)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return dataBlocks, pointerBlocks, nil
+}
+
+// collectIndirectBlocks recorre un bloque de apuntadores del nivel indicado
+func (sb *SuperBlock) collectIndirectBlocks(path string, blockIndex int32, level int, dataBlocks *[]int32, pointerBlocks *[]int32) error {
+	pointerBlock := sb.NewPointerBlock()
+	err := pointerBlock.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+	if err != nil {
+		return err
+	}
+	*pointerBlocks = append(*pointerBlocks, blockIndex)
+
+	for _, pointer := range pointerBlock.P_pointers {
+		if pointer == -1 {
+			continue
+		}
+		if level == 1 {
+			*dataBlocks = append(*dataBlocks, pointer)
+			continue
+		}
+		err := sb.collectIndirectBlocks(path, pointer, level-1, dataBlocks, pointerBlocks)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setInodeBlock enlaza blockIndex como el bloque lógico n del inodo, creando los bloques de apuntadores necesarios.
+// Solo modifica el inodo en memoria; quien llama debe serializarlo.
+func (sb *SuperBlock) setInodeBlock(path string, inode *Inode, n int32, blockIndex int32) error {
+	if n < directBlocks {
+		inode.I_block[n] = blockIndex
+		return nil
+	}
+
+	n -= directBlocks
+	for level := 1; level <= 3; level++ {
+		span := sb.levelSpan(level)
+		if n >= span {
+			n -= span
+			continue
+		}
+
+		slot := directBlocks + level - 1
+		if inode.I_block[slot] == -1 {
+			fmt.Println("Creando bloque de apuntadores indirecto de nivel", level)
+			pointerIndex, err := sb.newPointerBlock(path)
+			if err != nil {
+				return err
+			}
+			inode.I_block[slot] = pointerIndex
+		}
+		return sb.setIndirectBlock(path, inode.I_block[slot], level, n, blockIndex)
+	}
+
+	return fmt.Errorf("el archivo excede el máximo de %d bloques", sb.MaxFileBlocks())
+}
+
+// setIndirectBlock baja por el árbol de apuntadores hasta la posición n y la enlaza a blockIndex
+func (sb *SuperBlock) setIndirectBlock(path string, pointerIndex int32, level int, n int32, blockIndex int32) error {
+	pointerBlock := sb.NewPointerBlock()
+	err := pointerBlock.Deserialize(path, int64(sb.S_block_start+(pointerIndex*sb.S_block_size)))
+	if err != nil {
+		return err
+	}
+
+	if level == 1 {
+		pointerBlock.P_pointers[n] = blockIndex
+		return pointerBlock.Serialize(path, int64(sb.S_block_start+(pointerIndex*sb.S_block_size)))
+	}
+
+	span := sb.levelSpan(level - 1)
+	slot := n / span
+	if pointerBlock.P_pointers[slot] == -1 {
+		childIndex, err := sb.newPointerBlock(path)
+		if err != nil {
+			return err
+		}
+		pointerBlock.P_pointers[slot] = childIndex
+		err = pointerBlock.Serialize(path, int64(sb.S_block_start+(pointerIndex*sb.S_block_size)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return sb.setIndirectBlock(path, pointerBlock.P_pointers[slot], level-1, n%span, blockIndex)
+}
+
+// truncateInodeBlocks conserva los primeros keep bloques de datos del inodo y libera el resto,
+// junto con los bloques de apuntadores que queden vacíos. Con keep = 0 libera todo.
+// Solo modifica el inodo en memoria; quien llama debe serializarlo.
+func (sb *SuperBlock) truncateInodeBlocks(path string, inode *Inode, keep int32) error {
+	// Apuntadores directos
+	for i := keep; i < directBlocks; i++ {
+		if i < 0 || inode.I_block[i] == -1 {
+			continue
+		}
+		err := sb.FreeBitmapBlock(path, inode.I_block[i])
+		if err != nil {
+			return err
+		}
+		inode.I_block[i] = -1
+	}
+
+	// Apuntadores indirectos
+	base := int32(directBlocks)
+	for level := 1; level <= 3; level++ {
+		slot := directBlocks + level - 1
+		span := sb.levelSpan(level)
+
+		if inode.I_block[slot] != -1 {
+			empty, err := sb.truncateIndirect(path, inode.I_block[slot], level, clampBlocks(keep-base, span))
+			if err != nil {
+				return err
+			}
+			if empty {
+				err := sb.FreeBitmapBlock(path, inode.I_block[slot])
+				if err != nil {
+					return err
+				}
+				inode.I_block[slot] = -1
+			}
+		}
+		base += span
+	}
+
+	return nil
+}
+
+// truncateIndirect libera lo que sobra después de keep dentro de un bloque de apuntadores y
+// reporta si el bloque quedó vacío
+func (sb *SuperBlock) truncateIndirect(path string, pointerIndex int32, level int, keep int32) (bool, error) {
+	pointerBlock := sb.NewPointerBlock()
+	err := pointerBlock.Deserialize(path, int64(sb.S_block_start+(pointerIndex*sb.S_block_size)))
+	if err != nil {
+		return false, err
+	}
+
+	span := sb.levelSpan(level - 1)
+	empty := true
+	for i, pointer := range pointerBlock.P_pointers {
+		if pointer == -1 {
+			continue
+		}
+
+		childKeep := clampBlocks(keep-int32(i)*span, span)
+		release := childKeep == 0
+		if level > 1 {
+			release, err = sb.truncateIndirect(path, pointer, level-1, childKeep)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		if release {
+			err := sb.FreeBitmapBlock(path, pointer)
+			if err != nil {
+				return false, err
+			}
+			pointerBlock.P_pointers[i] = -1
+			continue
+		}
+		empty = false
+	}
+
+	err = pointerBlock.Serialize(path, int64(sb.S_block_start+(pointerIndex*sb.S_block_size)))
+	if err != nil {
+		return false, err
+	}
+	return empty, nil
+}
+
+// clampBlocks limita n al rango [0, max]
+func clampBlocks(n int32, max int32) int32 {
+	if n < 0 {
+		return 0
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// ReadInodeContent devuelve el contenido completo de los bloques de datos de un archivo
+func (sb *SuperBlock) ReadInodeContent(path string, inode *Inode) (string, error) {
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return "", err
+	}
+
+	allContent := ""
+	for _, blockIndex := range dataBlocks {
+		fileBlock := sb.NewFileBlock()
+		err := fileBlock.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+		if err != nil {
+			return "", err
+		}
+		allContent += string(fileBlock.B_content[:])
+	}
+
+	return allContent, nil
+}
+
+// writeInodeContent reemplaza el contenido de un archivo: reutiliza sus bloques, reserva los que
+// falten y libera los que sobren. Un archivo vacío conserva un bloque vacío, igual que al crearlo.
+// Solo modifica el inodo en memoria; quien llama debe serializarlo.
+func (sb *SuperBlock) writeInodeContent(path string, inode *Inode, content string) error {
+	// En ext3 el contenido completo queda en el journal junto con la transacción
+	recordPayload(path, int64(sb.S_inode_start), content)
+
+	// dividir el contenido en bloques del tamaño de bloque
+	blockSize := int(sb.S_block_size)
+	contentBlocks := make([]string, 0)
+	for i := 0; i < len(content); i += blockSize {
+		if i+blockSize > len(content) {
+			contentBlocks = append(contentBlocks, content[i:])
+		} else {
+			contentBlocks = append(contentBlocks, content[i:i+blockSize])
+		}
+	}
+	if len(contentBlocks) == 0 {
+		contentBlocks = append(contentBlocks, "")
+	}
+	if int32(len(contentBlocks)) > sb.MaxFileBlocks() {
+		return fmt.Errorf("el contenido necesita %d bloques y un archivo admite como máximo %d", len(contentBlocks), sb.MaxFileBlocks())
+	}
+
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+
+	// Liberar los bloques que sobran antes de reservar, así se pueden reutilizar
+	if len(dataBlocks) > len(contentBlocks) {
+		err := sb.truncateInodeBlocks(path, inode, int32(len(contentBlocks)))
+		if err != nil {
+			return err
+		}
+		dataBlocks = dataBlocks[:len(contentBlocks)]
+	}
+
+	// Reservar todos los bloques que faltan de una vez para que el ajuste los deje contiguos
+	if len(contentBlocks) > len(dataBlocks) {
+		newBlocks, err := sb.AllocateBlocks(path, int32(len(contentBlocks)-len(dataBlocks)))
+		if err != nil {
+			return err
+		}
+		for _, blockIndex := range newBlocks {
+			err := sb.setInodeBlock(path, inode, int32(len(dataBlocks)), blockIndex)
+			if err != nil {
+				return err
+			}
+			dataBlocks = append(dataBlocks, blockIndex)
+		}
+	}
+
+	for i, blockIndex := range dataBlocks {
+		fileBlock := sb.NewFileBlock()
+		copy(fileBlock.B_content, contentBlocks[i])
+
+		err := fileBlock.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+		if err != nil {
+			return err
+		}
+	}
+
+	inode.I_size = int32(len(content))
+	inode.I_mtime = float32(time.Now().Unix())
+	return nil
+}
@@ -4,87 +4,94 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
-	"os"
 	"time"
 )
 
 // Inode represents a filesystem inode, which stores metadata about a file or directory.
 // It includes information such as ownership, size, timestamps, block pointers, type, and permissions.
 type Inode struct {
-	I_uid   int32     // User ID of the owner
-	I_gid   int32     // Group ID of the owner
-	I_size  int32     // Size of the file in bytes
-	I_atime float32   // Last access time (as a Unix timestamp)
-	I_ctime float32   // Creation time (as a Unix timestamp)
-	I_mtime float32   // Last modification time (as a Unix timestamp)
-	I_block [15]int32 // Pointers to data blocks (15 blocks)
-	I_type  [1]byte   // Type of the inode (e.g., file, directory)
-	I_perm  [3]byte   // Permissions (e.g., read, write, execute)
-	// Total size: 88 bytes
+	I_uid      int32     // User ID of the owner
+	I_gid      int32     // Group ID of the owner
+	I_size     int32     // Size of the file in bytes
+	I_atime    float32   // Last access time (as a Unix timestamp)
+	I_ctime    float32   // Creation time (as a Unix timestamp)
+	I_mtime    float32   // Last modification time (as a Unix timestamp)
+	I_block    [15]int32 // Pointers to data blocks (15 blocks)
+	I_type     [1]byte   // Type of the inode: '0' directory, '1' file, '2' symbolic link
+	I_perm     [3]byte   // Permissions (e.g., read, write, execute)
+	I_links    int32     // Number of directory entries (hard links) pointing to this inode
+	I_checksum uint32    // CRC32C of the inode (FeatureMetadataCsum)
+	// Total size: 96 bytes
 }
 
+// Offsets of the optional fields inside an encoded Inode
+const (
+	inodeLinksOffset    = legacyInodeSize
+	inodeChecksumOffset = legacyInodeSize + 4
+)
+
 // Serialize writes the Inode structure to a binary file at the specified offset.
 // This function is used to persist the Inode data to disk.
+// In a mounted partition only the fields of its layout are written: I_links with FeatureLinkCount and
+// I_checksum with FeatureMetadataCsum, never past S_inode_size.
 func (inode *Inode) Serialize(path string, offset int64) error {
-	// Open the file for writing or create it if it doesn't exist
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	err := sealStruct(path, offset, inode, &inode.I_checksum)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	layout, ok := layoutAt(path, offset)
+	if !ok {
+		return writeStruct(path, offset, inode)
+	}
 
-	// Move the file pointer to the specified offset
-	_, err = file.Seek(offset, 0)
+	var buffer bytes.Buffer
+	err = binary.Write(&buffer, binary.LittleEndian, inode)
 	if err != nil {
 		return err
 	}
+	data := buffer.Bytes()
 
-	// Serialize the Inode structure directly into the file
-	err = binary.Write(file, binary.LittleEndian, inode)
-	if err != nil {
+	size := int64(legacyInodeSize)
+	if layout.has(FeatureLinkCount) && layout.inodeSize >= inodeChecksumOffset {
+		size = inodeChecksumOffset
+	}
+	withChecksum := layout.has(FeatureMetadataCsum) && layout.inodeSize >= int64(len(data))
+	if withChecksum && size == inodeChecksumOffset {
+		return DeviceWriteAt(path, data, offset)
+	}
+	err = DeviceWriteAt(path, data[:size], offset)
+	if err != nil || !withChecksum {
 		return err
 	}
-
-	return nil
+	return DeviceWriteAt(path, data[inodeChecksumOffset:], offset+inodeChecksumOffset)
 }
 
 // Deserialize reads the Inode structure from a binary file at the specified offset.
 // This function is used to load the Inode data from disk into memory.
+// The optional fields that the layout of the partition does not store are left at zero.
 func (inode *Inode) Deserialize(path string, offset int64) error {
-	// Open the file for reading
-	file, err := os.Open(path)
-	if err != nil {
-		return err
+	buffer := make([]byte, binary.Size(Inode{}))
+	layout, ok := layoutAt(path, offset)
+	if ok && layout.inodeSize < int64(len(buffer)) {
+		buffer = buffer[:max(layout.inodeSize, legacyInodeSize)]
 	}
-	defer file.Close()
-
-	// Move the file pointer to the specified offset
-	_, err = file.Seek(offset, 0)
+	err := DeviceReadAt(path, buffer, offset)
 	if err != nil {
 		return err
 	}
-
-	// Get the size of the Inode structure
-	inodeSize := binary.Size(inode)
-	if inodeSize <= 0 {
-		return fmt.Errorf("invalid Inode size: %d", inodeSize)
-	}
-
-	// Read only the number of bytes corresponding to the size of the Inode structure
-	buffer := make([]byte, inodeSize)
-	_, err = file.Read(buffer)
+	buffer = append(buffer, make([]byte, binary.Size(Inode{})-len(buffer))...)
+	err = binary.Read(bytes.NewReader(buffer), binary.LittleEndian, inode)
 	if err != nil {
 		return err
 	}
 
-	// Deserialize the read bytes into the Inode structure
-	reader := bytes.NewReader(buffer)
-	err = binary.Read(reader, binary.LittleEndian, inode)
-	if err != nil {
-		return err
+	if ok && !layout.has(FeatureLinkCount) {
+		inode.I_links = 0
 	}
-
-	return nil
+	if ok && !layout.has(FeatureMetadataCsum) {
+		inode.I_checksum = 0
+	}
+	return verifyStruct("el inodo", path, offset, inode, &inode.I_checksum)
 }
 
 // Print displays the attributes of the Inode in a human-readable format.
@@ -105,4 +112,6 @@ func (inode *Inode) Print() {
 	fmt.Printf("I_block: %v\n", inode.I_block)
 	fmt.Printf("I_type: %s\n", string(inode.I_type[:]))
 	fmt.Printf("I_perm: %s\n", string(inode.I_perm[:]))
+	fmt.Printf("I_links: %d\n", inode.I_links)
+	fmt.Printf("I_checksum: %08x\n", inode.I_checksum)
 }
@@ -1,16 +1,38 @@
 package structures
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 )
 
+// El journal de EXT3 es un registro de escritura anticipada: cada comando es una transacción con un
+// registro begin (la operación), registros payload (el contenido completo de los archivos que escribe,
+// repartido en tantos registros como haga falta), registros update (los bytes que escribe en la partición)
+// y un registro commit. Los cambios se escriben en su lugar solo después del commit, así una transacción interrumpida
+// se descarta y una confirmada se puede rehacer cuantas veces sea necesario.
+
+// Tipos de registro del journal
+const (
+	JournalBegin   byte = 'B' // inicio de una transacción, J_data lleva la Information y J_offset la fecha Unix
+	JournalPayload byte = 'P' // parte del contenido completo de un archivo escrito, J_offset es el número de contenido
+	JournalUpdate  byte = 'U' // J_length bytes de J_data que la transacción escribe en J_offset
+	JournalCommit  byte = 'C' // la transacción está completa pero sus cambios aún no se aplicaron
+	JournalApplied byte = 'A' // commit cuyos cambios ya están escritos en la partición
+)
+
 type Journal struct {
-	J_count   int32       // 4 bytes
-	J_content Information // 110 bytes
-	// Total: 114 bytes
+	J_count  int32     // 4 bytes, número de secuencia del registro
+	J_type   [1]byte   // 1 byte, tipo de registro
+	J_tx     int32     // 4 bytes, número de su transacción
+	J_offset int64     // 8 bytes, posición absoluta de una actualización o fecha de un begin
+	J_length int32     // 4 bytes, bytes usados de J_data
+	J_data   [110]byte // 110 bytes, Information o contenido de la actualización
+	// Total: 131 bytes
 }
 
 type Information struct {
@@ -21,68 +43,370 @@ type Information struct {
 	// Total: 110 bytes
 }
 
-// SerializeJournal escribe la estructura Journal en un archivo binario
-func (journal *Journal) Serialize(path string, journauling_start int64) error {
-	// Calcular la posición en el archivo
-	if journauling_start == -1 {
-		fmt.Println("Not serializing journal, no journaling start")
-		return nil
+// NewInformation crea la descripción de una operación, recortando los textos a su campo
+func NewInformation(operation string, path string, content string) Information {
+	info := Information{I_date: float32(time.Now().Unix())}
+	copy(info.I_operation[:], operation)
+	copy(info.I_path[:], path)
+	copy(info.I_content[:], content)
+	return info
+}
+
+// Operation, Path y Content devuelven los campos de Information sin los bytes nulos
+func (info *Information) Operation() string {
+	return strings.TrimRight(string(info.I_operation[:]), "\x00")
+}
+
+func (info *Information) Path() string {
+	return strings.TrimRight(string(info.I_path[:]), "\x00")
+}
+
+func (info *Information) Content() string {
+	return strings.TrimRight(string(info.I_content[:]), "\x00")
+}
+
+// Serialize escribe el registro del journal en la posición indicada
+func (journal *Journal) Serialize(path string, offset int64) error {
+	return writeStruct(path, offset, journal)
+}
+
+// Deserialize lee el registro del journal desde la posición indicada
+func (journal *Journal) Deserialize(path string, offset int64) error {
+	return readStruct(path, offset, journal)
+}
+
+// Information decodifica la operación guardada en un registro begin
+func (journal *Journal) Information() (Information, error) {
+	info := Information{}
+	err := binary.Read(bytes.NewReader(journal.J_data[:]), binary.LittleEndian, &info)
+	return info, err
+}
+
+// date devuelve la fecha de un registro begin. I_date es un float32 que pierde los segundos, por eso el begin
+// guarda la fecha completa en J_offset; un begin sin ella usa I_date.
+func (journal *Journal) date(info Information) time.Time {
+	if journal.J_offset > 0 {
+		return time.Unix(journal.J_offset, 0)
 	}
-	offset := journauling_start + (int64(binary.Size(Journal{})) * int64(journal.J_count))
+	return time.Unix(int64(info.I_date), 0)
+}
 
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+// setInformation guarda la operación en un registro begin
+func (journal *Journal) setInformation(info Information) error {
+	var buffer bytes.Buffer
+	err := binary.Write(&buffer, binary.LittleEndian, info)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	copy(journal.J_data[:], buffer.Bytes())
+	journal.J_length = int32(buffer.Len())
+	return nil
+}
 
-	// Mover el puntero del archivo a la posición especificada
-	_, err = file.Seek(offset, 0)
-	if err != nil {
-		return err
+// PrintJournal imprime en consola la estructura Journal
+func (journal *Journal) Print() {
+	fmt.Println("Journal:")
+	fmt.Printf("J_count: %d\n", journal.J_count)
+	fmt.Printf("J_type: %c\n", journal.J_type[0])
+	fmt.Printf("J_tx: %d\n", journal.J_tx)
+
+	switch journal.J_type[0] {
+	case JournalBegin:
+		info, err := journal.Information()
+		if err != nil {
+			fmt.Println("Information ilegible:", err)
+			return
+		}
+		date := journal.date(info)
+		fmt.Println("Information:")
+		fmt.Printf("I_operation: %s\n", info.Operation())
+		fmt.Printf("I_path: %s\n", info.Path())
+		fmt.Printf("I_content: %s\n", info.Content())
+		fmt.Printf("I_date: %s\n", date.Format(time.RFC3339))
+	case JournalPayload:
+		fmt.Printf("Contenido: %d\n", journal.J_offset)
+		fmt.Printf("J_length: %d\n", journal.J_length)
+	case JournalUpdate:
+		fmt.Printf("J_offset: %d\n", journal.J_offset)
+		fmt.Printf("J_length: %d\n", journal.J_length)
 	}
+}
+
+// JournalMagic identifica un journal inicializado por mkfs
+const JournalMagic = 0x4A524E4C
+
+// JournalSuperBlock ocupa el primer registro del área del journal. Los demás registros forman un anillo:
+// las transacciones se escriben en tail y el checkpoint libera las más antiguas avanzando head.
+type JournalSuperBlock struct {
+	JS_magic       int32   // 4 bytes, JournalMagic
+	JS_slots       int32   // 4 bytes, registros del anillo
+	JS_head        int32   // 4 bytes, registro más antiguo en uso
+	JS_tail        int32   // 4 bytes, siguiente registro libre
+	JS_used        int32   // 4 bytes, registros en uso desde head
+	JS_sequence    int32   // 4 bytes, número de secuencia del siguiente registro
+	JS_checkpoints int32   // 4 bytes, checkpoints realizados
+	JS_checkpoint  float32 // 4 bytes, fecha del último checkpoint
+	JS_transaction int32   // 4 bytes, número de la siguiente transacción
+	// Total: 36 bytes
+}
+
+// Serialize escribe el superbloque del journal al inicio del área del journal
+func (js *JournalSuperBlock) Serialize(path string, offset int64) error {
+	return writeStruct(path, offset, js)
+}
 
-	// Serializar la estructura Journal directamente en el archivo
-	err = binary.Write(file, binary.LittleEndian, journal)
+// Deserialize lee el superbloque del journal
+func (js *JournalSuperBlock) Deserialize(path string, offset int64) error {
+	return readStruct(path, offset, js)
+}
+
+// Free devuelve cuántos registros del anillo están libres
+func (js *JournalSuperBlock) Free() int32 {
+	return js.JS_slots - js.JS_used
+}
+
+// MinJournalSlots es la menor cantidad de registros con que se formatea un journal: un mkdir ocupa 8 y un mkfile
+// pequeño 10, con menos el journal no alcanzaría para ningún comando
+const MinJournalSlots = 32
+
+// JournalSlots devuelve cuántos registros caben en el área del journal, uno por inodo. En el formato anterior
+// el área tiene una entrada más corta por inodo y los registros se reparten en el mismo espacio.
+func (sb *SuperBlock) JournalSlots() int32 {
+	if sb.Legacy() {
+		return int32(int64(sb.TotalInodes()) * legacyJournalSize / int64(binary.Size(Journal{})))
+	}
+	return sb.TotalInodes()
+}
+
+// journalSlotOffset devuelve la posición absoluta del registro slot del anillo, después del superbloque del journal
+func (sb *SuperBlock) journalSlotOffset(slot int32) int64 {
+	return sb.JournalStart() + int64(slot+1)*int64(binary.Size(Journal{}))
+}
+
+// ClearJournal deja el journal vacío con su superbloque inicializado, se usa al formatear
+func (sb *SuperBlock) ClearJournal(path string) error {
+	size := int64(sb.JournalSlots()) * int64(binary.Size(Journal{}))
+	err := DeviceWriteAt(path, make([]byte, size), sb.JournalStart())
 	if err != nil {
 		return err
 	}
 
-	return nil
+	js := &JournalSuperBlock{
+		JS_magic:       JournalMagic,
+		JS_slots:       sb.JournalSlots() - 1,
+		JS_sequence:    1,
+		JS_transaction: 1,
+		JS_checkpoint:  float32(time.Now().Unix()),
+	}
+	return js.Serialize(path, sb.JournalStart())
 }
 
-// DeserializeJournal lee la estructura Journal desde un archivo binario
-func (journal *Journal) Deserialize(path string, offset int64) error {
-	file, err := os.Open(path)
+// ReadJournalSuperBlock lee el superbloque del journal de la partición
+func (sb *SuperBlock) ReadJournalSuperBlock(path string) (*JournalSuperBlock, error) {
+	js := &JournalSuperBlock{}
+	err := js.Deserialize(path, sb.JournalStart())
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if js.JS_magic != JournalMagic || js.JS_slots != sb.JournalSlots()-1 || js.JS_used < 0 || js.JS_used > js.JS_slots {
+		return nil, fmt.Errorf("el journal de la partición no está inicializado o está dañado")
+	}
+	return js, nil
+}
+
+// JournalTransaction es una transacción leída del journal
+type JournalTransaction struct {
+	Number   int32       // número de la transacción, consecutivo
+	Slot     int32       // posición de su registro begin en el anillo
+	Info     Information // operación que la originó
+	Date     time.Time   // fecha de su registro begin
+	Payloads []string    // contenidos completos de los archivos que escribió, en orden
+	Updates  []Journal   // registros update en orden
+	Status   byte        // JournalCommit, JournalApplied o JournalBegin si quedó incompleta
+	records  int32       // registros del anillo que ocupa
+}
+
+// PayloadSize devuelve el total de bytes de contenido que guardó la transacción
+func (transaction *JournalTransaction) PayloadSize() int {
+	size := 0
+	for _, payload := range transaction.Payloads {
+		size += len(payload)
 	}
+	return size
+}
 
-	// Mover el puntero del archivo a la posición especificada
-	_, err = file.Seek(offset, 0)
+// PayloadHash devuelve el SHA-256 en hexadecimal de los contenidos de la transacción, uno tras otro
+func (transaction *JournalTransaction) PayloadHash() string {
+	hash := sha256.New()
+	for _, payload := range transaction.Payloads {
+		hash.Write([]byte(payload))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Records devuelve cuántos registros del anillo ocupa la transacción
+func (transaction *JournalTransaction) Records() int32 {
+	return transaction.records
+}
+
+// ReadJournal devuelve las transacciones del journal desde head, en orden, y el superbloque del journal.
+// Una transacción sin commit solo puede ser la última, es la de un comando que se interrumpió.
+func (sb *SuperBlock) ReadJournal(path string) ([]JournalTransaction, *JournalSuperBlock, error) {
+	js, err := sb.ReadJournalSuperBlock(path)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	// Deserializar la estructura Journal directamente desde el archivo
-	err = binary.Read(file, binary.LittleEndian, journal)
+	transactions := []JournalTransaction{}
+	var current *JournalTransaction
+
+	for i := int32(0); i < js.JS_used; i++ {
+		slot := (js.JS_head + i) % js.JS_slots
+		record := &Journal{}
+		err := record.Deserialize(path, sb.journalSlotOffset(slot))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error al leer el registro %d del journal: %w", slot, err)
+		}
+
+		switch record.J_type[0] {
+		case JournalBegin:
+			if current != nil {
+				transactions = append(transactions, *current)
+			}
+			info, err := record.Information()
+			if err != nil {
+				return nil, nil, err
+			}
+			current = &JournalTransaction{Number: record.J_tx, Slot: slot, Info: info, Date: record.date(info), Status: JournalBegin}
+		case JournalPayload:
+			if current == nil || record.J_tx != current.Number || current.Status != JournalBegin || len(current.Updates) > 0 {
+				return nil, nil, fmt.Errorf("el registro %d del journal no pertenece a ninguna transacción abierta", slot)
+			}
+			// Los registros de un mismo contenido son consecutivos y comparten J_offset
+			index := int(record.J_offset)
+			if index == len(current.Payloads) {
+				current.Payloads = append(current.Payloads, "")
+			} else if index != len(current.Payloads)-1 {
+				return nil, nil, fmt.Errorf("el registro %d del journal tiene un contenido fuera de orden", slot)
+			}
+			current.Payloads[index] += string(record.J_data[:record.J_length])
+		case JournalUpdate:
+			if current == nil || record.J_tx != current.Number || current.Status != JournalBegin {
+				return nil, nil, fmt.Errorf("el registro %d del journal no pertenece a ninguna transacción abierta", slot)
+			}
+			current.Updates = append(current.Updates, *record)
+		case JournalCommit, JournalApplied:
+			if current == nil || record.J_tx != current.Number || current.Status != JournalBegin {
+				return nil, nil, fmt.Errorf("el commit %d del journal no pertenece a ninguna transacción abierta", slot)
+			}
+			current.Status = record.J_type[0]
+		default:
+			return nil, nil, fmt.Errorf("el registro %d del journal tiene un tipo desconocido", slot)
+		}
+		current.records++
+	}
+	if current != nil {
+		transactions = append(transactions, *current)
+	}
+	return transactions, js, nil
+}
+
+// ReplayJournal rehace las transacciones confirmadas del journal en orden. Con all se rehacen todas las que
+// conserva el anillo (recovery); si no, solo las que no se alcanzaron a aplicar (mount).
+// La transacción incompleta del final se descarta. Devuelve cuántas transacciones se rehicieron.
+func (sb *SuperBlock) ReplayJournal(path string, all bool) (int, error) {
+	transactions, js, err := sb.ReadJournal(path)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	replayed := 0
+	for _, transaction := range transactions {
+		if transaction.Status == JournalBegin {
+			fmt.Printf("Descartando la transacción %d (%s) que no tiene commit\n", transaction.Number, transaction.Info.Operation())
+			js.JS_tail = transaction.Slot
+			js.JS_used -= transaction.Records()
+			err := js.Serialize(path, sb.JournalStart())
+			if err != nil {
+				return replayed, err
+			}
+			break
+		}
+		if transaction.Status == JournalApplied && !all {
+			continue
+		}
+
+		err := sb.applyTransaction(path, js, &transaction)
+		if err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+	return replayed, nil
 }
 
-// PrintJournal imprime en consola la estructura Journal
-func (journal *Journal) Print() {
-	// Convertir el tiempo de montaje a una fecha
-	date := time.Unix(int64(journal.J_content.I_date), 0)
+// applyTransaction escribe en su lugar las actualizaciones de una transacción confirmada y marca su commit
+func (sb *SuperBlock) applyTransaction(path string, js *JournalSuperBlock, transaction *JournalTransaction) error {
+	fmt.Printf("Rehaciendo la transacción %d (%s %s)\n", transaction.Number, transaction.Info.Operation(), transaction.Info.Path())
+	for _, update := range transaction.Updates {
+		err := DeviceWriteAt(path, update.J_data[:update.J_length], update.J_offset)
+		if err != nil {
+			return err
+		}
+	}
+	commitSlot := (transaction.Slot + transaction.Records() - 1) % js.JS_slots
+	return sb.markApplied(path, commitSlot)
+}
 
-	fmt.Println("Journal:")
-	fmt.Printf("J_count: %d\n", journal.J_count)
-	fmt.Println("Information:")
-	fmt.Printf("I_operation: %s\n", string(journal.J_content.I_operation[:]))
-	fmt.Printf("I_path: %s\n", string(journal.J_content.I_path[:]))
-	fmt.Printf("I_content: %s\n", string(journal.J_content.I_content[:]))
-	fmt.Printf("I_date: %s\n", date.Format(time.RFC3339))
+// CheckpointJournal aplica las transacciones confirmadas que estén pendientes y libera las más antiguas del anillo
+// hasta que haya needed registros libres; con needed negativo libera todas. Devuelve cuántas transacciones liberó.
+func (sb *SuperBlock) CheckpointJournal(path string, needed int32) (int, error) {
+	transactions, js, err := sb.ReadJournal(path)
+	if err != nil {
+		return 0, err
+	}
+
+	freed := 0
+	for _, transaction := range transactions {
+		if needed >= 0 && js.Free() >= needed {
+			break
+		}
+		// Una transacción incompleta no se libera, la descarta el siguiente mount o recovery
+		if transaction.Status == JournalBegin {
+			break
+		}
+		if transaction.Status == JournalCommit {
+			err := sb.applyTransaction(path, js, &transaction)
+			if err != nil {
+				return freed, err
+			}
+		}
+		js.JS_head = (js.JS_head + transaction.Records()) % js.JS_slots
+		js.JS_used -= transaction.Records()
+		freed++
+	}
+
+	// Los cambios liberados ya están en la partición antes de mover head
+	err = SyncDevice(path)
+	if err != nil {
+		return freed, err
+	}
+	js.JS_checkpoints++
+	js.JS_checkpoint = float32(time.Now().Unix())
+	fmt.Printf("Checkpoint del journal: %d transacciones liberadas, %d registros libres\n", freed, js.Free())
+	return freed, js.Serialize(path, sb.JournalStart())
+}
+
+// markApplied marca el commit guardado en slot como aplicado
+func (sb *SuperBlock) markApplied(path string, slot int32) error {
+	record := &Journal{}
+	err := record.Deserialize(path, sb.journalSlotOffset(slot))
+	if err != nil {
+		return err
+	}
+	if record.J_type[0] == JournalApplied {
+		return nil
+	}
+	record.J_type[0] = JournalApplied
+	return record.Serialize(path, sb.journalSlotOffset(slot))
 }
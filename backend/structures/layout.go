@@ -0,0 +1,83 @@
+package structures
+
+import "sync"
+
+// A mounted partition is registered with the layout of its filesystem, so the structures read and written
+// inside it know what is on disk even when the superblock that describes it is not at hand.
+
+// partitionLayout describes a mounted partition.
+type partitionLayout struct {
+	start     int64 // partition bounds
+	end       int64
+	fit       byte  // Part_fit of the partition, the allocator fit of a legacy filesystem
+	inodeSize int64 // S_inode_size
+	features  int32 // S_features
+}
+
+var (
+	layoutsMu sync.Mutex
+	layouts   = make(map[string][]partitionLayout)
+)
+
+// TrackLayout registers the layout of the filesystem of sb for the partition of the disk at path, or
+// unregisters the partition if sb does not describe a filesystem.
+// It is called when the partition is mounted, formatted or its superblock is restored.
+func (sb *SuperBlock) TrackLayout(path string, partition *Partition) {
+	UntrackLayout(path, partition.Part_start)
+	if !sb.Formatted() {
+		return
+	}
+
+	layoutsMu.Lock()
+	defer layoutsMu.Unlock()
+	layouts[path] = append(layouts[path], partitionLayout{
+		start:     int64(partition.Part_start),
+		end:       int64(partition.Part_start) + int64(partition.Part_size),
+		fit:       partition.Part_fit[0],
+		inodeSize: int64(sb.S_inode_size),
+		features:  sb.S_features,
+	})
+}
+
+// UntrackLayout forgets the partition that starts at partStart (unmount).
+func UntrackLayout(path string, partStart int32) {
+	layoutsMu.Lock()
+	defer layoutsMu.Unlock()
+
+	kept := layouts[path][:0]
+	for _, layout := range layouts[path] {
+		if layout.start != int64(partStart) {
+			kept = append(kept, layout)
+		}
+	}
+	if len(kept) == 0 {
+		delete(layouts, path)
+		return
+	}
+	layouts[path] = kept
+}
+
+// forgetLayouts drops every partition of a disk that is being deleted or recreated.
+func forgetLayouts(path string) {
+	layoutsMu.Lock()
+	defer layoutsMu.Unlock()
+	delete(layouts, path)
+}
+
+// has reports whether the filesystem of the partition has the feature.
+func (layout partitionLayout) has(feature int32) bool {
+	return layout.features&feature != 0
+}
+
+// layoutAt returns the registered partition that contains offset, if any.
+func layoutAt(path string, offset int64) (partitionLayout, bool) {
+	layoutsMu.Lock()
+	defer layoutsMu.Unlock()
+
+	for _, layout := range layouts[path] {
+		if offset >= layout.start && offset < layout.end {
+			return layout, true
+		}
+	}
+	return partitionLayout{}, false
+}
@@ -1,10 +1,8 @@
 package structures
 
 import (
-	"bytes"
-	"encoding/binary"
 	"fmt"
-	"os"
+	"sort"
 	"strings"
 	"time"
 )
@@ -17,70 +15,221 @@ type MBR struct {
 	Mbr_partitions		[4]Partition
 }
 
-// serializes the MBR struct to a byte array
+// serializes the MBR struct to a byte array; a compat disk keeps it in LBA 1 and its standard MBR in sync
 func(mbr *MBR) SerializeMBR(path string) error {
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		return err
+	if IsCompatMBR(path) {
+		return mbr.SerializeCompatMBR(path)
 	}
-	defer file.Close()
+	return writeStruct(path, 0, mbr)
+}
 
-	err = binary.Write(file, binary.LittleEndian, mbr)
-	if err != nil {
-		return err
+// deserializes a byte array to a MBR struct
+func (mbr *MBR) DeserializeMBR(path string) error {
+	if IsCompatMBR(path) {
+		return readStruct(path, SectorSize, mbr)
 	}
+	return readStruct(path, 0, mbr)
+}
 
-	return nil
+// FreeGap is a run of unused bytes of the disk
+type FreeGap struct {
+	Start int32
+	Size  int32
 }
 
-// deserializes a byte array to a MBR struct
-func (mbr *MBR) DeserializeMBR(path string) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return err
+// Get the first free slot of the MBR and where a partition of size bytes goes with the given fit
+func (mbr *MBR) GetFreePartition(path string, size int32, fit byte) (*Partition, int, int, error) {
+	for i := 0; i < len(mbr.Mbr_partitions); i++ {
+		// if the start of the partition is -1 then it is free
+		if mbr.Mbr_partitions[i].Part_start == -1 {
+			start, err := mbr.FindGap(path, size, fit)
+			if err != nil {
+				return nil, -1, -1, err
+			}
+			return &mbr.Mbr_partitions[i], int(start), i, nil
+		}
 	}
 
-	defer file.Close()
+	return nil, -1, -1, fmt.Errorf("the disk already has %d partitions", len(mbr.Mbr_partitions))
+}
 
-	// get the size of the MBR struct
-	mbrSize := binary.Size(mbr)
-	if mbrSize <= 0 {
-		return fmt.Errorf("invalid size of MBR struct: %d", mbrSize)
+// usedPartitions returns the partitions that take space in the disk ordered by their start
+func (mbr *MBR) usedPartitions() []Partition {
+	used := make([]Partition, 0, len(mbr.Mbr_partitions))
+	for _, partition := range mbr.Mbr_partitions {
+		if partition.Part_start != -1 && partition.Part_size > 0 {
+			used = append(used, partition)
+		}
 	}
+	sort.Slice(used, func(i, j int) bool { return used[i].Part_start < used[j].Part_start })
+	return used
+}
 
-	// read the number of bytes of the MBR struct
-	buffer := make([]byte, mbrSize)
-	_, err = file.Read(buffer)
-	if err != nil {
-		return err
+// GetFreeGaps returns the free space of the disk between the MBR and its end, in order.
+// On a compat disk the gaps start and end on sector boundaries.
+func (mbr *MBR) GetFreeGaps(path string) []FreeGap {
+	first, align := mbrLayout(path)
+	gaps := []FreeGap{}
+	cursor := first
+	for _, partition := range mbr.usedPartitions() {
+		if end := alignDown(partition.Part_start, align); end > cursor {
+			gaps = append(gaps, FreeGap{Start: cursor, Size: end - cursor})
+		}
+		cursor = max(cursor, alignUp(partition.Part_start+partition.Part_size, align))
+	}
+	if end := alignDown(mbr.Mbr_size, align); cursor < end {
+		gaps = append(gaps, FreeGap{Start: cursor, Size: end - cursor})
 	}
+	return gaps
+}
 
-	// Deserialize the MBR struct
-	reader := bytes.NewReader(buffer)
-	err = binary.Read(reader, binary.LittleEndian, mbr)
-	if err != nil {
-		return err
+// FindGap chooses where a new partition of size bytes starts: in the first gap where it fits (F),
+// in the smallest one (B) or in the largest one (W)
+func (mbr *MBR) FindGap(path string, size int32, fit byte) (int32, error) {
+	return pickGap(mbr.GetFreeGaps(path), size, fit)
+}
+
+// pickGap chooses among gaps the one for size bytes with the given fit and returns its start
+func pickGap(gaps []FreeGap, size int32, fit byte) (int32, error) {
+	var chosen *FreeGap
+	largest := int32(0)
+	for i := range gaps {
+		gap := &gaps[i]
+		largest = max(largest, gap.Size)
+		if gap.Size < size {
+			continue
+		}
+		switch {
+		case chosen == nil:
+			chosen = gap
+		case fit == 'B' && gap.Size < chosen.Size:
+			chosen = gap
+		case fit == 'W' && gap.Size > chosen.Size:
+			chosen = gap
+		}
+		if fit != 'B' && fit != 'W' {
+			break
+		}
+	}
+	if chosen == nil {
+		return -1, fmt.Errorf("not enough contiguous space in the disk: %d bytes needed, the largest gap has %d", size, largest)
 	}
+	return chosen.Start, nil
+}
 
+// ValidateLayout checks that every partition lies inside the disk after the MBR and that none overlap.
+// On a compat disk a partition takes whole sectors.
+func (mbr *MBR) ValidateLayout(path string) error {
+	first, align := mbrLayout(path)
+	used := mbr.usedPartitions()
+	for i, partition := range used {
+		name := strings.Trim(string(partition.Part_name[:]), "\x00")
+		if partition.Part_start < first || partition.Part_start%align != 0 || alignUp(partition.Part_start+partition.Part_size, align) > mbr.Mbr_size {
+			return fmt.Errorf("partition %s [%d, %d) is outside the disk of %d bytes", name, partition.Part_start, partition.Part_start+partition.Part_size, mbr.Mbr_size)
+		}
+		if i > 0 && alignUp(used[i-1].Part_start+used[i-1].Part_size, align) > partition.Part_start {
+			previous := strings.Trim(string(used[i-1].Part_name[:]), "\x00")
+			return fmt.Errorf("partitions %s and %s overlap", previous, name)
+		}
+	}
 	return nil
 }
 
-// Get the first free partition in the MBR
-func (mbr *MBR) GetFreePartition() (*Partition, int, int) {
-	// calculate the offset of the first partition
-	offset := binary.Size(mbr)
+// GetLogicalPartitions walks the EBR chain of the extended partition, starting with its first EBR
+// (which may be empty). It returns nil if the disk has no extended partition.
+func (mbr *MBR) GetLogicalPartitions(path string) ([]LogicalPartition, error) {
+	extended, _ := mbr.GetExtendedPartition()
+	if extended == nil {
+		return nil, nil
+	}
 
-	// iterate over the partitions to find the first free partition
-	for i := 0; i < len(mbr.Mbr_partitions); i++ {
-		// if the start of the partition is -1 then it is free
-		if mbr.Mbr_partitions[i].Part_start == -1 {
-			return &mbr.Mbr_partitions[i], offset, i
-		} else {
-			offset += binary.Size(mbr.Mbr_partitions[i])
+	logicals := []LogicalPartition{}
+	number := len(mbr.Mbr_partitions)
+	for offset := extended.Part_start; offset != -1; {
+		if offset < extended.Part_start || offset+EBRSize > extended.Part_start+extended.Part_size || len(logicals) > int(extended.Part_size/EBRSize) {
+			return nil, fmt.Errorf("the EBR chain of the extended partition is corrupted at byte %d", offset)
+		}
+		logical := LogicalPartition{Offset: offset}
+		err := logical.EBR.DeserializeEBR(path, int64(offset))
+		if err != nil {
+			return nil, err
+		}
+		if !logical.Empty() {
+			number++
+			logical.Number = number
+		}
+		logicals = append(logicals, logical)
+		offset = logical.EBR.Ebr_part_next
+	}
+	return logicals, nil
+}
+
+// GetLogicalPartitionByName finds the logical partition with the given name
+func (mbr *MBR) GetLogicalPartitionByName(path string, name string) (*LogicalPartition, error) {
+	logicals, err := mbr.GetLogicalPartitions(path)
+	if err != nil {
+		return nil, err
+	}
+	for i := range logicals {
+		if !logicals[i].Empty() && strings.EqualFold(logicals[i].Name(), strings.Trim(name, "\x00")) {
+			return &logicals[i], nil
 		}
 	}
+	return nil, nil
+}
 
-	return nil, -1, -1
+// GetLogicalPartitionAt finds the logical partition whose EBR is at offset
+func (mbr *MBR) GetLogicalPartitionAt(path string, offset int32) (*LogicalPartition, error) {
+	logicals, err := mbr.GetLogicalPartitions(path)
+	if err != nil {
+		return nil, err
+	}
+	for i := range logicals {
+		if !logicals[i].Empty() && logicals[i].Offset == offset {
+			return &logicals[i], nil
+		}
+	}
+	return nil, fmt.Errorf("there is no logical partition at byte %d", offset)
+}
+
+// GetLogicalGaps returns the free space of the extended partition, in order. An empty first EBR
+// counts as free: the next logical partition created there takes it.
+func (mbr *MBR) GetLogicalGaps(path string) ([]FreeGap, error) {
+	extended, _ := mbr.GetExtendedPartition()
+	if extended == nil {
+		return nil, fmt.Errorf("the disk has no extended partition")
+	}
+	logicals, err := mbr.GetLogicalPartitions(path)
+	if err != nil {
+		return nil, err
+	}
+
+	gaps := []FreeGap{}
+	cursor := extended.Part_start
+	for _, logical := range logicals {
+		if logical.Empty() {
+			// Only the first EBR can be empty; the bytes it takes are reused with it
+			continue
+		}
+		if logical.Offset > cursor {
+			gaps = append(gaps, FreeGap{Start: cursor, Size: logical.Offset - cursor})
+		}
+		cursor = max(cursor, logical.EBR.Ebr_part_start+logical.EBR.Ebr_part_size)
+	}
+	end := extended.Part_start + extended.Part_size
+	if cursor < end {
+		gaps = append(gaps, FreeGap{Start: cursor, Size: end - cursor})
+	}
+	return gaps, nil
+}
+
+// FindLogicalGap chooses with the given fit where the EBR of a new logical partition of size bytes goes
+func (mbr *MBR) FindLogicalGap(path string, size int32, fit byte) (int32, error) {
+	gaps, err := mbr.GetLogicalGaps(path)
+	if err != nil {
+		return -1, err
+	}
+	return pickGap(gaps, EBRSize+size, fit)
 }
 
 // Get the partition with the given name
@@ -101,6 +250,50 @@ func (mbr *MBR) GetPartitionByName(name string) (*Partition, int) {
 	return nil, -1
 }
 
+// GetPartitionByLabel finds the partition whose filesystem has the given volume label
+func (mbr *MBR) GetPartitionByLabel(path string, label string) (*Partition, int) {
+	for i, partition := range mbr.Mbr_partitions {
+		if partition.Part_size <= 0 {
+			continue
+		}
+		sb := &SuperBlock{}
+		err := sb.Deserialize(path, int64(partition.Part_start))
+		if err != nil || !sb.Formatted() {
+			continue
+		}
+		ext, err := sb.ReadExtended(path, partition.Part_start)
+		if err != nil || ext == nil {
+			continue
+		}
+		if strings.EqualFold(ext.Label(), label) {
+			return &partition, i+1
+		}
+	}
+
+	// Then the logical partitions of the extended one
+	logicals, err := mbr.GetLogicalPartitions(path)
+	if err != nil {
+		return nil, -1
+	}
+	for _, logical := range logicals {
+		if logical.Empty() {
+			continue
+		}
+		partition := logical.Partition()
+		sb := &SuperBlock{}
+		err := sb.Deserialize(path, int64(partition.Part_start))
+		if err != nil || !sb.Formatted() {
+			continue
+		}
+		ext, err := sb.ReadExtended(path, partition.Part_start)
+		if err == nil && ext != nil && strings.EqualFold(ext.Label(), label) {
+			return partition, logical.Number
+		}
+	}
+
+	return nil, -1
+}
+
 func (mbr *MBR) GetPartitionByID(id string) (*Partition, error) {
 	for _, partition := range mbr.Mbr_partitions {
 		partID := strings.Trim(string(partition.Part_id[:]), "\x00")
@@ -122,15 +315,13 @@ func (mbr *MBR) GetExtendedPartition() (*Partition, int) {
 	return nil, -1
 }
 
-// Get free space in the MBR
-func (mbr *MBR) GetFreeSpace() int32 {
-	var totalUsedSpace int32
-	for _, partition := range mbr.Mbr_partitions {
-		if partition.Part_start != -1 {
-			totalUsedSpace += partition.Part_size
-		}
+// Get free space in the MBR, adding up every gap
+func (mbr *MBR) GetFreeSpace(path string) int32 {
+	var totalFreeSpace int32
+	for _, gap := range mbr.GetFreeGaps(path) {
+		totalFreeSpace += gap.Size
 	}
-	return mbr.Mbr_size - totalUsedSpace
+	return totalFreeSpace
 }
 // print the values of the MBR struct
 func (mbr *MBR) PrintMBR() {
@@ -0,0 +1,42 @@
+package structures
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// NameBlock guarda un tramo de un nombre largo de una entrada de carpeta.
+// Los tramos se encadenan con B_next hasta completar el nombre.
+type NameBlock struct {
+	B_name []byte // Tramo del nombre, ocupa el bloque salvo los 4 bytes de B_next
+	B_next int32  // Siguiente bloque del nombre (-1 si es el último)
+}
+
+// NewNameBlock crea un bloque de nombre del tamaño de bloque del sistema de archivos
+func (sb *SuperBlock) NewNameBlock() *NameBlock {
+	return &NameBlock{B_name: make([]byte, sb.S_block_size-4), B_next: -1}
+}
+
+// Serialize escribe la estructura NameBlock en un archivo binario en la posición especificada
+func (nb *NameBlock) Serialize(path string, offset int64) error {
+	err := writeStruct(path, offset, nb.B_name)
+	if err != nil {
+		return err
+	}
+	return writeStruct(path, offset+int64(len(nb.B_name)), nb.B_next)
+}
+
+// Deserialize lee la estructura NameBlock desde un archivo binario en la posición especificada
+func (nb *NameBlock) Deserialize(path string, offset int64) error {
+	err := readStruct(path, offset, nb.B_name)
+	if err != nil {
+		return err
+	}
+	return readStruct(path, offset+int64(len(nb.B_name)), &nb.B_next)
+}
+
+// Print imprime el tramo del nombre y el siguiente bloque
+func (nb *NameBlock) Print() {
+	fmt.Printf("B_name: %s\n", bytes.TrimRight(nb.B_name[:], "\x00"))
+	fmt.Printf("B_next: %d\n", nb.B_next)
+}
@@ -39,6 +39,13 @@ func (p *Partition) MountPartition(correlative int, id string) error {
 	return nil
 }
 
+// Unmount a partition, leaving it as it was after being created
+func (p *Partition) UnmountPartition() {
+	p.Part_status[0] = '0' // 0 = Created, 1 = Mounted
+	p.Part_correlative = 0
+	p.Part_id = [4]byte{}
+}
+
 // GetLastEBR returns the last EBR in the linked list
 func (p *Partition) GetLastEBR(ebr *EBR, path string) (*EBR, int32, error) {
 	// get the last EBR in the linked list
@@ -1,70 +1,32 @@
 package structures
 
 import (
-	"bytes"
-	"encoding/binary"
 	"fmt"
-	"os"
 )
 
 type PointerBlock struct {
-	P_pointers [16]int32 // 16 * 4 = 64 bytes
-	// Total: 64 bytes
+	P_pointers []int32 // S_block_size / 4 pointers
 }
 
-func  (pb *PointerBlock) Serialize(path string, offset int64) error {
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		return err
-	}
-
-	defer file.Close()
-
-	_, err = file.Seek(offset, 0)
-	if err != nil {
-		return err
-	}
+// NewPointerBlock creates a zeroed pointer block sized for the filesystem
+func (sb *SuperBlock) NewPointerBlock() *PointerBlock {
+	return &PointerBlock{P_pointers: make([]int32, sb.pointersPerBlock())}
+}
 
-	err = binary.Write(file, binary.LittleEndian, pb)
+func  (pb *PointerBlock) Serialize(path string, offset int64) error {
+	err := sealBlock(path, offset, pb.P_pointers)
 	if err != nil {
 		return err
 	}
-
-	return nil
+	return writeStruct(path, offset, pb.P_pointers)
 }
 
 func (pb *PointerBlock) Deserialize(path string, offset int64) error {
-	file, err := os.Open(path)
+	err := readStruct(path, offset, pb.P_pointers)
 	if err != nil {
 		return err
 	}
-
-	defer file.Close()
-
-	_, err = file.Seek(offset, 0)
-	if err != nil {
-		return err
-	}
-
-	pbSize := binary.Size(pb)
-	if pbSize <= 0 {
-		return fmt.Errorf("invalid PointerBlock size: %d", pbSize)
-	}
-
-	buffer := make([]byte, pbSize)
-	_, err = file.Read(buffer)
-	if err != nil {
-		return err
-	}
-
-	reader := bytes.NewReader(buffer)
-	err = binary.Read(reader, binary.LittleEndian, pb)
-	if err != nil {
-		return err
-	}
-
-	return nil
-
+	return verifyBlock("el bloque de apuntadores", path, offset, pb.P_pointers)
 }
 
 func (pb *PointerBlock) Print() {
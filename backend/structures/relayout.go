@@ -0,0 +1,327 @@
+package structures
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+)
+
+// Relayout pasa un sistema de archivos a otra geometría (con o sin journal, otra cantidad de inodos o bloques)
+// sin perder su contenido. Todo lo que está en uso se lee a memoria; los inodos y bloques que no caben en la
+// nueva geometría se reubican en los primeros libres y se corrigen todas las referencias a ellos.
+
+// Tipos de bloque según quién los referencia; define cómo se corrigen al reubicar
+const (
+	relayoutData    byte = iota // contenido de archivos y enlaces, se copia tal cual
+	relayoutFolder              // bloque de carpeta: entradas con inodos y nombres largos
+	relayoutPointer             // bloque de apuntadores
+	relayoutName                // tramo de un nombre largo, encadenado con B_next
+)
+
+// relayouts numera los discos en memoria donde se arma cada nueva geometría
+var relayouts atomic.Int64
+
+// RelayoutResult resume lo que se movió al cambiar la geometría
+type RelayoutResult struct {
+	MovedInodes int
+	MovedBlocks int
+}
+
+// Relayout escribe en la partición el sistema de archivos de sb con la geometría de target, que debe traer
+// calculadas sus posiciones y su tipo. Las transacciones pendientes del journal se aplican antes y, si target
+// es ext3, su journal empieza vacío. Al terminar target queda con los contadores al día y es el superbloque escrito.
+func (sb *SuperBlock) Relayout(path string, partition *Partition, target *SuperBlock) (*RelayoutResult, error) {
+	if target.S_block_size != sb.S_block_size {
+		return nil, fmt.Errorf("no se puede cambiar el tamaño de bloque de %d a %d", sb.S_block_size, target.S_block_size)
+	}
+	if sb.S_filesystem_type == 3 {
+		_, err := sb.ReplayJournal(path, false)
+		if err != nil {
+			return nil, fmt.Errorf("error al aplicar el journal: %w", err)
+		}
+	}
+
+	inodeBitmap, err := readBitmap(path, sb.S_bm_inode_start, sb.TotalInodes())
+	if err != nil {
+		return nil, err
+	}
+	blockBitmap, err := readBitmap(path, sb.S_bm_block_start, sb.TotalBlocks())
+	if err != nil {
+		return nil, err
+	}
+
+	inodeMap, err := relocation(inodeBitmap, '1', target.TotalInodes())
+	if err != nil {
+		return nil, fmt.Errorf("los inodos en uso no caben: %w", err)
+	}
+	blockMap, err := relocation(blockBitmap, 'X', target.TotalBlocks())
+	if err != nil {
+		return nil, fmt.Errorf("los bloques en uso no caben: %w", err)
+	}
+
+	// Leer los inodos en uso y clasificar sus bloques
+	inodes := make(map[int32]*Inode)
+	kinds := make(map[int32]byte)
+	for index := range inodeMap {
+		inode := &Inode{}
+		err := inode.Deserialize(path, int64(sb.S_inode_start+(index*sb.S_inode_size)))
+		if err != nil {
+			return nil, err
+		}
+		inodes[index] = inode
+
+		dataBlocks, pointerBlocks, err := sb.collectInodeBlocks(path, inode)
+		if err != nil {
+			return nil, err
+		}
+		for _, blockIndex := range pointerBlocks {
+			kinds[blockIndex] = relayoutPointer
+		}
+		for _, blockIndex := range dataBlocks {
+			if inode.I_type[0] != '0' {
+				kinds[blockIndex] = relayoutData
+				continue
+			}
+			kinds[blockIndex] = relayoutFolder
+
+			folderBlock := sb.NewFolderBlock()
+			err := folderBlock.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+			if err != nil {
+				return nil, err
+			}
+			for _, content := range folderBlock.B_content {
+				if content.B_inodo == -1 {
+					continue
+				}
+				nameBlocks, err := sb.EntryNameBlocks(path, content)
+				if err != nil {
+					return nil, err
+				}
+				for _, nameBlock := range nameBlocks {
+					kinds[nameBlock] = relayoutName
+				}
+			}
+		}
+	}
+
+	// Leer los bloques en uso tal cual están en el disco
+	blocks := make(map[int32][]byte)
+	for index := range blockMap {
+		raw := make([]byte, sb.S_block_size)
+		err := DeviceReadAt(path, raw, int64(sb.S_block_start+(index*sb.S_block_size)))
+		if err != nil {
+			return nil, err
+		}
+		blocks[index] = raw
+	}
+
+	remapBlock := func(blockIndex int32) int32 {
+		if moved, ok := blockMap[blockIndex]; ok {
+			return moved
+		}
+		return blockIndex
+	}
+
+	// Corregir las referencias a inodos y bloques reubicados
+	for _, inode := range inodes {
+		for i, blockIndex := range inode.I_block {
+			if blockIndex != -1 {
+				inode.I_block[i] = remapBlock(blockIndex)
+			}
+		}
+	}
+	structs := make(map[int32]any)
+	for index, raw := range blocks {
+		switch kinds[index] {
+		case relayoutPointer:
+			pointerBlock := sb.NewPointerBlock()
+			err = binary.Read(bytes.NewReader(raw), binary.LittleEndian, pointerBlock.P_pointers)
+			for i, pointer := range pointerBlock.P_pointers {
+				if pointer != -1 {
+					pointerBlock.P_pointers[i] = remapBlock(pointer)
+				}
+			}
+			structs[index] = pointerBlock
+		case relayoutFolder:
+			folderBlock := sb.NewFolderBlock()
+			err = binary.Read(bytes.NewReader(raw), binary.LittleEndian, folderBlock.B_content)
+			for i := range folderBlock.B_content {
+				content := &folderBlock.B_content[i]
+				if content.B_inodo == -1 {
+					continue
+				}
+				if moved, ok := inodeMap[content.B_inodo]; ok {
+					content.B_inodo = moved
+				}
+				if content.B_name[0] == longNameMarker {
+					first := int32(binary.LittleEndian.Uint32(content.B_name[1:5]))
+					binary.LittleEndian.PutUint32(content.B_name[1:5], uint32(remapBlock(first)))
+				}
+			}
+			structs[index] = folderBlock
+		case relayoutName:
+			nameBlock := sb.NewNameBlock()
+			nameBlock.B_next = int32(binary.LittleEndian.Uint32(raw[len(nameBlock.B_name):]))
+			copy(nameBlock.B_name, raw)
+			if nameBlock.B_next != -1 {
+				nameBlock.B_next = remapBlock(nameBlock.B_next)
+			}
+			structs[index] = nameBlock
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// La nueva geometría se arma completa en un disco en memoria; la partición solo se toca cuando ya está
+	// construida, así un error a medio camino la deja como estaba
+	partEnd := int64(partition.Part_start) + int64(partition.Part_size)
+	target.S_mtime = sb.S_mtime
+	target.S_umtime = sb.S_umtime
+	target.S_mnt_count = sb.S_mnt_count
+	target.S_features = sb.S_features
+	scratch := fmt.Sprintf("%srelayout-%d", MemoryPathPrefix, relayouts.Add(1))
+	err = CreateMemoryDevice(scratch, partEnd)
+	if err != nil {
+		return nil, err
+	}
+	defer DropDevice(scratch)
+	target.TrackChecksums(scratch, partition.Part_start, partition.Part_size)
+	target.TrackLayout(scratch, partition)
+
+	newInodeBitmap := bytes.Repeat([]byte{'0'}, int(target.TotalInodes()))
+	for _, moved := range inodeMap {
+		newInodeBitmap[moved] = '1'
+	}
+	newBlockBitmap := bytes.Repeat([]byte{'O'}, int(target.TotalBlocks()))
+	for _, moved := range blockMap {
+		newBlockBitmap[moved] = 'X'
+	}
+	err = DeviceWriteAt(scratch, newInodeBitmap, int64(target.S_bm_inode_start))
+	if err != nil {
+		return nil, err
+	}
+	err = DeviceWriteAt(scratch, newBlockBitmap, int64(target.S_bm_block_start))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RelayoutResult{}
+	for index, inode := range inodes {
+		moved := inodeMap[index]
+		if moved != index {
+			result.MovedInodes++
+		}
+		err := inode.Serialize(scratch, int64(target.S_inode_start+(moved*target.S_inode_size)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for index, raw := range blocks {
+		moved := blockMap[index]
+		if moved != index {
+			result.MovedBlocks++
+		}
+		offset := int64(target.S_block_start + (moved * target.S_block_size))
+		switch block := structs[index].(type) {
+		case *PointerBlock:
+			err = block.Serialize(scratch, offset)
+		case *FolderBlock:
+			err = block.Serialize(scratch, offset)
+		case *NameBlock:
+			err = block.Serialize(scratch, offset)
+		default:
+			err = DeviceWriteAt(scratch, raw, offset)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = target.RecountFromBitmaps(scratch)
+	if err != nil {
+		return nil, err
+	}
+	if target.S_filesystem_type == 3 {
+		err = target.ClearJournal(scratch)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = target.WriteBackups(scratch, partition)
+	if err != nil {
+		return nil, err
+	}
+
+	// Se copia a la partición todo lo que sigue al superbloque y, si lo hay, al superbloque extendido, que no
+	// cambia; el superbloque se escribe al final, cuando el resto ya está en su lugar
+	copyStart := ExtendedOffset(partition.Part_start)
+	if sb.HasFeature(FeatureExtended) {
+		copyStart += int64(binary.Size(ExtendedSuperBlock{}))
+	}
+	err = copyRange(scratch, path, copyStart, partEnd)
+	if err != nil {
+		return nil, fmt.Errorf("error al copiar la nueva geometría a la partición: %w", err)
+	}
+	UntrackChecksums(path, partition.Part_start)
+	target.TrackChecksums(path, partition.Part_start, partition.Part_size)
+	target.TrackLayout(path, partition)
+	err = target.Serialize(path, int64(partition.Part_start))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// relocation asigna a cada índice en uso del bitmap su índice en una geometría con total entradas:
+// los que caben se quedan donde están y el resto pasa a los primeros libres
+func relocation(bitmap []byte, used byte, total int32) (map[int32]int32, error) {
+	mapping := make(map[int32]int32)
+	taken := make(map[int32]bool)
+	overflow := make([]int32, 0)
+	for i, b := range bitmap {
+		if b != used {
+			continue
+		}
+		if int32(i) < total {
+			mapping[int32(i)] = int32(i)
+			taken[int32(i)] = true
+		} else {
+			overflow = append(overflow, int32(i))
+		}
+	}
+	if len(mapping)+len(overflow) > int(total) {
+		return nil, fmt.Errorf("hay %d en uso y la nueva geometría solo tiene %d", len(mapping)+len(overflow), total)
+	}
+
+	next := int32(0)
+	for _, index := range overflow {
+		for taken[next] {
+			next++
+		}
+		mapping[index] = next
+		taken[next] = true
+	}
+	return mapping, nil
+}
+
+// copyRange copia [start, end) del disco en from al disco en to
+func copyRange(from string, to string, start int64, end int64) error {
+	buffer := make([]byte, 64*1024)
+	for offset := start; offset < end; offset += int64(len(buffer)) {
+		length := int64(len(buffer))
+		if end-offset < length {
+			length = end - offset
+		}
+		err := DeviceReadAt(from, buffer[:length], offset)
+		if err != nil {
+			return err
+		}
+		err = DeviceWriteAt(to, buffer[:length], offset)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package structures
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Una vista en el tiempo reconstruye una partición EXT3 a partir de su journal: sobre un disco en memoria vacío
+// se rehacen, en orden, las transacciones confirmadas desde mkfs hasta el momento pedido. El disco real no se toca.
+
+// snapshots numera los discos en memoria de las vistas para que no choquen entre sí
+var snapshots atomic.Int64
+
+// JournalSnapshot reconstruye en un disco en memoria la partición tal como quedó después de la última transacción
+// para la que include devuelve true. Devuelve la ruta del disco en memoria, su superbloque y cuántas transacciones
+// se rehicieron; quien llama debe liberar el disco con DropDevice.
+func (sb *SuperBlock) JournalSnapshot(path string, partition *Partition, include func(*JournalTransaction) bool) (string, *SuperBlock, int, error) {
+	transactions, _, err := sb.ReadJournal(path)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	// Sin la transacción de mkfs no hay un punto de partida conocido
+	if len(transactions) == 0 || transactions[0].Info.Operation() != "mkfs" {
+		return "", nil, 0, errors.New("el journal ya no conserva la transacción de mkfs, no se puede reconstruir la partición")
+	}
+	if !include(&transactions[0]) {
+		return "", nil, 0, errors.New("el momento pedido es anterior a la creación del sistema de archivos")
+	}
+
+	snapshotPath := fmt.Sprintf("%ssnapshot-%d", MemoryPathPrefix, snapshots.Add(1))
+	err = CreateMemoryDevice(snapshotPath, int64(partition.Part_start)+int64(partition.Part_size))
+	if err != nil {
+		return "", nil, 0, err
+	}
+
+	replayed := 0
+	for i := range transactions {
+		transaction := &transactions[i]
+		if transaction.Status == JournalBegin || !include(transaction) {
+			break
+		}
+		for _, update := range transaction.Updates {
+			err := DeviceWriteAt(snapshotPath, update.J_data[:update.J_length], update.J_offset)
+			if err != nil {
+				DropDevice(snapshotPath)
+				return "", nil, 0, err
+			}
+		}
+		replayed++
+	}
+
+	snapshotSb := &SuperBlock{}
+	err = snapshotSb.Deserialize(snapshotPath, int64(partition.Part_start))
+	if err != nil {
+		DropDevice(snapshotPath)
+		return "", nil, 0, err
+	}
+	return snapshotPath, snapshotSb, replayed, nil
+}
@@ -2,9 +2,10 @@ package structures
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/binary"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 )
 
@@ -12,8 +13,8 @@ import (
 // It contains metadata about the filesystem, such as the number of inodes, blocks, and their sizes.
 type SuperBlock struct {
 	S_filesystem_type   int32   // Type of the filesystem
-	S_inodes_count      int32   // Total number of inodes
-	S_blocks_count      int32   // Total number of blocks
+	S_inodes_count      int32   // Number of inodes in use
+	S_blocks_count      int32   // Number of blocks in use
 	S_free_inodes_count int32   // Number of free inodes
 	S_free_blocks_count int32   // Number of free blocks
 	S_mtime             float32 // Last mount time (as a Unix timestamp)
@@ -28,71 +29,203 @@ type SuperBlock struct {
 	S_bm_block_start    int32   // Starting position of the block bitmap
 	S_inode_start       int32   // Starting position of the inode table
 	S_block_start       int32   // Starting position of the block table
-	// Total size: 68 bytes
+	S_fit               [1]byte // Fit used by the allocator (B, F, W), taken from the partition
+	S_features          int32   // Optional on-disk features enabled at format time (Feature* flags)
+	S_csum_start        int32   // Starting position of the block checksum table (FeatureMetadataCsum)
+	S_checksum          uint32  // CRC32C of the superblock (FeatureMetadataCsum)
+	// Total size: 81 bytes
 }
 
-// Serialize writes the SuperBlock structure to a binary file at the specified offset.
-// This function is used to persist the SuperBlock data to disk.
-func (sb *SuperBlock) Serialize(path string, offset int64) error {
-	// Open the file for writing or create it if it doesn't exist
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		return err
+// Magic numbers stored in S_magic. Partitions formatted before the superblock grew past S_block_start carry
+// legacySuperBlockMagic: their superblock ends there (68 bytes), the inode bitmap follows right after it and
+// the fields from S_fit on are not on disk.
+const (
+	legacySuperBlockMagic = 0xEF53
+	SuperBlockMagic       = 0xEF54
+
+	legacySuperBlockSize = 68
+	legacyInodeSize      = 88    // without I_links and I_checksum
+	legacyJournalSize    = 114   // an EXT3 journal entry: J_count and the Information
+	magicOffset          = 8 * 4 // S_magic follows eight 4 byte fields
+)
+
+// Formatted reports whether the superblock belongs to a filesystem, in either layout.
+func (sb *SuperBlock) Formatted() bool {
+	return sb.S_magic == SuperBlockMagic || sb.S_magic == legacySuperBlockMagic
+}
+
+// Legacy reports whether the filesystem uses the original layout.
+func (sb *SuperBlock) Legacy() bool {
+	return sb.S_magic == legacySuperBlockMagic
+}
+
+// inodeSize returns the size of the inodes of the layout of the filesystem.
+func (sb *SuperBlock) inodeSize() int32 {
+	if sb.Legacy() {
+		return legacyInodeSize
+	}
+	return int32(binary.Size(Inode{}))
+}
+
+// Size returns how many bytes the superblock takes on disk.
+func (sb *SuperBlock) Size() int {
+	if sb.S_magic != SuperBlockMagic {
+		return legacySuperBlockSize
 	}
-	defer file.Close()
+	return binary.Size(SuperBlock{})
+}
+
+// End returns the absolute offset right after the last block of the filesystem.
+func (sb *SuperBlock) End() int64 {
+	return int64(sb.S_block_start) + int64(sb.TotalBlocks())*int64(sb.S_block_size)
+}
+
+// Feature flags stored in S_features
+const (
+	// FeatureLongNames allows directory entries with names longer than 12 bytes (stored in NameBlocks)
+	FeatureLongNames int32 = 1 << iota
+	// FeatureLinkCount marks the inode format with I_links; without it every inode has a single name
+	FeatureLinkCount
+	// FeatureMetadataCsum protects the superblock, inodes, folder blocks and pointer blocks with CRC32C checksums
+	FeatureMetadataCsum
+	// FeatureExtended marks that an ExtendedSuperBlock follows the superblock
+	FeatureExtended
+)
+
+// ExtendedSuperBlock keeps the mkfs options that do not fit in the original superblock.
+// It is stored right after the primary superblock when FeatureExtended is set.
+type ExtendedSuperBlock struct {
+	S_label       [16]byte // Volume label, may be empty
+	S_uuid        [16]byte // Random identifier generated at format time
+	S_inode_ratio int32    // Bytes of partition per inode requested at format time (0: 3 blocks per inode)
+	S_checksum    uint32   // CRC32C of the structure (FeatureMetadataCsum)
+	// Total size: 40 bytes
+}
 
-	// Move the file pointer to the specified offset
-	_, err = file.Seek(offset, 0)
+// NewExtendedSuperBlock creates the extended superblock of a new filesystem with a random version 4 UUID.
+func NewExtendedSuperBlock(label string, inodeRatio int32) (*ExtendedSuperBlock, error) {
+	ext := &ExtendedSuperBlock{S_inode_ratio: inodeRatio}
+	copy(ext.S_label[:], label)
+	_, err := rand.Read(ext.S_uuid[:])
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("error al generar el UUID: %w", err)
 	}
+	ext.S_uuid[6] = (ext.S_uuid[6] & 0x0f) | 0x40
+	ext.S_uuid[8] = (ext.S_uuid[8] & 0x3f) | 0x80
+	return ext, nil
+}
+
+// ExtendedOffset returns where the extended superblock of the partition that starts at partStart is stored.
+func ExtendedOffset(partStart int32) int64 {
+	return int64(partStart) + int64(binary.Size(SuperBlock{}))
+}
 
-	// Serialize the SuperBlock structure directly into the file
-	err = binary.Write(file, binary.LittleEndian, sb)
+// ReadExtended loads the extended superblock of the partition, or returns nil if the filesystem has none.
+func (sb *SuperBlock) ReadExtended(path string, partStart int32) (*ExtendedSuperBlock, error) {
+	if !sb.HasFeature(FeatureExtended) {
+		return nil, nil
+	}
+	ext := &ExtendedSuperBlock{}
+	err := ext.Deserialize(path, ExtendedOffset(partStart))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return ext, nil
+}
 
-	return nil
+// Label returns the volume label.
+func (ext *ExtendedSuperBlock) Label() string {
+	return strings.TrimRight(string(ext.S_label[:]), "\x00")
 }
 
-// Deserialize reads the SuperBlock structure from a binary file at the specified offset.
-// This function is used to load the SuperBlock data from disk into memory.
-func (sb *SuperBlock) Deserialize(path string, offset int64) error {
-	// Open the file for reading
-	file, err := os.Open(path)
+// UUID returns the UUID in its canonical textual form.
+func (ext *ExtendedSuperBlock) UUID() string {
+	u := ext.S_uuid
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// Serialize writes the ExtendedSuperBlock structure to a binary file at the specified offset.
+func (ext *ExtendedSuperBlock) Serialize(path string, offset int64) error {
+	err := sealStruct(path, offset, ext, &ext.S_checksum)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	return writeStruct(path, offset, ext)
+}
 
-	// Move the file pointer to the specified offset
-	_, err = file.Seek(offset, 0)
+// Deserialize reads the ExtendedSuperBlock structure from a binary file at the specified offset.
+func (ext *ExtendedSuperBlock) Deserialize(path string, offset int64) error {
+	err := readStruct(path, offset, ext)
 	if err != nil {
 		return err
 	}
+	return verifyStruct("el superbloque extendido", path, offset, ext, &ext.S_checksum)
+}
+
+// HasFeature reports whether the given feature flag is enabled on this filesystem
+func (sb *SuperBlock) HasFeature(feature int32) bool {
+	return sb.S_features&feature != 0
+}
 
-	// Get the size of the SuperBlock structure
-	sbSize := binary.Size(sb)
-	if sbSize <= 0 {
-		return fmt.Errorf("invalid SuperBlock size: %d", sbSize)
+// JournalStart returns the absolute position of the journal area.
+// The journal holds JournalSlots records and sits right before the inode bitmap,
+// so it is derived from the layout and does not depend on the superblock size.
+func (sb *SuperBlock) JournalStart() int64 {
+	return int64(sb.S_bm_inode_start) - int64(sb.JournalSlots())*int64(binary.Size(Journal{}))
+}
+
+// Serialize writes the SuperBlock structure to a binary file at the specified offset.
+// This function is used to persist the SuperBlock data to disk.
+// Only the fields of its own layout are written, so a legacy superblock never overwrites the inode bitmap.
+func (sb *SuperBlock) Serialize(path string, offset int64) error {
+	if sb.S_magic != SuperBlockMagic {
+		var buffer bytes.Buffer
+		err := binary.Write(&buffer, binary.LittleEndian, sb)
+		if err != nil {
+			return err
+		}
+		return DeviceWriteAt(path, buffer.Bytes()[:legacySuperBlockSize], offset)
 	}
 
-	// Read only the number of bytes corresponding to the size of the SuperBlock structure
-	buffer := make([]byte, sbSize)
-	_, err = file.Read(buffer)
+	err := sealStruct(path, offset, sb, &sb.S_checksum)
 	if err != nil {
 		return err
 	}
+	return writeStruct(path, offset, sb)
+}
 
-	// Deserialize the read bytes into the SuperBlock structure
-	reader := bytes.NewReader(buffer)
-	err = binary.Read(reader, binary.LittleEndian, sb)
+// Deserialize reads the SuperBlock structure from a binary file at the specified offset.
+// This function is used to load the SuperBlock data from disk into memory.
+// A legacy superblock has no fit of its own: it takes the one of its partition if the partition is mounted.
+func (sb *SuperBlock) Deserialize(path string, offset int64) error {
+	err := readSuperBlock(path, offset, sb)
 	if err != nil {
 		return err
 	}
+	if sb.S_magic != SuperBlockMagic {
+		if layout, ok := layoutAt(path, offset); ok {
+			sb.S_fit[0] = layout.fit
+		}
+		return nil
+	}
+	return verifyStruct("el superbloque", path, offset, sb, &sb.S_checksum)
+}
 
-	return nil
+// readSuperBlock reads the superblock stored at offset without checking it. The fields that are not part of
+// its layout are left at zero.
+func readSuperBlock(path string, offset int64, sb *SuperBlock) error {
+	buffer := make([]byte, binary.Size(SuperBlock{}))
+	err := DeviceReadAt(path, buffer[:legacySuperBlockSize], offset)
+	if err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint32(buffer[magicOffset:]) == SuperBlockMagic {
+		err = DeviceReadAt(path, buffer[legacySuperBlockSize:], offset+legacySuperBlockSize)
+		if err != nil {
+			return err
+		}
+	}
+	return binary.Read(bytes.NewReader(buffer), binary.LittleEndian, sb)
 }
 
 // PrintSuperBlock displays the values of the SuperBlock structure in a human-readable format.
@@ -120,14 +253,22 @@ func (sb *SuperBlock) Print() {
 	fmt.Printf("Bitmap Block Start: %d\n", sb.S_bm_block_start)
 	fmt.Printf("Inode Start: %d\n", sb.S_inode_start)
 	fmt.Printf("Block Start: %d\n", sb.S_block_start)
+	fmt.Printf("Fit: %c\n", rune(sb.S_fit[0]))
+	fmt.Printf("Features: %d\n", sb.S_features)
+	fmt.Printf("Checksum Table Start: %d\n", sb.S_csum_start)
+	fmt.Printf("Checksum: %08x\n", sb.S_checksum)
 }
 
 // PrintInodes displays all inodes in the filesystem.
 // This function is used for debugging and visualization purposes.
 func (sb *SuperBlock) PrintInodes(path string) error {
 	fmt.Println("\nInodes\n----------------")
-	// Iterate over each inode
-	for i := int32(0); i < sb.S_inodes_count; i++ {
+	// Iterate over each inode in use
+	usedInodes, err := sb.UsedInodes(path)
+	if err != nil {
+		return err
+	}
+	for _, i := range usedInodes {
 		inode := &Inode{}
 		// Deserialize the inode
 		err := inode.Deserialize(path, int64(sb.S_inode_start+(i*sb.S_inode_size)))
@@ -146,23 +287,27 @@ func (sb *SuperBlock) PrintInodes(path string) error {
 // This function is used for debugging and visualization purposes.
 func (sb *SuperBlock) PrintBlocks(path string) error {
 	fmt.Println("\nBlocks\n----------------")
-	// Iterate over each inode
-	for i := int32(0); i < sb.S_inodes_count; i++ {
+	// Iterate over each inode in use
+	usedInodes, err := sb.UsedInodes(path)
+	if err != nil {
+		return err
+	}
+	for _, i := range usedInodes {
 		inode := &Inode{}
 		// Deserialize the inode
 		err := inode.Deserialize(path, int64(sb.S_inode_start+(i*sb.S_inode_size)))
 		if err != nil {
 			return err
 		}
-		// Iterate over each block pointer in the inode
-		for _, blockIndex := range inode.I_block {
-			// Skip if the block doesn't exist
-			if blockIndex == -1 {
-				break
-			}
+		// Iterate over each data block of the inode, following the indirect pointers
+		dataBlocks, err := sb.InodeDataBlocks(path, inode)
+		if err != nil {
+			return err
+		}
+		for _, blockIndex := range dataBlocks {
 			// Handle folder blocks
 			if inode.I_type[0] == '0' {
-				block := &FolderBlock{}
+				block := sb.NewFolderBlock()
 				// Deserialize the folder block
 				err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
 				if err != nil {
@@ -173,9 +318,9 @@ func (sb *SuperBlock) PrintBlocks(path string) error {
 				block.Print()
 				continue
 			}
-			// Handle file blocks
-			if inode.I_type[0] == '1' {
-				block := &FileBlock{}
+			// Handle file blocks (symbolic links store their target as raw bytes too)
+			if inode.I_type[0] == '1' || inode.I_type[0] == '2' {
+				block := sb.NewFileBlock()
 				// Deserialize the file block
 				err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
 				if err != nil {
@@ -193,19 +338,43 @@ func (sb *SuperBlock) PrintBlocks(path string) error {
 }
 
 // CreateFolder crea una carpeta en el sistema de archivos
-func (sb *SuperBlock) CreateFolder(path string, parentsDir []string, destDir string, uid int32, gid int32, folderPath string, journalStart int64) error {
-	return sb.createFolderInode(path, 0, parentsDir, destDir, uid, gid, folderPath, journalStart)
-
+func (sb *SuperBlock) CreateFolder(path string, parentsDir []string, destDir string, uid int32, gid int32) error {
+	// Resolver la carpeta padre siguiendo enlaces simbólicos
+	folderIndex, err := sb.resolveFolder(path, parentsDir)
+	if err != nil {
+		return err
+	}
+	if folderIndex == -1 {
+		return fmt.Errorf("no existe la carpeta padre de %s", destDir)
+	}
+	return sb.createFolderInode(path, folderIndex, nil, destDir, uid, gid)
 }
 
 // CreateFile crea un archivo en el sistema de archivos
-func (sb *SuperBlock) CreateFile(path string, parentsDir []string, destDir string, r bool, size int, content string, uid int32, gid int32, folderPath string, journalStart int64) error {
+func (sb *SuperBlock) CreateFile(path string, parentsDir []string, destDir string, r bool, size int, content string, uid int32, gid int32) error {
 	fmt.Println("Creando archivo:", path, "contenido:", content)
-	return sb.createFileInodeExt2(path, 0, parentsDir, destDir, r, size, content, uid, gid, folderPath, journalStart)
+	// Resolver la carpeta padre siguiendo enlaces simbólicos
+	folderIndex, err := sb.resolveFolder(path, parentsDir)
+	if err != nil {
+		return err
+	}
+	if folderIndex == -1 {
+		return fmt.Errorf("no existe la carpeta padre de %s", destDir)
+	}
+	return sb.createFileInodeExt2(path, folderIndex, nil, destDir, r, size, content, uid, gid)
 }
 
 func (sb *SuperBlock) ExistsFolcer(path string, parentsDir []string, destDir string) (bool, error) {
-	exists, err := sb.folderExists(path, 0, parentsDir, destDir)
+	// Resolver la carpeta padre siguiendo enlaces simbólicos
+	folderIndex, err := sb.resolveFolder(path, parentsDir)
+	if err != nil {
+		return false, err
+	}
+	if folderIndex == -1 {
+		return false, nil
+	}
+
+	exists, err := sb.folderExists(path, folderIndex, nil, destDir)
 	if err != nil {
 		return false, err
 	}
@@ -216,7 +385,26 @@ func (sb *SuperBlock) ExistsFolcer(path string, parentsDir []string, destDir str
 
 // ReadFile lee el contenido de un archivo en el sistema de archivos
 func (sb *SuperBlock) ReadFile(path string, parentsDir []string, destDir string) (string, error) {
-	content, err := sb.readFileInInode(path, 0, parentsDir, destDir)
+	// Resolver la ruta completa, si el archivo es un enlace se lee su destino
+	fileIndex, err := sb.ResolvePath(path, parentsDir, destDir, true)
+	if err != nil {
+		return "", err
+	}
+	if fileIndex == -1 {
+		return "", fmt.Errorf("no se encontró el archivo")
+	}
+
+	inode := &Inode{}
+	err = inode.Deserialize(path, int64(sb.S_inode_start+(fileIndex*sb.S_inode_size)))
+	if err != nil {
+		return "", err
+	}
+	if inode.I_type[0] != '1' {
+		return "", fmt.Errorf("el inodo no es de tipo archivo")
+	}
+
+	// Leer todos los bloques del archivo, incluidos los indirectos
+	content, err := sb.ReadInodeContent(path, inode)
 	if err != nil {
 		return "", err
 	}
@@ -224,11 +412,15 @@ func (sb *SuperBlock) ReadFile(path string, parentsDir []string, destDir string)
 	return content, nil
 }
 
+// GetInode devuelve el inodo de una ruta, siguiendo enlaces simbólicos
 func (sb *SuperBlock) GetInode(path string, parentsDir []string, destDir string) (int32, error) {
-	inode, err := sb.getInodeFromPath(path, 0, parentsDir, destDir)
+	inode, err := sb.ResolvePath(path, parentsDir, destDir, true)
 	if err != nil {
 		return -1, err
 	}
+	if inode == -1 {
+		return -1, fmt.Errorf("no se encontró el inodo para '%s'", destDir)
+	}
 
 	return inode, nil
 }
@@ -300,11 +492,27 @@ func (sb *SuperBlock) RenameFile(path string, parentsDir []string, destDir strin
 }
 
 func (sb *SuperBlock) CopyFile(path string, parentsDir []string, destDir string, destinoParentDirs []string, destinoDir string, uid int32, gid int32) error {
-	return sb.CopyFileInInode(path, 0, parentsDir, destDir, destinoParentDirs, destinoDir, uid, gid)
+	// Resolver la carpeta del origen siguiendo enlaces simbólicos
+	folderIndex, err := sb.resolveFolder(path, parentsDir)
+	if err != nil {
+		return err
+	}
+	if folderIndex == -1 {
+		return fmt.Errorf("no se encontró el archivo")
+	}
+	return sb.CopyFileInInode(path, folderIndex, nil, destDir, destinoParentDirs, destinoDir, uid, gid)
 }
 
 func (sb *SuperBlock) MoveFile(path string, parentsDir []string, destDir string, destinoParentDirs []string, destinoDir string, uid int32, gid int32) error {
-	return sb.MoveFileInInode(path, 0, parentsDir, destDir, destinoParentDirs, destinoDir, uid, gid)
+	// Resolver la carpeta del origen siguiendo enlaces simbólicos; un enlace se mueve como enlace
+	folderIndex, err := sb.resolveFolder(path, parentsDir)
+	if err != nil {
+		return err
+	}
+	if folderIndex == -1 {
+		return fmt.Errorf("no se encontró el archivo")
+	}
+	return sb.MoveFileInInode(path, folderIndex, nil, destDir, destinoParentDirs, destinoDir, uid, gid)
 }
 
 func (sb *SuperBlock) GetUidGidByName(name, path string) (int32, int32, error) {
@@ -324,11 +532,22 @@ func (sb *SuperBlock) Chmod(path string, parentsDir []string, destDir string, ug
 	return sb.ChmodInInode(path, 0, parentsDir, destDir, ugo, uid, gid)
 }
 
+// Find busca desde la carpeta indicada las entradas cuyo nombre coincide con name, siguiendo enlaces simbólicos
 func (sb *SuperBlock) Find(path string, parentsDir []string, destDir string, name string) ([]string, error) {
-	err := sb.FindInInode(path, 0, parentsDir, destDir, name)
+	folderIndex, err := sb.ResolvePath(path, parentsDir, destDir, true)
+	if err != nil {
+		return nil, err
+	}
+	if folderIndex == -1 {
+		return nil, fmt.Errorf("no se encontró la carpeta %s", destDir)
+	}
+
+	folderPath := "/" + strings.Join(append(append([]string{}, parentsDir...), destDir), "/")
+	results := make([]string, 0)
+	err = sb.findInFolder(path, folderIndex, folderPath, name, make(map[int32]bool), &results)
 	if err != nil {
 		return nil, err
 	}
 
-	return nil, nil
+	return results, nil
 }
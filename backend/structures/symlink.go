@@ -0,0 +1,269 @@
+package structures
+
+import (
+	"fmt"
+	pathpkg "path"
+	"strings"
+	"time"
+)
+
+// maxLinkHops limita cuántos enlaces simbólicos se siguen al resolver una ruta; más saltos se toman como un ciclo
+const maxLinkHops = 16
+
+// CreateSymlink crea el enlace simbólico destDir dentro de parentsDir que apunta a target.
+// El destino se guarda tal cual en los bloques del enlace, puede ser absoluto o relativo y no necesita existir.
+func (sb *SuperBlock) CreateSymlink(path string, parentsDir []string, destDir string, target string, uid int32, gid int32) error {
+	if target == "" {
+		return fmt.Errorf("el destino del enlace no puede estar vacío")
+	}
+
+	// Buscar la carpeta donde se creará el enlace, siguiendo enlaces
+	folderIndex, folderInode, err := sb.newEntryFolder(path, parentsDir, destDir)
+	if err != nil {
+		return err
+	}
+
+	// Validar el nombre antes de reservar nada
+	err = sb.validateEntryName(destDir)
+	if err != nil {
+		return err
+	}
+
+	// Reservar el inodo del enlace
+	linkInodeIndex, err := sb.AllocateInode(path)
+	if err != nil {
+		return err
+	}
+
+	// Agregar la entrada en la carpeta padre
+	err = sb.addFolderEntry(path, folderIndex, folderInode, destDir, linkInodeIndex)
+	if err != nil {
+		return err
+	}
+
+	// Crear el inodo del enlace, los permisos de un enlace no se usan
+	linkInode := &Inode{
+		I_uid:   uid,
+		I_gid:   gid,
+		I_size:  int32(len(target)),
+		I_atime: float32(time.Now().Unix()),
+		I_ctime: float32(time.Now().Unix()),
+		I_mtime: float32(time.Now().Unix()),
+		I_block: [15]int32{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
+		I_type:  [1]byte{'2'},
+		I_perm:  [3]byte{'7', '7', '7'},
+		I_links: 1,
+	}
+
+	// Guardar la ruta destino en los bloques del enlace
+	err = sb.writeInodeContent(path, linkInode, target)
+	if err != nil {
+		return err
+	}
+
+	return linkInode.Serialize(path, int64(sb.S_inode_start+(linkInodeIndex*sb.S_inode_size)))
+}
+
+// LinkTarget devuelve la ruta a la que apunta un inodo de tipo enlace simbólico
+func (sb *SuperBlock) LinkTarget(path string, inode *Inode) (string, error) {
+	if inode.I_type[0] != '2' {
+		return "", fmt.Errorf("el inodo no es un enlace simbólico")
+	}
+
+	content, err := sb.ReadInodeContent(path, inode)
+	if err != nil {
+		return "", err
+	}
+	if int(inode.I_size) < len(content) {
+		content = content[:inode.I_size]
+	}
+	return content, nil
+}
+
+// ResolvePath devuelve el inodo de parentsDir/destDir partiendo de la raíz y siguiendo enlaces simbólicos.
+// Si followLast es falso y destDir es un enlace, se devuelve el inodo del enlace. Devuelve -1 si la ruta no existe.
+func (sb *SuperBlock) ResolvePath(path string, parentsDir []string, destDir string, followLast bool) (int32, error) {
+	components := append(append([]string{}, parentsDir...), destDir)
+	hops := 0
+	return sb.resolveComponents(path, 0, components, followLast, &hops)
+}
+
+// resolveFolder devuelve el inodo de la carpeta parentsDir siguiendo enlaces simbólicos, o -1 si no existe
+func (sb *SuperBlock) resolveFolder(path string, parentsDir []string) (int32, error) {
+	hops := 0
+	folderIndex, err := sb.resolveComponents(path, 0, parentsDir, true, &hops)
+	if err != nil || folderIndex == -1 {
+		return folderIndex, err
+	}
+
+	folderInode := &Inode{}
+	err = folderInode.Deserialize(path, int64(sb.S_inode_start+(folderIndex*sb.S_inode_size)))
+	if err != nil {
+		return -1, err
+	}
+	if folderInode.I_type[0] != '0' {
+		return -1, fmt.Errorf("/%s no es una carpeta", strings.Join(parentsDir, "/"))
+	}
+	return folderIndex, nil
+}
+
+// resolveComponents recorre components desde la carpeta dirIndex. hops cuenta los enlaces seguidos en toda la
+// resolución (incluidas las rutas de los enlaces), así un ciclo termina con error en lugar de recursión infinita.
+func (sb *SuperBlock) resolveComponents(path string, dirIndex int32, components []string, followLast bool, hops *int) (int32, error) {
+	current := dirIndex
+	for i, name := range components {
+		name = strings.Trim(name, "\x00 ")
+		if name == "" || name == "." {
+			continue
+		}
+
+		inode := &Inode{}
+		err := inode.Deserialize(path, int64(sb.S_inode_start+(current*sb.S_inode_size)))
+		if err != nil {
+			return -1, err
+		}
+		if inode.I_type[0] != '0' {
+			return -1, fmt.Errorf("no se puede entrar a '%s' porque su carpeta padre no es una carpeta", name)
+		}
+
+		var child int32
+		if name == ".." {
+			child, err = sb.parentFolder(path, inode)
+		} else {
+			child, err = sb.findFolderEntry(path, inode, name)
+		}
+		if err != nil {
+			return -1, err
+		}
+		if child == -1 {
+			return -1, nil
+		}
+
+		childInode := &Inode{}
+		err = childInode.Deserialize(path, int64(sb.S_inode_start+(child*sb.S_inode_size)))
+		if err != nil {
+			return -1, err
+		}
+
+		// Seguir el enlace si es un componente intermedio o si se pidió seguir el último
+		if childInode.I_type[0] == '2' && (i < len(components)-1 || followLast) {
+			*hops++
+			if *hops > maxLinkHops {
+				return -1, fmt.Errorf("demasiados niveles de enlaces simbólicos al resolver '%s', posible ciclo", name)
+			}
+
+			target, err := sb.LinkTarget(path, childInode)
+			if err != nil {
+				return -1, err
+			}
+			fmt.Printf("Siguiendo el enlace %s -> %s\n", name, target)
+
+			// Las rutas absolutas parten de la raíz, las relativas de la carpeta del enlace
+			start := current
+			if strings.HasPrefix(target, "/") {
+				start = 0
+			}
+			child, err = sb.resolveComponents(path, start, strings.Split(target, "/"), true, hops)
+			if err != nil {
+				return -1, err
+			}
+			if child == -1 {
+				return -1, nil
+			}
+		}
+
+		current = child
+	}
+	return current, nil
+}
+
+// parentFolder devuelve el inodo al que apunta la entrada .. de una carpeta
+func (sb *SuperBlock) parentFolder(path string, inode *Inode) (int32, error) {
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return -1, err
+	}
+	if len(dataBlocks) == 0 {
+		return -1, nil
+	}
+
+	block := sb.NewFolderBlock()
+	err = block.Deserialize(path, int64(sb.S_block_start+(dataBlocks[0]*sb.S_block_size)))
+	if err != nil {
+		return -1, err
+	}
+	return block.B_content[1].B_inodo, nil
+}
+
+// findInFolder busca recursivamente las entradas cuyo nombre coincide con pattern (admite * y ?).
+// Entra a las carpetas apuntadas por enlaces; visited evita recorrer dos veces la misma carpeta en un ciclo.
+func (sb *SuperBlock) findInFolder(path string, folderIndex int32, folderPath string, pattern string, visited map[int32]bool, results *[]string) error {
+	if visited[folderIndex] {
+		return nil
+	}
+	visited[folderIndex] = true
+
+	inode := &Inode{}
+	err := inode.Deserialize(path, int64(sb.S_inode_start+(folderIndex*sb.S_inode_size)))
+	if err != nil {
+		return err
+	}
+
+	dataBlocks, err := sb.InodeDataBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+	for _, blockIndex := range dataBlocks {
+		block := sb.NewFolderBlock()
+		err := block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
+		if err != nil {
+			return err
+		}
+
+		// Desde el index 2 porque los primeros dos son . y ..
+		for indexContent := 2; indexContent < len(block.B_content); indexContent++ {
+			content := block.B_content[indexContent]
+			if content.B_inodo == -1 {
+				continue
+			}
+			contentName, err := sb.EntryName(path, content)
+			if err != nil {
+				return err
+			}
+			contentName = strings.Trim(contentName, "\x00 ")
+			childPath := strings.TrimSuffix(folderPath, "/") + "/" + contentName
+
+			matched, err := pathpkg.Match(pattern, contentName)
+			if err != nil {
+				return fmt.Errorf("patrón de búsqueda inválido: %w", err)
+			}
+			if matched {
+				*results = append(*results, childPath)
+			}
+
+			// Resolver el hijo siguiendo enlaces para saber si hay que entrar
+			hops := 0
+			childIndex, err := sb.resolveComponents(path, folderIndex, []string{contentName}, true, &hops)
+			if err != nil {
+				fmt.Printf("No se sigue %s: %v\n", childPath, err)
+				continue
+			}
+			if childIndex == -1 {
+				continue
+			}
+			childInode := &Inode{}
+			err = childInode.Deserialize(path, int64(sb.S_inode_start+(childIndex*sb.S_inode_size)))
+			if err != nil {
+				return err
+			}
+			if childInode.I_type[0] != '0' {
+				continue
+			}
+			err = sb.findInFolder(path, childIndex, childPath, pattern, visited, results)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package structures
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Transaction holds back every write to an EXT3 partition while a command runs. Reads see the
+// pending writes, so the command works as usual; on Commit the writes are logged in the journal and
+// only then applied in place, and on Abort they are dropped and the partition is left untouched.
+
+// Transaction is the set of pending writes of one command.
+type Transaction struct {
+	path         string
+	sb           *SuperBlock
+	start        int64 // partition bounds
+	end          int64
+	journalStart int64 // writes to the journal itself go straight to the device
+	journalEnd   int64
+	info         Information
+	payloads     []string       // full content of every file written, logged before the updates
+	pending      map[int64]byte // last byte written at each offset
+}
+
+// transactionKey identifies a partition: partitions of the same disk can have a transaction open at the same time
+type transactionKey struct {
+	path  string
+	start int64
+}
+
+var (
+	transactionsMu sync.Mutex
+	transactions   = make(map[transactionKey]*Transaction)
+)
+
+// BeginTransaction starts buffering the writes to the partition of the disk at path.
+// There can be a single transaction per partition at a time.
+func (sb *SuperBlock) BeginTransaction(path string, partition *Partition, info Information) (*Transaction, error) {
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+
+	key := transactionKey{path: path, start: int64(partition.Part_start)}
+	if _, ok := transactions[key]; ok {
+		return nil, fmt.Errorf("ya hay una transacción en curso en la partición del byte %d del disco %s", partition.Part_start, path)
+	}
+	tx := &Transaction{
+		path:         path,
+		sb:           sb,
+		start:        int64(partition.Part_start),
+		end:          int64(partition.Part_start) + int64(partition.Part_size),
+		journalStart: sb.JournalStart(),
+		journalEnd:   int64(sb.S_bm_inode_start),
+		info:         info,
+		pending:      make(map[int64]byte),
+	}
+	transactions[key] = tx
+	return tx, nil
+}
+
+// activeTransaction returns the transaction that must hold a write at offset, if any.
+func activeTransaction(path string, offset int64) *Transaction {
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+	return transactionAt(path, offset)
+}
+
+// transactionAt returns the transaction of the partition of path that contains offset, outside of its journal.
+// The caller holds transactionsMu.
+func transactionAt(path string, offset int64) *Transaction {
+	for key, tx := range transactions {
+		if key.path == path && offset >= tx.start && offset < tx.end && (offset < tx.journalStart || offset >= tx.journalEnd) {
+			return tx
+		}
+	}
+	return nil
+}
+
+// recordPayload keeps the full content of a file written while a transaction is open on the partition that
+// contains offset.
+func recordPayload(path string, offset int64, content string) {
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+	if tx := transactionAt(path, offset); tx != nil {
+		tx.payloads = append(tx.payloads, content)
+	}
+}
+
+// payloadRecords splits the payloads in journal records; a payload spans as many records as it needs.
+func (tx *Transaction) payloadRecords() []Journal {
+	records := []Journal{}
+	for index, payload := range tx.payloads {
+		for start := 0; start == 0 || start < len(payload); start += len(Journal{}.J_data) {
+			record := Journal{J_type: [1]byte{JournalPayload}, J_offset: int64(index)}
+			record.J_length = int32(copy(record.J_data[:], payload[start:]))
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// bufferWrite keeps p as a pending write of the transaction instead of writing it to the device.
+func (tx *Transaction) bufferWrite(p []byte, off int64) {
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+	for i, b := range p {
+		tx.pending[off+int64(i)] = b
+	}
+}
+
+// overlay copies over p the pending writes that fall inside [off, off+len(p)).
+func (tx *Transaction) overlay(p []byte, off int64) {
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+
+	if len(tx.pending) < len(p) {
+		for position, b := range tx.pending {
+			if position >= off && position < off+int64(len(p)) {
+				p[position-off] = b
+			}
+		}
+		return
+	}
+	for i := range p {
+		if b, ok := tx.pending[off+int64(i)]; ok {
+			p[i] = b
+		}
+	}
+}
+
+// finish unregisters the transaction so later writes go straight to the device.
+func (tx *Transaction) finish() {
+	transactionsMu.Lock()
+	defer transactionsMu.Unlock()
+	key := transactionKey{path: tx.path, start: tx.start}
+	if transactions[key] == tx {
+		delete(transactions, key)
+	}
+}
+
+// Abort drops the pending writes.
+func (tx *Transaction) Abort() {
+	tx.finish()
+	fmt.Printf("Transacción %s descartada\n", tx.info.Operation())
+}
+
+// updates groups the pending writes in runs of consecutive bytes that fit in one journal record.
+func (tx *Transaction) updates() []Journal {
+	offsets := make([]int64, 0, len(tx.pending))
+	for offset := range tx.pending {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	records := []Journal{}
+	for _, offset := range offsets {
+		last := len(records) - 1
+		if last >= 0 {
+			record := &records[last]
+			if record.J_offset+int64(record.J_length) == offset && int(record.J_length) < len(record.J_data) {
+				record.J_data[record.J_length] = tx.pending[offset]
+				record.J_length++
+				continue
+			}
+		}
+		record := Journal{J_type: [1]byte{JournalUpdate}, J_offset: offset, J_length: 1}
+		record.J_data[0] = tx.pending[offset]
+		records = append(records, record)
+	}
+	return records
+}
+
+// Commit logs the pending writes in the journal (begin, payloads, updates and commit), applies them in place
+// and marks the commit as applied. When the ring has no room the oldest transactions are checkpointed.
+// A command is a single transaction: if it does not fit in the whole ring it fails and nothing is written.
+func (tx *Transaction) Commit() error {
+	tx.finish()
+	if len(tx.pending) == 0 {
+		return nil
+	}
+
+	js, err := tx.sb.ReadJournalSuperBlock(tx.path)
+	if err != nil {
+		return err
+	}
+	records := append(tx.payloadRecords(), tx.updates()...)
+	needed := int32(len(records)) + 2
+	if needed > js.JS_slots {
+		return fmt.Errorf("la operación %s necesita %d registros del journal y el journal de la partición solo tiene %d, no se escribió nada",
+			tx.info.Operation(), needed, js.JS_slots)
+	}
+	if js.Free() < needed {
+		_, err := tx.sb.CheckpointJournal(tx.path, needed)
+		if err != nil {
+			return fmt.Errorf("error al hacer checkpoint del journal: %w", err)
+		}
+		js, err = tx.sb.ReadJournalSuperBlock(tx.path)
+		if err != nil {
+			return err
+		}
+		if js.Free() < needed {
+			return fmt.Errorf("el journal está lleno, la operación necesita %d registros y quedan %d", needed, js.Free())
+		}
+	}
+
+	sequence := js.JS_sequence
+	number := js.JS_transaction
+	begin := Journal{J_type: [1]byte{JournalBegin}, J_offset: time.Now().Unix()}
+	err = begin.setInformation(tx.info)
+	if err != nil {
+		return err
+	}
+	records = append([]Journal{begin}, records...)
+	records = append(records, Journal{J_type: [1]byte{JournalCommit}})
+
+	for i := range records {
+		records[i].J_count = sequence + int32(i)
+		records[i].J_tx = number
+		err := records[i].Serialize(tx.path, tx.sb.journalSlotOffset((js.JS_tail+int32(i))%js.JS_slots))
+		if err != nil {
+			return fmt.Errorf("error al escribir el journal: %w", err)
+		}
+	}
+	err = SyncDevice(tx.path)
+	if err != nil {
+		return err
+	}
+
+	// Moving the tail is the commit point: if the command stops before it the records are ignored
+	commitSlot := (js.JS_tail + needed - 1) % js.JS_slots
+	js.JS_tail = (js.JS_tail + needed) % js.JS_slots
+	js.JS_used += needed
+	js.JS_sequence += needed
+	js.JS_transaction++
+	err = js.Serialize(tx.path, tx.sb.JournalStart())
+	if err != nil {
+		return err
+	}
+	err = SyncDevice(tx.path)
+	if err != nil {
+		return err
+	}
+
+	// Then the changes in place: if the command stops here mount redoes them from the journal
+	applied := 0
+	for _, update := range records {
+		if update.J_type[0] != JournalUpdate {
+			continue
+		}
+		err := DeviceWriteAt(tx.path, update.J_data[:update.J_length], update.J_offset)
+		if err != nil {
+			return err
+		}
+		applied++
+	}
+	err = tx.sb.markApplied(tx.path, commitSlot)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Transacción %d (%s) confirmada con %d actualizaciones\n", number, tx.info.Operation(), applied)
+	return nil
+}
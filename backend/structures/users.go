@@ -93,7 +93,7 @@ func (sb *SuperBlock) loginUserInInode(user string, password string, path string
 }
 
 func (sb *SuperBlock) createGroupInInode(name string, path string) error {
-	useBlock := &FileBlock{}
+	useBlock := sb.NewFileBlock()
 
 	// Deserializar el bloque
 	err := useBlock.Deserialize(path, int64(sb.S_block_start+(1*sb.S_block_size)))
@@ -164,7 +164,7 @@ func (sb *SuperBlock) createGroupInInode(name string, path string) error {
 }
 
 func (sb *SuperBlock) removeGroupInInode(name string, path string) error {
-	userBlock := &FileBlock{}
+	userBlock := sb.NewFileBlock()
 
 	// Deserializar el bloque
 	err := userBlock.Deserialize(path, int64(sb.S_block_start+(1*sb.S_block_size)))
@@ -262,7 +262,7 @@ func (sb *SuperBlock) removeGroupInInode(name string, path string) error {
 }
 
 func (sb *SuperBlock) createUserInInode(user string, pass string, grp string, path string) error {
-	userBlock := &FileBlock{}
+	userBlock := sb.NewFileBlock()
 
 	// Deserializar el bloque
 	err := userBlock.Deserialize(path, int64(sb.S_block_start+(1*sb.S_block_size)))
@@ -352,7 +352,7 @@ func (sb *SuperBlock) createUserInInode(user string, pass string, grp string, pa
 }
 
 func (sb *SuperBlock) removeUserInInode(user string, path string) error {
-	userBlock := &FileBlock{}
+	userBlock := sb.NewFileBlock()
 
 	// Deserializar el bloque
 	err := userBlock.Deserialize(path, int64(sb.S_block_start+(1*sb.S_block_size)))
@@ -446,7 +446,7 @@ func (sb *SuperBlock) removeUserInInode(user string, path string) error {
 }
 
 func (sb *SuperBlock) changeGroupInInode(user string, group string, path string) error {
-	userBlock := &FileBlock{}
+	userBlock := sb.NewFileBlock()
 	// Deserializar el bloque
 	err := userBlock.Deserialize(path, int64(sb.S_block_start+(1*sb.S_block_size)))
 	if err != nil {
@@ -561,31 +561,12 @@ func (sb *SuperBlock) getUsersContent(path string) string {
 	fmt.Println("Inodo de users.txt")
 	inode.Print()
 
-	// iterar sobre cada bloque del inodo y obtener el contenido
-	contentBlocks := "" // para almacenar el contenido de los bloques
-	for _, blockIndex := range inode.I_block {
-		if blockIndex == -1 {
-			break
-		}
-
-		// crear un nuevo bloque de archivo
-		block := &FileBlock{}
-		// deserializar el bloque
-		err = block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
-		if err != nil {
-			return ""
-		}
-
-		fmt.Println("Bloque de archivo: ", blockIndex)
-		block.Print()
-		// convertir el bloque a string
-		blockContent := string(block.B_content[:])
-		fmt.Println("Contenido del bloque: ", blockContent)
-		// agregar el contenido del bloque al contenido total
-		contentBlocks += blockContent
-		fmt.Println("Contenido total: ", contentBlocks)
-
+	// obtener el contenido de todos los bloques del inodo (directos e indirectos)
+	contentBlocks, err := sb.ReadInodeContent(path, inode)
+	if err != nil {
+		return ""
 	}
+	fmt.Println("Contenido total: ", contentBlocks)
 
 	// returnar el contenido
 	return contentBlocks
@@ -593,11 +574,8 @@ func (sb *SuperBlock) getUsersContent(path string) string {
 
 // funcion para setear el contenido de users.txt
 func (sb *SuperBlock) setUsersContent(path string, content string) error {
-	fmt.Println("Proximo bloque disponible: ", sb.S_blocks_count)
+	fmt.Println("Bloques en uso: ", sb.S_blocks_count)
 	// obtener el inodo para users.txt, este siempre será el inodo 1
-	// dividir el contenido en bloques de 64 bytes - contentDivide
-	// iterar sobre cada bloque del inodo y settear el contenido
-	// si len(contentDivide) > bloques utilizados, entonces se necesita crear nuevo bloque para alamacenar el contenido
 	inode := &Inode{}
 	err := inode.Deserialize(path, int64(sb.S_inode_start+(1*sb.S_inode_size)))
 	if err != nil {
@@ -606,88 +584,15 @@ func (sb *SuperBlock) setUsersContent(path string, content string) error {
 
 	fmt.Println("Inodo de users.txt")
 	inode.Print()
-	// obtener el contenido de los bloques del inodo
-
-	// crear un arreglo de bloques que almacene el contenido de 64 bytes
-	contentBlocks := make([]string, 0)
-	for i := 0; i < len(content); i += 64 {
-		if i+64 > len(content) {
-			contentBlocks = append(contentBlocks, content[i:])
-		} else {
-			contentBlocks = append(contentBlocks, content[i:i+64])
-		}
-	}
-
-	fmt.Println("Contenido de los bloques: ", contentBlocks)
-
-	// iterar sobre cada bloque del inodo y settear el contenido
-	for i, blockIndex := range inode.I_block {
-		fmt.Println("Bloque: ", blockIndex)
-		// a cada bloque del inodo se le asigna el contenido de 64 bytes
-		if blockIndex == -1 {
-			if len(contentBlocks) > i {
-				// todavia hay contenido por asignar pero el bloque no existe
-				// actualizar el inodo para que apunte al nuevo bloque
-				inode.I_block[i] = sb.S_blocks_count
-				// serializar el inodo
-				err = inode.Serialize(path, int64(sb.S_inode_start+(1*sb.S_inode_size)))
-				if err != nil {
-					return err
-				}
-
-				newBlock := &FileBlock{
-					B_content: [64]byte{},
-				}
-				copy(newBlock.B_content[:], contentBlocks[i])
-				fmt.Println("Bloque de archivo: ", blockIndex)
-				// serializar el bloque
-				err = newBlock.Serialize(path, int64(sb.S_block_start+(sb.S_blocks_count*sb.S_block_size)))
-				if err != nil {
-					return err
-				}
 
-				err = sb.UpdateBitmapBlock(path)
-				if err != nil {
-					return err
-				}
-
-				// Actualizamos el superbloque
-				sb.S_blocks_count++
-				sb.S_free_blocks_count--
-				sb.S_first_blo += sb.S_block_size
-				break
-
-			}
-			break
-		}
-
-		// obtener el bloque
-		block := &FileBlock{}
-		// deserializar el bloque
-		err = block.Deserialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
-		if err != nil {
-			return err
-		}
-
-		// limpiar el bloque
-		for j := range block.B_content {
-			block.B_content[j] = 0
-		}
-
-		contentBytes := []byte(contentBlocks[i])
-		// Copiar byte por byte el nuevo contenido
-		for i := 0; i < len(contentBytes); i++ {
-			block.B_content[i] = contentBytes[i]
-		}
-		fmt.Println("Bloque después de actualizar: ", string(block.B_content[:]))
-		// serializar el bloque
-		err = block.Serialize(path, int64(sb.S_block_start+(blockIndex*sb.S_block_size)))
-		if err != nil {
-			return err
-		}
+	// escribir el contenido, reservando o liberando bloques según haga falta
+	err = sb.writeInodeContent(path, inode, content)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	// serializar el inodo
+	return inode.Serialize(path, int64(sb.S_inode_start+(1*sb.S_inode_size)))
 }
 
 // función para obtener el uid y gid de un usuario por el nombre
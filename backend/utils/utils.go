@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 // convert a size and unit to bytes
@@ -52,8 +53,14 @@ var pathToLetter = make(map[string]string)
 
 var nextLetterIndex = 0
 
+// lettersMu guards the letter assignments, since disks on different requests can be mounted at the same time
+var lettersMu sync.Mutex
+
 // get the letter assigned to a path
 func GetLetter(path string) (string, error) {
+	lettersMu.Lock()
+	defer lettersMu.Unlock()
+
 	// Assign a letter to the path if it does not have one
 	if _, exists := pathToLetter[path]; !exists {
 		if nextLetterIndex < len(alphabet) {
@@ -68,6 +75,28 @@ func GetLetter(path string) (string, error) {
 	return pathToLetter[path], nil
 }
 
+// Letters returns a copy of the letters assigned to the disks and the index of the next free letter
+func Letters() (map[string]string, int) {
+	lettersMu.Lock()
+	defer lettersMu.Unlock()
+	letters := make(map[string]string, len(pathToLetter))
+	for path, letter := range pathToLetter {
+		letters[path] = letter
+	}
+	return letters, nextLetterIndex
+}
+
+// RestoreLetters replaces the letter assignments, so the ids of the mounted partitions keep their meaning
+func RestoreLetters(letters map[string]string, next int) {
+	lettersMu.Lock()
+	defer lettersMu.Unlock()
+	pathToLetter = make(map[string]string, len(letters))
+	for path, letter := range letters {
+		pathToLetter[path] = letter
+	}
+	nextLetterIndex = next
+}
+
 // createParentDirs crea las carpetas padre si no existen
 func CreateParentDirs(path string) error {
 	dir := filepath.Dir(path)
@@ -52,8 +52,8 @@ export default function FileSystemSimulator() {
 
         const output = response.data.output;
 
-        // verificar si se ejecuto el comando journaling
-        if (command.includes("journaling")) {
+        // verificar si se ejecuto el comando journaling (-status y -checkpoint devuelven texto, -until un árbol)
+        if (command.includes("journaling") && !command.includes("-until") && output.trim().startsWith("[")) {
           console.log("se ejecuto el comando journaling");
           // convertir el output en json
           const jsonData = JSON.parse(output);
@@ -1,14 +1,15 @@
 'use client'
 import React, { useEffect, useState } from 'react'
 import api from '@/lib/api';
-import { FaFolder, FaFolderOpen, FaFile, FaChevronRight, FaChevronDown } from 'react-icons/fa';
+import { FaFolder, FaFolderOpen, FaFile, FaLink, FaChevronRight, FaChevronDown } from 'react-icons/fa';
 import Image from 'next/image';
 import { Button } from './ui/button';
 import { useRouter } from 'next/navigation';
 
 interface FSNode {
     name: string;
-    type: number;
+    type: number;  // 0: carpeta, 1: archivo, 2: enlace simbólico
+    target?: string;  // Ruta a la que apunta un enlace simbólico
     content: FSNode[] | string[];
 }
 
@@ -31,7 +32,7 @@ interface Disk {
 }
 
 interface SelectedItem {
-    type: 'disk' | 'partition' | 'file' | 'folder';
+    type: 'disk' | 'partition' | 'file' | 'folder' | 'link';
     data: any;
     path: string[];
     nodeData?: FSNode;  // Nuevo campo para almacenar los datos del nodo FSNode
@@ -65,14 +66,14 @@ const FileExplorer = () => {
         }));
     };
 
-    const handleItemClick = (type: 'disk' | 'partition' | 'file' | 'folder', data: any, path: string[], nodeData?: FSNode) => {
+    const handleItemClick = (type: 'disk' | 'partition' | 'file' | 'folder' | 'link', data: any, path: string[], nodeData?: FSNode) => {
         setSelectedItem({ type, data, path, nodeData });
     };
 
     interface TreeItem {
         id: string;
         name: string;
-        type: 'disk' | 'partition' | 'file' | 'folder';
+        type: 'disk' | 'partition' | 'file' | 'folder' | 'link';
         icon: React.ReactNode;
         size?: string;
         children?: React.ReactNode;
@@ -153,16 +154,19 @@ const FileExplorer = () => {
     const renderFsNodes = (node: FSNode, parentPath: string): React.ReactNode => {
         const nodePath = `${parentPath}/${node.name}`;
         const isDirectory = node.type === 0;
+        const isLink = node.type === 2;
 
         return renderTreeItem({
             id: nodePath,
-            name: node.name,
-            type: isDirectory ? 'folder' : 'file',
+            name: isLink ? `${node.name} -> ${node.target}` : node.name,
+            type: isDirectory ? 'folder' : isLink ? 'link' : 'file',
             icon: isDirectory ? 
                 (expandedItems[nodePath] ? 
                     <FaFolderOpen className="text-yellow-500" /> : 
                     <FaFolder className="text-yellow-500" />) : 
-                <FaFile className="text-blue-400" />,
+                isLink ? 
+                    <FaLink className="text-purple-500" /> : 
+                    <FaFile className="text-blue-400" />,
             children: expandedItems[nodePath] && isDirectory && Array.isArray(node.content) && (
                 <div>
                     {(node.content as FSNode[]).map((child, index) => 
@@ -290,6 +294,20 @@ const FileExplorer = () => {
                     </div>
                 );
 
+            case 'link':
+                // Un enlace simbólico solo guarda la ruta a la que apunta
+                return (
+                    <div className="p-4">
+                        <h3 className="text-lg font-bold mb-4">Enlace {selectedItem.nodeData?.name}</h3>
+                        <div className="grid grid-cols-2 gap-4">
+                            <div>
+                                <p className="font-medium">Apunta a:</p>
+                                <p className="break-all">{selectedItem.nodeData?.target}</p>
+                            </div>
+                        </div>
+                    </div>
+                );
+
             default:
                 return null;
         }
@@ -1,5 +1,7 @@
 import axios from 'axios';
 
+const TOKEN_KEY = 'sessionToken';
+
 const api = axios.create({
   baseURL: 'http://localhost:8000',
   headers: {
@@ -7,4 +9,25 @@ const api = axios.create({
   },
 });
 
+// Each browser keeps its own session: the token from login goes back on every request
+api.interceptors.request.use((config) => {
+  const token = localStorage.getItem(TOKEN_KEY);
+  if (token) {
+    config.headers['X-Session-Token'] = token;
+  }
+  return config;
+});
+
+api.interceptors.response.use((response) => {
+  if (response.config.url === '/execute') {
+    const token = response.data?.token;
+    if (token) {
+      localStorage.setItem(TOKEN_KEY, token);
+    } else {
+      localStorage.removeItem(TOKEN_KEY);
+    }
+  }
+  return response;
+});
+
 export default api;